@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+	"sync"
+)
+
+// ABArmConfig описывает вариант (плечо) A/B-эксперимента. Незаданные параметры берутся из
+// основной конфигурации канала.
+type ABArmConfig struct {
+	Name   string        `json:"name"`   // Имя варианта в статистике
+	Weight float64       `json:"weight"` // Доля трафика (относительно суммы весов); по умолчанию 1
+	Codec  string        `json:"codec"`  // Кодек варианта (по умолчанию — кодек канала)
+	Stages []StageConfig `json:"stages"` // Этапы обработки потока (не задано — как у канала; [] — без этапов)
+	// Decision — режим решений варианта (hard/soft; по умолчанию — как у канала), например для
+	// измерения выигрыша мягкого декодирования
+	Decision string `json:"decision"`
+}
+
+// ABArmStats — статистика итогов сегментов, обработанных вариантом эксперимента.
+type ABArmStats struct {
+	Name       string            `json:"name"`
+	Codec      string            `json:"codec"`
+	Stages     []string          `json:"stages,omitempty"`
+	Decision   string            `json:"decision,omitempty"`
+	Weight     float64           `json:"weight"`
+	Total      StatsCounters     `json:"total"`
+	Efficiency *CodingEfficiency `json:"efficiency,omitempty"`
+}
+
+// abArm — вариант эксперимента с собственным экземпляром канала.
+type abArm struct {
+	name    string
+	weight  float64
+	channel *ChannelLayer
+	mu      sync.Mutex
+	total   StatsCounters
+}
+
+// ABSplit распределяет принятые сегменты между вариантами канала и ведет статистику итогов
+// по каждому варианту. Вариант выбирается по хешу (sender, send_time, segment_number), поэтому
+// повторные передачи сегмента попадают в тот же вариант. Все варианты используют одну модель
+// искажений (цепочку из конфигурации с вероятностями P и R канала), различаясь кодированием.
+// Все методы допускают вызов на nil (эксперимент отключен).
+type ABSplit struct {
+	arms        []*abArm
+	totalWeight float64
+}
+
+// NewABSplit создает варианты эксперимента на основе настроенного канала base.
+func NewABSplit(arms []ABArmConfig, base *ChannelLayer, impairments []ImpairmentConfig) (*ABSplit, error) {
+	if len(arms) < 2 {
+		return nil, fmt.Errorf("для A/B-эксперимента нужно не менее двух вариантов, задано %d", len(arms))
+	}
+	split := &ABSplit{}
+	names := make(map[string]bool)
+	for i, cfg := range arms {
+		if cfg.Name == "" || names[cfg.Name] {
+			return nil, fmt.Errorf("вариант %d: имя должно быть непустым и уникальным", i+1)
+		}
+		names[cfg.Name] = true
+		weight := cfg.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("вариант %s: вес не может быть отрицательным", cfg.Name)
+		}
+		cl := &ChannelLayer{
+			ErrorProbability: base.ErrorProbability,
+			LossProbability:  base.LossProbability,
+			Coder:            base.Coder,
+			FCS:              base.FCS,
+			Puncture:         base.Puncture,
+			Scrambler:        base.Scrambler,
+			Shortening:       base.Shortening,
+			Retries:          base.Retries,
+			HARQ:             base.HARQ,
+			Decision:         base.Decision,
+			Stages:           base.Stages,
+			Bitrate:          base.Bitrate,
+			PropagationDelay: base.PropagationDelay,
+			link:             base.link,
+			medium:           base.medium,
+			Impairments:      defaultImpairmentChain(),
+			Schedule:         base.Schedule,
+			Outage:           base.Outage,
+			PathLoss:         base.PathLoss,
+		}
+		// Генератор варианта выводится из генератора канала, чтобы прогон воспроизводился по одному seed
+		baseSeed, _ := base.RandState()
+		cl.source = newLockedSource(baseSeed + int64(i) + 1)
+		cl.rng = rand.New(cl.source)
+		var err error
+		if cfg.Codec != "" {
+			if cl.Coder, err = LookupCoder(cfg.Codec); err != nil {
+				return nil, fmt.Errorf("вариант %s: %w", cfg.Name, err)
+			}
+		}
+		if cfg.Stages != nil {
+			if cl.Stages, err = NewStreamStages(cfg.Stages); err != nil {
+				return nil, fmt.Errorf("вариант %s: %w", cfg.Name, err)
+			}
+		}
+		if cfg.Decision != "" {
+			cl.Decision = cfg.Decision
+		}
+		if err := validateDecision(cl.Decision, cl.Stages); err != nil {
+			return nil, fmt.Errorf("вариант %s: %w", cfg.Name, err)
+		}
+		if len(impairments) > 0 {
+			if cl.Impairments, err = NewImpairmentChain(impairments); err != nil {
+				return nil, fmt.Errorf("вариант %s: %w", cfg.Name, err)
+			}
+			if err := cl.Impairments.CheckBitrate(cl.Bitrate); err != nil {
+				return nil, fmt.Errorf("вариант %s: %w", cfg.Name, err)
+			}
+		}
+		split.arms = append(split.arms, &abArm{name: cfg.Name, weight: weight, channel: cl})
+		split.totalWeight += weight
+	}
+	if split.totalWeight <= 0 {
+		return nil, fmt.Errorf("сумма весов вариантов должна быть положительной")
+	}
+	return split, nil
+}
+
+// Describe возвращает описание вариантов для журнала.
+func (s *ABSplit) Describe() string {
+	parts := make([]string, 0, len(s.arms))
+	for _, arm := range s.arms {
+		desc := fmt.Sprintf("%s (вес %g, кодек %s", arm.name, arm.weight, arm.channel.Coder.Name())
+		for _, stage := range arm.channel.Stages {
+			desc += ", " + stage.Name()
+		}
+		if arm.channel.Decision == DecisionSoft {
+			desc += ", мягкие решения"
+		}
+		parts = append(parts, desc+")")
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Pick выбирает вариант для сегмента. Возвращает имя варианта и его канал
+// (пустое имя и channelLayer, если эксперимент отключен).
+func (s *ABSplit) Pick(sender, sendTime string, segmentNumber int) (string, *ChannelLayer) {
+	if s == nil {
+		return "", channelLayer
+	}
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s|%s|%d", sender, sendTime, segmentNumber)
+	point := float64(h.Sum64()%1_000_000) / 1_000_000 * s.totalWeight
+	for _, arm := range s.arms {
+		if point < arm.weight {
+			return arm.name, arm.channel
+		}
+		point -= arm.weight
+	}
+	last := s.arms[len(s.arms)-1]
+	return last.name, last.channel
+}
+
+// Reseed перезапускает генераторы вариантов с начальными значениями, выведенными из seed канала.
+func (s *ABSplit) Reseed(seed int64) {
+	if s == nil {
+		return
+	}
+	for i, arm := range s.arms {
+		arm.channel.Reseed(seed + int64(i) + 1)
+	}
+}
+
+// Record учитывает итог сегмента в статистике варианта name.
+func (s *ABSplit) Record(name string, rec SegmentOutcomeRecord) {
+	if s == nil {
+		return
+	}
+	for _, arm := range s.arms {
+		if arm.name == name {
+			arm.mu.Lock()
+			arm.total.add(rec)
+			arm.mu.Unlock()
+			return
+		}
+	}
+}
+
+// Stats возвращает статистику вариантов эксперимента.
+func (s *ABSplit) Stats() []ABArmStats {
+	if s == nil {
+		return nil
+	}
+	stats := make([]ABArmStats, 0, len(s.arms))
+	for _, arm := range s.arms {
+		arm.mu.Lock()
+		total := arm.total
+		arm.mu.Unlock()
+		armStats := ABArmStats{
+			Name:       arm.name,
+			Codec:      arm.channel.Coder.Name(),
+			Decision:   arm.channel.Decision,
+			Weight:     arm.weight,
+			Total:      total,
+			Efficiency: total.Efficiency(),
+		}
+		for _, stage := range arm.channel.Stages {
+			armStats.Stages = append(armStats.Stages, stage.Name())
+		}
+		stats = append(stats, armStats)
+	}
+	return stats
+}
+
+var abSplit *ABSplit // Глобальный A/B-эксперимент (nil, если отключен)
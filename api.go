@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Версии формата ответов /code.
+//
+// Версия 1 (исходная): потеря сегмента — 408, неисправимая ошибка канала — 500, как и сбой пересылки,
+// поэтому клиенты путают итоги моделирования канала с ошибками сервера.
+//
+// Версия 2: итог моделирования канала — штатный результат, а не ошибка. Потерянный сегмент и сегмент
+// с неисправимой ошибкой возвращаются со статусом 200 и полем outcome ("lost" или "channel_error")
+// и delivered=false; сбой пересылки на /transfer возвращается как 502. Ответ всегда имеет одну
+// структуру (SegmentResponseV2), а итог дублируется в заголовке X-Segment-Outcome.
+const (
+	APIVersion1 = 1
+	APIVersion2 = 2
+
+	APIVersionHeader     = "X-API-Version"     // Заголовок запроса (и ответа) с версией формата ответа
+	SegmentOutcomeHeader = "X-Segment-Outcome" // Заголовок ответа версии 2 с итогом обработки
+)
+
+var defaultAPIVersion = APIVersion1 // Версия для запросов без явного указания
+
+// requestedAPIVersion определяет версию формата ответа по заголовку X-API-Version
+// или параметру ?api_version=; без них используется версия по умолчанию.
+func requestedAPIVersion(r *http.Request) (int, error) {
+	v := r.Header.Get(APIVersionHeader)
+	if v == "" {
+		v = r.URL.Query().Get("api_version")
+	}
+	if v == "" {
+		return defaultAPIVersion, nil
+	}
+	version, err := strconv.Atoi(v)
+	if err != nil || version < APIVersion1 || version > APIVersion2 {
+		return 0, fmt.Errorf("неподдерживаемая версия API '%s' (допустимо: %d, %d)", v, APIVersion1, APIVersion2)
+	}
+	return version, nil
+}
+
+// SegmentResponseV2 — ответ /code версии 2.
+type SegmentResponseV2 struct {
+	SegmentID      string       `json:"segment_id"`
+	Outcome        string       `json:"outcome"`   // Итог обработки (см. Outcome*)
+	Delivered      bool         `json:"delivered"` // Передан ли сегмент транспортному уровню (в том числе ранее, для дубликатов)
+	Status         string       `json:"status,omitempty"`
+	Error          string       `json:"error,omitempty"`
+	TransferStatus string       `json:"transfer_status,omitempty"`
+	TransferBody   string       `json:"transfer_response_body,omitempty"`
+	Duplicate      bool         `json:"duplicate,omitempty"`
+	Coding         *CodingStats `json:"coding,omitempty"` // Накладные расходы кодирования сегмента
+}
+
+// statusCodeV2 возвращает HTTP статус ответа версии 2 для итога обработки.
+func statusCodeV2(result SegmentResult) int {
+	switch result.Outcome {
+	case OutcomeLost, OutcomeChannelError:
+		return http.StatusOK
+	case OutcomeForwardFailed:
+		return http.StatusBadGateway
+	default:
+		return result.StatusCode
+	}
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const AdminARQEndpoint = "/admin/arq" // Конечная точка настроек таймера и окна ARQ
+
+// Режимы автоматического запроса повторной передачи (arq.mode).
+const (
+	ARQStopAndWait = "stop_and_wait" // Передача с ожиданием подтверждения каждого кадра
+	ARQGoBackN     = "go_back_n"     // Окно из N кадров, после ошибки повторяется все окно
+)
+
+const (
+	defaultARQTimeout            = 200 * time.Millisecond // Время ожидания подтверждения по умолчанию
+	defaultARQMaxRetransmissions = 5                      // Число повторных передач кадра по умолчанию
+	defaultARQWindow             = 7                      // Размер окна Go-Back-N по умолчанию
+)
+
+// ARQConfig задает автоматический запрос повторной передачи между канальным и транспортным уровнями.
+type ARQConfig struct {
+	Mode               string   `json:"mode"`                // Режим (см. ARQ*); пустая строка отключает ARQ
+	Timeout            Duration `json:"timeout"`             // Время ожидания ACK/NAK передатчиком (по умолчанию 200 мс)
+	MaxRetransmissions int      `json:"max_retransmissions"` // Число повторных передач кадра (по умолчанию 5)
+	AckLoss            float64  `json:"ack_loss"`            // Вероятность потери управляющего кадра ACK или NAK
+	Window             int      `json:"window"`              // Размер окна Go-Back-N в кадрах (по умолчанию 7)
+}
+
+// ARQRecord — передачи кадра сегмента по протоколу ARQ.
+type ARQRecord struct {
+	Transmissions int `json:"transmissions"`          // Число передач кадра (первая и повторные)
+	Timeouts      int `json:"timeouts,omitempty"`     // Повторные передачи по истечении времени ожидания
+	Naks          int `json:"naks,omitempty"`         // Повторные передачи по NAK приемника
+	ControlLost   int `json:"control_lost,omitempty"` // Потерянные управляющие кадры ACK и NAK
+	Duplicates    int `json:"duplicates,omitempty"`   // Повторные копии принятого кадра, отброшенные приемником
+	OutOfOrder    int `json:"out_of_order,omitempty"` // Копии, отброшенные приемником Go-Back-N до приема предыдущих кадров
+}
+
+// ARQStats — статистика ARQ для /stats.
+type ARQStats struct {
+	Mode               string   `json:"mode"`
+	Timeout            Duration `json:"timeout"`             // Действующее время ожидания подтверждения (см. /admin/arq)
+	MaxRetransmissions int      `json:"max_retransmissions"` // Действующее число повторных передач кадра
+	Frames             int64    `json:"frames"`              // Кадров сегментов, переданных по протоколу
+	Transmissions      int64    `json:"transmissions"`       // Всего передач кадров
+	Retransmissions    int64    `json:"retransmissions"`     // Повторных передач
+	Timeouts           int64    `json:"timeouts"`            // Истечений времени ожидания подтверждения
+	Naks               int64    `json:"naks"`                // Принятых NAK
+	ControlLost        int64    `json:"control_lost"`        // Потерянных ACK и NAK
+	Failed             int64    `json:"failed"`              // Кадров, не принятых после всех повторных передач
+	// TransmissionsPerFrame — среднее число передач на кадр (1 — повторных передач не было)
+	TransmissionsPerFrame float64 `json:"transmissions_per_frame"`
+	Window                int     `json:"window,omitempty"`       // Размер окна Go-Back-N
+	OutOfOrder            int64   `json:"out_of_order,omitempty"` // Копий, отброшенных приемником Go-Back-N вне очереди
+	GoBack                int64   `json:"go_back,omitempty"`      // Повторных передач из-за возврата окна Go-Back-N
+}
+
+// ARQ — автоматический запрос повторной передачи, симулируемый внутри канального уровня: приемник
+// отвечает на каждый кадр управляющим кадром ACK (кадр принят) или NAK (неисправимая ошибка),
+// а передатчик повторяет кадр по NAK или по истечении времени ожидания (кадр или подтверждение
+// потеряны). Кадр, принятый повторно из-за потери ACK, распознается приемником по порядковому биту
+// и отбрасывается, так что транспортному уровню он доставляется один раз. Методы допускают вызов
+// на nil (ARQ отключен, кадр передается один раз).
+type ARQ struct {
+	mode    string
+	ackLoss float64
+
+	// Таймер и окно настраиваются во время работы (/admin/arq); кадр передается с настройками,
+	// действовавшими в начале его передачи
+	timeout            atomic.Int64 // Время ожидания подтверждения (нс)
+	maxRetransmissions atomic.Int64
+	window             atomic.Int64
+
+	store StateStore // Состояние приемника Go-Back-N (общее для экземпляров при хранилище Redis)
+
+	mu      sync.Mutex
+	wakeups map[string]*gbnWakeup // Ожидающие сдвига окна передатчики этого экземпляра по сообщениям
+
+	frames        atomic.Int64
+	transmissions atomic.Int64
+	timeouts      atomic.Int64
+	naks          atomic.Int64
+	controlLost   atomic.Int64
+	failed        atomic.Int64
+	outOfOrder    atomic.Int64
+	goBack        atomic.Int64
+}
+
+// NewARQ создает ARQ по конфигурации (nil, если режим не задан). Состояние приемника Go-Back-N
+// хранится в store.
+func NewARQ(cfg ARQConfig, store StateStore) (*ARQ, error) {
+	if cfg.Mode == "" {
+		return nil, nil
+	}
+	if cfg.Mode != ARQStopAndWait && cfg.Mode != ARQGoBackN {
+		return nil, fmt.Errorf("неизвестный режим ARQ '%s' (допустимо: %s, %s)", cfg.Mode, ARQStopAndWait, ARQGoBackN)
+	}
+	if err := validProbability(&cfg.AckLoss); err != nil {
+		return nil, fmt.Errorf("ack_loss: %w", err)
+	}
+	if cfg.Timeout.Duration < 0 || cfg.MaxRetransmissions < 0 || cfg.Window < 0 {
+		return nil, fmt.Errorf("время ожидания и число повторных передач не могут быть отрицательными")
+	}
+	a := &ARQ{
+		mode:    cfg.Mode,
+		ackLoss: cfg.AckLoss,
+		store:   store,
+		wakeups: make(map[string]*gbnWakeup),
+	}
+	if cfg.Timeout.Duration == 0 {
+		cfg.Timeout.Duration = defaultARQTimeout
+	}
+	if cfg.MaxRetransmissions == 0 {
+		cfg.MaxRetransmissions = defaultARQMaxRetransmissions
+	}
+	if cfg.Window == 0 {
+		cfg.Window = defaultARQWindow
+	}
+	a.timeout.Store(int64(cfg.Timeout.Duration))
+	a.maxRetransmissions.Store(int64(cfg.MaxRetransmissions))
+	a.window.Store(int64(cfg.Window))
+	return a, nil
+}
+
+// ackTimeout возвращает текущее время ожидания подтверждения.
+func (a *ARQ) ackTimeout() time.Duration {
+	return time.Duration(a.timeout.Load())
+}
+
+// retransmissionLimit возвращает текущее число повторных передач кадра.
+func (a *ARQ) retransmissionLimit() int {
+	return int(a.maxRetransmissions.Load())
+}
+
+// Describe возвращает описание настроек ARQ для журнала.
+func (a *ARQ) Describe() string {
+	if a.mode == ARQGoBackN {
+		return fmt.Sprintf("%s, окно %d кадров, ожидание подтверждения %s, повторных передач до %d",
+			a.mode, a.window.Load(), a.ackTimeout(), a.retransmissionLimit())
+	}
+	return fmt.Sprintf("%s, ожидание подтверждения %s, повторных передач до %d, потеря ACK/NAK %.4f",
+		a.mode, a.ackTimeout(), a.retransmissionLimit(), a.ackLoss)
+}
+
+// Window возвращает размер окна Go-Back-N (0, если ARQ отключен или работает без окна).
+func (a *ARQ) Window() int {
+	if a == nil || a.mode != ARQGoBackN {
+		return 0
+	}
+	return int(a.window.Load())
+}
+
+// Transmit передает кадр сегмента сообщения message по каналу channel по протоколу ARQ и возвращает
+// результат приема, который доставляется транспортному уровню: первую копию кадра, принятую без
+// неисправимой ошибки, или результат последней передачи, если все передачи неудачны. window задает
+// окно Go-Back-N, согласованное с отправителем (0 — окно из конфигурации). Ожидание подтверждений
+// прерывается отменой ctx. Пока передатчик ждет сдвига окна, обработчик очереди slot возвращается очереди.
+func (a *ARQ) Transmit(ctx context.Context, channel *ChannelLayer, message string, segment *Segment, window int, slot *QueueSlot, opts ProcessOptions) (*Segment, ChannelReport) {
+	if a == nil {
+		return channel.ProcessSegmentWith(segment, opts)
+	}
+	a.frames.Add(1)
+	if a.mode == ARQGoBackN {
+		if maxWindow := a.Window(); window <= 0 || window > maxWindow {
+			window = maxWindow
+		}
+		return a.transmitGoBackN(ctx, channel, message, segment, window, slot, opts)
+	}
+	return a.transmitStopAndWait(ctx, channel, segment, opts)
+}
+
+// transmitStopAndWait передает кадр с ожиданием подтверждения после каждой передачи.
+func (a *ARQ) transmitStopAndWait(ctx context.Context, channel *ChannelLayer, segment *Segment, opts ProcessOptions) (*Segment, ChannelReport) {
+	var record ARQRecord
+	var received *Segment // Кадр, принятый без ошибки (доставляется транспортному уровню)
+	var receivedReport ChannelReport
+	var processed *Segment
+	var report ChannelReport
+	timeout, limit := a.ackTimeout(), a.retransmissionLimit()
+	for attempt := 0; attempt <= limit; attempt++ {
+		if attempt > 0 {
+			log.Printf("ChannelLayer: ARQ: повторная передача %d/%d сегмента #%d/%d",
+				attempt, limit, segment.SegmentNumber, segment.TotalSegments)
+		}
+		processed, report = channel.ProcessSegmentWith(segment, opts)
+		record.Transmissions++
+		a.transmissions.Add(1)
+
+		// Ответ приемника: ACK на принятый кадр (в том числе на повторную копию), NAK на кадр
+		// с неисправимой ошибкой, ничего — на потерянный кадр
+		var wait time.Duration
+		switch {
+		case processed == nil:
+			record.Timeouts++
+			a.timeouts.Add(1)
+			wait = timeout
+		case processed.IsChannelError:
+			if channel.rng.Float64() < a.ackLoss {
+				record.ControlLost++
+				a.controlLost.Add(1)
+				record.Timeouts++
+				a.timeouts.Add(1)
+				wait = timeout
+				break
+			}
+			record.Naks++
+			a.naks.Add(1)
+			// NAK приходит через время доставки кадра и обратного распространения
+			wait = report.Delay() + channel.PropagationDelay
+		default:
+			if received != nil {
+				record.Duplicates++
+				log.Printf("ChannelLayer: ARQ: повторная копия сегмента #%d/%d отброшена по порядковому биту",
+					segment.SegmentNumber, segment.TotalSegments)
+			} else {
+				received, receivedReport = processed, report
+			}
+			if channel.rng.Float64() >= a.ackLoss {
+				return a.finish(received, receivedReport, record)
+			}
+			record.ControlLost++
+			a.controlLost.Add(1)
+			record.Timeouts++
+			a.timeouts.Add(1)
+			wait = timeout
+		}
+		if attempt == limit || !sleepContext(ctx, wait) {
+			break
+		}
+	}
+	if received != nil {
+		// Подтверждения потеряны, и передатчик исчерпал повторные передачи, но кадр принят
+		// и доставляется транспортному уровню
+		return a.finish(received, receivedReport, record)
+	}
+	a.failed.Add(1)
+	return a.finish(processed, report, record)
+}
+
+// finish записывает передачи кадра в отчет канала.
+func (a *ARQ) finish(segment *Segment, report ChannelReport, record ARQRecord) (*Segment, ChannelReport) {
+	report.ARQ = &record
+	return segment, report
+}
+
+// sleepContext ожидает d или отмены ctx; возвращает false при отмене.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Stats возвращает статистику ARQ (nil, если ARQ отключен).
+func (a *ARQ) Stats() *ARQStats {
+	if a == nil {
+		return nil
+	}
+	transmissions := a.transmissions.Load()
+	frames := a.frames.Load()
+	stats := &ARQStats{
+		Mode:               a.mode,
+		Timeout:            Duration{a.ackTimeout()},
+		MaxRetransmissions: a.retransmissionLimit(),
+		Frames:             frames,
+		Transmissions:      transmissions,
+		Retransmissions:    transmissions - frames,
+		Timeouts:           a.timeouts.Load(),
+		Naks:               a.naks.Load(),
+		ControlLost:        a.controlLost.Load(),
+		Failed:             a.failed.Load(),
+	}
+	if frames > 0 {
+		stats.TransmissionsPerFrame = float64(transmissions) / float64(frames)
+	}
+	if a.mode == ARQGoBackN {
+		stats.Window = a.Window()
+		stats.OutOfOrder = a.outOfOrder.Load()
+		stats.GoBack = a.goBack.Load()
+	}
+	return stats
+}
+
+// ARQTimersRequest — изменение настроек ARQ на /admin/arq; незаданные поля не меняются.
+type ARQTimersRequest struct {
+	Timeout            *Duration `json:"timeout,omitempty"`             // Время ожидания подтверждения
+	MaxRetransmissions *int      `json:"max_retransmissions,omitempty"` // Число повторных передач кадра (0 — без повторов)
+	Window             *int      `json:"window,omitempty"`              // Размер окна Go-Back-N в кадрах
+}
+
+// SetTimers применяет изменение настроек ARQ. Настройки проверяются целиком до применения;
+// кадры, передаваемые в момент изменения, завершаются с прежними настройками.
+func (a *ARQ) SetTimers(req ARQTimersRequest) error {
+	if req.Timeout != nil && req.Timeout.Duration <= 0 {
+		return fmt.Errorf("время ожидания подтверждения должно быть положительным")
+	}
+	if req.MaxRetransmissions != nil && *req.MaxRetransmissions < 0 {
+		return fmt.Errorf("число повторных передач не может быть отрицательным")
+	}
+	if req.Window != nil {
+		if a.mode != ARQGoBackN {
+			return fmt.Errorf("окно настраивается только в режиме %s", ARQGoBackN)
+		}
+		if *req.Window < 1 {
+			return fmt.Errorf("размер окна должен быть не менее 1, задано %d", *req.Window)
+		}
+	}
+	if req.Timeout != nil {
+		a.timeout.Store(int64(req.Timeout.Duration))
+	}
+	if req.MaxRetransmissions != nil {
+		a.maxRetransmissions.Store(int64(*req.MaxRetransmissions))
+	}
+	if req.Window != nil {
+		a.window.Store(int64(*req.Window))
+	}
+	return nil
+}
+
+// handleAdminARQ возвращает (GET) или изменяет (PUT) время ожидания подтверждения, число повторных
+// передач и окно ARQ во время работы. Ответ — статистика ARQ с действующими настройками, в том числе
+// число истечений таймера (timeouts).
+func handleAdminARQ(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if linkARQ == nil {
+		sendErrorResponse(w, "ARQ не используется (arq.mode в конфигурации)", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req ARQTimersRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&req); err != nil {
+			sendErrorResponse(w, fmt.Sprintf("Неверный JSON: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := linkARQ.SetTimers(req); err != nil {
+			sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("ChannelLayer: Настройки ARQ изменены: %s", linkARQ.Describe())
+	default:
+		sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(linkARQ.Stats())
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+const gbnSessionIdle = time.Minute // Время хранения состояния приемника Go-Back-N для кадра
+
+// Отметки кадров приемника Go-Back-N в хранилище состояния.
+const (
+	gbnFrameAccepted = "accepted" // Кадр принят
+	gbnFrameSkipped  = "skipped"  // Приемник сдвинул окно без кадра
+)
+
+// gbnWakeup — сигнал передатчикам этого экземпляра, ожидающим сдвига окна приемника сообщения.
+type gbnWakeup struct {
+	advanced chan struct{} // Закрывается при сдвиге окна этим экземпляром
+	touched  time.Time     // Момент последнего обращения
+}
+
+// gbnFrameKey формирует ключ отметки кадра k сообщения в хранилище состояния.
+func gbnFrameKey(message string, k int) string {
+	return fmt.Sprintf("arq:gbn:%s|%d", message, k)
+}
+
+// Состояние приемника Go-Back-N хранится в StateStore отметками кадров сообщения: кадры нумеруются
+// номерами сегментов (с 1), и приемник принимает кадр k, только если кадр k-1 уже отмечен. Отметки
+// ставятся SetNX, поэтому кадр принимается один раз, даже если сегменты сообщения обрабатываются
+// разными экземплярами, а окно приемника (первый неотмеченный кадр) только сдвигается вперед.
+
+// frameDone сообщает, что кадр k сообщения принят или пропущен приемником. При недоступности
+// хранилища кадр считается неотмеченным.
+func (a *ARQ) frameDone(message string, k int) bool {
+	if k < 1 {
+		return true
+	}
+	_, ok, err := a.store.Get(gbnFrameKey(message, k))
+	if err != nil {
+		log.Printf("ChannelLayer ERROR: ARQ: Не удалось прочитать состояние кадра #%d: %v", k, err)
+		return false
+	}
+	return ok
+}
+
+// markFrame отмечает кадр k сообщения и возвращает true, если отметка поставлена этим вызовом.
+// При недоступности хранилища отметка считается поставленной (отказ не блокирует канал).
+func (a *ARQ) markFrame(message string, k int, mark string) bool {
+	marked, err := a.store.SetNX(gbnFrameKey(message, k), mark, gbnSessionIdle)
+	if err != nil {
+		log.Printf("ChannelLayer ERROR: ARQ: Не удалось отметить кадр #%d: %v", k, err)
+		return true
+	}
+	if marked {
+		a.notify(message)
+	}
+	return marked
+}
+
+// advance сдвигает окно приемника сообщения так, чтобы все кадры до to были отмечены:
+// неотмеченные кадры отмечаются пропущенными.
+func (a *ARQ) advance(message string, to int) {
+	for k := to - 1; k >= 1 && !a.frameDone(message, k); k-- {
+		a.markFrame(message, k, gbnFrameSkipped)
+	}
+}
+
+// wakeup возвращает сигнал сдвига окна сообщения, удаляя давно не используемые сигналы.
+func (a *ARQ) wakeup(message string) *gbnWakeup {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	for key, w := range a.wakeups {
+		if now.Sub(w.touched) > gbnSessionIdle {
+			delete(a.wakeups, key)
+		}
+	}
+	w, ok := a.wakeups[message]
+	if !ok {
+		w = &gbnWakeup{advanced: make(chan struct{})}
+		a.wakeups[message] = w
+	}
+	w.touched = now
+	return w
+}
+
+// notify будит передатчики этого экземпляра, ожидающие сдвига окна сообщения.
+func (a *ARQ) notify(message string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if w, ok := a.wakeups[message]; ok {
+		close(w.advanced)
+		w.advanced = make(chan struct{})
+	}
+}
+
+// await ожидает, пока ready() не станет истинным. Сдвиг окна этим экземпляром будит ожидание сразу,
+// сдвиг другим экземпляром обнаруживается опросом хранилища с периодом времени ожидания подтверждения.
+// Если окно не сдвигается дольше idle, ожидание прекращается (предыдущие кадры не переданы
+// отправителем) и возвращается false. На время ожидания обработчик очереди slot возвращается очереди.
+func (a *ARQ) await(ctx context.Context, slot *QueueSlot, message string, ready func() bool, idle time.Duration) bool {
+	if ready() {
+		return true
+	}
+	return slot.Suspend(ctx, func() bool {
+		deadline := time.NewTimer(idle)
+		defer deadline.Stop()
+		for {
+			w := a.wakeup(message)
+			a.mu.Lock()
+			advanced := w.advanced
+			a.mu.Unlock()
+			if ready() {
+				return true
+			}
+			poll := time.NewTimer(a.ackTimeout())
+			select {
+			case <-advanced:
+				deadline.Reset(idle)
+			case <-poll.C:
+			case <-deadline.C:
+				poll.Stop()
+				return false
+			case <-ctx.Done():
+				poll.Stop()
+				return false
+			}
+			poll.Stop()
+		}
+	})
+}
+
+// transmitGoBackN передает кадр по протоколу Go-Back-N: передатчик держит в пути до window
+// неподтвержденных кадров сообщения, приемник принимает кадры строго по порядку и отбрасывает
+// кадры, пришедшие после потерянного или искаженного. После ошибки передатчик возвращается
+// к непринятому кадру и повторяет его и все следующие кадры окна. Подтверждения накопительные,
+// поэтому потеря ACK восполняется следующим ACK и повторной передачи не вызывает.
+func (a *ARQ) transmitGoBackN(ctx context.Context, channel *ChannelLayer, message string, segment *Segment, window int, slot *QueueSlot, opts ProcessOptions) (*Segment, ChannelReport) {
+	k := segment.SegmentNumber
+	timeout, limit := a.ackTimeout(), a.retransmissionLimit()
+	idle := timeout * time.Duration(limit+1)
+	if a.frameDone(message, k) {
+		// Кадр уже принят приемником: отправитель повторил сегмент сам, окно не используется
+		return a.transmitStopAndWait(ctx, channel, segment, opts)
+	}
+	// Передатчик не выходит за окно: кадр k передается, когда приняты кадры до k-window
+	if !a.await(ctx, slot, message, func() bool { return a.frameDone(message, k-window) }, idle) {
+		if ctx.Err() != nil {
+			return a.finish(nil, ChannelReport{Lost: true}, ARQRecord{})
+		}
+		a.advance(message, k-window+1)
+	}
+
+	var record ARQRecord
+	var processed *Segment
+	var report ChannelReport
+	for attempt := 0; attempt <= limit; attempt++ {
+		if attempt > 0 {
+			log.Printf("ChannelLayer: ARQ: повторная передача %d/%d сегмента #%d/%d (Go-Back-N)",
+				attempt, limit, k, segment.TotalSegments)
+		}
+		processed, report = channel.ProcessSegmentWith(segment, opts)
+		record.Transmissions++
+		a.transmissions.Add(1)
+		ok := processed != nil && !processed.IsChannelError
+
+		// Приемник принимает кадр, если приняты все предыдущие и кадр еще не отмечен
+		inOrder := a.frameDone(message, k-1)
+		accepted := ok && inOrder && a.markFrame(message, k, gbnFrameAccepted)
+
+		switch {
+		case accepted:
+			if channel.rng.Float64() < a.ackLoss {
+				record.ControlLost++
+				a.controlLost.Add(1)
+			}
+			return a.finish(processed, report, record)
+		case (ok && inOrder) || a.frameDone(message, k):
+			// Окно сдвинуто без этого кадра: приемник отбрасывает его как кадр вне окна
+			a.failed.Add(1)
+			report.Lost = true
+			return a.finish(nil, report, record)
+		case ok:
+			// Предыдущий кадр не принят: приемник отбрасывает кадр, пришедший вне очереди
+			record.OutOfOrder++
+			a.outOfOrder.Add(1)
+		case processed == nil:
+			record.Timeouts++
+			a.timeouts.Add(1)
+			if !sleepContext(ctx, timeout) {
+				return a.finish(processed, report, record)
+			}
+		default:
+			if channel.rng.Float64() < a.ackLoss {
+				record.ControlLost++
+				a.controlLost.Add(1)
+				record.Timeouts++
+				a.timeouts.Add(1)
+				if !sleepContext(ctx, timeout) {
+					return a.finish(processed, report, record)
+				}
+				break
+			}
+			record.Naks++
+			a.naks.Add(1)
+			if !sleepContext(ctx, report.Delay()+channel.PropagationDelay) {
+				return a.finish(processed, report, record)
+			}
+		}
+		if attempt == limit {
+			break
+		}
+		// Возврат: кадр повторяется, когда приняты все предыдущие кадры
+		if !inOrder {
+			if !a.await(ctx, slot, message, func() bool { return a.frameDone(message, k-1) }, idle) {
+				if ctx.Err() != nil {
+					return a.finish(nil, report, record)
+				}
+				a.advance(message, k)
+			}
+			a.goBack.Add(1)
+		}
+	}
+	// Кадр не принят после всех повторных передач: приемник пропускает его, чтобы не остановить
+	// передачу следующих кадров сообщения
+	a.failed.Add(1)
+	if a.frameDone(message, k-1) {
+		a.markFrame(message, k, gbnFrameSkipped)
+	}
+	if processed != nil && !processed.IsChannelError {
+		// Последняя копия пришла вне очереди и отброшена приемником
+		processed = nil
+		report.Lost = true
+	}
+	return a.finish(processed, report, record)
+}
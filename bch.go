@@ -0,0 +1,242 @@
+package main
+
+import "fmt"
+
+// gfPrimitivePolys — примитивные многочлены полей GF(2^m), m = 3..12 (бит i — коэффициент при x^i).
+var gfPrimitivePolys = map[int]int{
+	3: 0xB, 4: 0x13, 5: 0x25, 6: 0x43, 7: 0x89, 8: 0x11D,
+	9: 0x211, 10: 0x409, 11: 0x805, 12: 0x1053,
+}
+
+// gfField — поле GF(2^m), заданное таблицами степеней и логарифмов примитивного элемента α.
+type gfField struct {
+	order int   // Порядок мультипликативной группы 2^m - 1
+	exp   []int // exp[i] = α^i, i = 0..2*order-1
+	log   []int // log[α^i] = i
+}
+
+// newGFField строит поле GF(2^m).
+func newGFField(m int) (*gfField, error) {
+	poly, ok := gfPrimitivePolys[m]
+	if !ok {
+		return nil, fmt.Errorf("m должно быть в диапазоне [3, 12], задано %d", m)
+	}
+	order := 1<<m - 1
+	f := &gfField{order: order, exp: make([]int, 2*order), log: make([]int, order+1)}
+	x := 1
+	for i := 0; i < order; i++ {
+		f.exp[i] = x
+		f.exp[i+order] = x
+		f.log[x] = i
+		x <<= 1
+		if x&(1<<m) != 0 {
+			x ^= poly
+		}
+	}
+	return f, nil
+}
+
+func (f *gfField) mul(a, b int) int {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return f.exp[f.log[a]+f.log[b]]
+}
+
+func (f *gfField) div(a, b int) int {
+	if a == 0 {
+		return 0
+	}
+	return f.exp[f.log[a]+f.order-f.log[b]]
+}
+
+// pow возвращает α^e.
+func (f *gfField) pow(e int) int {
+	return f.exp[((e%f.order)+f.order)%f.order]
+}
+
+// polyEval вычисляет многочлен (коэффициенты по возрастанию степеней) в точке x.
+func (f *gfField) polyEval(poly []int, x int) int {
+	y := 0
+	for i := len(poly) - 1; i >= 0; i-- {
+		y = f.mul(y, x) ^ poly[i]
+	}
+	return y
+}
+
+// bchCoder — примитивный двоичный код БЧХ длины n = 2^m - 1, исправляющий до t ошибок в блоке.
+// Порождающий многочлен — НОК минимальных многочленов α^1 .. α^(2t), k = n - deg g(x).
+// Кодирование систематическое, как у циклического кода [7,4]: информационные биты, затем
+// проверочные (остаток от деления i(x)·x^(n-k) на g(x)). Декодирование: синдромы S_j = r(α^j),
+// алгоритм Берлекэмпа — Мэсси и процедура Ченя.
+type bchCoder struct {
+	name      string
+	t         int
+	n, k      int
+	field     *gfField
+	generator []uint8 // Порождающий многочлен над GF(2) (по возрастанию степеней)
+}
+
+// newBCHCoder создает кодек БЧХ с параметрами m и t и именем name (по умолчанию bch<n>_<k>).
+func newBCHCoder(name string, m, t int) (*bchCoder, error) {
+	field, err := newGFField(m)
+	if err != nil {
+		return nil, err
+	}
+	if t < 1 {
+		return nil, fmt.Errorf("t должно быть не менее 1, задано %d", t)
+	}
+	n := field.order
+	// Перемножаем минимальные многочлены различных классов сопряженных элементов α^i, i = 1..2t
+	generator := []int{1}
+	used := make([]bool, n)
+	for i := 1; i <= 2*t; i++ {
+		if used[i%n] {
+			continue
+		}
+		minimal := []int{1}
+		for e := i % n; !used[e]; e = e * 2 % n {
+			used[e] = true
+			// minimal *= (x + α^e)
+			next := make([]int, len(minimal)+1)
+			for j, c := range minimal {
+				next[j+1] ^= c
+				next[j] ^= field.mul(c, field.pow(e))
+			}
+			minimal = next
+		}
+		product := make([]int, len(generator)+len(minimal)-1)
+		for a, ca := range generator {
+			for b, cb := range minimal {
+				product[a+b] ^= field.mul(ca, cb)
+			}
+		}
+		generator = product
+	}
+	k := n - (len(generator) - 1)
+	if k < 1 {
+		return nil, fmt.Errorf("при m=%d и t=%d не остается информационных бит (deg g(x) = %d)", m, t, len(generator)-1)
+	}
+	if name == "" {
+		name = fmt.Sprintf("bch%d_%d", n, k)
+	}
+	coder := &bchCoder{name: name, t: t, n: n, k: k, field: field, generator: make([]uint8, len(generator))}
+	for i, c := range generator {
+		coder.generator[i] = uint8(c) // Коэффициенты минимальных многочленов лежат в GF(2)
+	}
+	return coder, nil
+}
+
+func (c *bchCoder) Name() string { return c.name }
+func (c *bchCoder) N() int       { return c.n }
+func (c *bchCoder) K() int       { return c.k }
+
+// EncodeBlock кодирует k информационных бит в n кодовых бит.
+func (c *bchCoder) EncodeBlock(infoBits []uint8) []uint8 {
+	parityLen := c.n - c.k
+	codeword := make([]uint8, c.n)
+	copy(codeword, infoBits)
+	remainder := make([]uint8, parityLen) // remainder[0] — старший коэффициент
+	for _, bit := range infoBits {
+		feedback := bit ^ remainder[0]
+		copy(remainder, remainder[1:])
+		remainder[parityLen-1] = 0
+		if feedback != 0 {
+			for j := 0; j < parityLen; j++ {
+				remainder[j] ^= c.generator[parityLen-1-j]
+			}
+		}
+	}
+	copy(codeword[c.k:], remainder)
+	return codeword
+}
+
+// syndromes вычисляет синдромы S_j = r(α^j), j = 1..2t (syndromes[j-1]); бит received[i] —
+// коэффициент при x^(n-1-i).
+func (c *bchCoder) syndromes(received []uint8) ([]int, bool) {
+	syndromes := make([]int, 2*c.t)
+	nonzero := false
+	for j := range syndromes {
+		s := 0
+		for i, bit := range received {
+			if bit == 1 {
+				s ^= c.field.pow((j + 1) * (c.n - 1 - i))
+			}
+		}
+		syndromes[j] = s
+		nonzero = nonzero || s != 0
+	}
+	return syndromes, nonzero
+}
+
+// DecodeBlock декодирует n бит, исправляя до t ошибок.
+func (c *bchCoder) DecodeBlock(codedBits []uint8) ([]uint8, bool, bool) {
+	received := append([]uint8(nil), codedBits...)
+	syndromes, nonzero := c.syndromes(received)
+	if !nonzero {
+		return received[:c.k], false, false
+	}
+	f := c.field
+
+	// Берлекэмп — Мэсси: locator (по возрастанию степеней, locator[0] = 1)
+	locator, previous := []int{1}, []int{1}
+	degree, shift, lastDiscrepancy := 0, 1, 1
+	for step := range syndromes {
+		discrepancy := syndromes[step]
+		for i := 1; i <= degree && i < len(locator); i++ {
+			discrepancy ^= f.mul(locator[i], syndromes[step-i])
+		}
+		if discrepancy == 0 {
+			shift++
+			continue
+		}
+		scale := f.div(discrepancy, lastDiscrepancy)
+		updated := make([]int, max(len(locator), len(previous)+shift))
+		copy(updated, locator)
+		for i, coef := range previous {
+			updated[i+shift] ^= f.mul(scale, coef)
+		}
+		if 2*degree <= step {
+			previous, locator = locator, updated
+			degree = step + 1 - degree
+			lastDiscrepancy = discrepancy
+			shift = 1
+		} else {
+			locator = updated
+			shift++
+		}
+	}
+	if degree > c.t {
+		return codedBits[:c.k], false, true
+	}
+
+	// Процедура Ченя: бит received[i] ошибочен, если Λ(α^-(n-1-i)) = 0
+	found := 0
+	for i := range received {
+		if f.polyEval(locator, f.pow(-(c.n-1-i))) == 0 {
+			received[i] ^= 1
+			found++
+		}
+	}
+	if found != degree {
+		return codedBits[:c.k], false, true
+	}
+	if _, nonzero := c.syndromes(received); nonzero {
+		return codedBits[:c.k], false, true
+	}
+	return received[:c.k], true, false
+}
+
+func init() {
+	// Коды БЧХ для сравнения с [7,4]: (15,7) и (31,21) с t = 2, (63,45) с t = 3
+	for _, params := range []struct{ m, t int }{{4, 2}, {5, 2}, {6, 3}} {
+		coder, err := newBCHCoder("", params.m, params.t)
+		if err != nil {
+			panic(err)
+		}
+		RegisterCoder(coder)
+	}
+	RegisterCodecType("bch", func(cfg CodecConfig) (BlockCoder, error) {
+		return newBCHCoder(cfg.Name, cfg.M, cfg.T)
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	callbackAttempts = 5               // Максимальное число попыток доставки уведомления (callback_url, NACK)
+	callbackBackoff  = 1 * time.Second // Пауза перед второй попыткой (удваивается с каждой попыткой)
+	callbackTimeout  = 5 * time.Second // Таймаут одного запроса уведомления
+)
+
+var callbackClient = &http.Client{Timeout: callbackTimeout}
+
+// SegmentCallback — уведомление об итоге обработки сегмента, отправляемое на callback_url.
+type SegmentCallback struct {
+	SegmentID     string    `json:"segment_id"`
+	Sender        string    `json:"sender"`
+	SendTime      string    `json:"send_time"`
+	SegmentNumber int       `json:"segment_number"`
+	TotalSegments int       `json:"total_segments"`
+	CompletedAt   time.Time `json:"completed_at"`
+	SegmentResult
+}
+
+// validateCallbackURL проверяет, что callback_url — абсолютный URL со схемой http или https.
+func validateCallbackURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("ожидается абсолютный URL со схемой http или https")
+	}
+	return nil
+}
+
+// notifyCallback отправляет итог обработки сегмента POST запросом на callback_url клиента.
+// Доставка повторяется с экспоненциальной паузой, пока получатель не ответит статусом 2xx
+// или не будут исчерпаны попытки. Результат доставки отражается в жизненном цикле сегмента.
+func notifyCallback(job *segmentJob, result SegmentResult) {
+	req := job.Request
+	notification := SegmentCallback{
+		SegmentID:     job.ID,
+		Sender:        req.Sender,
+		SendTime:      req.SendTime,
+		SegmentNumber: req.SegmentNumber,
+		TotalSegments: req.TotalSegments,
+		CompletedAt:   time.Now().UTC(),
+		SegmentResult: result,
+	}
+	body, err := json.Marshal(notification)
+	if err != nil {
+		log.Printf("Callback ERROR: Не удалось сериализовать уведомление для сегмента %s: %v", job.ID, err)
+		return
+	}
+
+	status, attempts, err := postWithRetry(req.CallbackURL, body)
+	if err != nil {
+		log.Printf("Callback ERROR: Итог сегмента %s не доставлен на %s после %d попыток: %v", job.ID, req.CallbackURL, attempts, err)
+		segmentRegistry.Event(job.ID, "callback", fmt.Sprintf("Уведомление не доставлено после %d попыток: %v", attempts, err))
+		return
+	}
+	log.Printf("Callback: Итог сегмента %s (%s) доставлен на %s", job.ID, result.Outcome, req.CallbackURL)
+	segmentRegistry.Event(job.ID, "callback", fmt.Sprintf("Уведомление доставлено (%s, попытка %d)", status, attempts))
+}
+
+// postWithRetry отправляет JSON POST запросом на targetURL, повторяя отправку с экспоненциальной
+// паузой, пока получатель не ответит статусом 2xx или не будут исчерпаны попытки.
+// Возвращает статус успешного ответа и число выполненных попыток.
+func postWithRetry(targetURL string, body []byte) (string, int, error) {
+	var lastErr error
+	backoff := callbackBackoff
+	for attempt := 1; attempt <= callbackAttempts; attempt++ {
+		resp, err := callbackClient.Post(targetURL, "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				return resp.Status, attempt, nil
+			}
+			err = fmt.Errorf("получатель ответил статусом %s", resp.Status)
+		}
+		lastErr = err
+		log.Printf("Callback: Попытка %d/%d отправки на %s не удалась: %v", attempt, callbackAttempts, targetURL, err)
+		if attempt < callbackAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return "", callbackAttempts, lastErr
+}
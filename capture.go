@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Параметры формата pcapng (https://www.ietf.org/archive/id/draft-ietf-opsawg-pcapng-02.html).
+const (
+	pcapngBlockSHB = 0x0A0D0D0A // Section Header Block
+	pcapngBlockIDB = 0x00000001 // Interface Description Block
+	pcapngBlockEPB = 0x00000006 // Enhanced Packet Block
+
+	pcapngOptEnd         = 0 // opt_endofopt
+	pcapngOptComment     = 1 // opt_comment
+	pcapngOptIfName      = 2 // if_name
+	pcapngOptShbUserAppl = 4 // shb_userappl
+
+	pcapngLinkTypeUser0 = 147 // LINKTYPE_USER0: пользовательский тип канала (кадр — упакованные биты)
+)
+
+// Интерфейсы в файле захвата: кадр до искажений и после них.
+const (
+	captureIfaceTx = 0 // Кадр, переданный в канал
+	captureIfaceRx = 1 // Кадр, принятый из канала
+)
+
+// PacketCapture — запись симулируемых кадров в файл pcapng для просмотра в Wireshark.
+// Каждый кадр записывается дважды: на интерфейсе channel-tx (до искажений) и channel-rx
+// (после искажений); комментарий пакета описывает сегмент и примененные искажения.
+// Биты кадра упаковываются в байты старшим битом вперед, неполный последний байт дополняется нулями.
+// Все методы допускают вызов на nil-захвате (захват отключен в конфигурации).
+type PacketCapture struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// OpenPacketCapture открывает файл захвата для дозаписи и начинает в нем новую секцию pcapng.
+func OpenPacketCapture(path string) (*PacketCapture, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть файл захвата %s: %w", path, err)
+	}
+	pc := &PacketCapture{path: path, file: file}
+
+	var header bytes.Buffer
+	shb := new(bytes.Buffer)
+	binary.Write(shb, binary.LittleEndian, uint32(0x1A2B3C4D)) // Byte-order magic
+	binary.Write(shb, binary.LittleEndian, uint16(1))          // Major version
+	binary.Write(shb, binary.LittleEndian, uint16(0))          // Minor version
+	binary.Write(shb, binary.LittleEndian, int64(-1))          // Длина секции не указана
+	writePcapngOptions(shb, pcapngOptShbUserAppl, "channel-layer")
+	writePcapngBlock(&header, pcapngBlockSHB, shb.Bytes())
+	for _, name := range []string{"channel-tx", "channel-rx"} {
+		idb := new(bytes.Buffer)
+		binary.Write(idb, binary.LittleEndian, uint16(pcapngLinkTypeUser0))
+		binary.Write(idb, binary.LittleEndian, uint16(0)) // Reserved
+		binary.Write(idb, binary.LittleEndian, uint32(0)) // SnapLen: без ограничения
+		writePcapngOptions(idb, pcapngOptIfName, name)
+		writePcapngBlock(&header, pcapngBlockIDB, idb.Bytes())
+	}
+	if _, err := file.Write(header.Bytes()); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("не удалось записать заголовок файла захвата %s: %w", path, err)
+	}
+	return pc, nil
+}
+
+// writePcapngBlock дописывает блок: тип, полная длина, тело (выровненное до 4 байт), полная длина.
+func writePcapngBlock(buf *bytes.Buffer, blockType uint32, body []byte) {
+	padded := (len(body) + 3) &^ 3
+	total := uint32(12 + padded)
+	binary.Write(buf, binary.LittleEndian, blockType)
+	binary.Write(buf, binary.LittleEndian, total)
+	buf.Write(body)
+	buf.Write(make([]byte, padded-len(body)))
+	binary.Write(buf, binary.LittleEndian, total)
+}
+
+// writePcapngOptions дописывает строковую опцию (если value не пусто) и opt_endofopt.
+func writePcapngOptions(buf *bytes.Buffer, code uint16, value string) {
+	if value != "" {
+		binary.Write(buf, binary.LittleEndian, code)
+		binary.Write(buf, binary.LittleEndian, uint16(len(value)))
+		buf.WriteString(value)
+		buf.Write(make([]byte, (4-len(value)%4)%4))
+	}
+	binary.Write(buf, binary.LittleEndian, uint16(pcapngOptEnd))
+	binary.Write(buf, binary.LittleEndian, uint16(0))
+}
+
+// packBits упаковывает поток бит в байты (старший бит первым), дополняя последний байт нулями.
+func packBits(bits []uint8) []byte {
+	return bitStreamToBytes(padBits(bits, 8))
+}
+
+// writePacket записывает Enhanced Packet Block с кадром на интерфейсе iface.
+func (pc *PacketCapture) writePacket(buf *bytes.Buffer, iface uint32, at time.Time, frame []uint8, comment string) {
+	data := packBits(frame)
+	usec := uint64(at.UnixMicro()) // Разрешение меток времени по умолчанию — микросекунды
+	epb := new(bytes.Buffer)
+	binary.Write(epb, binary.LittleEndian, iface)
+	binary.Write(epb, binary.LittleEndian, uint32(usec>>32))
+	binary.Write(epb, binary.LittleEndian, uint32(usec))
+	binary.Write(epb, binary.LittleEndian, uint32(len(data))) // Captured length
+	binary.Write(epb, binary.LittleEndian, uint32(len(data))) // Original length
+	epb.Write(data)
+	epb.Write(make([]byte, (4-len(data)%4)%4))
+	writePcapngOptions(epb, pcapngOptComment, comment)
+	writePcapngBlock(buf, pcapngBlockEPB, epb.Bytes())
+}
+
+// WriteFrame записывает кадр сегмента до и после искажений по отчету канала.
+func (pc *PacketCapture) WriteFrame(req IncomingCodeRequest, report ChannelReport) {
+	if pc == nil || report.TxFrame == nil {
+		return
+	}
+	now := time.Now()
+	segment := fmt.Sprintf("сегмент %s #%d/%d send_time=%s", req.Sender, req.SegmentNumber, req.TotalSegments, req.SendTime)
+	coding := "без кодирования"
+	if report.Codec != "" {
+		coding = "кодек " + report.Codec
+	}
+	if len(report.Stages) > 0 {
+		coding += ", этапы: " + strings.Join(report.Stages, ", ")
+	}
+
+	var buf bytes.Buffer
+	txComment := fmt.Sprintf("%s: кадр %d бит (%s), до искажений", segment, len(report.TxFrame), coding)
+	if report.Lost {
+		txComment += "; кадр потерян"
+	}
+	pc.writePacket(&buf, captureIfaceTx, now, report.TxFrame, txComment)
+	if report.RxFrame != nil {
+		impairments := "искажения не симулировались"
+		if !report.ImpairmentsSkipped {
+			impairments = fmt.Sprintf("инвертированы биты %v", report.FlippedBits)
+			if len(report.FlippedBits) == 0 {
+				impairments = "ошибок нет"
+			}
+		}
+		rxComment := fmt.Sprintf("%s: после искажений, %s; декодирование: %s", segment, impairments, report.Decode)
+		if report.StageViolations > 0 {
+			rxComment += fmt.Sprintf(", нарушений кодирования: %d", report.StageViolations)
+		}
+		pc.writePacket(&buf, captureIfaceRx, now, report.RxFrame, rxComment)
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if _, err := pc.file.Write(buf.Bytes()); err != nil {
+		log.Printf("Capture ERROR: Не удалось записать кадр в файл захвата %s: %v", pc.path, err)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+// checksumCoder — режим без помехоустойчивого кодирования: к кадру (вся полезная нагрузка —
+// один блок) дописывается 16-битная контрольная сумма в стиле Интернета (RFC 1071), а при приеме
+// несовпадение суммы помечает кадр как ошибку канала. Служит незащищенным эталоном для сравнения
+// с кодеками: избыточность минимальна, исправления нет, а ошибки, взаимно компенсирующиеся в сумме
+// с обратным переносом, пропускаются.
+type checksumCoder struct{}
+
+const checksumBits = 16 // Длина контрольной суммы в битах
+
+func (checksumCoder) Name() string { return "checksum16" }
+func (checksumCoder) N() int       { return PayloadBitLength + checksumBits }
+func (checksumCoder) K() int       { return PayloadBitLength }
+
+// internetChecksum возвращает дополнение до единицы суммы 16-битных слов потока
+// (сумма в арифметике с обратным переносом; неполное последнее слово дополняется нулями).
+func internetChecksum(bits []uint8) int {
+	sum := 0
+	for i := 0; i < len(bits); i += checksumBits {
+		end := min(i+checksumBits, len(bits))
+		word := bitsToUint(bits[i:end]) << (checksumBits - (end - i))
+		sum += word
+		sum = (sum & 0xFFFF) + (sum >> 16)
+	}
+	return ^sum & 0xFFFF
+}
+
+// EncodeBlock дописывает к кадру контрольную сумму.
+func (checksumCoder) EncodeBlock(infoBits []uint8) []uint8 {
+	out := append(make([]uint8, 0, len(infoBits)+checksumBits), infoBits...)
+	return appendUintBits(out, internetChecksum(infoBits), checksumBits)
+}
+
+// DecodeBlock сверяет контрольную сумму; несовпадение означает неисправимую ошибку.
+func (c checksumCoder) DecodeBlock(codedBits []uint8) ([]uint8, bool, bool) {
+	infoBits := append([]uint8(nil), codedBits[:c.K()]...)
+	received := bitsToUint(codedBits[c.K():])
+	return infoBits, false, internetChecksum(infoBits) != received
+}
+
+func init() {
+	RegisterCoder(checksumCoder{})
+}
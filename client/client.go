@@ -0,0 +1,257 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// RetryPolicy задает повторную отправку сегмента.
+type RetryPolicy struct {
+	MaxAttempts       int           // Максимальное число попыток (1 — без повторов)
+	Backoff           time.Duration // Пауза перед второй попыткой (удваивается с каждой попыткой)
+	RetryLost         bool          // Повторять сегменты, потерянные в канале
+	RetryChannelError bool          // Повторять сегменты с неисправимой ошибкой канала
+}
+
+// DefaultRetryPolicy повторяет отправку при сетевых ошибках, перегрузке (429, 503) и сбое
+// пересылки на /transfer; итоги моделирования канала возвращаются вызывающему.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, Backoff: 200 * time.Millisecond}
+
+// Client — клиент канального уровня.
+type Client struct {
+	BaseURL    string       // Адрес канального уровня, например "http://localhost:8081"
+	APIKey     string       // Ключ API (заголовок X-API-Key), если используются квоты
+	HTTPClient *http.Client // HTTP-клиент (по умолчанию http.DefaultClient)
+	Retry      RetryPolicy
+	// PayloadSize — размер полезной нагрузки сегмента канального уровня (флаг -payload-size);
+	// 0 — MaxPayloadSize.
+	PayloadSize int
+}
+
+// New создает клиент канального уровня с политикой повторов по умолчанию.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), Retry: DefaultRetryPolicy}
+}
+
+// payloadSize возвращает максимальный размер полезной нагрузки сегмента.
+func (c *Client) payloadSize() int {
+	if c.PayloadSize > 0 {
+		return c.PayloadSize
+	}
+	return MaxPayloadSize
+}
+
+// httpClient возвращает используемый HTTP-клиент.
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// retryable сообщает, следует ли повторить отправку после итога result.
+func (c *Client) retryable(statusCode int, result *Result) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	}
+	if result == nil {
+		return false
+	}
+	switch result.Outcome {
+	case OutcomeForwardFailed:
+		return true
+	case OutcomeLost:
+		return c.Retry.RetryLost
+	case OutcomeChannelError:
+		return c.Retry.RetryChannelError
+	}
+	return false
+}
+
+// SendSegment отправляет сегмент на /code (формат ответа версии 2) с повторами согласно c.Retry.
+// Итоги моделирования канала (потеря, неисправимая ошибка) возвращаются в Result без ошибки;
+// ошибка возвращается, если итог не получен.
+func (c *Client) SendSegment(ctx context.Context, segment Segment) (*Result, error) {
+	if len(segment.Payload) > c.payloadSize() {
+		return nil, fmt.Errorf("полезная нагрузка %d байт превышает %d байт", len(segment.Payload), c.payloadSize())
+	}
+	body, err := json.Marshal(segment)
+	if err != nil {
+		return nil, err
+	}
+	attempts := max(c.Retry.MaxAttempts, 1)
+	backoff := c.Retry.Backoff
+	var result *Result
+	for attempt := 1; ; attempt++ {
+		var statusCode int
+		statusCode, result, err = c.postSegment(ctx, body)
+		if err == nil && !c.retryable(statusCode, result) || attempt == attempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return result, err
+}
+
+// postSegment выполняет одну попытку отправки сегмента.
+func (c *Client) postSegment(ctx context.Context, body []byte) (int, *Result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/code", bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Version", "2")
+	if c.APIKey != "" {
+		req.Header.Set("X-API-Key", c.APIKey)
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	var result Result
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("неверный ответ канального уровня (%s): %w", resp.Status, err)
+	}
+	if result.Outcome == "" {
+		// Ошибка запроса (неверный сегмент, квота, перегрузка) возвращается без итога обработки
+		return resp.StatusCode, &result, fmt.Errorf("канальный уровень ответил %s: %s", resp.Status, result.Error)
+	}
+	return resp.StatusCode, &result, nil
+}
+
+// SplitMessage разбивает сообщение на полезные нагрузки сегментов не длиннее MaxPayloadSize байт,
+// не разрывая символы UTF-8.
+func SplitMessage(message string) []string {
+	return SplitMessageSize(message, MaxPayloadSize)
+}
+
+// SplitMessageSize разбивает сообщение на полезные нагрузки сегментов не длиннее size байт,
+// не разрывая символы UTF-8.
+func SplitMessageSize(message string, size int) []string {
+	var parts []string
+	for len(message) > size {
+		cut := size
+		for cut > 0 && !utf8.RuneStart(message[cut]) {
+			cut--
+		}
+		parts = append(parts, message[:cut])
+		message = message[cut:]
+	}
+	return append(parts, message)
+}
+
+// SendMessage разбивает сообщение на сегменты и отправляет их по порядку с общим send_time.
+// Возвращает итоги отправленных сегментов; при ошибке отправка прекращается.
+func (c *Client) SendMessage(ctx context.Context, sender, message string) ([]*Result, error) {
+	parts := SplitMessageSize(message, c.payloadSize())
+	sendTime := time.Now().UTC().Format(SendTimeLayout)
+	results := make([]*Result, 0, len(parts))
+	for i, part := range parts {
+		result, err := c.SendSegment(ctx, Segment{
+			SegmentNumber: i + 1,
+			TotalSegments: len(parts),
+			Sender:        sender,
+			SendTime:      sendTime,
+			Payload:       part,
+		})
+		if err != nil {
+			return results, fmt.Errorf("сегмент %d/%d: %w", i+1, len(parts), err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// SubscribeEvents подписывается на события канального уровня (/events, Server-Sent Events),
+// начиная с события после since. Канал закрывается при отмене ctx или разрыве соединения.
+func (c *Client) SubscribeEvents(ctx context.Context, since uint64) (<-chan Event, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/events?since="+strconv.FormatUint(since, 10), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("канальный уровень ответил %s", resp.Status)
+	}
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var event Event
+			if json.Unmarshal([]byte(data), &event) != nil {
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// TransferHandler возвращает обработчик конечной точки /transfer транспортного уровня:
+// принятый от канального уровня сегмент передается в handle; ошибка handle возвращается
+// канальному уровню статусом 500 (и приводит к повторной пересылке, если она настроена).
+func TransferHandler(handle func(ctx context.Context, segment TransferSegment) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Метод не допускается", http.StatusMethodNotAllowed)
+			return
+		}
+		var segment TransferSegment
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&segment); err != nil {
+			http.Error(w, "Неверный JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := handle(r.Context(), segment); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// ErrPayloadTruncated возвращается TrimPayload, если длина полезной нагрузки меньше заявленной.
+var ErrPayloadTruncated = errors.New("полезная нагрузка короче payload_length")
+
+// TrimPayload возвращает полезную нагрузку пересланного сегмента без нулевого паддинга:
+// по payload_length, если он передан, иначе отсекая завершающие нулевые байты.
+func (s TransferSegment) TrimPayload() (string, error) {
+	if s.PayloadLength > 0 {
+		if len(s.Payload) < s.PayloadLength {
+			return s.Payload, ErrPayloadTruncated
+		}
+		return s.Payload[:s.PayloadLength], nil
+	}
+	return strings.TrimRight(s.Payload, "\x00"), nil
+}
@@ -0,0 +1,110 @@
+// Package client — клиент канального уровня для соседних уровней стека: прикладной и
+// транспортный уровни отправляют сегменты на /code и подписываются на события, а транспортный
+// уровень принимает сегменты, пересылаемые канальным уровнем на /transfer.
+//
+// Структуры пакета повторяют формат JSON канального уровня, поэтому командам верхних уровней
+// не нужно поддерживать собственные копии.
+package client
+
+import "time"
+
+// MaxPayloadSize — максимальный размер полезной нагрузки одного сегмента в байтах по умолчанию
+// (FixedPayloadSize канального уровня, запущенного без флага -payload-size). Если канальный
+// уровень запущен с другим размером, его нужно задать в Client.PayloadSize.
+const MaxPayloadSize = 140
+
+// SendTimeLayout — рекомендуемый формат send_time (RFC 3339 с наносекундами).
+const SendTimeLayout = time.RFC3339Nano
+
+// Типы сегментов.
+const (
+	SegmentTypeData    = "data"    // Сегмент данных (по умолчанию)
+	SegmentTypeControl = "control" // Управляющий сегмент (установление и разрыв соединения, keepalive)
+)
+
+// Итоги обработки сегмента (поле outcome).
+const (
+	OutcomeDelivered     = "delivered"      // Сегмент обработан и передан на /transfer
+	OutcomeLost          = "lost"           // Кадр потерян в канале
+	OutcomeChannelError  = "channel_error"  // Обнаружена неисправимая ошибка канала
+	OutcomeForwardFailed = "forward_failed" // Сегмент обработан, но передача на /transfer не удалась
+	OutcomeRejected      = "rejected"       // Сегмент отклонен до обработки (квота, перегрузка)
+	OutcomeDuplicate     = "duplicate"      // Сегмент уже был доставлен ранее
+)
+
+// HopRecord — сведения о прохождении сегмента через одно звено многозвенного канала.
+type HopRecord struct {
+	Hop         int     `json:"hop"`
+	Node        string  `json:"node"`
+	Codec       string  `json:"codec,omitempty"`
+	FlippedBits int     `json:"flipped_bits"`
+	Decode      string  `json:"decode,omitempty"`
+	DelayMs     float64 `json:"delay_ms,omitempty"`
+}
+
+// Segment — сегмент, отправляемый на /code.
+type Segment struct {
+	SegmentNumber int         `json:"segment_number"` // Номер сегмента (с 1)
+	TotalSegments int         `json:"total_segments"` // Число сегментов сообщения
+	Sender        string      `json:"sender"`
+	SendTime      string      `json:"send_time"` // Время отправки сообщения (общее для всех его сегментов)
+	Payload       string      `json:"payload"`   // Не более Client.PayloadSize байт
+	Type          string      `json:"type,omitempty"`
+	CallbackURL   string      `json:"callback_url,omitempty"`
+	Hops          []HopRecord `json:"hops,omitempty"`
+	Codec         string      `json:"codec,omitempty"` // Кодек сегмента (по умолчанию — кодек канала)
+}
+
+// TransferSegment — сегмент, пересылаемый канальным уровнем на /transfer.
+type TransferSegment struct {
+	SegmentNumber  int         `json:"segment_number"`
+	TotalSegments  int         `json:"total_segments"`
+	Sender         string      `json:"sender"`
+	SendTime       string      `json:"send_time"`
+	Payload        string      `json:"payload,omitempty"` // FixedPayloadSize байт с нулевым паддингом (или исходной длины при trim_padding)
+	Type           string      `json:"type,omitempty"`
+	Lost           bool        `json:"lost,omitempty"`             // Заглушка вместо потерянного сегмента
+	Degraded       string      `json:"degraded,omitempty"`         // Действие режима деградации
+	IsChannelError bool        `json:"is_channel_error,omitempty"` // Полезная нагрузка может быть искажена
+	Corrected      bool        `json:"corrected,omitempty"`        // Ошибки канала исправлены декодером
+	Recovered      bool        `json:"recovered,omitempty"`        // Потерянный сегмент восстановлен по кадру четности
+	CRC32C         string      `json:"crc32c,omitempty"`           // CRC-32C исходной полезной нагрузки
+	PayloadLength  int         `json:"payload_length,omitempty"`   // Длина исходной полезной нагрузки
+	Hops           []HopRecord `json:"hops,omitempty"`
+}
+
+// CodingStats — накладные расходы кодирования сегмента.
+type CodingStats struct {
+	Codec               string  `json:"codec,omitempty"`
+	N                   int     `json:"n"`
+	K                   int     `json:"k"`
+	PayloadBits         int     `json:"payload_bits"`
+	InfoBits            int     `json:"info_bits"`
+	EncodedBits         int     `json:"encoded_bits"`
+	CodeRate            float64 `json:"code_rate"`
+	RedundancyBytes     float64 `json:"redundancy_bytes"`
+	EffectiveThroughput float64 `json:"effective_throughput"`
+	ErrorMultiplication float64 `json:"error_multiplication,omitempty"`
+}
+
+// Result — итог обработки сегмента (ответ /code версии 2).
+type Result struct {
+	SegmentID      string       `json:"segment_id"`
+	Outcome        string       `json:"outcome"`   // См. Outcome*
+	Delivered      bool         `json:"delivered"` // Передан ли сегмент транспортному уровню
+	Status         string       `json:"status,omitempty"`
+	Error          string       `json:"error,omitempty"`
+	TransferStatus string       `json:"transfer_status,omitempty"`
+	TransferBody   string       `json:"transfer_response_body,omitempty"`
+	Duplicate      bool         `json:"duplicate,omitempty"`
+	Coding         *CodingStats `json:"coding,omitempty"`
+}
+
+// Event — событие канального уровня (/events).
+type Event struct {
+	ID      uint64                 `json:"id"`
+	Time    time.Time              `json:"time"`
+	Type    string                 `json:"type"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BlockCoder — блочный код [n,k]: каждые k информационных бит кодируются в n кодовых бит.
+// Кодек выбирается по имени (см. RegisterCoder, LookupCoder).
+type BlockCoder interface {
+	Name() string // Имя кодека в конфигурации
+	N() int       // Длина кодового слова в битах
+	K() int       // Число информационных бит в кодовом слове
+
+	// EncodeBlock кодирует K информационных бит в N кодовых бит.
+	EncodeBlock(infoBits []uint8) []uint8
+	// DecodeBlock декодирует N принятых бит и возвращает K информационных бит.
+	// corrected означает, что в блоке была обнаружена и исправлена ошибка;
+	// uncorrectable — что обнаружена ошибка, которую кодек исправить не может.
+	DecodeBlock(codedBits []uint8) (infoBits []uint8, corrected bool, uncorrectable bool)
+}
+
+const DefaultCodec = "cyclic74" // Кодек по умолчанию — исходный циклический код [7,4]
+
+var coderRegistry = map[string]BlockCoder{} // Зарегистрированные кодеки по имени
+
+// RegisterCoder регистрирует кодек под его именем.
+func RegisterCoder(coder BlockCoder) {
+	coderRegistry[coder.Name()] = coder
+}
+
+// LookupCoder возвращает кодек по имени.
+func LookupCoder(name string) (BlockCoder, error) {
+	coder, ok := coderRegistry[name]
+	if !ok {
+		return nil, fmt.Errorf("неизвестный кодек '%s' (допустимо: %s)", name, strings.Join(CoderNames(), ", "))
+	}
+	return coder, nil
+}
+
+// CoderNames возвращает отсортированный список имен зарегистрированных кодеков.
+func CoderNames() []string {
+	names := make([]string, 0, len(coderRegistry))
+	for name := range coderRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CodecConfig описывает параметрический кодек, регистрируемый под именем Name
+// (например, {"name": "rs64", "type": "reed_solomon", "n": 80, "k": 64}). Набор используемых
+// параметров зависит от типа кодека.
+type CodecConfig struct {
+	Name string `json:"name"` // Имя кодека в конфигурации и запросах
+	Type string `json:"type"` // Тип кодека (см. RegisterCodecType)
+	N    int    `json:"n,omitempty"`
+	K    int    `json:"k,omitempty"`
+	M    int    `json:"m,omitempty"` // Степень поля GF(2^m) (bch)
+	T    int    `json:"t,omitempty"` // Число исправляемых ошибок в блоке (bch)
+	// Polynomial — порождающий многочлен g(x) (cyclic): "x^4+x+1", "10011" или "0x13";
+	// Correct — исправлять одиночную ошибку по таблице синдромов (иначе только обнаружение)
+	Polynomial string `json:"polynomial,omitempty"`
+	Correct    bool   `json:"correct,omitempty"`
+	// File — файл с матрицами G и H линейного кода (matrix): JSON или CSV
+	File string `json:"file,omitempty"`
+	// Outer и Inner — имена внешнего и внутреннего кодеков каскадного кода (concatenated), заданных
+	// ранее; Depth — число слов внешнего кода, перемежаемых перед внутренним кодированием (по умолчанию 1)
+	Outer string `json:"outer,omitempty"`
+	Inner string `json:"inner,omitempty"`
+	Depth int    `json:"depth,omitempty"`
+}
+
+var codecFactories = map[string]func(CodecConfig) (BlockCoder, error){} // Конструкторы параметрических кодеков по типу
+
+// RegisterCodecType регистрирует конструктор параметрического кодека. Конструктор должен вернуть
+// кодек, имя которого (Name) совпадает с cfg.Name.
+func RegisterCodecType(codecType string, factory func(CodecConfig) (BlockCoder, error)) {
+	codecFactories[codecType] = factory
+}
+
+// RegisterCodecs создает параметрические кодеки по конфигурации и регистрирует их.
+func RegisterCodecs(configs []CodecConfig) error {
+	for i, cfg := range configs {
+		if cfg.Name == "" {
+			return fmt.Errorf("кодек %d: не задано имя", i+1)
+		}
+		if _, exists := coderRegistry[cfg.Name]; exists {
+			return fmt.Errorf("кодек %d: имя '%s' уже занято", i+1, cfg.Name)
+		}
+		factory, ok := codecFactories[cfg.Type]
+		if !ok {
+			types := make([]string, 0, len(codecFactories))
+			for t := range codecFactories {
+				types = append(types, t)
+			}
+			sort.Strings(types)
+			return fmt.Errorf("кодек %s: неизвестный тип '%s' (допустимо: %s)", cfg.Name, cfg.Type, strings.Join(types, ", "))
+		}
+		coder, err := factory(cfg)
+		if err != nil {
+			return fmt.Errorf("кодек %s (%s): %w", cfg.Name, cfg.Type, err)
+		}
+		RegisterCoder(coder)
+	}
+	return nil
+}
+
+// numBlocks возвращает число блоков кодека, необходимое для infoLen информационных бит.
+// Если infoLen не кратно k, последний блок дополняется нулевыми битами.
+func numBlocks(coder BlockCoder, infoLen int) int {
+	return (infoLen + coder.K() - 1) / coder.K()
+}
+
+// encodeBitStream разбивает поток информационных бит на блоки по k бит (последний блок
+// дополняется нулями) и кодирует каждый блок.
+func encodeBitStream(coder BlockCoder, bitStream []uint8) []uint8 {
+	n, k := coder.N(), coder.K()
+	blocks := numBlocks(coder, len(bitStream))
+	padded := make([]uint8, blocks*k)
+	copy(padded, bitStream)
+
+	encoded := make([]uint8, blocks*n)
+	for i := 0; i < blocks; i++ {
+		copy(encoded[i*n:(i+1)*n], coder.EncodeBlock(padded[i*k:(i+1)*k]))
+	}
+	return encoded
+}
+
+// decodeBitStream декодирует поток кодовых бит поблочно и возвращает первые infoLen информационных бит,
+// а также число блоков с исправленными и с неисправимыми ошибками.
+func decodeBitStream(coder BlockCoder, encoded []uint8, infoLen int) (decoded []uint8, correctedBlocks, errorBlocks int) {
+	n, k := coder.N(), coder.K()
+	blocks := len(encoded) / n
+	decoded = make([]uint8, blocks*k)
+	for i := 0; i < blocks; i++ {
+		infoBits, corrected, uncorrectable := coder.DecodeBlock(encoded[i*n : (i+1)*n])
+		copy(decoded[i*k:(i+1)*k], infoBits)
+		if corrected {
+			correctedBlocks++
+		}
+		if uncorrectable {
+			errorBlocks++
+		}
+	}
+	return decoded[:infoLen], correctedBlocks, errorBlocks
+}
+
+// cyclic74Coder — исходный циклический код [7,4] с g(x) = x^3 + x + 1 (только обнаружение ошибок).
+type cyclic74Coder struct{}
+
+func (cyclic74Coder) Name() string { return DefaultCodec }
+func (cyclic74Coder) N() int       { return CodedBitsPerBlock }
+func (cyclic74Coder) K() int       { return InfoBitsPerBlock }
+
+func (cyclic74Coder) EncodeBlock(infoBits []uint8) []uint8 {
+	return cyclicEncode7_4Block(infoBits)
+}
+
+func (cyclic74Coder) DecodeBlock(codedBits []uint8) ([]uint8, bool, bool) {
+	infoBits, detectedError := cyclicDecode7_4Block(codedBits)
+	return infoBits, false, detectedError
+}
+
+func init() {
+	RegisterCoder(cyclic74Coder{})
+}
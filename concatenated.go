@@ -0,0 +1,104 @@
+package main
+
+import "fmt"
+
+// concatenatedCoder — каскадный код: depth кодовых слов внешнего кода перемежаются побитно
+// (бит j слова d занимает позицию j*depth + d) и кодируются внутренним кодом. Внутренний код
+// исправляет одиночные ошибки канала, а его ошибки декодирования (пакеты ошибок в пределах
+// внутреннего блока) после деперемежения распределяются по depth словам внешнего кода, который
+// их исправляет, как в классической схеме RS + сверточный/блочный код.
+//
+// Каскадный код сам является блочным кодом [n,k] с k = depth·k_outer и n, равным длине
+// внутреннего кодирования depth·n_outer бит, поэтому каскады можно вкладывать друг в друга
+// и использовать везде, где выбирается кодек.
+type concatenatedCoder struct {
+	name         string
+	outer, inner BlockCoder
+	depth        int // Число перемежаемых слов внешнего кода (1 — без перемежения)
+	n, k         int
+}
+
+// newConcatenatedCoder создает каскадный код из зарегистрированных кодеков outer и inner.
+func newConcatenatedCoder(name, outerName, innerName string, depth int) (*concatenatedCoder, error) {
+	outer, err := LookupCoder(outerName)
+	if err != nil {
+		return nil, fmt.Errorf("внешний кодек: %w", err)
+	}
+	inner, err := LookupCoder(innerName)
+	if err != nil {
+		return nil, fmt.Errorf("внутренний кодек: %w", err)
+	}
+	if depth == 0 {
+		depth = 1
+	}
+	if depth < 1 {
+		return nil, fmt.Errorf("глубина перемежения должна быть не менее 1, задано %d", depth)
+	}
+	if name == "" {
+		name = outer.Name() + "+" + inner.Name()
+	}
+	outerBits := depth * outer.N()
+	return &concatenatedCoder{
+		name:  name,
+		outer: outer,
+		inner: inner,
+		depth: depth,
+		n:     numBlocks(inner, outerBits) * inner.N(),
+		k:     depth * outer.K(),
+	}, nil
+}
+
+func (c *concatenatedCoder) Name() string { return c.name }
+func (c *concatenatedCoder) N() int       { return c.n }
+func (c *concatenatedCoder) K() int       { return c.k }
+
+// EncodeBlock кодирует k информационных бит внешним кодом, перемежает слова и кодирует внутренним.
+func (c *concatenatedCoder) EncodeBlock(infoBits []uint8) []uint8 {
+	ok, on := c.outer.K(), c.outer.N()
+	stream := make([]uint8, c.depth*on)
+	for d := 0; d < c.depth; d++ {
+		codeword := c.outer.EncodeBlock(infoBits[d*ok : (d+1)*ok])
+		for j, bit := range codeword {
+			stream[j*c.depth+d] = bit
+		}
+	}
+	return encodeBitStream(c.inner, stream)
+}
+
+// decodeOuter деперемежает поток, декодированный внутренним кодом, и декодирует слова внешнего кода.
+func (c *concatenatedCoder) decodeOuter(stream []uint8, innerCorrected, innerErrors int) ([]uint8, bool, bool) {
+	ok, on := c.outer.K(), c.outer.N()
+	infoBits := make([]uint8, c.k)
+	codeword := make([]uint8, on)
+	corrected, uncorrectable := innerCorrected > 0, false
+	for d := 0; d < c.depth; d++ {
+		for j := range codeword {
+			codeword[j] = stream[j*c.depth+d]
+		}
+		info, outerCorrected, outerUncorrectable := c.outer.DecodeBlock(codeword)
+		copy(infoBits[d*ok:(d+1)*ok], info)
+		corrected = corrected || outerCorrected
+		uncorrectable = uncorrectable || outerUncorrectable
+	}
+	// Ошибки, обнаруженные внутренним кодом, исправлены внешним, если он не сообщил о неисправимой ошибке
+	corrected = corrected || innerErrors > 0
+	return infoBits, corrected && !uncorrectable, uncorrectable
+}
+
+// DecodeBlock декодирует внутренний код, деперемежает поток и декодирует внешний код.
+func (c *concatenatedCoder) DecodeBlock(codedBits []uint8) ([]uint8, bool, bool) {
+	stream, innerCorrected, innerErrors := decodeBitStream(c.inner, codedBits, c.depth*c.outer.N())
+	return c.decodeOuter(stream, innerCorrected, innerErrors)
+}
+
+// DecodeBlockSoft декодирует внутренний код по мягким решениям, внешний — по жестким.
+func (c *concatenatedCoder) DecodeBlockSoft(llr []float64) ([]uint8, bool, bool) {
+	stream, innerCorrected, innerErrors := decodeBitStreamSoft(c.inner, llr, c.depth*c.outer.N())
+	return c.decodeOuter(stream, innerCorrected, innerErrors)
+}
+
+func init() {
+	RegisterCodecType("concatenated", func(cfg CodecConfig) (BlockCoder, error) {
+		return newConcatenatedCoder(cfg.Name, cfg.Outer, cfg.Inner, cfg.Depth)
+	})
+}
@@ -0,0 +1,496 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// ConfigPathEnv задает имя переменной окружения с путем к файлу конфигурации.
+const (
+	ConfigPathEnv     = "CHANNEL_LAYER_CONFIG" // Переменная окружения с путем к JSON-файлу конфигурации
+	DefaultConfigPath = "config.json"          // Путь к файлу конфигурации по умолчанию
+)
+
+// Config содержит параметры канального уровня, загружаемые из JSON-файла конфигурации.
+// Поля, отсутствующие в файле, принимают значения по умолчанию (см. DefaultConfig).
+type Config struct {
+	// Quotas задает квоты для отдельных ключей API (ключ передается в заголовке X-API-Key).
+	Quotas map[string]Quota `json:"quotas"`
+	// DefaultQuota применяется к ключам без собственной квоты, в том числе к запросам без ключа.
+	// Нулевые значения лимитов означают отсутствие ограничения.
+	DefaultQuota Quota `json:"default_quota"`
+	// Stats задает параметры сохранения статистики.
+	Stats StatsConfig `json:"stats"`
+	// Journal задает параметры журнала исходящих сегментов.
+	Journal JournalConfig `json:"journal"`
+	// HA задает режим высокой доступности (активный/резервный экземпляр).
+	HA HAConfig `json:"ha"`
+	// State задает хранилище разделяемого состояния (кэш дубликатов, состояние ARQ).
+	State StateConfig `json:"state"`
+	// Dedup задает обнаружение повторно присланных сегментов.
+	Dedup DedupConfig `json:"dedup"`
+	// Queue задает очередь обработки сегментов.
+	Queue QueueConfig `json:"queue"`
+	// Overload задает автоматический переход в режим деградации при длительной перегрузке.
+	Overload OverloadConfig `json:"overload"`
+	// Async задает асинхронную обработку сегментов.
+	Async AsyncConfig `json:"async"`
+	// API задает версию формата ответов /code по умолчанию.
+	API APIConfig `json:"api"`
+	// ChannelErrorPolicy задает обработку сегментов с неисправимой ошибкой канала:
+	// "error" (по умолчанию) — вернуть ошибку отправителю, "drop" — отбросить сегмент как потерянный,
+	// "forward" — переслать на /transfer с is_channel_error=true, чтобы решение принял транспортный уровень.
+	ChannelErrorPolicy string `json:"channel_error_policy"`
+	// Forward задает параметры пересылки сегментов на /transfer.
+	Forward ForwardConfig `json:"forward"`
+	// Codec задает кодек полезной нагрузки: "cyclic74" (только обнаружение ошибок), "cyclic74_syndrome"
+	// и "cyclic74_majority" (тот же код с табличным или мажоритарным исправлением), "hamming1511", "secded84"
+	// (расширенный Хэмминг [8,4]: исправление одиночной и обнаружение двойной ошибки), "product8x8",
+	// "parity_even", "parity_odd" (бит четности на байт) или "checksum16" (без кодирования,
+	// только 16-битная контрольная сумма кадра).
+	Codec string `json:"codec"`
+	// Codecs задает дополнительные параметрические кодеки, доступные по имени в codec, в вариантах
+	// A/B-эксперимента и в запросах (например, [{"name": "rs32", "type": "reed_solomon", "n": 32, "k": 28}]).
+	// Типы: reed_solomon (RS(n,k) над GF(256), n и k в байтах), bch (двоичный код БЧХ длины 2^m - 1,
+	// исправляющий t ошибок, например {"name": "bch127", "type": "bch", "m": 7, "t": 5}), repetition
+	// (код повторения с n повторениями бита), cyclic (циклический код [n,k] с порождающим многочленом,
+	// например {"name": "cyclic15", "type": "cyclic", "n": 15, "k": 11, "polynomial": "x^4+x+1", "correct": true}),
+	// matrix (линейный код по матрицам G и H из файла JSON или CSV, {"name": "golay", "type": "matrix", "file": "golay.json"}),
+	// concatenated (каскадный код из заданных ранее кодеков с перемежением depth слов внешнего кода,
+	// например {"name": "rs_cyclic", "type": "concatenated", "outer": "rs160_140", "inner": "cyclic74_syndrome", "depth": 2}).
+	// Кодеки rs255_223, rs160_140, bch15_7, bch31_21, bch63_45, repetition3 и repetition5 доступны всегда.
+	Codecs []CodecConfig `json:"codecs"`
+	// Stages задает этапы обработки закодированного потока перед передачей по каналу
+	// (например, {"type": "conv_interleaver", "depth": 8, "delay": 2}), применяемые по порядку.
+	// Типы этапов: conv_interleaver, block_interleaver (глубина depth строк), differential, 4b5b,
+	// 8b10b, manchester, hdlc (флаги и бит-стаффинг), byte_stuffing (флаги 0x7E и экранирование байтов, как в PPP),
+	// cobs (Consistent Overhead Byte Stuffing с нулевым разделителем).
+	Stages []StageConfig `json:"stages"`
+	// CRC32C включает контроль целостности: CRC-32C исходной полезной нагрузки проверяется после
+	// декодирования и передается на /transfer (поля crc32c и payload_length).
+	CRC32C bool `json:"crc32c"`
+	// FCS задает контрольную последовательность кадра: "crc16" (CRC-16/CCITT) или "crc32" (CRC-32 IEEE).
+	// Она дописывается к полезной нагрузке перед кодированием и проверяется после декодирования;
+	// несовпадение (ошибка, пропущенная или неверно исправленная кодеком) считается ошибкой канала
+	// и учитывается в /stats отдельно (fcs_failures). Пусто — не используется.
+	FCS string `json:"fcs"`
+	// Puncture задает шаблон выкалывания закодированного потока из 0 и 1 (например, "1110": каждый
+	// четвертый бит не передается). Удаленные биты восстанавливаются перед декодированием как стирания,
+	// итоговая скорость кода (k/n, деленное на долю передаваемых бит) выводится в /stats. Пусто — без выкалывания.
+	Puncture string `json:"puncture"`
+	// Scrambler задает аддитивный скремблер информационных бит кадра (полезная нагрузка и FCS) перед
+	// кодированием, например {"polynomial": "x^7+x^4+1", "seed": 93}; дескремблирование выполняется
+	// после декодирования. Не задано — без скремблирования.
+	Scrambler ScramblerConfig `json:"scrambler"`
+	// Seed задает начальное значение генератора случайных чисел канала (0 — по текущему времени;
+	// заменяется флагом -seed). При одном seed и одном порядке обработки сегментов (queue.workers = 1)
+	// прогон воспроизводится бит в бит; seed и позиция генератора для каждого кадра записываются в журнал
+	// и отчет канала.
+	Seed int64 `json:"seed"`
+	// Schedule задает изменение вероятностей P и R канала во времени, например {"steps": [{"duration": "60s",
+	// "p": 0.01}, {"duration": "60s", "p": 0.2, "r": 0.1}], "loop": true}. Время отсчитывается от запуска
+	// по монотонным часам; после последнего интервала действует он же (или расписание повторяется при loop).
+	Schedule ScheduleConfig `json:"schedule"`
+	// Outages задает перерывы связи, во время которых теряются все кадры независимо от R, например
+	// [{"start": "30s", "duration": "10s"}, {"start": "2m", "duration": "5s", "every": "1m"}]. Начало
+	// отсчитывается от запуска; перерыв также можно запустить или отменить через /admin/outage.
+	Outages []OutageConfig `json:"outages"`
+	// PathLoss задает P через длину линии связи вместо постоянной вероятности, например {"distance": 100,
+	// "exponent": 3, "reference_snr_db": 40}: SNR(d) = SNR(d0) - 10·n·lg(d/d0), P = Q(√(2·SNR)).
+	// Длину линии можно менять во время работы через /admin/distance. Расписание, задающее P, имеет приоритет.
+	PathLoss PathLossConfig `json:"path_loss"`
+	// Shortening включает укорочение кода вместо дополнения полезной нагрузки нулями до FixedPayloadSize байт (-payload-size):
+	// в кадр передаются заголовок с длиной полезной нагрузки и сама нагрузка, а нулевые информационные
+	// биты последнего блока систематического кодека не передаются. На /transfer пересылаются ровно
+	// исходные байты (как при forward.trim_padding).
+	Shortening bool `json:"shortening"`
+	// Retries задает число внутренних повторных передач: кадр, потерянный в канале или принятый
+	// с неисправимой ошибкой, передается заново до retries раз, и транспортному уровню сообщается
+	// о неудаче только после последней попытки. Повторы моделируются сразу, без ожидания подтверждений;
+	// для моделирования протокола с подтверждениями используется arq (с retries несовместим).
+	Retries int `json:"retries"`
+	// HARQ включает гибридный ARQ для внутренних повторных передач: принятые передачи кадра
+	// не отбрасываются, а складываются и декодируются совместно. При выкалывании (puncture) первая
+	// передача содержит биты, оставленные шаблоном, вторая — выколотые биты (incremental redundancy),
+	// последующие — весь закодированный поток. Требует retries.
+	HARQ bool `json:"harq"`
+	// Decision задает режим решений о принятых битах: "hard" (по умолчанию) — декодер получает биты,
+	// "soft" — логарифмические отношения правдоподобия (LLR) канала BPSK с гауссовым шумом, вероятность
+	// ошибки бита в котором равна доле искаженных бит кадра; ошибки остаются теми же, что смоделировала
+	// цепочка искажений.
+	// Мягкие решения декодирует код повторения (сумма LLR), остальные кодеки — алгоритмом Чейза.
+	// Совместимы только этапы-перемежители.
+	Decision string `json:"decision"`
+	// Control задает обработку управляющих сегментов (type = "control").
+	Control ControlConfig `json:"control"`
+	// Jitter задает буфер джиттера перед пересылкой на /transfer.
+	Jitter JitterConfig `json:"jitter"`
+	// FEC задает межкадровую коррекцию потерь: после каждых group_size кадров данных сообщения
+	// передается кадр четности (XOR кадров группы), по которому восстанавливается один потерянный
+	// кадр группы. Восстановленный сегмент пересылается на /transfer с флагом recovered.
+	FEC FECConfig `json:"fec"`
+	// ARQ задает автоматический запрос повторной передачи: приемник подтверждает каждый кадр (ACK)
+	// или сообщает о неисправимой ошибке (NAK), передатчик повторяет кадр по NAK или по истечении
+	// времени ожидания, например {"mode": "stop_and_wait", "timeout": "100ms", "max_retransmissions": 3,
+	// "ack_loss": 0.05}. Транспортному уровню доставляется первая копия кадра, принятая без ошибки.
+	// В режиме go_back_n кадры сообщения передаются окном из window кадров ({"mode": "go_back_n",
+	// "window": 4}), приемник принимает их строго по порядку, а после ошибки повторяется все окно.
+	ARQ ARQConfig `json:"arq"`
+	// FlowControl задает управление потоком кредитами: отправителю разрешено не более window сегментов
+	// в обработке, свободные кредиты объявляются в заголовках X-Channel-Window и X-Channel-Credits ответа
+	// /code, а возврат кредита сообщается на update_url, например {"window": 4, "queue": true,
+	// "update_url": "http://localhost:8082/window"}. Сегменты сверх окна отклоняются (429) или ожидают кредита.
+	FlowControl FlowControlConfig `json:"flow_control"`
+	// Sequence включает порядковые номера кадров канального уровня, независимые от segment_number:
+	// приемник обнаруживает по ним пропущенные кадры, кадры вне порядка и дубликаты и сообщает о них
+	// в поле link сегмента, пересылаемого на /transfer, например {"enabled": true, "drop_duplicates": true}.
+	Sequence SequenceConfig `json:"sequence"`
+	// Handshake требует установления соединения перед передачей данных: отправитель посылает
+	// управляющий кадр {"sender": "...", "frame": "connect"} на /link и получает подтверждение accept,
+	// разрывает соединение кадром disconnect. Сегменты без соединения отклоняются (409). Таблица
+	// соединений доступна на /admin/links, например {"required": true}.
+	Handshake HandshakeConfig `json:"handshake"`
+	// Keepalive задает проверку доступности транспортного уровня: на forward.url каждые interval
+	// отправляется кадр {"type": "keepalive", "seq": N, "send_time": "..."}; после misses пропущенных
+	// ответов подряд звено признается недоступным (событие link_down, состояние в /stats), например
+	// {"interval": "5s", "misses": 3, "reject_while_down": true}. С reject_while_down сегменты на /code
+	// отклоняются (503), пока транспортный уровень не ответит снова.
+	Keepalive KeepaliveConfig `json:"keepalive"`
+	// Retransmit задает буфер повторной передачи: сегменты данных последних messages сообщений
+	// хранятся ttl, и транспортный уровень может запросить повторную обработку сегмента через /nak
+	// ({"send_time": "...", "segment_number": N} или {"timestamp": <нс>, ...}), например
+	// {"messages": 256, "ttl": "5m"}.
+	Retransmit RetransmitConfig `json:"retransmit"`
+	// Negotiation включает согласование параметров звена при его установлении: транспортный уровень
+	// предлагает на /negotiate окно Go-Back-N и размер кадра ({"sender": "a", "window": 4, "frame_size": 64}),
+	// канальный уровень уменьшает их до arq.window и размера полезной нагрузки канала и сохраняет
+	// в хранилище состояния (state). Сегменты отправителя больше согласованного кадра отклоняются.
+	Negotiation NegotiationConfig `json:"negotiation"`
+	// Link задает скорость передачи и задержку распространения канала.
+	Link LinkConfig `json:"link"`
+	// ClockSkew задает рассинхронизацию часов: смещение и уход метки send_time, пересылаемой на /transfer.
+	ClockSkew ClockSkewConfig `json:"clock_skew"`
+	// Capture задает запись кадров до и после искажений в файл pcapng.
+	Capture CaptureConfig `json:"capture"`
+	// Tap задает ответвитель кадров: копии кадра до кодирования, после искажений и после декодирования
+	// отправляются на url без ожидания ответа, например {"url": "http://localhost:9000/tap"}.
+	Tap TapConfig `json:"tap"`
+	// Impairments задает цепочку искажений кадра в канале, применяемых по порядку
+	// (например, [{"type": "loss"}, {"type": "burst", "probability": 0.05, "length": 4}]). Типы:
+	//   - loss — потеря кадра с вероятностью R;
+	//   - burst_loss — потери кадров сериями со средней длиной length кадров и средней долей потерь R
+	//     ({"type": "burst_loss", "length": 5});
+	//   - bit_error — инверсия не более одного бита кадра с вероятностью P;
+	//   - bsc — независимая инверсия каждого бита с вероятностью P ({"type": "bsc", "probability": 0.001});
+	//   - bec — независимое стирание каждого бита с вероятностью P: декодер получает отметки стираний
+	//     ({"type": "bec", "probability": 0.05});
+	//   - z_channel — несимметричные ошибки с разными вероятностями инверсии 0→1 и 1→0
+	//     ({"type": "z_channel", "one_to_zero": 0.01});
+	//   - k_errors — ровно k случайных ошибок в кадре ({"type": "k_errors", "count": 2}
+	//     или {"type": "k_errors", "weights": [0, 1, 1]});
+	//   - burst — пакет ошибок заданной длины;
+	//   - slip — вставка или выпадение бит при потере синхронизации
+	//     ({"type": "slip", "probability": 0.01, "length": 1, "mode": "delete"});
+	//   - truncate — потеря конца кадра: последних length бит или со случайной позиции
+	//     ({"type": "truncate", "probability": 0.01});
+	//   - gilbert_elliott — пакеты ошибок по модели с хорошим и плохим состояниями
+	//     ({"type": "gilbert_elliott", "good_to_bad": 0.001, "bad_to_good": 0.1, "ber_bad": 0.5});
+	//   - markov — N состояний со своими вероятностями ошибки бита и потери кадра и матрицей переходов
+	//     на каждом кадре ({"type": "markov", "states": [{"name": "good", "bit_error_rate": 0.0001},
+	//     {"name": "bad", "bit_error_rate": 0.01, "loss_probability": 0.3}], "transitions": [[0.95, 0.05], [0.2, 0.8]]});
+	//   - awgn — BPSK с гауссовым шумом при заданном Eb/N0, формирует и мягкие решения ({"type": "awgn", "eb_n0_db": 4});
+	//   - fading — то же с замираниями Рэлея или Райса на кадр или на бит
+	//     ({"type": "fading", "eb_n0_db": 10, "fading": "rician", "rician_k": 3});
+	//   - ber_rate — ошибок в секунду при заданной скорости ({"type": "ber_rate", "errors_per_second": 2, "bitrate": 9600});
+	//     без bitrate используется link.bitrate, и хотя бы одна из скоростей должна быть задана;
+	//   - delay — постоянная или случайная задержка с распределением fixed, uniform, normal или exponential
+	//     ({"type": "delay", "delay": "50ms", "jitter": "20ms", "distribution": "normal"});
+	//   - duplicate — дополнительные копии кадра, при необходимости с интервалом между копиями
+	//     ({"type": "duplicate", "probability": 0.05, "copies": 1, "delay": "200ms"});
+	//   - fixture — решения канала по порядку из файла вместо генератора случайных чисел
+	//     ({"type": "fixture", "file": "channel.fixture"});
+	//   - trace — воспроизведение записанной трассы ошибок: номера кадров с индексами ошибочных бит
+	//     ({"type": "trace", "file": "measured.trace"}) или позиции ошибок в непрерывном потоке бит
+	//     ({"type": "trace", "file": "ber.trace", "mode": "stream", "loop": true});
+	//   - scenario — сценарий событий по номерам кадров, времени и номерам сегментов из JSON-файла
+	//     ({"type": "scenario", "file": "lab1.json"}, где, например, {"events": [{"at_frame": 50, "action": "burst",
+	//     "length": 8}, {"segments": [3, 5], "action": "drop"}, {"at": "30s", "duration": "10s", "action": "set", "r": 0.3}]}).
+	// По умолчанию — [loss, bit_error] с вероятностями R и P.
+	Impairments []ImpairmentConfig `json:"impairments"`
+	// Hooks задает обработчики, преобразующие полезную нагрузку перед кодированием и после декодирования
+	// (например, {"pre_coding": [{"name": "invert"}], "post_decoding": [{"url": "http://localhost:9000/hook"}]}).
+	Hooks HooksConfig `json:"hooks"`
+	// AB задает A/B-эксперимент: сегменты распределяются между вариантами кодирования, а итоги
+	// учитываются по каждому варианту (например, {"arms": [{"name": "a", "codec": "cyclic74"},
+	// {"name": "b", "codec": "hamming1511"}]}).
+	AB ABConfig `json:"ab"`
+	// Profiles задает параметры канала по отправителю (sender), например {"alice": {"p": 0.2, "r": 0.05},
+	// "bob": {"p": 0, "codec": "hamming1511"}}. Сегменты отправителя с профилем обрабатываются
+	// его каналом, в том числе при A/B-эксперименте.
+	Profiles map[string]SenderProfileConfig `json:"profiles"`
+}
+
+// StatsConfig описывает сохранение статистики между перезапусками.
+type StatsConfig struct {
+	DBPath         string `json:"db_path"`         // Путь к файлу хранилища статистики; пустая строка отключает сохранение
+	RecordSegments bool   `json:"record_segments"` // Сохранять ли, помимо поминутных агрегатов, итог каждого сегмента (со случайными решениями канала)
+}
+
+// JournalConfig описывает персистентный журнал исходящих сегментов.
+type JournalConfig struct {
+	Path string `json:"path"` // Путь к файлу журнала; пустая строка отключает журнал и восстановление при запуске
+}
+
+// HAConfig описывает режим активный/резервный экземпляр.
+// Экземпляры должны использовать общий файл блокировки и общий журнал исходящих сегментов.
+type HAConfig struct {
+	LockFile string `json:"lock_file"` // Путь к файлу блокировки; пустая строка отключает режим
+}
+
+// StateConfig описывает хранилище разделяемого состояния.
+type StateConfig struct {
+	Backend       string `json:"backend"`        // "memory" (по умолчанию), "redis" или "file"
+	RedisAddr     string `json:"redis_addr"`     // Адрес Redis (host:port)
+	RedisPassword string `json:"redis_password"` // Пароль Redis (если требуется)
+	RedisDB       int    `json:"redis_db"`       // Номер базы Redis
+	KeyPrefix     string `json:"key_prefix"`     // Префикс ключей, позволяющий нескольким стендам делить один Redis
+	FilePath      string `json:"file_path"`      // Путь к файлу встроенного хранилища (backend "file")
+}
+
+// DedupConfig описывает обнаружение дубликатов.
+type DedupConfig struct {
+	Enabled bool     `json:"enabled"` // Включить обнаружение дубликатов
+	TTL     Duration `json:"ttl"`     // Сколько помнить доставленные сегменты
+	// Lease — срок отметки обрабатываемого сегмента; отметка продлевается, пока сегмент обрабатывается,
+	// поэтому после аварийного завершения экземпляра повторная передача принимается через Lease, а не через TTL
+	Lease Duration `json:"lease"`
+}
+
+// QueueConfig описывает очередь обработки со взвешенным справедливым обслуживанием отправителей.
+type QueueConfig struct {
+	Workers       int                `json:"workers"`        // Число одновременно обрабатываемых сегментов
+	Capacity      int                `json:"capacity"`       // Максимальное число ожидающих сегментов (0 — без ограничения)
+	Weights       map[string]float64 `json:"weights"`        // Веса отправителей (по полю sender)
+	DefaultWeight float64            `json:"default_weight"` // Вес отправителей, отсутствующих в weights
+}
+
+// OverloadConfig описывает режим деградации при длительной перегрузке очереди обработки.
+type OverloadConfig struct {
+	Enabled        bool     `json:"enabled"`         // Включить автоматический переход в режим деградации
+	QueueThreshold int      `json:"queue_threshold"` // Число ожидающих сегментов, начиная с которого очередь перегружена (0 — не учитывается)
+	WaitThreshold  Duration `json:"wait_threshold"`  // Время ожидания старейшего сегмента, начиная с которого очередь перегружена (0 — не учитывается)
+	Sustain        Duration `json:"sustain"`         // Сколько должна длиться перегрузка для перехода в режим деградации
+	Recover        Duration `json:"recover"`         // Сколько должна длиться работа без перегрузки для возврата в нормальный режим
+	Action         string   `json:"action"`          // "skip_impairments" или "skip_coding"
+}
+
+// AsyncConfig описывает асинхронный режим: /code сразу отвечает 202 с идентификатором сегмента,
+// а итог обработки доступен на /segments/{id}. Записи реестра также сохраняются в хранилище
+// состояния (state) на registry_ttl, поэтому с хранилищем file или redis итог сегмента
+// можно узнать и после перезапуска или на другом экземпляре.
+type AsyncConfig struct {
+	Enabled      bool     `json:"enabled"`       // Асинхронный режим по умолчанию (клиент может переопределить параметром ?async=)
+	RegistrySize int      `json:"registry_size"` // Сколько последних сегментов хранить в реестре состояний
+	RegistryTTL  Duration `json:"registry_ttl"`  // Сколько хранить записи реестра в хранилище состояния
+}
+
+// APIConfig описывает версионирование ответов /code.
+type APIConfig struct {
+	DefaultVersion int `json:"default_version"` // Версия для запросов без заголовка X-API-Version (1 или 2)
+}
+
+// ForwardConfig описывает пересылку сегментов транспортному уровню.
+type ForwardConfig struct {
+	// LostPlaceholders включает пересылку заглушки (метаданные сегмента без полезной нагрузки, lost=true)
+	// для потерянных кадров, чтобы транспортный уровень отличал потерю в канале от неотправленного сегмента.
+	LostPlaceholders bool `json:"lost_placeholders"`
+	// TrimPadding включает отсечение нулевого паддинга: на /transfer пересылается полезная нагрузка
+	// исходной длины вместо FixedPayloadSize байт.
+	TrimPadding bool `json:"trim_padding"`
+	// NackURL — адрес на стороне отправителя (прикладного уровня), на который POST запросом
+	// отправляются отрицательные подтверждения при потере сегмента и неисправимой ошибке.
+	// Пустая строка отключает отправку.
+	NackURL string `json:"nack_url"`
+	// URL — адрес пересылки обработанных сегментов (по умолчанию /transfer на localhost:8080).
+	// Для симуляции многозвенного канала указывается /code следующего экземпляра канального уровня
+	// (у которого отключен асинхронный режим).
+	URL string `json:"url"`
+	// CarryHops включает передачу сведений о пройденных звеньях (поле hops) на первом звене;
+	// экземпляр, получивший сегмент с hops, передает их дальше независимо от параметра.
+	CarryHops bool `json:"carry_hops"`
+	// NodeName — имя экземпляра в сведениях о звеньях (по умолчанию имя хоста и порт).
+	NodeName string `json:"node_name"`
+	// MaxHops — максимальное число звеньев, пройденных сегментом до этого экземпляра; сегменты,
+	// прошедшие больше, отклоняются (защита от зацикливания). 0 — без ограничения.
+	MaxHops int `json:"max_hops"`
+}
+
+// ControlConfig описывает обработку управляющих сегментов (установление и разрыв соединения,
+// keepalive), которые верхние уровни передают через канал наравне с данными.
+type ControlConfig struct {
+	ExemptImpairments bool `json:"exempt_impairments"` // Не симулировать потери и ошибки для управляющих сегментов
+}
+
+// JitterConfig описывает буфер джиттера, выдающий кадры транспортному уровню с фиксированной
+// задержкой воспроизведения (например, для голосовых сообщений).
+type JitterConfig struct {
+	Enabled       bool     `json:"enabled"`        // Включить буфер джиттера
+	PlayoutDelay  Duration `json:"playout_delay"`  // Задержка воспроизведения относительно прихода первого кадра сообщения
+	FrameInterval Duration `json:"frame_interval"` // Номинальный интервал между соседними сегментами сообщения
+}
+
+// LinkConfig описывает задержку кадра в канале: время передачи пропорционально длине
+// переданного кадра (t = L / C), задержка распространения постоянна.
+type LinkConfig struct {
+	Bitrate          float64  `json:"bitrate"`           // Скорость передачи (бит/с); 0 — без задержки передачи
+	PropagationDelay Duration `json:"propagation_delay"` // Задержка распространения
+	// Queueing — кадры передаются по линии по одному: кадр ждет окончания передачи предыдущих,
+	// так что пропускная способность канала ограничена скоростью передачи (требует bitrate)
+	Queueing bool `json:"queueing"`
+	// HalfDuplex — кадры передаются через общую полудуплексную среду: кадры, находящиеся в эфире
+	// одновременно (в пределах окна Airtime от начала передачи, по умолчанию — времени передачи кадра),
+	// сталкиваются и искажаются оба (Collision: corrupt — случайные биты, lose — потеря кадра)
+	HalfDuplex bool     `json:"half_duplex"`
+	Airtime    Duration `json:"airtime"`
+	Collision  string   `json:"collision"`
+}
+
+// ClockSkewConfig описывает симуляцию рассинхронизации часов отправителя и получателя.
+type ClockSkewConfig struct {
+	Offset   Duration `json:"offset"`    // Постоянное смещение (может быть отрицательным, например "-1.5s")
+	DriftPPM float64  `json:"drift_ppm"` // Уход часов в миллионных долях, накапливаемый с момента запуска
+}
+
+// CaptureConfig описывает захват кадров для просмотра в Wireshark.
+type CaptureConfig struct {
+	Path string `json:"path"` // Путь к файлу pcapng (дозапись); пустая строка отключает захват
+}
+
+// TapConfig описывает ответвитель кадров для внешних средств анализа.
+type TapConfig struct {
+	URL       string `json:"url"`                  // Адрес, на который отправляются копии кадров; пустая строка отключает ответвитель
+	QueueSize int    `json:"queue_size,omitempty"` // Емкость очереди копий (по умолчанию 1024); при переполнении копии отбрасываются
+}
+
+// HooksConfig описывает обработчики полезной нагрузки, применяемые по порядку.
+type HooksConfig struct {
+	PreCoding    []PayloadHookConfig `json:"pre_coding"`    // Перед паддингом и кодированием
+	PostDecoding []PayloadHookConfig `json:"post_decoding"` // После декодирования, перед пересылкой на /transfer
+}
+
+// ABConfig описывает A/B-эксперимент; пустой список вариантов отключает его.
+type ABConfig struct {
+	Arms []ABArmConfig `json:"arms"`
+}
+
+// Duration — продолжительность, задаваемая в конфигурации строкой в формате time.ParseDuration
+// (например, "500ms", "10m").
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON разбирает продолжительность из строки JSON.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("продолжительность должна быть строкой (например, \"10s\"): %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// MarshalJSON сериализует продолжительность в строку JSON.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// DefaultConfig возвращает конфигурацию по умолчанию (без квот, состояние в памяти).
+func DefaultConfig() *Config {
+	return &Config{
+		Quotas: map[string]Quota{},
+		State: StateConfig{
+			Backend:   StateBackendMemory,
+			RedisAddr: "localhost:6379",
+			KeyPrefix: "channel-layer:",
+			FilePath:  "channel-layer-state.jsonl",
+		},
+		Dedup: DedupConfig{
+			TTL:   Duration{10 * time.Minute},
+			Lease: Duration{30 * time.Second},
+		},
+		Queue: QueueConfig{
+			Workers:       8,
+			Capacity:      1000,
+			Weights:       map[string]float64{},
+			DefaultWeight: 1,
+		},
+		Overload: OverloadConfig{
+			QueueThreshold: 100,
+			WaitThreshold:  Duration{time.Second},
+			Sustain:        Duration{5 * time.Second},
+			Recover:        Duration{10 * time.Second},
+			Action:         DegradeSkipImpairments,
+		},
+		Async: AsyncConfig{
+			RegistrySize: 10000,
+			RegistryTTL:  Duration{24 * time.Hour},
+		},
+		API: APIConfig{
+			DefaultVersion: APIVersion1,
+		},
+		Forward: ForwardConfig{
+			URL:     DefaultTransferURL,
+			MaxHops: 8,
+		},
+		ChannelErrorPolicy: ChannelErrorPolicyError,
+		Codec:              DefaultCodec,
+		Control: ControlConfig{
+			ExemptImpairments: true,
+		},
+		Jitter: JitterConfig{
+			PlayoutDelay:  Duration{200 * time.Millisecond},
+			FrameInterval: Duration{20 * time.Millisecond},
+		},
+	}
+}
+
+// LoadConfig читает конфигурацию из JSON-файла по указанному пути.
+// Если файл не существует, возвращается конфигурация по умолчанию.
+func LoadConfig(path string) (*Config, error) {
+	cfg := DefaultConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			log.Printf("Config: Файл конфигурации %s не найден, используются значения по умолчанию", path)
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("не удалось прочитать файл конфигурации %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("не удалось разобрать файл конфигурации %s: %w", path, err)
+	}
+	if cfg.Quotas == nil {
+		cfg.Quotas = map[string]Quota{}
+	}
+
+	log.Printf("Config: Загружена конфигурация из %s", path)
+	return cfg, nil
+}
+
+// configPath возвращает путь к файлу конфигурации с учетом переменной окружения.
+func configPath() string {
+	if path := os.Getenv(ConfigPathEnv); path != "" {
+		return path
+	}
+	return DefaultConfigPath
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	LinkEndpoint       = "/link"        // Конечная точка управляющих кадров установления и разрыва соединения
+	AdminLinksEndpoint = "/admin/links" // Конечная точка таблицы соединений
+)
+
+// Управляющие кадры соединения канального уровня (аналоги кадров HDLC).
+const (
+	LinkFrameConnect      = "connect"      // Запрос установления соединения (SABM)
+	LinkFrameDisconnect   = "disconnect"   // Запрос разрыва соединения (DISC)
+	LinkFrameAccept       = "accept"       // Подтверждение запроса (UA)
+	LinkFrameDisconnected = "disconnected" // Ответ на разрыв неустановленного соединения (DM)
+)
+
+// Состояния соединения с отправителем.
+const (
+	LinkStateDisconnected = "disconnected"
+	LinkStateEstablished  = "established"
+)
+
+// HandshakeConfig задает установление соединения канального уровня перед передачей данных.
+type HandshakeConfig struct {
+	Required bool `json:"required"` // Отклонять сегменты отправителей без установленного соединения
+}
+
+// LinkEntry — соединение с отправителем в таблице соединений.
+type LinkEntry struct {
+	Sender        string     `json:"sender"`
+	State         string     `json:"state"`                    // Состояние (см. LinkState*)
+	EstablishedAt *time.Time `json:"established_at,omitempty"` // Момент установления текущего соединения
+	LastFrame     time.Time  `json:"last_frame"`               // Момент последнего кадра отправителя (на этом экземпляре)
+	Connects      int        `json:"connects"`                 // Число установлений соединения
+	DataFrames    int64      `json:"data_frames"`              // Сегментов, принятых по соединению этим экземпляром
+	Rejected      int64      `json:"rejected"`                 // Сегментов, отклоненных этим экземпляром без соединения
+}
+
+// LinkRequest — управляющий кадр отправителя на /link.
+type LinkRequest struct {
+	Sender string `json:"sender"`
+	Frame  string `json:"frame"` // connect или disconnect
+}
+
+// LinkResponse — ответный кадр канального уровня на /link.
+type LinkResponse struct {
+	Sender string `json:"sender"`
+	Frame  string `json:"frame"` // accept или disconnected
+	State  string `json:"state"` // Состояние соединения после обработки кадра
+}
+
+// linkCounters — счетчики кадров отправителя на этом экземпляре.
+type linkCounters struct {
+	lastFrame  time.Time
+	dataFrames int64
+	rejected   int64
+}
+
+// LinkTable — таблица соединений канального уровня с отправителями. Отправитель устанавливает
+// соединение управляющим кадром connect и разрывает кадром disconnect; управляющие кадры, как и
+// данные, проходят через канал и могут быть потеряны, тогда отправитель повторяет их. Сегменты
+// отправителя без установленного соединения отклоняются. Состояние соединений хранится в хранилище
+// состояния, поэтому соединение, установленное через один экземпляр, действует на всех; счетчики
+// кадров ведутся каждым экземпляром в памяти и только для отправителей, известных таблице.
+// Методы допускают вызов на nil (установление соединения не требуется).
+type LinkTable struct {
+	store StateStore
+
+	mu       sync.Mutex
+	counters map[string]*linkCounters
+	unknown  int64 // Сегментов, отклоненных от отправителей, ни разу не устанавливавших соединение
+}
+
+// NewLinkTable создает таблицу соединений по конфигурации (nil, если соединение не требуется).
+func NewLinkTable(cfg HandshakeConfig, store StateStore) *LinkTable {
+	if !cfg.Required {
+		return nil
+	}
+	return &LinkTable{store: store, counters: make(map[string]*linkCounters)}
+}
+
+// linkKey формирует ключ соединения отправителя в хранилище состояния.
+func linkKey(sender string) string {
+	return "link:" + sender
+}
+
+// load читает соединение отправителя из хранилища состояния.
+func (t *LinkTable) load(sender string) (LinkEntry, bool, error) {
+	data, ok, err := t.store.Get(linkKey(sender))
+	if err != nil || !ok {
+		return LinkEntry{Sender: sender, State: LinkStateDisconnected}, false, err
+	}
+	var e LinkEntry
+	if err := json.Unmarshal([]byte(data), &e); err != nil {
+		return LinkEntry{Sender: sender, State: LinkStateDisconnected}, false, fmt.Errorf("поврежденная запись соединения: %w", err)
+	}
+	return e, true, nil
+}
+
+// save сохраняет соединение отправителя в хранилище состояния.
+func (t *LinkTable) save(e LinkEntry) error {
+	data, err := json.Marshal(LinkEntry{Sender: e.Sender, State: e.State, EstablishedAt: e.EstablishedAt, Connects: e.Connects})
+	if err != nil {
+		return err
+	}
+	return t.store.Set(linkKey(e.Sender), string(data), 0)
+}
+
+// touch отмечает кадр отправителя в счетчиках экземпляра. Вызывается под t.mu.
+func (t *LinkTable) touch(sender string) *linkCounters {
+	c, ok := t.counters[sender]
+	if !ok {
+		c = &linkCounters{}
+		t.counters[sender] = c
+	}
+	c.lastFrame = time.Now().UTC()
+	return c
+}
+
+// Connect устанавливает соединение с отправителем. Повторный connect на установленном соединении
+// подтверждается без сброса (ответ на него мог быть потерян).
+func (t *LinkTable) Connect(sender string) (LinkResponse, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, _, err := t.load(sender)
+	if err != nil {
+		return LinkResponse{}, err
+	}
+	if e.State != LinkStateEstablished {
+		now := time.Now().UTC()
+		e.State = LinkStateEstablished
+		e.EstablishedAt = &now
+		e.Connects++
+		if err := t.save(e); err != nil {
+			return LinkResponse{}, err
+		}
+		eventLog.Publish("link_established", fmt.Sprintf("Установлено соединение с отправителем %s", sender),
+			map[string]interface{}{"sender": sender})
+	}
+	t.touch(sender)
+	return LinkResponse{Sender: sender, Frame: LinkFrameAccept, State: e.State}, nil
+}
+
+// Disconnect разрывает соединение с отправителем.
+func (t *LinkTable) Disconnect(sender string) (LinkResponse, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, known, err := t.load(sender)
+	if err != nil {
+		return LinkResponse{}, err
+	}
+	if known {
+		t.touch(sender)
+	}
+	if e.State != LinkStateEstablished {
+		return LinkResponse{Sender: sender, Frame: LinkFrameDisconnected, State: e.State}, nil
+	}
+	if err := t.reset(&e, "по запросу отправителя"); err != nil {
+		return LinkResponse{}, err
+	}
+	return LinkResponse{Sender: sender, Frame: LinkFrameAccept, State: e.State}, nil
+}
+
+// reset переводит соединение в состояние disconnected. Вызывается под t.mu.
+func (t *LinkTable) reset(e *LinkEntry, reason string) error {
+	e.State = LinkStateDisconnected
+	e.EstablishedAt = nil
+	if err := t.save(*e); err != nil {
+		return err
+	}
+	eventLog.Publish("link_disconnected", fmt.Sprintf("Соединение с отправителем %s разорвано %s", e.Sender, reason),
+		map[string]interface{}{"sender": e.Sender})
+	return nil
+}
+
+// Admit сообщает, установлено ли соединение с отправителем сегмента, и учитывает сегмент.
+// При недоступности хранилища сегмент принимается (отказ не блокирует канал).
+func (t *LinkTable) Admit(sender string) bool {
+	if t == nil {
+		return true
+	}
+	e, known, err := t.load(sender)
+	if err != nil {
+		log.Printf("LinkTable ERROR: Не удалось прочитать соединение с отправителем %s: %v", sender, err)
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !known {
+		t.unknown++
+		return false
+	}
+	c := t.touch(sender)
+	if e.State != LinkStateEstablished {
+		c.rejected++
+		return false
+	}
+	c.dataFrames++
+	return true
+}
+
+// senders возвращает отправителей, известных таблице соединений.
+func (t *LinkTable) senders() ([]string, error) {
+	keys, err := t.store.Keys(linkKey(""))
+	if err != nil {
+		return nil, err
+	}
+	senders := make([]string, len(keys))
+	for i, key := range keys {
+		senders[i] = strings.TrimPrefix(key, linkKey(""))
+	}
+	return senders, nil
+}
+
+// Drop разрывает соединения со всеми отправителями (sender = "") или с одним отправителем
+// и возвращает число разорванных соединений.
+func (t *LinkTable) Drop(sender string) (int, error) {
+	senders := []string{sender}
+	if sender == "" {
+		var err error
+		if senders, err = t.senders(); err != nil {
+			return 0, err
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	dropped := 0
+	for _, s := range senders {
+		e, _, err := t.load(s)
+		if err != nil {
+			return dropped, err
+		}
+		if e.State != LinkStateEstablished {
+			continue
+		}
+		if err := t.reset(&e, "администратором"); err != nil {
+			return dropped, err
+		}
+		dropped++
+	}
+	return dropped, nil
+}
+
+// Snapshot возвращает копию таблицы соединений, упорядоченную по отправителям, и число сегментов,
+// отклоненных этим экземпляром от отправителей, ни разу не устанавливавших соединение.
+func (t *LinkTable) Snapshot() ([]LinkEntry, int64, error) {
+	entries := []LinkEntry{}
+	if t == nil {
+		return entries, 0, nil
+	}
+	senders, err := t.senders()
+	if err != nil {
+		return entries, 0, err
+	}
+	for _, sender := range senders {
+		e, known, err := t.load(sender)
+		if err != nil {
+			return entries, 0, err
+		}
+		if known {
+			entries = append(entries, e)
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range entries {
+		if c, ok := t.counters[entries[i].Sender]; ok {
+			entries[i].LastFrame = c.lastFrame
+			entries[i].DataFrames = c.dataFrames
+			entries[i].Rejected = c.rejected
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Sender < entries[j].Sender })
+	return entries, t.unknown, nil
+}
+
+// handleLink принимает управляющий кадр connect или disconnect. Кадр передается через канал:
+// потерянный или неисправимо искаженный кадр остается без ответа (408), и отправитель повторяет его.
+func handleLink(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+		return
+	}
+	if linkTable == nil {
+		sendErrorResponse(w, "Установление соединения не используется (handshake.required = false)", http.StatusNotFound)
+		return
+	}
+	var req LinkRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&req); err != nil {
+		sendErrorResponse(w, fmt.Sprintf("Неверный JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Sender == "" {
+		sendErrorResponse(w, "Не указан отправитель (sender)", http.StatusBadRequest)
+		return
+	}
+	if req.Frame != LinkFrameConnect && req.Frame != LinkFrameDisconnect {
+		sendErrorResponse(w, fmt.Sprintf("Неизвестный управляющий кадр '%s' (допустимо: %s, %s)", req.Frame, LinkFrameConnect, LinkFrameDisconnect), http.StatusBadRequest)
+		return
+	}
+
+	frame := &Segment{Payload: make([]byte, FixedPayloadSize), Timestamp: time.Now().UnixNano(), OriginalLength: len(req.Frame)}
+	copy(frame.Payload, req.Frame)
+	received, _ := channelLayer.ProcessSegmentWith(frame, ProcessOptions{Quiet: true, SkipImpairments: controlConfig.ExemptImpairments})
+	if received == nil || received.IsChannelError {
+		log.Printf("ChannelLayer: Управляющий кадр %s от %s не принят (потерян или искажен в канале)", req.Frame, req.Sender)
+		sendErrorResponse(w, "Управляющий кадр потерян или искажен в канале, повторите его", http.StatusRequestTimeout)
+		return
+	}
+
+	var resp LinkResponse
+	var err error
+	if req.Frame == LinkFrameConnect {
+		resp, err = linkTable.Connect(req.Sender)
+	} else {
+		resp, err = linkTable.Disconnect(req.Sender)
+	}
+	if err != nil {
+		log.Printf("LinkTable ERROR: Управляющий кадр %s от %s не обработан: %v", req.Frame, req.Sender, err)
+		sendErrorResponse(w, fmt.Sprintf("Не удалось обновить соединение: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+	log.Printf("ChannelLayer: Управляющий кадр %s от %s: ответ %s, соединение %s", req.Frame, req.Sender, resp.Frame, resp.State)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleAdminLinks возвращает (GET) таблицу соединений или разрывает (DELETE) соединение
+// с отправителем из параметра sender (без параметра — со всеми отправителями).
+func handleAdminLinks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodDelete:
+		if linkTable == nil {
+			sendErrorResponse(w, "Установление соединения не используется (handshake.required = false)", http.StatusNotFound)
+			return
+		}
+		sender := r.URL.Query().Get("sender")
+		dropped, err := linkTable.Drop(sender)
+		log.Printf("ChannelLayer: Администратор разорвал соединений: %d", dropped)
+		if err != nil {
+			sendErrorResponse(w, fmt.Sprintf("Не удалось разорвать соединения: %v", err), http.StatusServiceUnavailable)
+			return
+		}
+	default:
+		sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+		return
+	}
+	links, unknown, err := linkTable.Snapshot()
+	if err != nil {
+		sendErrorResponse(w, fmt.Sprintf("Не удалось прочитать таблицу соединений: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"required":         linkTable != nil,
+		"links":            links,
+		"rejected_unknown": unknown,
+	})
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// cyclicCode — двоичный циклический код [n,k] с порождающим многочленом g(x) степени n-k.
+// Кодирование систематическое: кодовое слово (i_{k-1} .. i_0, r_{n-k-1} .. r_0), где
+// r(x) = i(x)·x^(n-k) mod g(x); бит слова с индексом j — коэффициент при x^(n-1-j).
+// Декодер вычисляет синдром s(x) = v(x) mod g(x): ненулевой синдром означает обнаруженную
+// ошибку. Если задано исправление, одиночная ошибка исправляется по таблице синдромов
+// (требуется, чтобы синдромы всех одиночных ошибок были различны).
+//
+// Исходный кодек cyclic74 сохраняет явные формулы проверочных бит (cyclicEncode7_4Block): они задают
+// код Хэмминга [7,4], который не совпадает с делением на x^3 + x + 1 в этом порядке бит, а на них
+// опираются декодеры cyclic74_syndrome и cyclic74_majority. Тот же g(x) с делением многочленов —
+// {"type": "cyclic", "n": 7, "k": 4, "polynomial": "x^3+x+1"}.
+type cyclicCode struct {
+	name      string
+	n, k      int
+	generator []uint8     // Коэффициенты g(x) по возрастанию степеней (generator[n-k] = 1)
+	syndromes map[int]int // Синдром -> позиция одиночной ошибки (nil — только обнаружение)
+}
+
+// newCyclicCode создает циклический код [n,k] с порождающим многочленом generator
+// (коэффициенты по возрастанию степеней).
+func newCyclicCode(name string, n, k int, generator []uint8, correct bool) (*cyclicCode, error) {
+	if k < 1 || n <= k {
+		return nil, fmt.Errorf("параметры [n,k] должны удовлетворять 0 < k < n, задано n=%d, k=%d", n, k)
+	}
+	if len(generator)-1 != n-k || generator[0] != 1 {
+		return nil, fmt.Errorf("степень g(x) должна быть равна n-k = %d, а свободный член — 1", n-k)
+	}
+	c := &cyclicCode{name: name, n: n, k: k, generator: generator}
+	// g(x) порождает циклический код длины n, только если делит x^n + 1
+	xn1 := make([]uint8, n+1)
+	xn1[0], xn1[n] = 1, 1
+	if c.polyMod(xn1) != 0 {
+		return nil, fmt.Errorf("g(x) не делит x^%d + 1, код не является циклическим", n)
+	}
+	if correct {
+		c.syndromes = make(map[int]int, n)
+		word := make([]uint8, n)
+		for pos := 0; pos < n; pos++ {
+			word[pos] = 1
+			syndrome := c.polyMod(word)
+			word[pos] = 0
+			if _, exists := c.syndromes[syndrome]; exists || syndrome == 0 {
+				return nil, fmt.Errorf("синдромы одиночных ошибок совпадают, исправление невозможно")
+			}
+			c.syndromes[syndrome] = pos
+		}
+	}
+	return c, nil
+}
+
+// polyMod возвращает остаток от деления многочлена с коэффициентами bits (от старшей степени
+// к младшей) на g(x) в виде битовой маски (бит i — коэффициент при x^i).
+func (c *cyclicCode) polyMod(bits []uint8) int {
+	r := c.n - c.k
+	var lower int // g(x) - x^r
+	for i := 0; i < r; i++ {
+		lower |= int(c.generator[i]) << i
+	}
+	remainder := 0
+	for _, bit := range bits {
+		top := remainder >> (r - 1) & 1
+		remainder = (remainder<<1 | int(bit)) & (1<<r - 1)
+		if top == 1 {
+			remainder ^= lower
+		}
+	}
+	return remainder
+}
+
+func (c *cyclicCode) Name() string { return c.name }
+func (c *cyclicCode) N() int       { return c.n }
+func (c *cyclicCode) K() int       { return c.k }
+
+// EncodeBlock кодирует k информационных бит в n кодовых бит.
+func (c *cyclicCode) EncodeBlock(infoBits []uint8) []uint8 {
+	codeword := make([]uint8, c.n)
+	copy(codeword, infoBits)
+	remainder := c.polyMod(codeword) // i(x)·x^(n-k) mod g(x)
+	for j := c.k; j < c.n; j++ {
+		codeword[j] = uint8(remainder>>(c.n-1-j)) & 1
+	}
+	return codeword
+}
+
+// DecodeBlock вычисляет синдром и, если задано исправление, исправляет одиночную ошибку.
+func (c *cyclicCode) DecodeBlock(codedBits []uint8) ([]uint8, bool, bool) {
+	syndrome := c.polyMod(codedBits)
+	if syndrome == 0 {
+		return append([]uint8(nil), codedBits[:c.k]...), false, false
+	}
+	pos, ok := c.syndromes[syndrome]
+	if !ok {
+		return append([]uint8(nil), codedBits[:c.k]...), false, true
+	}
+	infoBits := append([]uint8(nil), codedBits[:c.k]...)
+	if pos < c.k {
+		infoBits[pos] ^= 1
+	}
+	return infoBits, true, false
+}
+
+// parseGeneratorPolynomial разбирает порождающий многочлен, заданный в виде "x^3+x+1",
+// двоичной строки коэффициентов от старшей степени ("1011") или шестнадцатеричной маски ("0xB").
+// Возвращает коэффициенты по возрастанию степеней.
+func parseGeneratorPolynomial(s string) ([]uint8, error) {
+	s = strings.ReplaceAll(strings.ToLower(s), " ", "")
+	if s == "" {
+		return nil, fmt.Errorf("не задан порождающий многочлен (polynomial)")
+	}
+	var mask uint64
+	switch {
+	case strings.HasPrefix(s, "0x"):
+		v, err := strconv.ParseUint(s[2:], 16, 63)
+		if err != nil {
+			return nil, fmt.Errorf("неверный многочлен '%s': %v", s, err)
+		}
+		mask = v
+	case strings.Trim(s, "01") == "":
+		v, err := strconv.ParseUint(s, 2, 63)
+		if err != nil {
+			return nil, fmt.Errorf("неверный многочлен '%s': %v", s, err)
+		}
+		mask = v
+	default:
+		for _, term := range strings.Split(s, "+") {
+			degree := 0
+			switch {
+			case term == "1":
+			case term == "x":
+				degree = 1
+			case strings.HasPrefix(term, "x^"):
+				d, err := strconv.Atoi(term[2:])
+				if err != nil || d < 0 || d > 62 {
+					return nil, fmt.Errorf("неверный член многочлена '%s'", term)
+				}
+				degree = d
+			default:
+				return nil, fmt.Errorf("неверный член многочлена '%s'", term)
+			}
+			mask ^= 1 << degree
+		}
+	}
+	if mask == 0 {
+		return nil, fmt.Errorf("порождающий многочлен не может быть нулевым")
+	}
+	var coefficients []uint8
+	for ; mask != 0; mask >>= 1 {
+		coefficients = append(coefficients, uint8(mask&1))
+	}
+	return coefficients, nil
+}
+
+func init() {
+	RegisterCodecType("cyclic", func(cfg CodecConfig) (BlockCoder, error) {
+		generator, err := parseGeneratorPolynomial(cfg.Polynomial)
+		if err != nil {
+			return nil, err
+		}
+		return newCyclicCode(cfg.Name, cfg.N, cfg.K, generator, cfg.Correct)
+	})
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Состояния сегмента в кэше обнаружения дубликатов.
+const (
+	dedupProcessing = "processing" // Сегмент обрабатывается одним из экземпляров
+	dedupDelivered  = "delivered"  // Сегмент уже доставлен на /transfer
+)
+
+// DedupResult — результат попытки занять сегмент для обработки.
+type DedupResult int
+
+const (
+	DedupNew        DedupResult = iota // Сегмент встречается впервые (или предыдущие попытки не удались)
+	DedupDelivered                     // Сегмент уже был доставлен — это дубликат
+	DedupInProgress                    // Сегмент прямо сейчас обрабатывается (возможно, другим экземпляром)
+)
+
+// Deduplicator обнаруживает повторно присланные сегменты, уже доставленные транспортному уровню.
+// Сегмент занимается на время обработки; при неудаче (потеря, ошибка канала, сбой пересылки)
+// отметка снимается, чтобы повторная передача сегмента отправителем была обработана заново.
+// Отметка обрабатываемого сегмента ставится на короткий срок lease и продлевается, пока сегмент
+// обрабатывается: если экземпляр завершится аварийно, отметка истечет сама.
+// Все методы допускают вызов на nil (обнаружение дубликатов отключено).
+type Deduplicator struct {
+	store StateStore
+	ttl   time.Duration // Сколько помнить доставленные сегменты
+	lease time.Duration // Срок отметки обрабатываемого сегмента
+
+	mu     sync.Mutex
+	leases map[string]*dedupLease // Продления отметок сегментов, занятых этим экземпляром
+}
+
+// dedupLease — продление отметки занятого сегмента.
+type dedupLease struct {
+	stop chan struct{} // Закрывается для остановки продления
+	done chan struct{} // Закрывается, когда продление остановлено
+}
+
+// NewDeduplicator создает детектор дубликатов поверх хранилища состояния.
+func NewDeduplicator(store StateStore, ttl, lease time.Duration) *Deduplicator {
+	if lease <= 0 || lease > ttl {
+		lease = ttl
+	}
+	log.Printf("Deduplicator: Создан, доставленные сегменты запоминаются на %v, обрабатываемые занимаются на %v", ttl, lease)
+	return &Deduplicator{store: store, ttl: ttl, lease: lease, leases: make(map[string]*dedupLease)}
+}
+
+// dedupKey формирует ключ сегмента в хранилище состояния.
+func dedupKey(sender, sendTime string, segmentNumber int) string {
+	return fmt.Sprintf("dedup:%s|%s|%d", sender, sendTime, segmentNumber)
+}
+
+// Claim пытается занять сегмент для обработки.
+// При недоступности хранилища сегмент обрабатывается как новый (отказ не блокирует канал).
+func (d *Deduplicator) Claim(key string) DedupResult {
+	if d == nil {
+		return DedupNew
+	}
+	claimed, err := d.store.SetNX(key, dedupProcessing, d.lease)
+	if err != nil {
+		log.Printf("Deduplicator ERROR: Не удалось проверить сегмент %s: %v. Обрабатываем как новый.", key, err)
+		return DedupNew
+	}
+	if claimed {
+		d.holdLease(key)
+		return DedupNew
+	}
+
+	state, ok, err := d.store.Get(key)
+	if err != nil {
+		log.Printf("Deduplicator ERROR: Не удалось прочитать состояние сегмента %s: %v. Обрабатываем как новый.", key, err)
+		return DedupNew
+	}
+	switch {
+	case !ok:
+		// Запись истекла между SetNX и Get — пробуем занять сегмент еще раз
+		return d.Claim(key)
+	case state == dedupDelivered:
+		return DedupDelivered
+	default:
+		return DedupInProgress
+	}
+}
+
+// holdLease продлевает отметку занятого сегмента, пока не будет вызван Finish.
+func (d *Deduplicator) holdLease(key string) {
+	d.releaseLease(key) // Отметка могла истечь и быть занята этим экземпляром повторно
+	l := &dedupLease{stop: make(chan struct{}), done: make(chan struct{})}
+	d.mu.Lock()
+	d.leases[key] = l
+	d.mu.Unlock()
+	go func() {
+		defer close(l.done)
+		ticker := time.NewTicker(max(d.lease/3, time.Millisecond))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := d.store.Set(key, dedupProcessing, d.lease); err != nil {
+					log.Printf("Deduplicator ERROR: Не удалось продлить отметку сегмента %s: %v", key, err)
+				}
+			case <-l.stop:
+				return
+			}
+		}
+	}()
+}
+
+// releaseLease останавливает продление отметки сегмента и дожидается его остановки,
+// чтобы продление не перезаписало итоговое состояние сегмента.
+func (d *Deduplicator) releaseLease(key string) {
+	d.mu.Lock()
+	l, ok := d.leases[key]
+	delete(d.leases, key)
+	d.mu.Unlock()
+	if ok {
+		close(l.stop)
+		<-l.done
+	}
+}
+
+// Finish завершает обработку занятого сегмента: доставленный сегмент запоминается,
+// для недоставленного отметка снимается.
+func (d *Deduplicator) Finish(key string, delivered bool) {
+	if d == nil {
+		return
+	}
+	d.releaseLease(key)
+	var err error
+	if delivered {
+		err = d.store.Set(key, dedupDelivered, d.ttl)
+	} else {
+		err = d.store.Delete(key)
+	}
+	if err != nil {
+		log.Printf("Deduplicator ERROR: Не удалось обновить состояние сегмента %s: %v", key, err)
+	}
+}
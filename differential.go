@@ -0,0 +1,39 @@
+package main
+
+// differentialStage — дифференциальное кодирование потока: передается не сам бит, а его отличие
+// от предыдущего переданного бита (y[i] = x[i] XOR y[i-1], y[-1] = 0). Приемнику не нужно знать
+// абсолютную полярность сигнала, но одиночная ошибка в канале искажает два соседних бита
+// после декодирования (x[i] = y[i] XOR y[i-1]) — классический пример размножения ошибок.
+type differentialStage struct{}
+
+func newDifferentialStage(StageConfig) (StreamStage, error) {
+	return differentialStage{}, nil
+}
+
+func (differentialStage) Name() string { return "differential" }
+
+// Apply выполняет дифференциальное кодирование потока.
+func (differentialStage) Apply(bits []uint8) []uint8 {
+	out := make([]uint8, len(bits))
+	var prev uint8
+	for i, bit := range bits {
+		prev ^= bit
+		out[i] = prev
+	}
+	return out
+}
+
+// Invert выполняет дифференциальное декодирование потока.
+func (differentialStage) Invert(bits []uint8, length int) ([]uint8, int) {
+	out := make([]uint8, length)
+	var prev uint8
+	for i := 0; i < length; i++ {
+		out[i] = bits[i] ^ prev
+		prev = bits[i]
+	}
+	return out, 0
+}
+
+func init() {
+	RegisterStage("differential", newDifferentialStage)
+}
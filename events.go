@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	EventsEndpoint  = "/events" // Конечная точка журнала событий канального уровня
+	eventLogSize    = 1000      // Сколько последних событий хранится в памяти
+	eventSubscriber = 64        // Размер буфера канала одного подписчика
+)
+
+// Event — событие канального уровня (смена режима работы, состояние канала и т.п.).
+type Event struct {
+	ID      uint64                 `json:"id"`
+	Time    time.Time              `json:"time"`
+	Type    string                 `json:"type"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// EventLog хранит последние события в кольцевом буфере и рассылает новые события подписчикам.
+type EventLog struct {
+	mu          sync.Mutex
+	events      []Event
+	nextID      uint64
+	subscribers map[chan Event]struct{}
+}
+
+// NewEventLog создает пустой журнал событий.
+func NewEventLog() *EventLog {
+	return &EventLog{
+		nextID:      1,
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Publish добавляет событие в журнал и рассылает его подписчикам.
+// Подписчик, не успевающий читать события, пропускает их (публикация никогда не блокируется).
+func (l *EventLog) Publish(eventType, message string, details map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	event := Event{
+		ID:      l.nextID,
+		Time:    time.Now().UTC(),
+		Type:    eventType,
+		Message: message,
+		Details: details,
+	}
+	l.nextID++
+
+	l.events = append(l.events, event)
+	if len(l.events) > eventLogSize {
+		l.events = l.events[len(l.events)-eventLogSize:]
+	}
+	for ch := range l.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+	log.Printf("Event: [%s] %s", eventType, message)
+}
+
+// Since возвращает события с идентификатором больше since.
+func (l *EventLog) Since(since uint64) []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := []Event{}
+	for _, e := range l.events {
+		if e.ID > since {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Subscribe возвращает канал новых событий и функцию отмены подписки.
+func (l *EventLog) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, eventSubscriber)
+	l.mu.Lock()
+	l.subscribers[ch] = struct{}{}
+	l.mu.Unlock()
+
+	return ch, func() {
+		l.mu.Lock()
+		delete(l.subscribers, ch)
+		l.mu.Unlock()
+	}
+}
+
+var eventLog = NewEventLog() // Глобальный журнал событий
+
+// handleEvents возвращает события канального уровня (GET /events?since=<id>).
+// Если клиент запрашивает text/event-stream, события передаются потоком (Server-Sent Events).
+func handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var since uint64
+	if v := r.URL.Query().Get("since"); v != "" {
+		parsed, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			sendErrorResponse(w, fmt.Sprintf("Неверный параметр since: %v", err), http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(eventLog.Since(since))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		sendErrorResponse(w, "Потоковая передача не поддерживается", http.StatusInternalServerError)
+		return
+	}
+
+	// Подписываемся до выдачи накопленных событий, чтобы не пропустить опубликованные между ними
+	events, cancel := eventLog.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	writeEvent := func(e Event) {
+		data, _ := json.Marshal(e)
+		fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	}
+	last := since
+	for _, e := range eventLog.Since(since) {
+		writeEvent(e)
+		last = e.ID
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-events:
+			if e.ID <= last {
+				continue
+			}
+			writeEvent(e)
+			last = e.ID
+			flusher.Flush()
+		}
+	}
+}
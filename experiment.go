@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// ExperimentMaxErrorRateEndpoint — конечная точка автоматического поиска предельной вероятности ошибки.
+const ExperimentMaxErrorRateEndpoint = "/experiments/max-error-rate"
+
+// Модели ошибок эксперимента.
+const (
+	ExperimentModelFrame = "frame" // P — вероятность инверсии одного бита кадра (модель канала по умолчанию)
+	ExperimentModelBit   = "bit"   // P — вероятность ошибки каждого бита кадра (BER)
+)
+
+// Ограничения эксперимента.
+const (
+	experimentDefaultFrames = 1000
+	experimentMaxFrames     = 1_000_000 // Суммарно по всем шагам; при превышении поиск прекращается
+)
+
+// MaxErrorRateRequest — параметры поиска предельной вероятности ошибки.
+type MaxErrorRateRequest struct {
+	TargetFER float64 `json:"target_fer"`         // Допустимая доля ошибочных кадров после декодирования
+	Model     string  `json:"model,omitempty"`    // Модель ошибок (см. ExperimentModel*), по умолчанию frame
+	Codec     string  `json:"codec,omitempty"`    // Кодек (по умолчанию — кодек канала)
+	Decision  string  `json:"decision,omitempty"` // Режим решений hard/soft (по умолчанию — как у канала)
+	Start     float64 `json:"start"`              // Начальное значение P
+	Step      float64 `json:"step"`               // Шаг увеличения P
+	Max       float64 `json:"max"`                // Максимальное значение P
+	Frames    int     `json:"frames"`             // Число кадров на каждом шаге
+}
+
+// MaxErrorRateStep — результат одного шага эксперимента.
+type MaxErrorRateStep struct {
+	P          float64 `json:"p"`
+	Frames     int     `json:"frames"`
+	Detected   int     `json:"detected"`   // Кадров с обнаруженной неисправимой ошибкой
+	Undetected int     `json:"undetected"` // Кадров, принятых с необнаруженной ошибкой
+	FER        float64 `json:"fer"`        // Доля ошибочных кадров после декодирования
+	TargetMet  bool    `json:"target_met"`
+}
+
+// MaxErrorRateResult — итог эксперимента.
+type MaxErrorRateResult struct {
+	MaxErrorRateRequest
+	MaxP       *float64           `json:"max_p"` // Наибольшее P, при котором FER <= target_fer (null, если не достигнуто)
+	Steps      []MaxErrorRateStep `json:"steps"`
+	Truncated  bool               `json:"truncated,omitempty"` // Поиск прерван по ограничению числа кадров
+	DurationMs float64            `json:"duration_ms"`
+}
+
+// validate проверяет параметры и подставляет значения по умолчанию.
+func (req *MaxErrorRateRequest) validate() error {
+	if req.TargetFER <= 0 || req.TargetFER >= 1 {
+		return fmt.Errorf("target_fer должна быть в диапазоне (0, 1), задано %g", req.TargetFER)
+	}
+	switch req.Model {
+	case "":
+		req.Model = ExperimentModelFrame
+	case ExperimentModelFrame, ExperimentModelBit:
+	default:
+		return fmt.Errorf("неизвестная модель ошибок '%s' (допустимо: %s, %s)", req.Model, ExperimentModelFrame, ExperimentModelBit)
+	}
+	if req.Step == 0 {
+		req.Step = 0.01
+		if req.Model == ExperimentModelBit {
+			req.Step = 0.0005
+		}
+	}
+	if req.Max == 0 {
+		req.Max = 1
+	}
+	if req.Frames == 0 {
+		req.Frames = experimentDefaultFrames
+	}
+	if req.Start < 0 || req.Max > 1 || req.Start > req.Max || req.Step <= 0 {
+		return fmt.Errorf("диапазон P должен удовлетворять 0 <= start <= max <= 1 и step > 0")
+	}
+	if req.Frames < 1 || req.Frames > experimentMaxFrames {
+		return fmt.Errorf("число кадров на шаге должно быть в диапазоне [1, %d], задано %d", experimentMaxFrames, req.Frames)
+	}
+	return nil
+}
+
+// experimentImpairments возвращает цепочку искажений эксперимента для вероятности p.
+func experimentImpairments(model string, p float64) (*ImpairmentChain, error) {
+	probability := p
+	if model == ExperimentModelBit {
+		return NewImpairmentChain([]ImpairmentConfig{{Type: "bsc", Probability: &probability}})
+	}
+	return NewImpairmentChain([]ImpairmentConfig{{Type: "bit_error", Probability: &probability}})
+}
+
+// runMaxErrorRate увеличивает P от start до max и на каждом шаге измеряет долю кадров, ошибочных после
+// декодирования (обнаруженная неисправимая ошибка или несовпадение с переданной нагрузкой).
+// Поиск останавливается на первом шаге, где FER превышает целевое значение.
+func runMaxErrorRate(req MaxErrorRateRequest) (MaxErrorRateResult, error) {
+	started := time.Now()
+	// Отдельный канал эксперимента с тем же кодеком и этапами обработки потока, что и основной,
+	// но без потерь кадров
+	cl := &ChannelLayer{
+		Coder:      channelLayer.Coder,
+		FCS:        channelLayer.FCS,
+		Puncture:   channelLayer.Puncture,
+		Scrambler:  channelLayer.Scrambler,
+		Shortening: channelLayer.Shortening,
+		Stages:     channelLayer.Stages,
+		Decision:   channelLayer.Decision,
+		source:     newLockedSource(time.Now().UnixNano()),
+	}
+	cl.rng = rand.New(cl.source)
+	if req.Codec != "" {
+		var err error
+		if cl.Coder, err = LookupCoder(req.Codec); err != nil {
+			return MaxErrorRateResult{}, err
+		}
+	}
+	if req.Decision != "" {
+		cl.Decision = req.Decision
+	}
+	if err := validateDecision(cl.Decision, cl.Stages); err != nil {
+		return MaxErrorRateResult{}, err
+	}
+	req.Codec = cl.Coder.Name()
+	result := MaxErrorRateResult{MaxErrorRateRequest: req, Steps: []MaxErrorRateStep{}}
+	payload := make([]byte, FixedPayloadSize)
+	for i := 0; ; i++ {
+		p := req.Start + float64(i)*req.Step
+		if p > req.Max+req.Step*1e-9 {
+			break
+		}
+		p = min(p, req.Max)
+		if (i+1)*req.Frames > experimentMaxFrames {
+			result.Truncated = true
+			break
+		}
+		chain, err := experimentImpairments(req.Model, p)
+		if err != nil {
+			return MaxErrorRateResult{}, err
+		}
+		cl.Impairments = chain
+		step := MaxErrorRateStep{P: p, Frames: req.Frames}
+		for n := 0; n < req.Frames; n++ {
+			cl.rng.Read(payload)
+			segment := &Segment{Payload: payload, SegmentNumber: n + 1, TotalSegments: req.Frames, OriginalLength: FixedPayloadSize}
+			out, _ := cl.ProcessSegmentWith(segment, ProcessOptions{Quiet: true})
+			switch {
+			case out == nil || out.IsChannelError:
+				step.Detected++
+			case !bytes.Equal(out.Payload, payload):
+				step.Undetected++
+			}
+		}
+		step.FER = float64(step.Detected+step.Undetected) / float64(step.Frames)
+		step.TargetMet = step.FER <= req.TargetFER
+		result.Steps = append(result.Steps, step)
+		if !step.TargetMet {
+			break
+		}
+		result.MaxP = &step.P
+	}
+	result.DurationMs = float64(time.Since(started).Microseconds()) / 1000
+	return result, nil
+}
+
+// handleExperimentMaxErrorRate выполняет поиск наибольшей вероятности ошибки, при которой доля ошибочных
+// кадров после декодирования не превышает целевую (POST /experiments/max-error-rate).
+// Эксперимент выполняется на отдельном канале и не влияет на статистику и обработку сегментов.
+func handleExperimentMaxErrorRate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+		return
+	}
+	var req MaxErrorRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, fmt.Sprintf("Неверный JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := runMaxErrorRate(req)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if result.MaxP != nil {
+		log.Printf("Experiment: Предельная вероятность ошибки (%s, кодек %s) при FER <= %g: P = %g",
+			req.Model, result.Codec, req.TargetFER, *result.MaxP)
+	} else {
+		log.Printf("Experiment: FER <= %g не достигается уже при P = %g (%s)", req.TargetFER, req.Start, req.Model)
+	}
+	json.NewEncoder(w).Encode(result)
+}
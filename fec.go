@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultFECTimeout = time.Minute // Время хранения незавершенной группы по умолчанию
+
+// FECConfig задает межкадровую коррекцию потерь кадром четности.
+type FECConfig struct {
+	GroupSize int      `json:"group_size"` // Число кадров данных в группе; 0 — коррекция отключена
+	Timeout   Duration `json:"timeout"`    // Время ожидания остальных кадров группы (по умолчанию 1 мин)
+}
+
+// FECStats — статистика межкадровой коррекции для /stats.
+type FECStats struct {
+	GroupSize     int   `json:"group_size"`
+	ParityFrames  int64 `json:"parity_frames"`  // Кадров четности, переданных в канал
+	ParityLost    int64 `json:"parity_lost"`    // Кадров четности, потерянных или искаженных в канале
+	Recovered     int64 `json:"recovered"`      // Кадров данных, восстановленных по кадру четности
+	Unrecoverable int64 `json:"unrecoverable"`  // Групп, в которых потеряно больше одного кадра
+	Expired       int64 `json:"expired"`        // Групп, не завершенных до истечения времени ожидания
+	Pending       int   `json:"pending_groups"` // Незавершенных групп
+}
+
+// fecLostFrame — кадр данных группы, потерянный или принятый с неисправимой ошибкой, вместе
+// с данными приемника, необходимыми для его пересылки после восстановления.
+type fecLostFrame struct {
+	job        *segmentJob
+	report     ChannelReport // Отчет канала о передаче кадра
+	linkSeq    uint32        // Порядковый номер кадра звена
+	channelCRC string        // CRC-32C переданной в канал полезной нагрузки (если CRC-32C включен)
+}
+
+// fecGroup — группа кадров данных одного сообщения, защищенная общим кадром четности.
+type fecGroup struct {
+	size         int                   // Число кадров данных в группе
+	created      time.Time             // Момент передачи первого кадра группы
+	sent         int                   // Кадров группы, переданных в канал
+	parity       []byte                // XOR полезных нагрузок переданных кадров (после паддинга)
+	parityLength int                   // XOR длин полезных нагрузок (передается в заголовке кадра четности)
+	received     map[int]*Segment      // Кадры, принятые без ошибок, по номеру сегмента
+	missing      map[int]*fecLostFrame // Кадры, потерянные или принятые с неисправимой ошибкой
+	channel      *ChannelLayer         // Канал, по которому передается кадр четности
+}
+
+// ParityFEC — межкадровая коррекция потерь: после каждых GroupSize кадров данных сообщения
+// (последняя группа может быть короче) по каналу передается кадр четности — XOR полезных нагрузок
+// кадров группы. Если в группе потерян (или принят с неисправимой ошибкой) ровно один кадр,
+// а кадр четности принят, потерянный кадр восстанавливается как XOR кадра четности и остальных
+// кадров группы и, пройдя те же шаги приемника, что и принятый кадр (проверку CRC-32C, обработчики
+// после декодирования, номер кадра звена), пересылается на /transfer с флагом recovered. Отправитель получает итог
+// потерянного сегмента как обычно; восстановление выполняется в фоне по завершении группы.
+// Все методы допускают вызов на nil (коррекция отключена).
+type ParityFEC struct {
+	groupSize int
+	timeout   time.Duration
+
+	mu     sync.Mutex
+	groups map[string]*fecGroup // Незавершенные группы по (sender, send_time, номер группы)
+
+	parityFrames  atomic.Int64
+	parityLost    atomic.Int64
+	recovered     atomic.Int64
+	unrecoverable atomic.Int64
+	expired       atomic.Int64
+}
+
+var parityFEC *ParityFEC // Глобальная межкадровая коррекция потерь (nil — отключена)
+
+// NewParityFEC создает межкадровую коррекцию по конфигурации (nil, если она отключена).
+func NewParityFEC(cfg FECConfig) (*ParityFEC, error) {
+	if cfg.GroupSize == 0 {
+		return nil, nil
+	}
+	if cfg.GroupSize < 2 {
+		return nil, fmt.Errorf("размер группы должен быть не менее 2, задано %d", cfg.GroupSize)
+	}
+	timeout := cfg.Timeout.Duration
+	if timeout == 0 {
+		timeout = defaultFECTimeout
+	}
+	if timeout < 0 {
+		return nil, fmt.Errorf("время ожидания группы не может быть отрицательным")
+	}
+	return &ParityFEC{groupSize: cfg.GroupSize, timeout: timeout, groups: make(map[string]*fecGroup)}, nil
+}
+
+// Record учитывает кадр данных, переданный в канал: sent — переданный сегмент (после паддинга),
+// received — результат канала (nil, если кадр потерян), report — отчет канала, linkSeq — номер
+// кадра звена. Когда переданы все кадры группы, в фоне передается кадр четности и, если возможно,
+// восстанавливается потерянный кадр.
+func (f *ParityFEC) Record(job *segmentJob, sent, received *Segment, channel *ChannelLayer, report ChannelReport, linkSeq uint32) {
+	if f == nil || job.Request.Type == SegmentTypeControl {
+		return
+	}
+	req := job.Request
+	index := (req.SegmentNumber - 1) / f.groupSize
+	key := fmt.Sprintf("%s|%s|%d", req.Sender, req.SendTime, index)
+	now := time.Now()
+
+	f.mu.Lock()
+	for k, g := range f.groups {
+		if now.Sub(g.created) > f.timeout {
+			delete(f.groups, k)
+			f.expired.Add(1)
+		}
+	}
+	group, ok := f.groups[key]
+	if !ok {
+		first := index*f.groupSize + 1
+		group = &fecGroup{
+			size:     min(f.groupSize, req.TotalSegments-first+1),
+			created:  now,
+			parity:   make([]byte, FixedPayloadSize),
+			received: make(map[int]*Segment),
+			missing:  make(map[int]*fecLostFrame),
+		}
+		f.groups[key] = group
+	}
+	for i, b := range sent.Payload {
+		group.parity[i] ^= b
+	}
+	group.parityLength ^= sent.OriginalLength
+	if received != nil && !received.IsChannelError {
+		group.received[req.SegmentNumber] = received
+	} else {
+		lost := &fecLostFrame{job: job, report: report, linkSeq: linkSeq}
+		if crc32cEnabled {
+			lost.channelCRC = payloadCRC32C(sent.Payload[:sent.OriginalLength])
+		}
+		group.missing[req.SegmentNumber] = lost
+	}
+	group.channel = channel
+	group.sent++
+	complete := group.sent >= group.size
+	if complete {
+		delete(f.groups, key)
+	}
+	f.mu.Unlock()
+
+	if complete {
+		go f.finish(req, group)
+	}
+}
+
+// finish передает кадр четности завершенной группы и восстанавливает потерянный кадр.
+func (f *ParityFEC) finish(req IncomingCodeRequest, group *fecGroup) {
+	f.parityFrames.Add(1)
+	paritySegment := &Segment{
+		Payload:        group.parity,
+		Timestamp:      time.Now().UnixNano(),
+		TotalSegments:  req.TotalSegments,
+		OriginalLength: FixedPayloadSize,
+	}
+	parity, report := group.channel.ProcessSegmentWith(paritySegment, ProcessOptions{Quiet: true})
+	if delay := report.Delay(); delay > 0 {
+		time.Sleep(delay)
+	}
+	if parity == nil || parity.IsChannelError {
+		f.parityLost.Add(1)
+		log.Printf("ChannelLayer: Кадр четности группы сообщения %s (%s) не принят: восстановление невозможно.", req.Sender, req.SendTime)
+		return
+	}
+	switch len(group.missing) {
+	case 0:
+		return
+	case 1:
+	default:
+		f.unrecoverable.Add(1)
+		log.Printf("ChannelLayer: В группе сообщения %s (%s) потеряно кадров: %d, кадр четности восстанавливает только один.",
+			req.Sender, req.SendTime, len(group.missing))
+		return
+	}
+	payload := append([]byte(nil), parity.Payload...)
+	length := group.parityLength
+	for _, segment := range group.received {
+		for i, b := range segment.Payload {
+			payload[i] ^= b
+		}
+		length ^= segment.OriginalLength
+	}
+	for _, lost := range group.missing {
+		job := lost.job
+		if length < 0 || length > FixedPayloadSize {
+			log.Printf("ChannelLayer ERROR: Восстановленная длина полезной нагрузки %d вне допустимого диапазона, сегмент не восстановлен.", length)
+			return
+		}
+		// Кадр четности, искаженный незаметно для декодера, восстанавливает неверную нагрузку
+		if lost.channelCRC != "" && payloadCRC32C(payload[:length]) != lost.channelCRC {
+			f.unrecoverable.Add(1)
+			log.Printf("ChannelLayer: CRC-32C сегмента #%d/%d, восстановленного по кадру четности, не совпадает: сегмент не восстановлен.",
+				job.Request.SegmentNumber, job.Request.TotalSegments)
+			return
+		}
+		f.recovered.Add(1)
+		log.Printf("ChannelLayer: Сегмент #%d/%d от %s восстановлен по кадру четности.", job.Request.SegmentNumber, job.Request.TotalSegments, job.Request.Sender)
+		forwardRecoveredSegment(lost, &Segment{Payload: payload, OriginalLength: length}, group.channel)
+	}
+}
+
+// forwardRecoveredSegment пересылает восстановленный сегмент на /transfer и отмечает его доставленным.
+// Восстановленная полезная нагрузка — нагрузка, переданная в канал, поэтому перед пересылкой она
+// проходит обработчики после декодирования, как и нагрузка принятого кадра.
+func forwardRecoveredSegment(lost *fecLostFrame, recovered *Segment, channel *ChannelLayer) {
+	job := lost.job
+	req := job.Request
+	payload, err := decodedPayload(context.Background(), payloadHookContext(job), recovered, forwardConfig.TrimPadding || channel.Shortening)
+	if err != nil {
+		log.Printf("Web Server ERROR: Обработчик полезной нагрузки после декодирования для восстановленного сегмента #%d/%d: %v", req.SegmentNumber, req.TotalSegments, err)
+		return
+	}
+	outgoingRequest := OutgoingTransferRequest{
+		SegmentNumber: req.SegmentNumber,
+		TotalSegments: req.TotalSegments,
+		Sender:        req.Sender,
+		SendTime:      clockSkew.SkewSendTime(req.SendTime),
+		Payload:       string(payload),
+		Type:          outgoingSegmentType(req.Type),
+		Link:          linkSequencer.Receive(req.Sender, lost.linkSeq),
+		Recovered:     true,
+	}
+	if crc32cEnabled {
+		outgoingRequest.CRC32C = payloadCRC32C(job.OriginalPayload)
+		outgoingRequest.PayloadLength = len(job.OriginalPayload)
+	}
+	if forwardConfig.CarryHops || len(req.Hops) > 0 {
+		outgoingRequest.Hops = appendHop(req.Hops, lost.report)
+	}
+	outgoingJSON, err := json.Marshal(outgoingRequest)
+	if err != nil {
+		log.Printf("Web Server ERROR: Не удалось сериализовать восстановленный сегмент #%d/%d: %v", req.SegmentNumber, req.TotalSegments, err)
+		return
+	}
+	resp, err := forwardSegment(job.ID, outgoingJSON)
+	if err != nil {
+		log.Printf("Web Server ERROR: Не удалось отправить восстановленный сегмент #%d/%d на %s: %v", req.SegmentNumber, req.TotalSegments, TransferURL, err)
+		return
+	}
+	log.Printf("Web Server: Восстановленный сегмент #%d/%d отправлен на %s (Status: %s)", req.SegmentNumber, req.TotalSegments, TransferURL, resp.Status)
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+	// Повторная передача восстановленного сегмента отправителем не пересылается
+	key := dedupKey(req.Sender, req.SendTime, req.SegmentNumber)
+	if deduplicator.Claim(key) == DedupNew {
+		deduplicator.Finish(key, true)
+	}
+	segmentRegistry.Event(job.ID, "fec", "Сегмент восстановлен по кадру четности и передан на /transfer")
+	statistics.Record(SegmentOutcomeRecord{
+		Time:          time.Now().UTC(),
+		Sender:        req.Sender,
+		SendTime:      req.SendTime,
+		SegmentNumber: req.SegmentNumber,
+		TotalSegments: req.TotalSegments,
+		PayloadBytes:  len(job.OriginalPayload),
+		Outcome:       OutcomeRecovered,
+	})
+}
+
+// Stats возвращает статистику межкадровой коррекции (nil, если она отключена).
+func (f *ParityFEC) Stats() *FECStats {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	pending := len(f.groups)
+	f.mu.Unlock()
+	return &FECStats{
+		GroupSize:     f.groupSize,
+		ParityFrames:  f.parityFrames.Load(),
+		ParityLost:    f.parityLost.Load(),
+		Recovered:     f.recovered.Load(),
+		Unrecoverable: f.unrecoverable.Load(),
+		Expired:       f.expired.Load(),
+		Pending:       pending,
+	}
+}
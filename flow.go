@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	FlowWindowHeader  = "X-Channel-Window"  // Размер окна управления потоком отправителя
+	FlowCreditsHeader = "X-Channel-Credits" // Свободные кредиты отправителя после приема сегмента
+
+	defaultFlowQueueTimeout = 30 * time.Second // Время ожидания кредита по умолчанию
+)
+
+// errNoCredits — у отправителя нет свободных кредитов (окно исчерпано).
+var errNoCredits = errors.New("окно управления потоком исчерпано")
+
+// FlowControlConfig задает управление потоком кредитами: канальный уровень разрешает каждому
+// отправителю не более window сегментов в обработке одновременно.
+type FlowControlConfig struct {
+	Window       int      `json:"window"`        // Размер окна (кредитов) на отправителя; 0 — управление потоком отключено
+	Queue        bool     `json:"queue"`         // Ожидать освобождения кредита вместо отказа
+	QueueTimeout Duration `json:"queue_timeout"` // Наибольшее ожидание кредита (по умолчанию 30 с)
+	UpdateURL    string   `json:"update_url"`    // Адрес, на который отправляются обновления окна (пусто — не отправляются)
+}
+
+// WindowUpdate — управляющее сообщение об обновлении окна, отправляемое транспортному уровню
+// при возврате кредита.
+type WindowUpdate struct {
+	Sender  string    `json:"sender"`
+	Window  int       `json:"window"`  // Размер окна
+	Credits int       `json:"credits"` // Свободные кредиты
+	Time    time.Time `json:"time"`
+}
+
+// FlowStats — статистика управления потоком для /stats.
+type FlowStats struct {
+	Window   int            `json:"window"`
+	Queue    bool           `json:"queue"`
+	Rejected int64          `json:"rejected"`  // Сегментов, отклоненных из-за исчерпанного окна
+	Queued   int64          `json:"queued"`    // Сегментов, ожидавших освобождения кредита
+	InFlight map[string]int `json:"in_flight"` // Сегментов в обработке по отправителям
+}
+
+// FlowControl — управление потоком кредитами: каждый принятый сегмент занимает кредит отправителя
+// до окончания обработки; при исчерпанном окне сегмент отклоняется (429) или ожидает кредита.
+// Свободные кредиты объявляются в заголовках ответа /code, а возврат кредита сообщается
+// транспортному уровню управляющим сообщением WindowUpdate. Методы допускают вызов на nil
+// (управление потоком отключено).
+type FlowControl struct {
+	window       int
+	queue        bool
+	queueTimeout time.Duration
+	updateURL    string
+
+	mu       sync.Mutex
+	inFlight map[string]int           // Занятые кредиты по отправителям
+	released map[string]chan struct{} // Закрывается при возврате кредита отправителя
+
+	rejected atomic.Int64
+	queued   atomic.Int64
+}
+
+// NewFlowControl создает управление потоком по конфигурации (nil, если окно не задано).
+func NewFlowControl(cfg FlowControlConfig) (*FlowControl, error) {
+	if cfg.Window == 0 {
+		return nil, nil
+	}
+	if cfg.Window < 0 {
+		return nil, fmt.Errorf("размер окна не может быть отрицательным, задано %d", cfg.Window)
+	}
+	if cfg.UpdateURL != "" {
+		if err := validateCallbackURL(cfg.UpdateURL); err != nil {
+			return nil, fmt.Errorf("update_url: %w", err)
+		}
+	}
+	f := &FlowControl{
+		window:       cfg.Window,
+		queue:        cfg.Queue,
+		queueTimeout: cfg.QueueTimeout.Duration,
+		updateURL:    cfg.UpdateURL,
+		inFlight:     make(map[string]int),
+		released:     make(map[string]chan struct{}),
+	}
+	if f.queueTimeout <= 0 {
+		f.queueTimeout = defaultFlowQueueTimeout
+	}
+	return f, nil
+}
+
+// Acquire занимает кредит отправителя и возвращает число оставшихся свободных кредитов.
+// При исчерпанном окне возвращает errNoCredits или (в режиме очереди) ожидает возврата кредита.
+func (f *FlowControl) Acquire(ctx context.Context, sender string) (int, error) {
+	if f == nil {
+		return 0, nil
+	}
+	var timeout <-chan time.Time
+	for {
+		f.mu.Lock()
+		if f.inFlight[sender] < f.window {
+			f.inFlight[sender]++
+			credits := f.window - f.inFlight[sender]
+			f.mu.Unlock()
+			return credits, nil
+		}
+		if !f.queue {
+			f.mu.Unlock()
+			f.rejected.Add(1)
+			return 0, errNoCredits
+		}
+		released, ok := f.released[sender]
+		if !ok {
+			released = make(chan struct{})
+			f.released[sender] = released
+		}
+		f.mu.Unlock()
+		if timeout == nil {
+			f.queued.Add(1)
+			timer := time.NewTimer(f.queueTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case <-released:
+		case <-timeout:
+			f.rejected.Add(1)
+			return 0, errNoCredits
+		case <-ctx.Done():
+			f.rejected.Add(1)
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// Release возвращает кредит отправителя и сообщает транспортному уровню об обновлении окна.
+func (f *FlowControl) Release(sender string) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	f.inFlight[sender]--
+	credits := f.window - f.inFlight[sender]
+	if f.inFlight[sender] <= 0 {
+		delete(f.inFlight, sender)
+	}
+	if released, ok := f.released[sender]; ok {
+		close(released)
+		delete(f.released, sender)
+	}
+	f.mu.Unlock()
+	if f.updateURL != "" {
+		go f.sendUpdate(WindowUpdate{Sender: sender, Window: f.window, Credits: credits, Time: time.Now().UTC()})
+	}
+}
+
+// sendUpdate отправляет обновление окна на update_url. Обновление не повторяется: следующее
+// обновление содержит актуальное число кредитов.
+func (f *FlowControl) sendUpdate(update WindowUpdate) {
+	body, err := json.Marshal(update)
+	if err != nil {
+		return
+	}
+	resp, err := callbackClient.Post(f.updateURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Flow ERROR: Обновление окна отправителя %s не доставлено на %s: %v", update.Sender, f.updateURL, err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// SetHeaders записывает размер окна и число свободных кредитов в заголовки ответа.
+func (f *FlowControl) SetHeaders(h http.Header, credits int) {
+	if f == nil {
+		return
+	}
+	h.Set(FlowWindowHeader, strconv.Itoa(f.window))
+	h.Set(FlowCreditsHeader, strconv.Itoa(credits))
+}
+
+// Stats возвращает статистику управления потоком (nil, если оно отключено).
+func (f *FlowControl) Stats() *FlowStats {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	inFlight := make(map[string]int, len(f.inFlight))
+	for sender, n := range f.inFlight {
+		inFlight[sender] = n
+	}
+	return &FlowStats{
+		Window:   f.window,
+		Queue:    f.queue,
+		Rejected: f.rejected.Load(),
+		Queued:   f.queued.Load(),
+		InFlight: inFlight,
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+var forwardConfig ForwardConfig // Глобальные параметры пересылки на /transfer
+
+// TransferResponse — результат отправки сегмента на конечную точку /transfer.
+type TransferResponse struct {
+	StatusCode int    // Код статуса HTTP ответа
+	Status     string // Строка статуса HTTP ответа (например, "200 OK")
+	Body       []byte // Тело ответа (nil, если прочитать его не удалось)
+}
+
+// postTransfer отправляет сериализованный сегмент POST запросом на TransferURL и читает ответ.
+// Ошибка возвращается только если запрос не удалось выполнить (например, целевой сервер недоступен);
+// ответ с любым статусом считается выполненным запросом.
+func postTransfer(outgoingJSON []byte) (*TransferResponse, error) {
+	resp, err := http.Post(TransferURL, "application/json", bytes.NewBuffer(outgoingJSON))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Чтение ответа от конечной точки /transfer (для логирования/отладки)
+	body, errReadBody := io.ReadAll(resp.Body)
+	if errReadBody != nil {
+		log.Printf("Web Server WARNING: Не удалось прочитать тело ответа от конечной точки /transfer: %v", errReadBody)
+		body = nil
+	}
+
+	return &TransferResponse{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Body:       body,
+	}, nil
+}
+
+// forwardSegment отправляет сериализованный сегмент на /transfer и сохраняет попытку
+// в жизненном цикле сегмента с идентификатором id.
+func forwardSegment(id string, outgoingJSON []byte) (*TransferResponse, error) {
+	attempt := ForwardAttempt{Time: time.Now().UTC()}
+	resp, err := postTransfer(outgoingJSON)
+	attempt.DurationMs = float64(time.Since(attempt.Time).Microseconds()) / 1000
+	if err != nil {
+		attempt.Error = err.Error()
+	} else {
+		attempt.StatusCode = resp.StatusCode
+		attempt.Status = resp.Status
+	}
+	segmentRegistry.AddForwardAttempt(id, attempt)
+	return resp, err
+}
+
+// forwardLostPlaceholder пересылает на /transfer заглушку потерянного сегмента: метаданные
+// без полезной нагрузки с lost=true. Заглушка носит уведомительный характер: она не журналируется,
+// а сбой ее отправки не меняет итог обработки сегмента. Возвращает статус ответа /transfer.
+func forwardLostPlaceholder(job *segmentJob) string {
+	req := job.Request
+	placeholderJSON, err := json.Marshal(OutgoingTransferRequest{
+		SegmentNumber: req.SegmentNumber,
+		TotalSegments: req.TotalSegments,
+		Sender:        req.Sender,
+		SendTime:      clockSkew.SkewSendTime(req.SendTime),
+		Type:          outgoingSegmentType(req.Type),
+		Lost:          true,
+	})
+	if err != nil {
+		log.Printf("Web Server ERROR: Не удалось сериализовать заглушку потерянного сегмента #%d/%d: %v", req.SegmentNumber, req.TotalSegments, err)
+		return ""
+	}
+
+	resp, err := forwardSegment(job.ID, placeholderJSON)
+	if err != nil {
+		log.Printf("Web Server ERROR: Не удалось отправить заглушку потерянного сегмента #%d/%d на %s: %v", req.SegmentNumber, req.TotalSegments, TransferURL, err)
+		return ""
+	}
+	log.Printf("Web Server: Заглушка потерянного сегмента #%d/%d отправлена на %s (Status: %s)", req.SegmentNumber, req.TotalSegments, TransferURL, resp.Status)
+	return resp.Status
+}
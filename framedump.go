@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DebugFramesEndpoint — префикс отладочных конечных точек с кадрами обработанных сегментов.
+const DebugFramesEndpoint = "/debug/frames/"
+
+// Параметры дампа кадра.
+const (
+	hexDumpBytesPerLine = 16 // Байт в строке шестнадцатеричного дампа
+	bitDumpBitsPerLine  = 64 // Примерная ширина строки битового дампа (выравнивается по группам)
+)
+
+// writeHexDump выводит шестнадцатеричный дамп переданного и принятого кадров; байты,
+// отличающиеся в принятом кадре, отмечаются звездочкой.
+func writeHexDump(b *strings.Builder, tx, rx []byte) {
+	for off := 0; off < len(tx); off += hexDumpBytesPerLine {
+		end := min(off+hexDumpBytesPerLine, len(tx))
+		fmt.Fprintf(b, "%04x  TX ", off)
+		for i := off; i < end; i++ {
+			fmt.Fprintf(b, " %02x ", tx[i])
+		}
+		b.WriteByte('\n')
+		if rx == nil {
+			continue
+		}
+		b.WriteString("      RX ")
+		for i := off; i < end; i++ {
+			mark := byte(' ')
+			if rx[i] != tx[i] {
+				mark = '*'
+			}
+			fmt.Fprintf(b, " %02x%c", rx[i], mark)
+		}
+		b.WriteByte('\n')
+	}
+}
+
+// writeBitDump выводит битовый дамп кадров группами по group бит (по кодовым словам, если кадр
+// передается без этапов обработки потока); под отличающимися битами ставится ^.
+func writeBitDump(b *strings.Builder, tx, rx []uint8, group int) {
+	perLine := max(bitDumpBitsPerLine/group, 1) * group
+	row := func(bits []uint8, from, to int) string {
+		var s strings.Builder
+		for i := from; i < to; i++ {
+			if i > from && (i-from)%group == 0 {
+				s.WriteByte(' ')
+			}
+			s.WriteByte('0' + bits[i])
+		}
+		return s.String()
+	}
+	for off := 0; off < len(tx); off += perLine {
+		end := min(off+perLine, len(tx))
+		fmt.Fprintf(b, "%5d  TX %s\n", off, row(tx, off, end))
+		if rx == nil {
+			continue
+		}
+		fmt.Fprintf(b, "       RX %s\n", row(rx, off, end))
+		var diff strings.Builder
+		differs := false
+		for i := off; i < end; i++ {
+			if i > off && (i-off)%group == 0 {
+				diff.WriteByte(' ')
+			}
+			if rx[i] != tx[i] {
+				diff.WriteByte('^')
+				differs = true
+			} else {
+				diff.WriteByte(' ')
+			}
+		}
+		if differs {
+			fmt.Fprintf(b, "          %s\n", strings.TrimRight(diff.String(), " "))
+		}
+	}
+}
+
+// handleDebugFrameHex возвращает аннотированный шестнадцатеричный и битовый дамп кадра сегмента
+// до и после искажений в канале (GET /debug/frames/{id}/hex).
+func handleDebugFrameHex(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+		return
+	}
+	rec, ok := segmentRegistry.Get(r.PathValue("id"))
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		sendErrorResponse(w, "Сегмент не найден (неизвестный идентификатор или запись вытеснена из реестра)", http.StatusNotFound)
+		return
+	}
+	if rec.Channel == nil || rec.Channel.TxFrame == nil {
+		w.Header().Set("Content-Type", "application/json")
+		sendErrorResponse(w, "Кадр сегмента недоступен: сегмент еще не передан в канал", http.StatusNotFound)
+		return
+	}
+	report := rec.Channel
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Сегмент %s #%d/%d (send_time %s, id %s)\n", rec.Sender, rec.SegmentNumber, rec.TotalSegments, rec.SendTime, rec.ID)
+	group := 8
+	switch {
+	case report.Codec == "":
+		fmt.Fprintf(&b, "Кадр передан без кодирования: %d бит\n", len(report.TxFrame))
+	case len(report.Stages) > 0:
+		fmt.Fprintf(&b, "Кодек %s [%d,%d], этапы: %s; кадр %d бит (группы по 8 бит)\n",
+			report.Codec, report.CodeN, report.CodeK, strings.Join(report.Stages, ", "), len(report.TxFrame))
+	default:
+		group = report.CodeN
+		fmt.Fprintf(&b, "Кодек %s [%d,%d]; кадр %d бит (группы — кодовые слова)\n",
+			report.Codec, report.CodeN, report.CodeK, len(report.TxFrame))
+	}
+	switch {
+	case report.Lost:
+		b.WriteString("Кадр потерян в канале: принятый кадр отсутствует\n")
+	case report.ImpairmentsSkipped:
+		b.WriteString("Симуляция искажений пропущена\n")
+	default:
+		fmt.Fprintf(&b, "Инвертированы биты: %v\n", report.FlippedBits)
+	}
+	if report.Decode != "" {
+		fmt.Fprintf(&b, "Декодирование: %s (исправлено блоков: %d, с неисправимой ошибкой: %d)\n",
+			report.Decode, report.CorrectedBlocks, report.ErrorBlocks)
+	}
+
+	rxFrame := report.RxFrame
+	if rxFrame != nil && len(rxFrame) != len(report.TxFrame) {
+		// Кадр с проскальзыванием бит сравнивается с переданным в пределах переданной длины
+		fmt.Fprintf(&b, "Длина принятого кадра %d бит отличается от переданной на %+d бит\n", len(rxFrame), report.LengthMismatch)
+		rxFrame = fitFrameLength(rxFrame, len(report.TxFrame))
+	}
+	var rxBytes []byte
+	if rxFrame != nil {
+		rxBytes = packBits(rxFrame)
+	}
+	b.WriteString("\nШестнадцатеричный дамп (* — байт искажен):\n")
+	writeHexDump(&b, packBits(report.TxFrame), rxBytes)
+	b.WriteString("\nБитовый дамп (^ — бит искажен):\n")
+	writeBitDump(&b, report.TxFrame, rxFrame, group)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
@@ -0,0 +1,252 @@
+package main
+
+// hdlcFlag — флаговая последовательность HDLC 01111110, ограничивающая кадр.
+var hdlcFlag = []uint8{0, 1, 1, 1, 1, 1, 1, 0}
+
+// hdlcStage — кадрирование HDLC с бит-стаффингом: закодированный поток заключается между флагами
+// 01111110, а внутри кадра после каждых пяти единиц подряд вставляется ноль, чтобы данные не
+// содержали флаговой последовательности. Приемник ищет открывающий флаг, удаляет вставленные нули
+// и завершает кадр на закрывающем флаге. Ошибка канала может исказить флаг, создать ложный флаг
+// внутри кадра, удалить бит данных как вставленный или образовать шесть и более единиц подряд
+// (аварийное завершение кадра); такие ошибки кадрирования считаются нарушениями.
+type hdlcStage struct{}
+
+func newHDLCStage(StageConfig) (StreamStage, error) {
+	return hdlcStage{}, nil
+}
+
+func (hdlcStage) Name() string { return "hdlc" }
+
+// Apply выполняет бит-стаффинг потока и добавляет открывающий и закрывающий флаги.
+func (hdlcStage) Apply(bits []uint8) []uint8 {
+	out := make([]uint8, 0, len(bits)+len(bits)/5+2*len(hdlcFlag))
+	out = append(out, hdlcFlag...)
+	ones := 0
+	for _, bit := range bits {
+		out = append(out, bit)
+		if bit == 0 {
+			ones = 0
+			continue
+		}
+		if ones++; ones == 5 {
+			out = append(out, 0)
+			ones = 0
+		}
+	}
+	return append(out, hdlcFlag...)
+}
+
+// Invert проверяет открывающий флаг, удаляет вставленные нули до закрывающего флага и возвращает
+// поток длиной length. Нарушениями считаются искаженный открывающий флаг, серия из шести и более
+// единиц, не образующая флаг, отсутствие закрывающего флага и несовпадение длины кадра; недостающие
+// биты дополняются нулями.
+func (hdlcStage) Invert(bits []uint8, length int) ([]uint8, int) {
+	violations := 0
+	isFlag := func(i int) bool {
+		if i+len(hdlcFlag) > len(bits) {
+			return false
+		}
+		for j, bit := range hdlcFlag {
+			if bits[i+j] != bit {
+				return false
+			}
+		}
+		return true
+	}
+	if !isFlag(0) {
+		violations++
+	}
+	out := make([]uint8, 0, length)
+	ones := 0
+	closed := false
+	for i := len(hdlcFlag); i < len(bits); i++ {
+		if isFlag(i) {
+			closed = true
+			break
+		}
+		bit := bits[i]
+		if bit == 0 {
+			if ones != 5 {
+				out = append(out, 0) // Ноль после пяти единиц вставлен передатчиком
+			}
+			ones = 0
+			continue
+		}
+		ones++
+		if ones == 6 {
+			violations++ // Шесть единиц подряд вне флага — аварийное завершение кадра
+		}
+		out = append(out, 1)
+	}
+	if !closed {
+		violations++
+	}
+	if len(out) != length {
+		violations++
+	}
+	if len(out) < length {
+		out = append(out, make([]uint8, length-len(out))...)
+	}
+	return out[:length], violations
+}
+
+// Байтовое кадрирование с экранированием (PPP, RFC 1662).
+const (
+	byteFlag       = 0x7E // Флаг начала и конца кадра
+	byteEscape     = 0x7D // Управляющий байт экранирования
+	byteEscapeMask = 0x20 // Маска, инвертирующая бит экранированного байта
+)
+
+// unframeBytes преобразует принятые байты кадра в поток длиной length: недостающие биты дополняются
+// нулями, биты дополнения последнего байта отбрасываются. Возвращает признак несовпадения длины кадра.
+func unframeBytes(data []byte, length int) ([]uint8, bool) {
+	size := (length + 7) / 8
+	mismatch := len(data) != size
+	if len(data) < size {
+		data = append(data, make([]byte, size-len(data))...)
+	}
+	return bytesToBitStream(data[:size])[:length], mismatch
+}
+
+// byteStuffingStage — байтовое кадрирование с экранированием (PPP): кадр ограничивается флагами 0x7E,
+// а байты 0x7E и 0x7D внутри кадра заменяются парой 0x7D, b^0x20. В отличие от бит-стаффинга,
+// кадр остается последовательностью целых байтов, но его длина зависит от содержимого. Ошибка канала
+// может исказить флаг, превратить байт данных в ложный флаг или в управляющий байт (тогда следующий
+// байт данных ошибочно снимается с экранирования) и сдвинуть все последующие байты кадра.
+type byteStuffingStage struct{}
+
+func newByteStuffingStage(StageConfig) (StreamStage, error) {
+	return byteStuffingStage{}, nil
+}
+
+func (byteStuffingStage) Name() string { return "byte_stuffing" }
+
+// Apply экранирует байты кадра и добавляет открывающий и закрывающий флаги.
+func (byteStuffingStage) Apply(bits []uint8) []uint8 {
+	data := packBits(bits)
+	out := make([]byte, 0, len(data)+len(data)/64+2)
+	out = append(out, byteFlag)
+	for _, b := range data {
+		if b == byteFlag || b == byteEscape {
+			out = append(out, byteEscape, b^byteEscapeMask)
+			continue
+		}
+		out = append(out, b)
+	}
+	return bytesToBitStream(append(out, byteFlag))
+}
+
+// Invert проверяет открывающий флаг и снимает экранирование до закрывающего флага. Нарушениями
+// считаются искаженный открывающий флаг, экранирование байта, который не требует экранирования,
+// отсутствие закрывающего флага и несовпадение длины кадра.
+func (byteStuffingStage) Invert(bits []uint8, length int) ([]uint8, int) {
+	data := packBits(bits)
+	violations := 0
+	if len(data) == 0 || data[0] != byteFlag {
+		violations++
+	}
+	out := make([]byte, 0, (length+7)/8)
+	closed := false
+	for i := 1; i < len(data); i++ {
+		b := data[i]
+		if b == byteFlag {
+			closed = true
+			break
+		}
+		if b == byteEscape {
+			if i+1 == len(data) || data[i+1] == byteFlag {
+				violations++ // Кадр завершен управляющим байтом (аварийное завершение)
+				continue
+			}
+			i++
+			b = data[i] ^ byteEscapeMask
+			if b != byteFlag && b != byteEscape {
+				violations++
+			}
+		}
+		out = append(out, b)
+	}
+	if !closed {
+		violations++
+	}
+	stream, mismatch := unframeBytes(out, length)
+	if mismatch {
+		violations++
+	}
+	return stream, violations
+}
+
+// cobsStage — кадрирование COBS (Consistent Overhead Byte Stuffing): нулевые байты кадра исключаются
+// кодированием, и единственный нулевой байт завершает кадр. Каждый блок начинается байтом кода —
+// расстоянием до следующего исключенного нуля (код 0xFF обозначает 254 байта без нуля), поэтому
+// накладные расходы не превышают одного байта на 254 байта данных. Ошибка в байте кода сдвигает
+// позиции всех последующих нулей, а искаженный байт, ставший нулем, преждевременно завершает кадр.
+type cobsStage struct{}
+
+func newCOBSStage(StageConfig) (StreamStage, error) {
+	return cobsStage{}, nil
+}
+
+func (cobsStage) Name() string { return "cobs" }
+
+// Apply кодирует байты кадра COBS и добавляет нулевой разделитель.
+func (cobsStage) Apply(bits []uint8) []uint8 {
+	data := packBits(bits)
+	out := make([]byte, 1, len(data)+len(data)/254+2)
+	codeIndex, code := 0, byte(1)
+	for _, b := range data {
+		if b != 0 {
+			out = append(out, b)
+			code++
+		}
+		if b == 0 || code == 0xFF {
+			out[codeIndex] = code
+			codeIndex, code = len(out), 1
+			out = append(out, 0)
+		}
+	}
+	out[codeIndex] = code
+	return bytesToBitStream(append(out, 0))
+}
+
+// Invert декодирует блоки COBS до нулевого разделителя. Нарушениями считаются блок, выходящий
+// за разделитель, отсутствие разделителя и несовпадение длины кадра.
+func (cobsStage) Invert(bits []uint8, length int) ([]uint8, int) {
+	data := packBits(bits)
+	violations := 0
+	out := make([]byte, 0, (length+7)/8)
+	closed := false
+	for i := 0; i < len(data); {
+		code := int(data[i])
+		if code == 0 {
+			closed = true
+			break
+		}
+		i++
+		end := i + code - 1
+		for ; i < end && i < len(data) && data[i] != 0; i++ {
+			out = append(out, data[i])
+		}
+		if i < end {
+			violations++ // Блок прерван разделителем или концом потока
+			continue
+		}
+		if code < 0xFF && i < len(data) && data[i] != 0 {
+			out = append(out, 0)
+		}
+	}
+	if !closed {
+		violations++
+	}
+	stream, mismatch := unframeBytes(out, length)
+	if mismatch {
+		violations++
+	}
+	return stream, violations
+}
+
+func init() {
+	RegisterStage("hdlc", newHDLCStage)
+	RegisterStage("byte_stuffing", newByteStuffingStage)
+	RegisterStage("cobs", newCOBSStage)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+var leaderLock *os.File // Файл блокировки активного экземпляра (удерживается до завершения процесса)
+
+// acquireLeadership переводит экземпляр в резервный режим до тех пор, пока он не получит
+// эксклюзивную блокировку файла lockPath. Блокировку удерживает активный экземпляр; при его
+// завершении (в том числе аварийном) ОС снимает блокировку, и резервный экземпляр становится
+// активным: занимает порт и продолжает отправку сегментов из журнала исходящих сегментов.
+func acquireLeadership(lockPath string) error {
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть файл блокировки %s: %w", lockPath, err)
+	}
+
+	acquired, err := tryLockFile(file)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("не удалось заблокировать файл %s: %w", lockPath, err)
+	}
+	if !acquired {
+		log.Printf("HA: Активный экземпляр удерживает %s, экземпляр работает в резервном режиме", lockPath)
+		waitStart := time.Now()
+		if err := lockFile(file); err != nil {
+			file.Close()
+			return fmt.Errorf("не удалось дождаться блокировки файла %s: %w", lockPath, err)
+		}
+		log.Printf("HA: Активный экземпляр недоступен, переход в активный режим после %v ожидания", time.Since(waitStart).Round(time.Millisecond))
+	}
+
+	// Записываем сведения об активном экземпляре для диагностики
+	hostname, _ := os.Hostname()
+	if err := file.Truncate(0); err == nil {
+		fmt.Fprintf(file, "pid=%d host=%s since=%s\n", os.Getpid(), hostname, time.Now().UTC().Format(time.RFC3339))
+		file.Sync()
+	}
+
+	leaderLock = file
+	log.Printf("HA: Экземпляр активен (блокировка %s)", lockPath)
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+var errLockUnsupported = errors.New("блокировка файлов не поддерживается на этой платформе")
+
+// tryLockFile не поддерживается на платформах, отличных от unix.
+func tryLockFile(file *os.File) (bool, error) {
+	return false, errLockUnsupported
+}
+
+// lockFile не поддерживается на платформах, отличных от unix.
+func lockFile(file *os.File) error {
+	return errLockUnsupported
+}
@@ -0,0 +1,83 @@
+package main
+
+// Параметры кода Хэмминга (15,11).
+const (
+	Hamming1511N = 15 // n: длина кодового слова
+	Hamming1511K = 11 // k: число информационных бит
+)
+
+// hamming1511Coder — код Хэмминга (15,11), исправляющий одиночную ошибку в блоке.
+// По сравнению с [7,4] избыточность ниже (4 проверочных бита на 11 информационных вместо 3 на 4),
+// но одиночная ошибка исправляется в блоке большей длины, поэтому две ошибки в одном блоке
+// встречаются чаще и приводят к неверному исправлению.
+//
+// Позиции кодового слова нумеруются с 1 (p = 1..15). Проверочные биты стоят на позициях,
+// равных степеням двойки (1, 2, 4, 8), информационные — на остальных позициях по порядку.
+// Проверочный бит на позиции 2^j равен сумме по модулю 2 всех позиций, в номере которых
+// установлен бит j. Синдром принятого слова равен номеру позиции с ошибкой (0 — ошибки нет).
+type hamming1511Coder struct{}
+
+func (hamming1511Coder) Name() string { return "hamming1511" }
+func (hamming1511Coder) N() int       { return Hamming1511N }
+func (hamming1511Coder) K() int       { return Hamming1511K }
+
+// isPowerOfTwo сообщает, является ли p степенью двойки (позиция проверочного бита).
+func isPowerOfTwo(p int) bool {
+	return p&(p-1) == 0
+}
+
+// EncodeBlock кодирует 11 информационных бит в кодовое слово (15,11).
+func (hamming1511Coder) EncodeBlock(infoBits []uint8) []uint8 {
+	codeword := make([]uint8, Hamming1511N)
+	// Информационные биты на позициях, не являющихся степенью двойки
+	i := 0
+	for p := 1; p <= Hamming1511N; p++ {
+		if !isPowerOfTwo(p) {
+			codeword[p-1] = infoBits[i]
+			i++
+		}
+	}
+	// Проверочные биты: XOR номеров позиций с единичными битами дает синдром; проверочные биты
+	// выбираются так, чтобы синдром кодового слова был нулевым
+	syndrome := hammingSyndrome(codeword)
+	for j := 0; 1<<j <= Hamming1511N; j++ {
+		codeword[(1<<j)-1] = uint8(syndrome>>j) & 1
+	}
+	return codeword
+}
+
+// DecodeBlock декодирует кодовое слово (15,11), исправляя одиночную ошибку.
+// Код совершенный: любой ненулевой синдром указывает на позицию, поэтому неисправимые ошибки
+// не обнаруживаются (две ошибки исправляются неверно).
+func (hamming1511Coder) DecodeBlock(codedBits []uint8) ([]uint8, bool, bool) {
+	received := append([]uint8(nil), codedBits...)
+	syndrome := hammingSyndrome(received)
+	corrected := false
+	if syndrome != 0 {
+		received[syndrome-1] ^= 1
+		corrected = true
+	}
+
+	infoBits := make([]uint8, 0, Hamming1511K)
+	for p := 1; p <= Hamming1511N; p++ {
+		if !isPowerOfTwo(p) {
+			infoBits = append(infoBits, received[p-1])
+		}
+	}
+	return infoBits, corrected, false
+}
+
+// hammingSyndrome возвращает XOR номеров позиций (с 1), на которых стоят единичные биты.
+func hammingSyndrome(codeword []uint8) int {
+	syndrome := 0
+	for i, bit := range codeword {
+		if bit == 1 {
+			syndrome ^= i + 1
+		}
+	}
+	return syndrome
+}
+
+func init() {
+	RegisterCoder(hamming1511Coder{})
+}
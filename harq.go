@@ -0,0 +1,88 @@
+package main
+
+// HARQBuffer — буфер приемника гибридного ARQ (HARQ) для кадра одного сегмента: мягкие решения
+// о битах закодированного потока (до выкалывания) накапливаются по всем передачам кадра и
+// декодируются совместно. Передачи используют разные версии избыточности: первая передает биты,
+// оставленные шаблоном выкалывания, вторая — выколотые биты (дополнительные проверочные биты,
+// incremental redundancy), последующие — весь поток, который складывается с принятым ранее
+// (Chase combining). Без выкалывания все передачи повторяют весь поток. Жестко принятый бит
+// учитывается как LLR ±1, стертый — как 0. Методы допускают вызов на nil (HARQ не используется).
+type HARQBuffer struct {
+	puncture *Puncturer
+	combined []float64 // Накопленные LLR бит закодированного потока
+	next     int       // Версия избыточности следующей передачи
+	version  int       // Версия избыточности текущей передачи
+}
+
+// NewHARQBuffer создает пустой буфер HARQ для кадра.
+func NewHARQBuffer() *HARQBuffer {
+	return &HARQBuffer{}
+}
+
+// sent сообщает, передается ли бит i закодированного потока в текущей версии избыточности.
+func (h *HARQBuffer) sent(i int) bool {
+	p := h.puncture
+	if p == nil || h.version >= 2 || (h.version == 1 && p.kept == len(p.pattern)) {
+		return true
+	}
+	kept := p.pattern[i%len(p.pattern)] == 1
+	return kept == (h.version == 0)
+}
+
+// Select начинает очередную передачу кадра, выкалываемого по шаблону puncture, и возвращает биты
+// закодированного потока, передаваемые в ее версии избыточности (без HARQ — поток после выкалывания).
+func (h *HARQBuffer) Select(puncture *Puncturer, bits []uint8) []uint8 {
+	if h == nil {
+		return puncture.Apply(bits)
+	}
+	if h.combined == nil {
+		h.combined = make([]float64, len(bits))
+	}
+	h.puncture = puncture
+	h.version = h.next
+	h.next++
+	out := make([]uint8, 0, len(bits))
+	for i, bit := range bits {
+		if h.sent(i) {
+			out = append(out, bit)
+		}
+	}
+	return out
+}
+
+// Version возвращает версию избыточности текущей передачи (0 — первая передача).
+func (h *HARQBuffer) Version() int {
+	if h == nil {
+		return 0
+	}
+	return h.version
+}
+
+// Combine добавляет LLR бит, принятых в текущей передаче, к накопленным и возвращает
+// накопленные LLR всего закодированного потока.
+func (h *HARQBuffer) Combine(received []float64) []float64 {
+	j := 0
+	for i := range h.combined {
+		if h.sent(i) && j < len(received) {
+			h.combined[i] += received[j]
+			j++
+		}
+	}
+	return append([]float64(nil), h.combined...)
+}
+
+// hardLLR представляет жестко принятые биты как LLR: +1 для 0, -1 для 1 и 0 для стертого бита
+// (erasedMask[i] != 0; nil — стираний нет).
+func hardLLR(bits []uint8, erasedMask []float64) []float64 {
+	llr := make([]float64, len(bits))
+	for i, bit := range bits {
+		switch {
+		case erasedMask != nil && erasedMask[i] != 0:
+		case bit == 1:
+			llr[i] = -1
+		default:
+			llr[i] = 1
+		}
+	}
+	return llr
+}
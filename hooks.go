@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"channel-layer/hooks"
+)
+
+// Точки подключения обработчиков полезной нагрузки.
+const (
+	HookStagePreCoding    = "pre_coding"    // Перед паддингом и кодированием
+	HookStagePostDecoding = "post_decoding" // После декодирования, перед пересылкой на /transfer
+)
+
+// defaultHookTimeout — время ожидания ответа внешнего обработчика по умолчанию.
+const defaultHookTimeout = 2 * time.Second
+
+// PayloadHookContext — сведения о сегменте, полезную нагрузку которого преобразует обработчик
+// (Stage — точка подключения, см. HookStage*).
+type PayloadHookContext = hooks.Context
+
+// PayloadHookConfig описывает обработчик в конфигурации: функцию, зарегистрированную в пакете
+// channel-layer/hooks, или внешний HTTP-обработчик.
+type PayloadHookConfig struct {
+	Name    string   `json:"name,omitempty"`    // Имя функции (см. hooks.Register)
+	URL     string   `json:"url,omitempty"`     // Адрес внешнего обработчика (POST, см. PayloadHookRequest)
+	Timeout Duration `json:"timeout,omitempty"` // Время ожидания ответа внешнего обработчика
+}
+
+// PayloadHookRequest — тело запроса к внешнему обработчику; полезная нагрузка передается в base64.
+type PayloadHookRequest struct {
+	PayloadHookContext
+	Payload []byte `json:"payload"`
+}
+
+// PayloadHookResponse — ответ внешнего обработчика.
+type PayloadHookResponse struct {
+	Payload []byte `json:"payload"`
+	Error   string `json:"error,omitempty"`
+}
+
+// payloadHook — обработчик цепочки вместе с его именем для журнала.
+type payloadHook struct {
+	name string
+	fn   func(ctx context.Context, hc PayloadHookContext, payload []byte) ([]byte, error)
+}
+
+// PayloadHooks — цепочки обработчиков полезной нагрузки перед кодированием и после декодирования.
+// Все методы допускают вызов на nil (обработчики не настроены).
+type PayloadHooks struct {
+	preCoding    []payloadHook
+	postDecoding []payloadHook
+}
+
+// NewPayloadHooks создает цепочки обработчиков по конфигурации (nil, если обработчики не заданы).
+func NewPayloadHooks(cfg HooksConfig) (*PayloadHooks, error) {
+	if len(cfg.PreCoding) == 0 && len(cfg.PostDecoding) == 0 {
+		return nil, nil
+	}
+	h := &PayloadHooks{}
+	var err error
+	if h.preCoding, err = newPayloadHookChain(HookStagePreCoding, cfg.PreCoding); err != nil {
+		return nil, err
+	}
+	if h.postDecoding, err = newPayloadHookChain(HookStagePostDecoding, cfg.PostDecoding); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// newPayloadHookChain создает обработчики одной точки подключения.
+func newPayloadHookChain(stage string, configs []PayloadHookConfig) ([]payloadHook, error) {
+	chain := make([]payloadHook, 0, len(configs))
+	for i, cfg := range configs {
+		switch {
+		case cfg.Name != "" && cfg.URL != "":
+			return nil, fmt.Errorf("обработчик %s %d: должно быть задано либо name, либо url", stage, i+1)
+		case cfg.Name != "":
+			fn, ok := hooks.Lookup(cfg.Name)
+			if !ok {
+				return nil, fmt.Errorf("обработчик %s %d: неизвестная функция '%s' (допустимо: %s)", stage, i+1, cfg.Name, strings.Join(hooks.Names(), ", "))
+			}
+			chain = append(chain, payloadHook{name: cfg.Name, fn: func(_ context.Context, hc PayloadHookContext, payload []byte) ([]byte, error) {
+				return fn(hc, payload)
+			}})
+		case cfg.URL != "":
+			timeout := cfg.Timeout.Duration
+			if timeout <= 0 {
+				timeout = defaultHookTimeout
+			}
+			client := &http.Client{Timeout: timeout}
+			url := cfg.URL
+			chain = append(chain, payloadHook{name: url, fn: func(ctx context.Context, hc PayloadHookContext, payload []byte) ([]byte, error) {
+				return callHTTPPayloadHook(ctx, client, url, hc, payload)
+			}})
+		default:
+			return nil, fmt.Errorf("обработчик %s %d: не задано ни name, ни url", stage, i+1)
+		}
+	}
+	return chain, nil
+}
+
+// callHTTPPayloadHook передает полезную нагрузку внешнему обработчику и возвращает результат.
+func callHTTPPayloadHook(ctx context.Context, client *http.Client, url string, hc PayloadHookContext, payload []byte) ([]byte, error) {
+	body, err := json.Marshal(PayloadHookRequest{PayloadHookContext: hc, Payload: payload})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	var hookResp PayloadHookResponse
+	if err := json.Unmarshal(respBody, &hookResp); err != nil {
+		return nil, fmt.Errorf("обработчик ответил статусом %s и неверным JSON: %v", resp.Status, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || hookResp.Error != "" {
+		return nil, fmt.Errorf("обработчик ответил статусом %s: %s", resp.Status, hookResp.Error)
+	}
+	return hookResp.Payload, nil
+}
+
+// Names возвращает имена обработчиков точки подключения stage.
+func (h *PayloadHooks) Names(stage string) []string {
+	var names []string
+	for _, hook := range h.chain(stage) {
+		names = append(names, hook.name)
+	}
+	return names
+}
+
+// chain возвращает обработчики точки подключения stage.
+func (h *PayloadHooks) chain(stage string) []payloadHook {
+	if h == nil {
+		return nil
+	}
+	if stage == HookStagePreCoding {
+		return h.preCoding
+	}
+	return h.postDecoding
+}
+
+// Has сообщает, настроены ли обработчики для точки подключения stage.
+func (h *PayloadHooks) Has(stage string) bool {
+	return len(h.chain(stage)) > 0
+}
+
+// Run последовательно применяет обработчики точки подключения hc.Stage к полезной нагрузке.
+func (h *PayloadHooks) Run(ctx context.Context, hc PayloadHookContext, payload []byte) ([]byte, error) {
+	for _, hook := range h.chain(hc.Stage) {
+		out, err := hook.fn(ctx, hc, append([]byte(nil), payload...))
+		if err != nil {
+			return nil, fmt.Errorf("обработчик %s: %w", hook.name, err)
+		}
+		payload = out
+	}
+	return payload, nil
+}
+
+var payloadHooks *PayloadHooks // Глобальные обработчики полезной нагрузки (nil, если не настроены)
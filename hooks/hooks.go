@@ -0,0 +1,83 @@
+// Package hooks — реестр функций преобразования полезной нагрузки канального уровня (сжатие,
+// шифрование, водяные знаки и т.п.). Функции регистрируются по имени и подключаются в конфигурации
+// канального уровня (hooks.pre_coding и hooks.post_decoding, поле name).
+//
+// Функции из других пакетов подключаются так же, как драйверы database/sql: пакет регистрирует
+// функции в init, а сборка канального уровня импортирует его ради побочного эффекта, например
+// файлом hooks_local.go в корне модуля:
+//
+//	package main
+//
+//	import _ "example.com/myhooks"
+package hooks
+
+import (
+	"sort"
+	"sync"
+)
+
+// Context — сведения о сегменте, полезную нагрузку которого преобразует обработчик.
+type Context struct {
+	Stage         string `json:"stage"` // Точка подключения: pre_coding или post_decoding
+	SegmentID     string `json:"segment_id"`
+	Sender        string `json:"sender"`
+	SendTime      string `json:"send_time"`
+	SegmentNumber int    `json:"segment_number"`
+	TotalSegments int    `json:"total_segments"`
+}
+
+// Func преобразует полезную нагрузку сегмента. Перед кодированием функция получает полезную нагрузку
+// без паддинга и может изменить ее длину (в пределах размера полезной нагрузки канала); после
+// декодирования — полезную нагрузку той же длины, что была передана в канал. Функция получает
+// собственную копию полезной нагрузки и может изменять ее на месте.
+type Func func(hc Context, payload []byte) ([]byte, error)
+
+var (
+	mu    sync.RWMutex
+	funcs = map[string]Func{} // Зарегистрированные функции по имени
+)
+
+// Register регистрирует функцию преобразования полезной нагрузки под именем name.
+// Повторная регистрация под тем же именем заменяет функцию.
+func Register(name string, fn Func) {
+	mu.Lock()
+	defer mu.Unlock()
+	funcs[name] = fn
+}
+
+// Lookup возвращает функцию, зарегистрированную под именем name.
+func Lookup(name string) (Func, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	fn, ok := funcs[name]
+	return fn, ok
+}
+
+// Names возвращает упорядоченные имена зарегистрированных функций.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(funcs))
+	for name := range funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	// Встроенные обратимые преобразования для проверки подключения обработчиков: одна и та же
+	// функция, примененная до кодирования и после декодирования, восстанавливает полезную нагрузку.
+	Register("reverse", func(_ Context, payload []byte) ([]byte, error) {
+		for i, j := 0, len(payload)-1; i < j; i, j = i+1, j-1 {
+			payload[i], payload[j] = payload[j], payload[i]
+		}
+		return payload, nil
+	})
+	Register("invert", func(_ Context, payload []byte) ([]byte, error) {
+		for i := range payload {
+			payload[i] ^= 0xFF
+		}
+		return payload, nil
+	})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// HopRecord — сведения о прохождении сегмента через одно звено многозвенного канала.
+// Каждый экземпляр канального уровня дописывает свою запись к полученным от предыдущего звена
+// и пересылает их дальше, поэтому транспортный уровень получает историю всего пути.
+type HopRecord struct {
+	Hop         int     `json:"hop"`                // Номер звена (с 1)
+	Node        string  `json:"node"`               // Имя экземпляра канального уровня (forward.node_name)
+	Codec       string  `json:"codec,omitempty"`    // Кодек звена
+	FlippedBits int     `json:"flipped_bits"`       // Число бит, инвертированных в канале звена
+	Decode      string  `json:"decode,omitempty"`   // Результат декодирования на звене (см. Decode*)
+	DelayMs     float64 `json:"delay_ms,omitempty"` // Задержка кадра в канале звена
+}
+
+var hopNodeName string // Имя этого экземпляра в записях о звеньях
+
+// defaultHopNodeName возвращает имя экземпляра по умолчанию: имя хоста и порт.
+func defaultHopNodeName() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	return host + ListenPort
+}
+
+// validateHops проверяет сведения о звеньях, пришедшие с сегментом: число звеньев ограничено
+// forward.max_hops, что защищает от зацикливания пересылки между экземплярами.
+func validateHops(hops []HopRecord) error {
+	if forwardConfig.MaxHops > 0 && len(hops) >= forwardConfig.MaxHops {
+		return fmt.Errorf("сегмент уже прошел %d звеньев (максимум %d), возможно зацикливание пересылки", len(hops), forwardConfig.MaxHops)
+	}
+	return nil
+}
+
+// appendHop возвращает сведения о звеньях, дополненные записью этого экземпляра.
+func appendHop(hops []HopRecord, report ChannelReport) []HopRecord {
+	return append(append([]HopRecord(nil), hops...), HopRecord{
+		Hop:         len(hops) + 1,
+		Node:        hopNodeName,
+		Codec:       report.Codec,
+		FlippedBits: len(report.FlippedBits),
+		Decode:      report.Decode,
+		DelayMs:     float64(report.Delay().Microseconds()) / 1000,
+	})
+}
@@ -0,0 +1,694 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Impairment — звено цепочки искажений кадра в канале (потеря, ошибки в битах, задержка и т.п.).
+// Звенья применяются к передаваемому кадру в порядке конфигурации; после потери кадра
+// остальные звенья не применяются.
+type Impairment interface {
+	Name() string
+	// Apply применяет искажение к кадру и возвращает число затронутых бит (для статистики звена)
+	// и признак того, что искажение было применено.
+	Apply(frame *ChannelFrame) (bits int, applied bool)
+}
+
+// ChannelFrame — кадр, находящийся в канале, вместе с отчетом о его прохождении.
+type ChannelFrame struct {
+	Bits    []uint8        // Передаваемый поток (после этапов обработки потока); звенья могут изменить его длину
+	Lost    bool           // Кадр потерян
+	Report  *ChannelReport // Отчет канала; звенья дополняют его и запись о случайных решениях
+	Channel *ChannelLayer  // Канал
+	Rand    *rand.Rand     // Генератор случайных чисел канала
+	Segment *Segment       // Передаваемый сегмент (номер сегмента в сообщении)
+	// ErrorProbability и LossProbability — вероятности P и R канала в момент передачи кадра (с учетом расписания)
+	ErrorProbability float64
+	LossProbability  float64
+	// Erasures — биты кадра, стертые в канале (nil — стираний нет); см. Erase
+	Erasures []bool
+	// LLR — мягкие решения о битах кадра, если их формирует звено цепочки (nil — в режиме мягких
+	// решений канал сформирует их по результату жестких искажений)
+	LLR []float64
+}
+
+// Flip инвертирует бит кадра и отмечает его в отчете.
+func (f *ChannelFrame) Flip(index int) {
+	f.Bits[index] ^= 1
+	f.Report.FlippedBits = append(f.Report.FlippedBits, index)
+	f.Report.Impairments.FlippedBits = append(f.Report.Impairments.FlippedBits, index)
+}
+
+// Erase отмечает бит кадра стертым: приемник знает, что значение бита неизвестно, и принимает его
+// равным нулю, а декодер получает отметку стирания.
+func (f *ChannelFrame) Erase(index int) {
+	if f.Erasures == nil {
+		f.Erasures = make([]bool, len(f.Bits))
+	}
+	f.Bits[index] = 0
+	f.Erasures[index] = true
+}
+
+// ImpairmentConfig описывает звено цепочки искажений в конфигурации. Набор используемых
+// параметров зависит от типа звена.
+type ImpairmentConfig struct {
+	Type        string   `json:"type"`                  // Тип звена (см. RegisterImpairment)
+	Probability *float64 `json:"probability,omitempty"` // Вероятность срабатывания (для loss, burst_loss, bit_error, bsc и bec по умолчанию — R и P канала)
+	Length      int      `json:"length,omitempty"`      // Длина пакета ошибок, проскальзывания или усечения (бит), серии потерь (кадров)
+	Mode        string   `json:"mode,omitempty"`        // Направление проскальзывания (slip): insert или delete; формат трассы (trace): frames или stream
+	Delay       Duration `json:"delay,omitempty"`       // Дополнительная задержка кадра (среднее для случайной) или интервал между копиями
+	// Distribution — распределение задержки (см. Delay*); Jitter — полуширина равномерного
+	// распределения или СКО нормального
+	Distribution string   `json:"distribution,omitempty"`
+	Jitter       Duration `json:"jitter,omitempty"`
+	Copies       int      `json:"copies,omitempty"` // Число дополнительных копий кадра
+	// Count — число ошибок в кадре (k_errors); Weights — относительные веса кадров с 0, 1, 2, ... ошибками
+	// вместо фиксированного Count
+	Count   int       `json:"count,omitempty"`
+	Weights []float64 `json:"weights,omitempty"`
+	// ErrorsPerSecond — интенсивность ошибок (ошибок в секунду) при скорости Bitrate (бит/с);
+	// по умолчанию Bitrate — скорость передачи канала (link.bitrate)
+	ErrorsPerSecond float64 `json:"errors_per_second,omitempty"`
+	Bitrate         float64 `json:"bitrate,omitempty"`
+	// File — файл сценария решений канала для звеньев fixture, scenario и trace; Loop — повторять сценарий
+	// fixture или трассу trace по кругу
+	File string `json:"file,omitempty"`
+	Loop bool   `json:"loop,omitempty"`
+	// GoodToBad и BadToGood — вероятности перехода между хорошим и плохим состояниями модели
+	// Гилберта–Эллиотта на каждом бите; BERGood и BERBad — вероятности ошибки в бите в этих
+	// состояниях (по умолчанию 0 и 0.5)
+	GoodToBad float64 `json:"good_to_bad,omitempty"`
+	BadToGood float64 `json:"bad_to_good,omitempty"`
+	BERGood   float64 `json:"ber_good,omitempty"`
+	BERBad    float64 `json:"ber_bad,omitempty"`
+	// ZeroToOne и OneToZero — вероятности инверсии нуля в единицу и единицы в ноль (z_channel)
+	ZeroToOne float64 `json:"zero_to_one,omitempty"`
+	OneToZero float64 `json:"one_to_zero,omitempty"`
+	// EbN0dB — отношение энергии на информационный бит к спектральной плотности шума (awgn), дБ
+	EbN0dB *float64 `json:"eb_n0_db,omitempty"`
+	// Fading — распределение замираний (fading): rayleigh или rician с K-фактором RicianK;
+	// PerBit — независимая амплитуда для каждого бита вместо одной на кадр
+	Fading  string  `json:"fading,omitempty"`
+	RicianK float64 `json:"rician_k,omitempty"`
+	PerBit  bool    `json:"per_bit,omitempty"`
+	// States, Transitions и Initial — состояния марковской модели (markov), матрица вероятностей
+	// переходов между ними на каждом кадре и имя начального состояния (по умолчанию первое)
+	States      []MarkovStateConfig `json:"states,omitempty"`
+	Transitions [][]float64         `json:"transitions,omitempty"`
+	Initial     string              `json:"initial,omitempty"`
+}
+
+// ImpairmentStats — статистика звена цепочки искажений, возвращаемая на /stats.
+type ImpairmentStats struct {
+	Name    string `json:"name"`
+	Frames  int64  `json:"frames"`          // Кадров, прошедших через звено
+	Applied int64  `json:"applied"`         // Кадров, к которым искажение было применено
+	Bits    int64  `json:"bits"`            // Затронутых бит (для звеньев, искажающих биты)
+	State   string `json:"state,omitempty"` // Текущее состояние канала (для звеньев с состоянием)
+}
+
+var impairmentFactories = map[string]func(ImpairmentConfig) (Impairment, error){} // Конструкторы звеньев по типу
+
+// RegisterImpairment регистрирует конструктор звена цепочки искажений.
+func RegisterImpairment(impairmentType string, factory func(ImpairmentConfig) (Impairment, error)) {
+	impairmentFactories[impairmentType] = factory
+}
+
+// DefaultImpairments — цепочка по умолчанию, воспроизводящая исходную модель канала:
+// потеря кадра с вероятностью R, затем инверсия одного бита с вероятностью P.
+func DefaultImpairments() []ImpairmentConfig {
+	return []ImpairmentConfig{{Type: "loss"}, {Type: "bit_error"}}
+}
+
+// defaultImpairmentChain создает цепочку по умолчанию.
+func defaultImpairmentChain() *ImpairmentChain {
+	chain, err := NewImpairmentChain(DefaultImpairments())
+	if err != nil {
+		panic(err)
+	}
+	return chain
+}
+
+// impairmentLink — звено цепочки со счетчиками статистики.
+type impairmentLink struct {
+	impairment Impairment
+	frames     atomic.Int64
+	applied    atomic.Int64
+	bits       atomic.Int64
+}
+
+// ImpairmentChain — упорядоченная цепочка искажений.
+type ImpairmentChain struct {
+	links []*impairmentLink
+}
+
+// NewImpairmentChain создает цепочку искажений по конфигурации.
+func NewImpairmentChain(configs []ImpairmentConfig) (*ImpairmentChain, error) {
+	chain := &ImpairmentChain{}
+	for i, cfg := range configs {
+		factory, ok := impairmentFactories[cfg.Type]
+		if !ok {
+			types := make([]string, 0, len(impairmentFactories))
+			for t := range impairmentFactories {
+				types = append(types, t)
+			}
+			sort.Strings(types)
+			return nil, fmt.Errorf("искажение %d: неизвестный тип '%s' (допустимо: %s)", i+1, cfg.Type, strings.Join(types, ", "))
+		}
+		impairment, err := factory(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("искажение %d (%s): %w", i+1, cfg.Type, err)
+		}
+		chain.links = append(chain.links, &impairmentLink{impairment: impairment})
+	}
+	return chain, nil
+}
+
+// bitrateImpairment — искажение, которому нужна скорость передачи канала.
+type bitrateImpairment interface {
+	needsChannelBitrate() bool
+}
+
+// CheckBitrate проверяет, что искажениям, которые рассчитываются от скорости передачи канала,
+// эта скорость задана: иначе такие искажения молча не действовали бы.
+func (c *ImpairmentChain) CheckBitrate(bitrate float64) error {
+	if c == nil || bitrate > 0 {
+		return nil
+	}
+	for i, link := range c.links {
+		if b, ok := link.impairment.(bitrateImpairment); ok && b.needsChannelBitrate() {
+			return fmt.Errorf("искажение %d (%s) требует скорости передачи: задайте bitrate искажения или link.bitrate", i+1, link.impairment.Name())
+		}
+	}
+	return nil
+}
+
+// Names возвращает имена звеньев цепочки.
+func (c *ImpairmentChain) Names() []string {
+	names := make([]string, 0, len(c.links))
+	for _, link := range c.links {
+		names = append(names, link.impairment.Name())
+	}
+	return names
+}
+
+// Apply пропускает кадр через звенья цепочки до первой потери.
+func (c *ImpairmentChain) Apply(frame *ChannelFrame) {
+	for _, link := range c.links {
+		link.frames.Add(1)
+		bits, applied := link.impairment.Apply(frame)
+		if applied {
+			link.applied.Add(1)
+			link.bits.Add(int64(bits))
+		}
+		if frame.Lost {
+			return
+		}
+	}
+}
+
+// Stats возвращает статистику звеньев цепочки.
+func (c *ImpairmentChain) Stats() []ImpairmentStats {
+	if c == nil {
+		return nil
+	}
+	stats := make([]ImpairmentStats, 0, len(c.links))
+	for _, link := range c.links {
+		linkStats := ImpairmentStats{
+			Name:    link.impairment.Name(),
+			Frames:  link.frames.Load(),
+			Applied: link.applied.Load(),
+			Bits:    link.bits.Load(),
+		}
+		if stateful, ok := link.impairment.(StatefulImpairment); ok {
+			linkStats.State = stateful.State()
+		}
+		stats = append(stats, linkStats)
+	}
+	return stats
+}
+
+// validProbability проверяет, что вероятность, заданная в конфигурации, лежит в [0, 1].
+func validProbability(p *float64) error {
+	if p != nil && (*p < 0 || *p > 1) {
+		return fmt.Errorf("вероятность должна быть в диапазоне [0, 1], задано %g", *p)
+	}
+	return nil
+}
+
+// lossImpairment — потеря всего кадра с вероятностью probability (по умолчанию R канала).
+type lossImpairment struct {
+	probability *float64
+}
+
+func newLossImpairment(cfg ImpairmentConfig) (Impairment, error) {
+	return &lossImpairment{probability: cfg.Probability}, validProbability(cfg.Probability)
+}
+
+func (l *lossImpairment) Name() string { return "loss" }
+
+func (l *lossImpairment) Apply(frame *ChannelFrame) (int, bool) {
+	p := frame.LossProbability
+	if l.probability != nil {
+		p = *l.probability
+	}
+	draw := frame.Rand.Float64()
+	frame.Report.Impairments.LossProbability = p
+	frame.Report.Impairments.LossDraw = draw
+	if draw > p {
+		return 0, false
+	}
+	frame.Lost = true
+	return len(frame.Bits), true
+}
+
+// bitErrorImpairment — инверсия одного случайного бита кадра с вероятностью probability
+// (по умолчанию P канала).
+type bitErrorImpairment struct {
+	probability *float64
+}
+
+func newBitErrorImpairment(cfg ImpairmentConfig) (Impairment, error) {
+	return &bitErrorImpairment{probability: cfg.Probability}, validProbability(cfg.Probability)
+}
+
+func (b *bitErrorImpairment) Name() string { return "bit_error" }
+
+func (b *bitErrorImpairment) Apply(frame *ChannelFrame) (int, bool) {
+	p := frame.ErrorProbability
+	if b.probability != nil {
+		p = *b.probability
+	}
+	draw := frame.Rand.Float64()
+	frame.Report.Impairments.ErrorProbability = p
+	frame.Report.Impairments.ErrorDraw = &draw
+	if draw > p {
+		return 0, false
+	}
+	frame.Flip(frame.Rand.Intn(len(frame.Bits)))
+	return 1, true
+}
+
+// bscImpairment — двоичный симметричный канал: каждый бит кадра инвертируется независимо
+// с вероятностью probability (по умолчанию P канала), так что число ошибок в кадре длиной L
+// распределено биномиально с параметрами L и P.
+type bscImpairment struct {
+	probability *float64
+}
+
+func newBSCImpairment(cfg ImpairmentConfig) (Impairment, error) {
+	return &bscImpairment{probability: cfg.Probability}, validProbability(cfg.Probability)
+}
+
+func (b *bscImpairment) Name() string {
+	if b.probability != nil {
+		return fmt.Sprintf("bsc(%g)", *b.probability)
+	}
+	return "bsc"
+}
+
+func (b *bscImpairment) Apply(frame *ChannelFrame) (int, bool) {
+	p := frame.ErrorProbability
+	if b.probability != nil {
+		p = *b.probability
+	}
+	frame.Report.Impairments.ErrorProbability = p
+	if p == 0 {
+		return 0, false
+	}
+	flipped := 0
+	for i := range frame.Bits {
+		if frame.Rand.Float64() < p {
+			frame.Flip(i)
+			flipped++
+		}
+	}
+	return flipped, flipped > 0
+}
+
+// becImpairment — двоичный канал со стиранием: каждый бит кадра стирается независимо
+// с вероятностью probability (по умолчанию P канала). Стертые биты не инвертируются, а отмечаются
+// как неизвестные, и декодер, умеющий использовать стирания, восстанавливает их.
+type becImpairment struct {
+	probability *float64
+}
+
+func newBECImpairment(cfg ImpairmentConfig) (Impairment, error) {
+	return &becImpairment{probability: cfg.Probability}, validProbability(cfg.Probability)
+}
+
+func (b *becImpairment) Name() string {
+	if b.probability != nil {
+		return fmt.Sprintf("bec(%g)", *b.probability)
+	}
+	return "bec"
+}
+
+func (b *becImpairment) Apply(frame *ChannelFrame) (int, bool) {
+	p := frame.ErrorProbability
+	if b.probability != nil {
+		p = *b.probability
+	}
+	frame.Report.Impairments.ErrorProbability = p
+	erased := 0
+	for i := range frame.Bits {
+		if frame.Rand.Float64() < p {
+			frame.Erase(i)
+			erased++
+		}
+	}
+	return erased, erased > 0
+}
+
+// zChannelImpairment — несимметричный двоичный канал: ноль инвертируется в единицу с вероятностью
+// zeroToOne, а единица в ноль — с вероятностью oneToZero. Если одна из вероятностей равна нулю,
+// это Z-канал (например, оптическая линия, в которой импульс может пропасть, но не возникнуть).
+type zChannelImpairment struct {
+	zeroToOne, oneToZero float64
+}
+
+func newZChannelImpairment(cfg ImpairmentConfig) (Impairment, error) {
+	for _, p := range []float64{cfg.ZeroToOne, cfg.OneToZero} {
+		if err := validProbability(&p); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.ZeroToOne == 0 && cfg.OneToZero == 0 {
+		return nil, fmt.Errorf("не заданы вероятности инверсии бит (zero_to_one, one_to_zero)")
+	}
+	return &zChannelImpairment{zeroToOne: cfg.ZeroToOne, oneToZero: cfg.OneToZero}, nil
+}
+
+func (z *zChannelImpairment) Name() string {
+	return fmt.Sprintf("z_channel(0→1 %g, 1→0 %g)", z.zeroToOne, z.oneToZero)
+}
+
+func (z *zChannelImpairment) Apply(frame *ChannelFrame) (int, bool) {
+	flipped := 0
+	for i, bit := range frame.Bits {
+		p := z.zeroToOne
+		if bit == 1 {
+			p = z.oneToZero
+		}
+		if p > 0 && frame.Rand.Float64() < p {
+			frame.Flip(i)
+			flipped++
+		}
+	}
+	return flipped, flipped > 0
+}
+
+// kErrorsImpairment — ровно k ошибок в кадре: инвертируются k различных случайных бит. Число k
+// задается явно (count) или выбирается для каждого кадра по распределению weights, где weights[i] —
+// относительный вес кадров с i ошибками (например, [0, 0, 1, 1] — поровну кадров с 2 и 3 ошибками).
+type kErrorsImpairment struct {
+	count   int
+	weights []float64 // Накопленные веса числа ошибок (nil — всегда count)
+}
+
+func newKErrorsImpairment(cfg ImpairmentConfig) (Impairment, error) {
+	if len(cfg.Weights) == 0 {
+		if cfg.Count < 1 {
+			return nil, fmt.Errorf("число ошибок в кадре (count) должно быть не менее 1, задано %d", cfg.Count)
+		}
+		return &kErrorsImpairment{count: cfg.Count}, nil
+	}
+	if cfg.Count != 0 {
+		return nil, fmt.Errorf("нельзя одновременно задавать count и weights")
+	}
+	cumulative := make([]float64, len(cfg.Weights))
+	total := 0.0
+	for i, w := range cfg.Weights {
+		if w < 0 {
+			return nil, fmt.Errorf("вес числа ошибок %d не может быть отрицательным, задано %g", i, w)
+		}
+		total += w
+		cumulative[i] = total
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("сумма весов числа ошибок должна быть положительной")
+	}
+	return &kErrorsImpairment{weights: cumulative}, nil
+}
+
+func (k *kErrorsImpairment) Name() string {
+	if k.weights == nil {
+		return fmt.Sprintf("k_errors(%d)", k.count)
+	}
+	return fmt.Sprintf("k_errors(0..%d)", len(k.weights)-1)
+}
+
+// draw возвращает число ошибок для очередного кадра.
+func (k *kErrorsImpairment) draw(frame *ChannelFrame) int {
+	if k.weights == nil {
+		return k.count
+	}
+	x := frame.Rand.Float64() * k.weights[len(k.weights)-1]
+	return sort.Search(len(k.weights), func(i int) bool { return k.weights[i] > x })
+}
+
+func (k *kErrorsImpairment) Apply(frame *ChannelFrame) (int, bool) {
+	count := min(k.draw(frame), len(frame.Bits))
+	if count == 0 {
+		return 0, false
+	}
+	// Частичная перестановка Фишера–Йетса: первые count индексов — различные случайные биты
+	indices := make([]int, len(frame.Bits))
+	for i := range indices {
+		indices[i] = i
+	}
+	for i := 0; i < count; i++ {
+		j := i + frame.Rand.Intn(len(indices)-i)
+		indices[i], indices[j] = indices[j], indices[i]
+	}
+	sort.Ints(indices[:count])
+	for _, index := range indices[:count] {
+		frame.Flip(index)
+	}
+	return count, true
+}
+
+// burstImpairment — пакет ошибок: с вероятностью probability инвертируются length подряд
+// идущих бит, начиная со случайной позиции.
+type burstImpairment struct {
+	probability float64
+	length      int
+}
+
+func newBurstImpairment(cfg ImpairmentConfig) (Impairment, error) {
+	if cfg.Probability == nil {
+		return nil, fmt.Errorf("не задана вероятность пакета ошибок (probability)")
+	}
+	if cfg.Length < 1 {
+		return nil, fmt.Errorf("длина пакета ошибок должна быть не менее 1, задано %d", cfg.Length)
+	}
+	return &burstImpairment{probability: *cfg.Probability, length: cfg.Length}, validProbability(cfg.Probability)
+}
+
+func (b *burstImpairment) Name() string { return fmt.Sprintf("burst(%d)", b.length) }
+
+func (b *burstImpairment) Apply(frame *ChannelFrame) (int, bool) {
+	if frame.Rand.Float64() > b.probability {
+		return 0, false
+	}
+	length := min(b.length, len(frame.Bits))
+	start := frame.Rand.Intn(len(frame.Bits) - length + 1)
+	for i := start; i < start+length; i++ {
+		frame.Flip(i)
+	}
+	return length, true
+}
+
+// Распределения дополнительной задержки звена delay.
+const (
+	DelayFixed       = "fixed"       // Постоянная задержка delay
+	DelayUniform     = "uniform"     // Равномерно в [delay - jitter, delay + jitter]
+	DelayNormal      = "normal"      // Нормально со средним delay и СКО jitter
+	DelayExponential = "exponential" // Экспоненциально со средним delay
+)
+
+// delayImpairment — дополнительная задержка кадра (с вероятностью probability, по умолчанию всегда),
+// постоянная или случайная с заданным распределением. Отрицательные значения (при нормальном
+// распределении) заменяются нулем. Случайная задержка меняет порядок кадров на входе /transfer.
+type delayImpairment struct {
+	probability  *float64
+	delay        time.Duration
+	jitter       time.Duration
+	distribution string
+}
+
+func newDelayImpairment(cfg ImpairmentConfig) (Impairment, error) {
+	if cfg.Delay.Duration <= 0 {
+		return nil, fmt.Errorf("задержка должна быть положительной, задано %s", cfg.Delay)
+	}
+	if cfg.Jitter.Duration < 0 {
+		return nil, fmt.Errorf("разброс задержки не может быть отрицательным, задано %s", cfg.Jitter)
+	}
+	d := &delayImpairment{probability: cfg.Probability, delay: cfg.Delay.Duration, jitter: cfg.Jitter.Duration, distribution: cfg.Distribution}
+	switch cfg.Distribution {
+	case "":
+		d.distribution = DelayFixed
+		if d.jitter > 0 {
+			d.distribution = DelayUniform
+		}
+	case DelayFixed, DelayExponential:
+	case DelayUniform, DelayNormal:
+		if d.jitter == 0 {
+			return nil, fmt.Errorf("для распределения %s не задан разброс задержки (jitter)", cfg.Distribution)
+		}
+	default:
+		return nil, fmt.Errorf("неизвестное распределение задержки '%s' (допустимо: %s, %s, %s, %s)",
+			cfg.Distribution, DelayFixed, DelayUniform, DelayNormal, DelayExponential)
+	}
+	return d, validProbability(cfg.Probability)
+}
+
+func (d *delayImpairment) Name() string {
+	switch d.distribution {
+	case DelayUniform, DelayNormal:
+		return fmt.Sprintf("delay(%s %s ± %s)", d.distribution, d.delay, d.jitter)
+	case DelayExponential:
+		return fmt.Sprintf("delay(%s, среднее %s)", d.distribution, d.delay)
+	}
+	return fmt.Sprintf("delay(%s)", d.delay)
+}
+
+// draw возвращает задержку очередного кадра.
+func (d *delayImpairment) draw(rng *rand.Rand) time.Duration {
+	var delay float64
+	switch d.distribution {
+	case DelayUniform:
+		delay = float64(d.delay) + (2*rng.Float64()-1)*float64(d.jitter)
+	case DelayNormal:
+		delay = float64(d.delay) + rng.NormFloat64()*float64(d.jitter)
+	case DelayExponential:
+		delay = rng.ExpFloat64() * float64(d.delay)
+	default:
+		return d.delay
+	}
+	return time.Duration(max(delay, 0))
+}
+
+func (d *delayImpairment) Apply(frame *ChannelFrame) (int, bool) {
+	if d.probability != nil && frame.Rand.Float64() > *d.probability {
+		return 0, false
+	}
+	frame.Report.ExtraDelayMs += float64(d.draw(frame.Rand).Microseconds()) / 1000
+	return 0, true
+}
+
+// duplicateImpairment — дублирование кадра: с вероятностью probability транспортному уровню
+// дополнительно доставляется copies копий обработанного сегмента. Если задана задержка delay,
+// копия i доставляется через i·delay после исходного сегмента (в фоне), иначе — сразу за ним.
+type duplicateImpairment struct {
+	probability float64
+	copies      int
+	delay       time.Duration
+}
+
+func newDuplicateImpairment(cfg ImpairmentConfig) (Impairment, error) {
+	if cfg.Probability == nil {
+		return nil, fmt.Errorf("не задана вероятность дублирования (probability)")
+	}
+	copies := cfg.Copies
+	if copies == 0 {
+		copies = 1
+	}
+	if copies < 0 {
+		return nil, fmt.Errorf("число копий не может быть отрицательным, задано %d", cfg.Copies)
+	}
+	if cfg.Delay.Duration < 0 {
+		return nil, fmt.Errorf("задержка между копиями не может быть отрицательной, задано %s", cfg.Delay)
+	}
+	return &duplicateImpairment{probability: *cfg.Probability, copies: copies, delay: cfg.Delay.Duration}, validProbability(cfg.Probability)
+}
+
+func (d *duplicateImpairment) Name() string {
+	if d.delay > 0 {
+		return fmt.Sprintf("duplicate(через %s)", d.delay)
+	}
+	return "duplicate"
+}
+
+func (d *duplicateImpairment) Apply(frame *ChannelFrame) (int, bool) {
+	if frame.Rand.Float64() > d.probability {
+		return 0, false
+	}
+	frame.Report.Duplicates += d.copies
+	frame.Report.DuplicateDelayMs = max(frame.Report.DuplicateDelayMs, float64(d.delay.Microseconds())/1000)
+	return 0, true
+}
+
+// berRateImpairment — ошибки, заданные как интенсивность «ошибок в секунду при скорости B»
+// (как в паспортах линий связи). Вероятность ошибки в бите p = errors_per_second / B; каждый бит
+// кадра инвертируется независимо, так что кадр длиной L (время передачи L / B) получает в среднем
+// errors_per_second * L / B ошибок.
+type berRateImpairment struct {
+	errorsPerSecond float64
+	bitrate         float64 // 0 — скорость передачи канала
+}
+
+func newBERRateImpairment(cfg ImpairmentConfig) (Impairment, error) {
+	if cfg.ErrorsPerSecond < 0 {
+		return nil, fmt.Errorf("интенсивность ошибок не может быть отрицательной, задано %g", cfg.ErrorsPerSecond)
+	}
+	if cfg.Bitrate < 0 {
+		return nil, fmt.Errorf("скорость передачи не может быть отрицательной, задано %g", cfg.Bitrate)
+	}
+	return &berRateImpairment{errorsPerSecond: cfg.ErrorsPerSecond, bitrate: cfg.Bitrate}, nil
+}
+
+func (b *berRateImpairment) Name() string {
+	if b.bitrate > 0 {
+		return fmt.Sprintf("ber_rate(%g/s @ %g бит/с)", b.errorsPerSecond, b.bitrate)
+	}
+	return fmt.Sprintf("ber_rate(%g/s)", b.errorsPerSecond)
+}
+
+func (b *berRateImpairment) needsChannelBitrate() bool { return b.bitrate <= 0 }
+
+// bitErrorProbability возвращает вероятность ошибки в бите при скорости передачи звена или канала
+// (без скорости передачи цепочка не создается, см. CheckBitrate).
+func (b *berRateImpairment) bitErrorProbability(cl *ChannelLayer) float64 {
+	bitrate := b.bitrate
+	if bitrate <= 0 {
+		bitrate = cl.Bitrate
+	}
+	if bitrate <= 0 {
+		return 0
+	}
+	return min(b.errorsPerSecond/bitrate, 1)
+}
+
+func (b *berRateImpairment) Apply(frame *ChannelFrame) (int, bool) {
+	p := b.bitErrorProbability(frame.Channel)
+	if p == 0 {
+		return 0, false
+	}
+	flipped := 0
+	for i := range frame.Bits {
+		if frame.Rand.Float64() < p {
+			frame.Flip(i)
+			flipped++
+		}
+	}
+	return flipped, flipped > 0
+}
+
+func init() {
+	RegisterImpairment("loss", newLossImpairment)
+	RegisterImpairment("bit_error", newBitErrorImpairment)
+	RegisterImpairment("bsc", newBSCImpairment)
+	RegisterImpairment("bec", newBECImpairment)
+	RegisterImpairment("z_channel", newZChannelImpairment)
+	RegisterImpairment("k_errors", newKErrorsImpairment)
+	RegisterImpairment("burst", newBurstImpairment)
+	RegisterImpairment("delay", newDelayImpairment)
+	RegisterImpairment("duplicate", newDuplicateImpairment)
+	RegisterImpairment("ber_rate", newBERRateImpairment)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Распределения амплитуды замираний звена fading.
+const (
+	FadingRayleigh = "rayleigh" // Нет прямой видимости: |h| распределен по Рэлею
+	FadingRician   = "rician"   // Прямая составляющая с K-фактором: |h| распределен по Райсу
+)
+
+// awgnImpairment — физическая модель канала: биты кадра передаются символами BPSK (0 → +1, 1 → -1),
+// к каждому символу добавляется гауссов шум с дисперсией σ² = 1 / (2·R·Eb/N0), где R — скорость кода
+// кадра (информационных бит на переданный бит), а приемник принимает жесткое решение по знаку отсчета.
+// Так вероятность ошибки задается отношением сигнал/шум на информационный бит, и кодеки разной
+// скорости сравниваются при одинаковой энергии на бит сообщения (кривые BER от Eb/N0).
+// Отсчеты канала передаются декодеру как мягкие решения (LLR = 2y/σ²), поэтому звено должно быть
+// последним звеном цепочки, искажающим биты.
+//
+// Звено fading дополнительно умножает сигнал на амплитуду замираний h (E[h²] = 1), постоянную
+// в течение кадра (медленные замирания) или независимую для каждого бита (быстрые замирания),
+// как в мобильном канале: y = h·s + n. Приемник считается знающим h, LLR = 2·h·y/σ².
+type awgnImpairment struct {
+	ebN0dB  float64
+	fading  string  // Распределение замираний (см. Fading*; пусто — без замираний)
+	ricianK float64 // K-фактор Райса: отношение мощностей прямой и рассеянной составляющих
+	perBit  bool    // Независимая амплитуда для каждого бита (иначе — одна на кадр)
+}
+
+func newAWGNImpairment(cfg ImpairmentConfig) (Impairment, error) {
+	if cfg.EbN0dB == nil {
+		return nil, fmt.Errorf("не задано отношение сигнал/шум (eb_n0_db)")
+	}
+	return &awgnImpairment{ebN0dB: *cfg.EbN0dB}, nil
+}
+
+func newFadingImpairment(cfg ImpairmentConfig) (Impairment, error) {
+	impairment, err := newAWGNImpairment(cfg)
+	if err != nil {
+		return nil, err
+	}
+	a := impairment.(*awgnImpairment)
+	switch cfg.Fading {
+	case "", FadingRayleigh:
+		a.fading = FadingRayleigh
+		if cfg.RicianK != 0 {
+			return nil, fmt.Errorf("K-фактор (rician_k) задается только для замираний %s", FadingRician)
+		}
+	case FadingRician:
+		if cfg.RicianK < 0 {
+			return nil, fmt.Errorf("K-фактор не может быть отрицательным, задано %g", cfg.RicianK)
+		}
+		a.fading = FadingRician
+	default:
+		return nil, fmt.Errorf("неизвестное распределение замираний '%s' (допустимо: %s, %s)", cfg.Fading, FadingRayleigh, FadingRician)
+	}
+	a.ricianK = cfg.RicianK
+	a.perBit = cfg.PerBit
+	return a, nil
+}
+
+func (a *awgnImpairment) Name() string {
+	if a.fading == "" {
+		return fmt.Sprintf("awgn(Eb/N0 %g дБ)", a.ebN0dB)
+	}
+	coherence := "на кадр"
+	if a.perBit {
+		coherence = "на бит"
+	}
+	if a.fading == FadingRician {
+		return fmt.Sprintf("fading(%s K=%g %s, Eb/N0 %g дБ)", a.fading, a.ricianK, coherence, a.ebN0dB)
+	}
+	return fmt.Sprintf("fading(%s %s, Eb/N0 %g дБ)", a.fading, coherence, a.ebN0dB)
+}
+
+// sigma возвращает среднеквадратичное отклонение шума для кадра со скоростью кода rate.
+func (a *awgnImpairment) sigma(rate float64) float64 {
+	ebN0 := math.Pow(10, a.ebN0dB/10)
+	return math.Sqrt(1 / (2 * rate * ebN0))
+}
+
+// gain возвращает амплитуду замираний |h| со средней мощностью E[h²] = 1 (1 — без замираний).
+// Рэлей — модуль комплексной гауссовой величины, Райс — то же с прямой составляющей мощности K/(K+1).
+func (a *awgnImpairment) gain(frame *ChannelFrame) float64 {
+	if a.fading == "" {
+		return 1
+	}
+	los := math.Sqrt(a.ricianK / (a.ricianK + 1))   // Прямая составляющая (0 для Рэлея)
+	scatter := math.Sqrt(1 / (2 * (a.ricianK + 1))) // СКО каждой квадратуры рассеянной составляющей
+	i := los + scatter*frame.Rand.NormFloat64()
+	q := scatter * frame.Rand.NormFloat64()
+	return math.Hypot(i, q)
+}
+
+func (a *awgnImpairment) Apply(frame *ChannelFrame) (int, bool) {
+	rate := 1.0
+	if frame.Report.TransmittedBits > 0 && frame.Report.InfoBits > 0 {
+		rate = float64(frame.Report.InfoBits) / float64(frame.Report.TransmittedBits)
+	}
+	sigma := a.sigma(rate)
+	frame.Report.Impairments.ErrorProbability = 0.5 * math.Erfc(1/(sigma*math.Sqrt2))
+	frame.LLR = make([]float64, len(frame.Bits))
+	h := a.gain(frame)
+	flipped := 0
+	for i, bit := range frame.Bits {
+		if a.perBit && i > 0 {
+			h = a.gain(frame)
+		}
+		y := h*(1-2*float64(bit)) + sigma*frame.Rand.NormFloat64()
+		frame.LLR[i] = 2 * h * y / (sigma * sigma)
+		if (y < 0) != (bit == 1) {
+			frame.Flip(i)
+			flipped++
+		}
+	}
+	return flipped, flipped > 0
+}
+
+func init() {
+	RegisterImpairment("awgn", newAWGNImpairment)
+	RegisterImpairment("fading", newFadingImpairment)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fixtureDecision — решение канала для одного кадра из файла сценария.
+type fixtureDecision struct {
+	lose  bool          // Потерять кадр
+	flips []int         // Индексы инвертируемых бит
+	delay time.Duration // Дополнительная задержка кадра
+}
+
+// fixtureImpairment — детерминированный канал для интеграционных тестов: решения для кадров
+// берутся по порядку из файла сценария вместо генератора случайных чисел. Каждая непустая строка
+// файла (кроме комментариев, начинающихся с #) — решение для очередного кадра, составленное из
+// действий через запятую:
+//
+//	clean               — передать кадр без искажений
+//	lose                — потерять кадр
+//	flip bit N [M ...]  — инвертировать биты с индексами N, M, ... (индексы вне кадра пропускаются)
+//	delay 200ms         — дополнительная задержка кадра
+//
+// Например: "flip bit 3 17, delay 50ms". Когда сценарий исчерпан, кадры передаются без искажений
+// (или сценарий начинается сначала, если задан loop).
+type fixtureImpairment struct {
+	file      string
+	loop      bool
+	decisions []fixtureDecision
+	mu        sync.Mutex
+	next      int // Индекс следующего решения
+}
+
+func newFixtureImpairment(cfg ImpairmentConfig) (Impairment, error) {
+	if cfg.File == "" {
+		return nil, fmt.Errorf("не задан файл сценария (file)")
+	}
+	decisions, err := loadFixtureDecisions(cfg.File)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Loop && len(decisions) == 0 {
+		return nil, fmt.Errorf("сценарий %s пуст, повтор по кругу невозможен", cfg.File)
+	}
+	return &fixtureImpairment{file: cfg.File, loop: cfg.Loop, decisions: decisions}, nil
+}
+
+// loadFixtureDecisions читает решения канала из файла сценария.
+func loadFixtureDecisions(path string) ([]fixtureDecision, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть сценарий: %w", err)
+	}
+	defer f.Close()
+	var decisions []fixtureDecision
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		decision, err := parseFixtureDecision(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		decisions = append(decisions, decision)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения сценария: %w", err)
+	}
+	return decisions, nil
+}
+
+// parseFixtureDecision разбирает строку сценария.
+func parseFixtureDecision(text string) (fixtureDecision, error) {
+	var decision fixtureDecision
+	for _, action := range strings.Split(text, ",") {
+		fields := strings.Fields(strings.ToLower(action))
+		switch {
+		case len(fields) == 1 && fields[0] == "clean":
+		case len(fields) == 1 && fields[0] == "lose":
+			decision.lose = true
+		case len(fields) >= 3 && fields[0] == "flip" && fields[1] == "bit":
+			for _, field := range fields[2:] {
+				index, err := strconv.Atoi(field)
+				if err != nil || index < 0 {
+					return decision, fmt.Errorf("неверный индекс бита '%s'", field)
+				}
+				decision.flips = append(decision.flips, index)
+			}
+		case len(fields) == 2 && fields[0] == "delay":
+			delay, err := time.ParseDuration(fields[1])
+			if err != nil || delay < 0 {
+				return decision, fmt.Errorf("неверная задержка '%s'", fields[1])
+			}
+			decision.delay += delay
+		default:
+			return decision, fmt.Errorf("неизвестное действие '%s' (допустимо: clean, lose, flip bit N, delay 200ms)", strings.TrimSpace(action))
+		}
+	}
+	return decision, nil
+}
+
+func (f *fixtureImpairment) Name() string {
+	if f.loop {
+		return fmt.Sprintf("fixture(%s, по кругу)", f.file)
+	}
+	return fmt.Sprintf("fixture(%s)", f.file)
+}
+
+// take возвращает решение для очередного кадра (false, если сценарий исчерпан).
+func (f *fixtureImpairment) take() (fixtureDecision, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.next >= len(f.decisions) {
+		if !f.loop {
+			return fixtureDecision{}, false
+		}
+		f.next = 0
+	}
+	decision := f.decisions[f.next]
+	f.next++
+	return decision, true
+}
+
+func (f *fixtureImpairment) Apply(frame *ChannelFrame) (int, bool) {
+	decision, ok := f.take()
+	if !ok {
+		return 0, false
+	}
+	if decision.delay > 0 {
+		frame.Report.ExtraDelayMs += float64(decision.delay.Microseconds()) / 1000
+	}
+	if decision.lose {
+		frame.Lost = true
+		return len(frame.Bits), true
+	}
+	flipped := 0
+	for _, index := range decision.flips {
+		if index < len(frame.Bits) {
+			frame.Flip(index)
+			flipped++
+		}
+	}
+	return flipped, flipped > 0 || decision.delay > 0
+}
+
+func init() {
+	RegisterImpairment("fixture", newFixtureImpairment)
+}
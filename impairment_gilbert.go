@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// gilbertElliottImpairment — двухсостоянийная модель канала Гилберта–Эллиотта: канал находится
+// в «хорошем» или «плохом» состоянии с разными вероятностями ошибки в бите (ber_good и ber_bad)
+// и перед каждым битом переходит из хорошего состояния в плохое с вероятностью good_to_bad,
+// а из плохого в хорошее — с вероятностью bad_to_good. Средняя длина пребывания в плохом состоянии —
+// 1 / bad_to_good бит, поэтому ошибки группируются в пакеты, которые код [7,4] исправить не может.
+// Состояние сохраняется между кадрами: пакет ошибок может начаться в одном кадре и закончиться в следующем.
+type gilbertElliottImpairment struct {
+	goodToBad, badToGood float64
+	berGood, berBad      float64
+
+	mu  sync.Mutex
+	bad bool // Канал в плохом состоянии
+}
+
+func newGilbertElliottImpairment(cfg ImpairmentConfig) (Impairment, error) {
+	for _, p := range []float64{cfg.GoodToBad, cfg.BadToGood, cfg.BERGood, cfg.BERBad} {
+		if err := validProbability(&p); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.BadToGood == 0 {
+		return nil, fmt.Errorf("не задана вероятность выхода из плохого состояния (bad_to_good)")
+	}
+	berBad := cfg.BERBad
+	if berBad == 0 {
+		berBad = 0.5
+	}
+	return &gilbertElliottImpairment{
+		goodToBad: cfg.GoodToBad,
+		badToGood: cfg.BadToGood,
+		berGood:   cfg.BERGood,
+		berBad:    berBad,
+	}, nil
+}
+
+func (g *gilbertElliottImpairment) Name() string {
+	return fmt.Sprintf("gilbert_elliott(G→B %g, B→G %g, BER %g/%g)", g.goodToBad, g.badToGood, g.berGood, g.berBad)
+}
+
+// State возвращает текущее состояние канала.
+func (g *gilbertElliottImpairment) State() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.bad {
+		return "bad"
+	}
+	return "good"
+}
+
+func (g *gilbertElliottImpairment) Apply(frame *ChannelFrame) (int, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	flipped := 0
+	for i := range frame.Bits {
+		if g.bad {
+			g.bad = frame.Rand.Float64() >= g.badToGood
+		} else {
+			g.bad = frame.Rand.Float64() < g.goodToBad
+		}
+		ber := g.berGood
+		if g.bad {
+			ber = g.berBad
+		}
+		if ber > 0 && frame.Rand.Float64() < ber {
+			frame.Flip(i)
+			flipped++
+		}
+	}
+	return flipped, flipped > 0
+}
+
+// burstLossImpairment — коррелированные потери кадров (модель Гилберта для кадров): канал находится
+// в состоянии приема или в состоянии потерь, в котором теряются все кадры. Средняя длина серии потерь —
+// length кадров (выход из состояния потерь с вероятностью 1/length), а переход в состояние потерь
+// подобран так, чтобы средняя доля потерянных кадров была равна probability (по умолчанию R канала):
+// p = R / (length · (1 - R)). При length = 1 потери независимы, как у звена loss.
+type burstLossImpairment struct {
+	probability *float64
+	length      int
+
+	mu     sync.Mutex
+	losing bool // Канал в состоянии потерь
+	run    int  // Длина текущей серии потерь
+}
+
+func newBurstLossImpairment(cfg ImpairmentConfig) (Impairment, error) {
+	if cfg.Length < 1 {
+		return nil, fmt.Errorf("средняя длина серии потерь должна быть не менее 1 кадра, задано %d", cfg.Length)
+	}
+	if cfg.Probability != nil && *cfg.Probability >= 1 {
+		return nil, fmt.Errorf("средняя доля потерь должна быть меньше 1, задано %g", *cfg.Probability)
+	}
+	return &burstLossImpairment{probability: cfg.Probability, length: cfg.Length}, validProbability(cfg.Probability)
+}
+
+func (b *burstLossImpairment) Name() string {
+	if b.probability != nil {
+		return fmt.Sprintf("burst_loss(%g, серия %d)", *b.probability, b.length)
+	}
+	return fmt.Sprintf("burst_loss(серия %d)", b.length)
+}
+
+// State возвращает состояние канала и длину текущей серии потерь.
+func (b *burstLossImpairment) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.losing {
+		return fmt.Sprintf("потери (серия %d кадров)", b.run)
+	}
+	return "прием"
+}
+
+func (b *burstLossImpairment) Apply(frame *ChannelFrame) (int, bool) {
+	r := frame.LossProbability
+	if b.probability != nil {
+		r = *b.probability
+	}
+	r = min(r, 0.999)
+	exit := 1 / float64(b.length)
+	enter := min(r*exit/(1-r), 1)
+	draw := frame.Rand.Float64()
+	frame.Report.Impairments.LossProbability = r
+	frame.Report.Impairments.LossDraw = draw
+
+	b.mu.Lock()
+	if b.losing {
+		b.losing = draw >= exit
+	} else {
+		b.losing = draw < enter
+		b.run = 0
+	}
+	if b.losing {
+		b.run++
+	}
+	losing := b.losing
+	b.mu.Unlock()
+
+	if !losing {
+		return 0, false
+	}
+	frame.Lost = true
+	return len(frame.Bits), true
+}
+
+func init() {
+	RegisterImpairment("gilbert_elliott", newGilbertElliottImpairment)
+	RegisterImpairment("burst_loss", newBurstLossImpairment)
+}
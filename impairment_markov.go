@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+// MarkovStateConfig — состояние марковской модели канала.
+type MarkovStateConfig struct {
+	Name            string  `json:"name"`
+	BitErrorRate    float64 `json:"bit_error_rate"`   // Вероятность ошибки каждого бита кадра в состоянии
+	LossProbability float64 `json:"loss_probability"` // Вероятность потери кадра в состоянии
+}
+
+// StatefulImpairment — звено цепочки с внутренним состоянием канала, которое выводится в /stats.
+type StatefulImpairment interface {
+	State() string
+}
+
+// markovImpairment — марковская модель канала с N состояниями: перед каждым кадром канал переходит
+// из состояния i в состояние j с вероятностью transitions[i][j], после чего кадр теряется
+// с вероятностью потери текущего состояния, а каждый бит кадра искажается независимо с его
+// вероятностью ошибки. Модель Гилберта–Эллиотта — частный случай с двумя состояниями (но с переходами
+// на каждом бите); несколько состояний позволяют описать, например, «норма — помехи — обрыв».
+type markovImpairment struct {
+	states      []MarkovStateConfig
+	transitions [][]float64
+
+	mu      sync.Mutex
+	current int     // Текущее состояние
+	visits  []int64 // Число кадров, переданных в каждом состоянии
+}
+
+func newMarkovImpairment(cfg ImpairmentConfig) (Impairment, error) {
+	n := len(cfg.States)
+	if n < 1 {
+		return nil, fmt.Errorf("не заданы состояния модели (states)")
+	}
+	if len(cfg.Transitions) != n {
+		return nil, fmt.Errorf("матрица переходов должна содержать %d строк, задано %d", n, len(cfg.Transitions))
+	}
+	m := &markovImpairment{states: cfg.States, transitions: cfg.Transitions, visits: make([]int64, n), current: -1}
+	for i, state := range cfg.States {
+		if state.Name == "" {
+			return nil, fmt.Errorf("состояние %d: не задано имя", i+1)
+		}
+		for _, p := range []float64{state.BitErrorRate, state.LossProbability} {
+			if err := validProbability(&p); err != nil {
+				return nil, fmt.Errorf("состояние %s: %w", state.Name, err)
+			}
+		}
+		if state.Name == cfg.Initial {
+			m.current = i
+		}
+		row := cfg.Transitions[i]
+		if len(row) != n {
+			return nil, fmt.Errorf("строка %d матрицы переходов должна содержать %d значений, задано %d", i+1, n, len(row))
+		}
+		sum := 0.0
+		for _, p := range row {
+			if err := validProbability(&p); err != nil {
+				return nil, fmt.Errorf("строка %d матрицы переходов: %w", i+1, err)
+			}
+			sum += p
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			return nil, fmt.Errorf("сумма вероятностей строки %d матрицы переходов должна быть равна 1, получено %g", i+1, sum)
+		}
+	}
+	if m.current < 0 {
+		if cfg.Initial != "" {
+			return nil, fmt.Errorf("начальное состояние '%s' не найдено среди состояний модели", cfg.Initial)
+		}
+		m.current = 0
+	}
+	return m, nil
+}
+
+func (m *markovImpairment) Name() string {
+	names := make([]string, len(m.states))
+	for i, state := range m.states {
+		names[i] = state.Name
+	}
+	return fmt.Sprintf("markov(%s)", strings.Join(names, ", "))
+}
+
+// State возвращает текущее состояние канала и число кадров, переданных в каждом состоянии.
+func (m *markovImpairment) State() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	visits := make([]string, len(m.states))
+	for i, state := range m.states {
+		visits[i] = fmt.Sprintf("%s: %d", state.Name, m.visits[i])
+	}
+	return fmt.Sprintf("%s (кадров в состояниях: %s)", m.states[m.current].Name, strings.Join(visits, ", "))
+}
+
+// step переводит канал в следующее состояние и возвращает его.
+func (m *markovImpairment) step(frame *ChannelFrame) MarkovStateConfig {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	draw := frame.Rand.Float64()
+	row := m.transitions[m.current]
+	next := len(row) - 1
+	for j, p := range row {
+		if draw < p {
+			next = j
+			break
+		}
+		draw -= p
+	}
+	m.current = next
+	m.visits[next]++
+	return m.states[next]
+}
+
+func (m *markovImpairment) Apply(frame *ChannelFrame) (int, bool) {
+	state := m.step(frame)
+	frame.Report.Impairments.LossProbability = state.LossProbability
+	frame.Report.Impairments.ErrorProbability = state.BitErrorRate
+	if state.LossProbability > 0 && frame.Rand.Float64() < state.LossProbability {
+		frame.Lost = true
+		return len(frame.Bits), true
+	}
+	flipped := 0
+	for i := range frame.Bits {
+		if state.BitErrorRate > 0 && frame.Rand.Float64() < state.BitErrorRate {
+			frame.Flip(i)
+			flipped++
+		}
+	}
+	return flipped, flipped > 0
+}
+
+func init() {
+	RegisterImpairment("markov", newMarkovImpairment)
+}
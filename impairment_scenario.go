@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Действия событий сценария канала.
+const (
+	ScenarioDrop  = "drop"  // Потерять кадр
+	ScenarioBurst = "burst" // Инвертировать length подряд идущих бит со случайной позиции
+	ScenarioFlip  = "flip"  // Инвертировать биты с индексами bits
+	ScenarioSet   = "set"   // Заменить P и (или) R канала для следующих звеньев цепочки
+)
+
+// ScenarioEvent — событие сценария: действие и условие, при котором оно выполняется. Условия
+// сочетаются по «и»; событие без условий выполняется для каждого кадра.
+type ScenarioEvent struct {
+	Action string `json:"action"` // См. Scenario*
+
+	// AtFrame и Frames — событие действует на кадры с номерами [at_frame, at_frame + frames)
+	// (нумерация кадров сценария с 1; frames по умолчанию 1)
+	AtFrame int `json:"at_frame,omitempty"`
+	Frames  int `json:"frames,omitempty"`
+	// At и Duration — событие действует в интервале времени [at, at + duration) от запуска
+	// (duration по умолчанию — до конца работы)
+	At       *Duration `json:"at,omitempty"`
+	Duration Duration  `json:"duration,omitempty"`
+	// Segments — диапазон номеров сегментов [from, to] каждого сообщения
+	Segments []int `json:"segments,omitempty"`
+
+	Length int      `json:"length,omitempty"` // Длина пакета ошибок (burst)
+	Bits   []int    `json:"bits,omitempty"`   // Индексы инвертируемых бит (flip)
+	P      *float64 `json:"p,omitempty"`      // Новая вероятность ошибки (set)
+	R      *float64 `json:"r,omitempty"`      // Новая вероятность потери (set)
+}
+
+// ScenarioFile — файл сценария канала.
+type ScenarioFile struct {
+	Events []ScenarioEvent `json:"events"`
+}
+
+// scenarioImpairment — сценарий канала для повторяемых лабораторных работ: вместо (или вместе
+// со) случайными искажениями выполняются события по кадрам, времени и номерам сегментов, например
+// «на 50-м кадре пакет ошибок», «терять сегменты 3–5 каждого сообщения», «поднять R до 0.3 на 10 с».
+// Звено set меняет P и R для следующих звеньев, поэтому сценарий ставится в начало цепочки:
+// [{"type": "scenario", "file": "lab1.json"}, {"type": "loss"}, {"type": "bit_error"}].
+type scenarioImpairment struct {
+	file    string
+	events  []ScenarioEvent
+	started time.Time
+
+	mu     sync.Mutex
+	frames int // Кадров, прошедших через сценарий
+}
+
+func newScenarioImpairment(cfg ImpairmentConfig) (Impairment, error) {
+	if cfg.File == "" {
+		return nil, fmt.Errorf("не задан файл сценария (file)")
+	}
+	data, err := os.ReadFile(cfg.File)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать сценарий: %w", err)
+	}
+	var scenario ScenarioFile
+	if err := json.Unmarshal(data, &scenario); err != nil {
+		return nil, fmt.Errorf("сценарий %s: %w", cfg.File, err)
+	}
+	for i := range scenario.Events {
+		if err := validateScenarioEvent(&scenario.Events[i]); err != nil {
+			return nil, fmt.Errorf("сценарий %s: событие %d: %w", cfg.File, i+1, err)
+		}
+	}
+	return &scenarioImpairment{file: cfg.File, events: scenario.Events, started: time.Now()}, nil
+}
+
+// validateScenarioEvent проверяет событие сценария и заполняет значения по умолчанию.
+func validateScenarioEvent(e *ScenarioEvent) error {
+	switch e.Action {
+	case ScenarioDrop:
+	case ScenarioBurst:
+		if e.Length < 1 {
+			return fmt.Errorf("длина пакета ошибок должна быть не менее 1, задано %d", e.Length)
+		}
+	case ScenarioFlip:
+		if len(e.Bits) == 0 {
+			return fmt.Errorf("не заданы индексы бит (bits)")
+		}
+		for _, index := range e.Bits {
+			if index < 0 {
+				return fmt.Errorf("неверный индекс бита %d", index)
+			}
+		}
+	case ScenarioSet:
+		if e.P == nil && e.R == nil {
+			return fmt.Errorf("не задана ни P, ни R")
+		}
+		if err := validProbability(e.P); err != nil {
+			return err
+		}
+		if err := validProbability(e.R); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("неизвестное действие '%s' (допустимо: %s, %s, %s, %s)", e.Action, ScenarioDrop, ScenarioBurst, ScenarioFlip, ScenarioSet)
+	}
+	if e.AtFrame < 0 || e.Frames < 0 {
+		return fmt.Errorf("номер и число кадров не могут быть отрицательными")
+	}
+	if e.AtFrame > 0 && e.Frames == 0 {
+		e.Frames = 1
+	}
+	if e.Duration.Duration < 0 || (e.At != nil && e.At.Duration < 0) {
+		return fmt.Errorf("время события не может быть отрицательным")
+	}
+	if e.Segments != nil && (len(e.Segments) != 2 || e.Segments[0] < 1 || e.Segments[1] < e.Segments[0]) {
+		return fmt.Errorf("диапазон сегментов должен быть задан как [from, to], 1 <= from <= to")
+	}
+	return nil
+}
+
+func (s *scenarioImpairment) Name() string {
+	return fmt.Sprintf("scenario(%s, событий: %d)", s.file, len(s.events))
+}
+
+// State возвращает число кадров, прошедших через сценарий.
+func (s *scenarioImpairment) State() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fmt.Sprintf("кадр %d, %s от запуска", s.frames, time.Since(s.started).Round(time.Second))
+}
+
+// matches проверяет условия события для кадра с номером number, переданного через elapsed после запуска.
+func (e *ScenarioEvent) matches(number int, elapsed time.Duration, frame *ChannelFrame) bool {
+	if e.AtFrame > 0 && (number < e.AtFrame || number >= e.AtFrame+e.Frames) {
+		return false
+	}
+	if e.At != nil && (elapsed < e.At.Duration || (e.Duration.Duration > 0 && elapsed >= e.At.Duration+e.Duration.Duration)) {
+		return false
+	}
+	if e.Segments != nil {
+		if frame.Segment == nil || frame.Segment.SegmentNumber < e.Segments[0] || frame.Segment.SegmentNumber > e.Segments[1] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *scenarioImpairment) Apply(frame *ChannelFrame) (int, bool) {
+	s.mu.Lock()
+	s.frames++
+	number := s.frames
+	s.mu.Unlock()
+	elapsed := time.Since(s.started)
+
+	bits, applied := 0, false
+	for i := range s.events {
+		e := &s.events[i]
+		if !e.matches(number, elapsed, frame) {
+			continue
+		}
+		applied = true
+		switch e.Action {
+		case ScenarioDrop:
+			frame.Lost = true
+			return len(frame.Bits), true
+		case ScenarioBurst:
+			length := min(e.Length, len(frame.Bits))
+			start := frame.Rand.Intn(len(frame.Bits) - length + 1)
+			for j := start; j < start+length; j++ {
+				frame.Flip(j)
+			}
+			bits += length
+		case ScenarioFlip:
+			for _, index := range e.Bits {
+				if index < len(frame.Bits) {
+					frame.Flip(index)
+					bits++
+				}
+			}
+		case ScenarioSet:
+			if e.P != nil {
+				frame.ErrorProbability = *e.P
+			}
+			if e.R != nil {
+				frame.LossProbability = *e.R
+			}
+		}
+	}
+	return bits, applied
+}
+
+func init() {
+	RegisterImpairment("scenario", newScenarioImpairment)
+}
@@ -0,0 +1,127 @@
+package main
+
+import "fmt"
+
+// Направления проскальзывания бит звена slip.
+const (
+	SlipInsert = "insert" // Вставка лишних бит (приемник тактируется быстрее передатчика)
+	SlipDelete = "delete" // Выпадение бит (приемник тактируется медленнее передатчика)
+)
+
+// slipImpairment — проскальзывание бит при потере синхронизации: с вероятностью probability
+// в случайную позицию кадра вставляются length случайных бит или из нее выпадают length бит
+// (направление задается mode, по умолчанию выбирается случайно). Длина принятого кадра
+// перестает совпадать с переданной, и все последующие биты сдвигаются относительно кодовых слов.
+type slipImpairment struct {
+	probability float64
+	length      int
+	mode        string // SlipInsert, SlipDelete или пусто — случайно
+}
+
+func newSlipImpairment(cfg ImpairmentConfig) (Impairment, error) {
+	if cfg.Probability == nil {
+		return nil, fmt.Errorf("не задана вероятность проскальзывания (probability)")
+	}
+	length := cfg.Length
+	if length == 0 {
+		length = 1
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("число бит проскальзывания не может быть отрицательным, задано %d", cfg.Length)
+	}
+	switch cfg.Mode {
+	case "", SlipInsert, SlipDelete:
+	default:
+		return nil, fmt.Errorf("неизвестное направление проскальзывания '%s' (допустимо: %s, %s)", cfg.Mode, SlipInsert, SlipDelete)
+	}
+	return &slipImpairment{probability: *cfg.Probability, length: length, mode: cfg.Mode}, validProbability(cfg.Probability)
+}
+
+func (s *slipImpairment) Name() string {
+	if s.mode != "" {
+		return fmt.Sprintf("slip(%s %d)", s.mode, s.length)
+	}
+	return fmt.Sprintf("slip(%d)", s.length)
+}
+
+func (s *slipImpairment) Apply(frame *ChannelFrame) (int, bool) {
+	if frame.Rand.Float64() > s.probability {
+		return 0, false
+	}
+	mode := s.mode
+	if mode == "" {
+		mode = SlipInsert
+		if frame.Rand.Intn(2) == 0 {
+			mode = SlipDelete
+		}
+	}
+	if mode == SlipDelete {
+		length := min(s.length, len(frame.Bits))
+		position := frame.Rand.Intn(len(frame.Bits) - length + 1)
+		frame.Bits = append(frame.Bits[:position:position], frame.Bits[position+length:]...)
+		return length, true
+	}
+	position := frame.Rand.Intn(len(frame.Bits) + 1)
+	bits := make([]uint8, 0, len(frame.Bits)+s.length)
+	bits = append(bits, frame.Bits[:position]...)
+	for i := 0; i < s.length; i++ {
+		bits = append(bits, uint8(frame.Rand.Intn(2)))
+	}
+	frame.Bits = append(bits, frame.Bits[position:]...)
+	return s.length, true
+}
+
+// truncateImpairment — частичный прием кадра: с вероятностью probability приемник теряет конец
+// кадра. Если задано length, отбрасываются последние length бит, иначе кадр обрывается
+// в случайной позиции.
+type truncateImpairment struct {
+	probability float64
+	length      int // Число отбрасываемых бит (0 — случайное)
+}
+
+func newTruncateImpairment(cfg ImpairmentConfig) (Impairment, error) {
+	if cfg.Probability == nil {
+		return nil, fmt.Errorf("не задана вероятность усечения кадра (probability)")
+	}
+	if cfg.Length < 0 {
+		return nil, fmt.Errorf("число отбрасываемых бит не может быть отрицательным, задано %d", cfg.Length)
+	}
+	return &truncateImpairment{probability: *cfg.Probability, length: cfg.Length}, validProbability(cfg.Probability)
+}
+
+func (t *truncateImpairment) Name() string {
+	if t.length > 0 {
+		return fmt.Sprintf("truncate(%d)", t.length)
+	}
+	return "truncate"
+}
+
+func (t *truncateImpairment) Apply(frame *ChannelFrame) (int, bool) {
+	if len(frame.Bits) == 0 || frame.Rand.Float64() > t.probability {
+		return 0, false
+	}
+	cut := t.length
+	if cut == 0 {
+		cut = 1 + frame.Rand.Intn(len(frame.Bits))
+	}
+	cut = min(cut, len(frame.Bits))
+	frame.Bits = frame.Bits[:len(frame.Bits)-cut]
+	return cut, true
+}
+
+// fitFrameLength приводит принятый кадр к переданной длине: лишние биты отбрасываются,
+// недостающие (проскальзывание или усечение) заменяются нулями. Декодер получает кадр ожидаемой длины, но ошибка
+// синхронизации учитывается как неисправимая.
+func fitFrameLength(bits []uint8, length int) []uint8 {
+	if len(bits) >= length {
+		return bits[:length]
+	}
+	fitted := make([]uint8, length)
+	copy(fitted, bits)
+	return fitted
+}
+
+func init() {
+	RegisterImpairment("slip", newSlipImpairment)
+	RegisterImpairment("truncate", newTruncateImpairment)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Форматы файла трассы ошибок (mode звена trace).
+const (
+	TraceFrames = "frames" // Строка — номер кадра и индексы ошибочных бит в нем или lost
+	TraceStream = "stream" // Строка — позиция ошибочного бита в непрерывном потоке переданных бит
+)
+
+// traceImpairment воспроизводит записанную трассу ошибок реального канала вместо случайных решений.
+// В формате frames (по умолчанию) каждая строка файла — номер кадра (с нуля, по порядку передачи)
+// и индексы инвертируемых бит этого кадра или слово lost:
+//
+//	# кадр  ошибки
+//	0 lost
+//	3 17 250 251
+//
+// Кадры, которых нет в трассе, передаются без искажений. В формате stream каждая строка — позиция
+// ошибочного бита в непрерывном потоке, который образуют переданные подряд кадры: так записываются
+// трассы измерителей BER, не знающих о границах кадров. При loop трасса повторяется по кругу
+// (с периодом, равным последнему кадру или последней позиции трассы плюс один).
+type traceImpairment struct {
+	file   string
+	mode   string
+	loop   bool
+	frames map[int]fixtureDecision // Решения по номерам кадров (frames)
+	bits   []int                   // Позиции ошибочных бит по возрастанию (stream)
+	period int                     // Число кадров или бит трассы
+
+	mu     sync.Mutex
+	frame  int // Номер следующего кадра
+	offset int // Позиция первого бита следующего кадра в потоке (stream)
+}
+
+func newTraceImpairment(cfg ImpairmentConfig) (Impairment, error) {
+	if cfg.File == "" {
+		return nil, fmt.Errorf("не задан файл трассы (file)")
+	}
+	t := &traceImpairment{file: cfg.File, mode: cfg.Mode, loop: cfg.Loop}
+	if t.mode == "" {
+		t.mode = TraceFrames
+	}
+	if t.mode != TraceFrames && t.mode != TraceStream {
+		return nil, fmt.Errorf("неизвестный формат трассы '%s' (допустимо: %s, %s)", cfg.Mode, TraceFrames, TraceStream)
+	}
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	if t.loop && t.period == 0 {
+		return nil, fmt.Errorf("трасса %s пуста, повтор по кругу невозможен", cfg.File)
+	}
+	return t, nil
+}
+
+// load читает файл трассы.
+func (t *traceImpairment) load() error {
+	f, err := os.Open(t.file)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть трассу: %w", err)
+	}
+	defer f.Close()
+	t.frames = make(map[int]fixtureDecision)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(strings.ReplaceAll(text, ",", " "))
+		numbers := make([]int, 0, len(fields))
+		lost := false
+		for i, field := range fields {
+			if i == 1 && len(fields) == 2 && t.mode == TraceFrames && strings.EqualFold(field, "lost") {
+				lost = true
+				continue
+			}
+			n, err := strconv.Atoi(field)
+			if err != nil || n < 0 {
+				return fmt.Errorf("%s:%d: неверное число '%s'", t.file, line, field)
+			}
+			numbers = append(numbers, n)
+		}
+		if t.mode == TraceStream {
+			if len(numbers) != 1 {
+				return fmt.Errorf("%s:%d: ожидается одна позиция бита", t.file, line)
+			}
+			t.bits = append(t.bits, numbers[0])
+			t.period = max(t.period, numbers[0]+1)
+			continue
+		}
+		index := numbers[0]
+		decision := t.frames[index]
+		decision.lose = decision.lose || lost
+		decision.flips = append(decision.flips, numbers[1:]...)
+		t.frames[index] = decision
+		t.period = max(t.period, index+1)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("ошибка чтения трассы: %w", err)
+	}
+	sort.Ints(t.bits)
+	t.bits = slices.Compact(t.bits) // Повторная запись позиции не должна отменять ошибку
+	return nil
+}
+
+func (t *traceImpairment) Name() string {
+	if t.loop {
+		return fmt.Sprintf("trace(%s, %s, по кругу)", t.file, t.mode)
+	}
+	return fmt.Sprintf("trace(%s, %s)", t.file, t.mode)
+}
+
+// State возвращает позицию воспроизведения трассы.
+func (t *traceImpairment) State() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	frame, offset := t.frame, t.offset
+	if t.loop {
+		frame, offset = frame%t.period, offset%t.period
+	}
+	if t.mode == TraceStream {
+		return fmt.Sprintf("бит %d из %d", offset, t.period)
+	}
+	return fmt.Sprintf("кадр %d из %d", frame, t.period)
+}
+
+// advance занимает позицию трассы для кадра длиной length бит и возвращает номер кадра
+// и позицию его первого бита в потоке (с учетом повтора по кругу).
+func (t *traceImpairment) advance(length int) (int, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.loop {
+		if t.mode == TraceFrames {
+			t.frame %= t.period
+		} else {
+			t.offset %= t.period
+		}
+	}
+	frame, offset := t.frame, t.offset
+	t.frame++
+	t.offset += length
+	return frame, offset
+}
+
+func (t *traceImpairment) Apply(frame *ChannelFrame) (int, bool) {
+	index, offset := t.advance(len(frame.Bits))
+	if t.mode == TraceFrames {
+		decision, ok := t.frames[index]
+		if !ok {
+			return 0, false
+		}
+		if decision.lose {
+			frame.Lost = true
+			return len(frame.Bits), true
+		}
+		flipped := 0
+		for _, bit := range decision.flips {
+			if bit < len(frame.Bits) {
+				frame.Flip(bit)
+				flipped++
+			}
+		}
+		return flipped, flipped > 0
+	}
+	// Кадр занимает биты потока начиная с offset; при повторе по кругу он может захватить начало трассы
+	flipped := 0
+	for done := 0; done < len(frame.Bits); {
+		n := len(frame.Bits) - done
+		if t.loop {
+			n = min(n, t.period-offset)
+		}
+		from := sort.SearchInts(t.bits, offset)
+		for _, position := range t.bits[from:] {
+			if position >= offset+n {
+				break
+			}
+			frame.Flip(done + position - offset)
+			flipped++
+		}
+		done += n
+		offset = 0
+	}
+	return flipped, flipped > 0
+}
+
+func init() {
+	RegisterImpairment("trace", newTraceImpairment)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"hash/crc32"
+	"log"
+)
+
+// crc32cTable — таблица CRC-32C (Castagnoli). На платформах с аппаратной поддержкой
+// (SSE 4.2 на amd64, CRC32 на arm64) hash/crc32 вычисляет ее аппаратно.
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+var crc32cEnabled bool // Вычислять ли CRC-32C исходной полезной нагрузки (см. Config.CRC32C)
+
+// Результаты проверки CRC-32C (ChannelReport.CRC32C).
+const (
+	CRCMatch    = "ok"       // Контрольная сумма декодированной полезной нагрузки совпала с исходной
+	CRCMismatch = "mismatch" // Декодер пропустил ошибку: полезная нагрузка искажена
+)
+
+// payloadCRC32C возвращает CRC-32C полезной нагрузки в виде 8 шестнадцатеричных цифр.
+func payloadCRC32C(payload []byte) string {
+	return fmt.Sprintf("%08x", crc32.Checksum(payload, crc32cTable))
+}
+
+// verifyCRC32C сверяет CRC-32C декодированной полезной нагрузки (без паддинга) с исходной.
+// Ошибка, пропущенная поблочным декодером, помечается в сегменте как неисправимая ошибка канала.
+func verifyCRC32C(segment *Segment, report *ChannelReport, expected string) {
+	if segment == nil || segment.Payload == nil || segment.IsChannelError {
+		return
+	}
+	if payloadCRC32C(segment.Payload[:segment.OriginalLength]) == expected {
+		report.CRC32C = CRCMatch
+		return
+	}
+	log.Printf("ChannelLayer: CRC-32C сегмента #%d/%d не совпадает: декодер пропустил ошибку. Помечаем как ошибку канала.",
+		segment.SegmentNumber, segment.TotalSegments)
+	report.CRC32C = CRCMismatch
+	report.Decode = DecodeUncorrectable
+	segment.IsChannelError = true
+}
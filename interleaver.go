@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+)
+
+// convInterleaver — сверточный перемежитель Форни. Биты по очереди распределяются коммутатором
+// по depth ветвям; ветвь i задерживает бит на i*delay своих тактов (i*delay*depth бит потока).
+// В деперемежителе ветвь i имеет задержку (depth-1-i)*delay, поэтому общая задержка любого бита
+// одинакова и равна depth*(depth-1)*delay бит. Пакет ошибок длиной до depth бит в канале после
+// деперемежения разносится на расстояние не менее depth*delay бит друг от друга.
+//
+// По сравнению с блочным перемежителем той же глубины сверточный вдвое меньше задерживает поток
+// и требует вдвое меньше памяти, но задержка добавляется к каждому кадру: кадр дополняется
+// нулевыми битами, чтобы полностью вытолкнуть его из ветвей.
+type convInterleaver struct {
+	depth int // Число ветвей
+	delay int // Шаг задержки ветви (в тактах коммутатора)
+}
+
+// newConvInterleaver создает сверточный перемежитель по конфигурации этапа.
+func newConvInterleaver(cfg StageConfig) (StreamStage, error) {
+	if cfg.Depth < 2 {
+		return nil, fmt.Errorf("глубина сверточного перемежителя должна быть не менее 2, задано %d", cfg.Depth)
+	}
+	if cfg.Delay < 1 {
+		return nil, fmt.Errorf("шаг задержки сверточного перемежителя должен быть не менее 1, задано %d", cfg.Delay)
+	}
+	return &convInterleaver{depth: cfg.Depth, delay: cfg.Delay}, nil
+}
+
+func (ci *convInterleaver) Name() string {
+	return fmt.Sprintf("conv_interleaver(%dx%d)", ci.depth, ci.delay)
+}
+
+// latency возвращает общую задержку перемежителя и деперемежителя в битах.
+func (ci *convInterleaver) latency() int {
+	return ci.depth * (ci.depth - 1) * ci.delay
+}
+
+// runDelayLines пропускает поток через depth ветвей с задержками branchDelay(i) тактов (линии
+// задержки изначально заполнены нулями) и возвращает выходной поток длины outLen.
+func runDelayLines[T uint8 | float64](bits []T, depth, outLen int, branchDelay func(i int) int) []T {
+	lines := make([][]T, depth)
+	for i := range lines {
+		lines[i] = make([]T, branchDelay(i))
+	}
+	out := make([]T, outLen)
+	for t := 0; t < outLen; t++ {
+		var in T
+		if t < len(bits) {
+			in = bits[t]
+		}
+		branch := t % depth
+		line := lines[branch]
+		if len(line) == 0 {
+			out[t] = in
+			continue
+		}
+		out[t] = line[0]
+		copy(line, line[1:])
+		line[len(line)-1] = in
+	}
+	return out
+}
+
+// Apply перемежает поток; кадр удлиняется на задержку перемежителя.
+func (ci *convInterleaver) Apply(bits []uint8) []uint8 {
+	return runDelayLines(bits, ci.depth, len(bits)+ci.latency(), func(i int) int { return i * ci.delay })
+}
+
+// Invert деперемежает поток и отбрасывает начальную задержку.
+func (ci *convInterleaver) Invert(bits []uint8, length int) ([]uint8, int) {
+	return deinterleaveConv(ci, bits, length), 0
+}
+
+// InvertSoft деперемежает поток мягких решений.
+func (ci *convInterleaver) InvertSoft(llr []float64, length int) []float64 {
+	return deinterleaveConv(ci, llr, length)
+}
+
+func deinterleaveConv[T uint8 | float64](ci *convInterleaver, bits []T, length int) []T {
+	out := runDelayLines(bits, ci.depth, len(bits), func(i int) int { return (ci.depth - 1 - i) * ci.delay })
+	return out[ci.latency() : ci.latency()+length]
+}
+
+// blockInterleaver — блочный перемежитель глубины depth. Кадр записывается построчно в матрицу
+// из depth строк (длина строки — ceil(L/depth) бит, недостающие биты последней строки дополняются
+// нулями) и считывается по столбцам. Соседние биты в канале принадлежат разным строкам, поэтому
+// пакет ошибок длиной до depth бит после деперемежения превращается в одиночные ошибки, отстоящие
+// друг от друга на длину строки, и попадает в разные кодовые блоки, если строка не короче блока.
+type blockInterleaver struct {
+	depth int // Число строк матрицы
+}
+
+// newBlockInterleaver создает блочный перемежитель по конфигурации этапа.
+func newBlockInterleaver(cfg StageConfig) (StreamStage, error) {
+	if cfg.Depth < 2 {
+		return nil, fmt.Errorf("глубина блочного перемежителя должна быть не менее 2, задано %d", cfg.Depth)
+	}
+	return &blockInterleaver{depth: cfg.Depth}, nil
+}
+
+func (bi *blockInterleaver) Name() string {
+	return fmt.Sprintf("block_interleaver(%d)", bi.depth)
+}
+
+// Apply записывает поток в матрицу по строкам и считывает по столбцам.
+func (bi *blockInterleaver) Apply(bits []uint8) []uint8 {
+	cols := (len(bits) + bi.depth - 1) / bi.depth
+	out := make([]uint8, 0, cols*bi.depth)
+	for c := 0; c < cols; c++ {
+		for r := 0; r < bi.depth; r++ {
+			var bit uint8
+			if i := r*cols + c; i < len(bits) {
+				bit = bits[i]
+			}
+			out = append(out, bit)
+		}
+	}
+	return out
+}
+
+// Invert записывает поток в матрицу по столбцам, считывает по строкам и отбрасывает дополнение.
+func (bi *blockInterleaver) Invert(bits []uint8, length int) ([]uint8, int) {
+	return deinterleaveBlock(bi.depth, bits, length), 0
+}
+
+// InvertSoft деперемежает поток мягких решений.
+func (bi *blockInterleaver) InvertSoft(llr []float64, length int) []float64 {
+	return deinterleaveBlock(bi.depth, llr, length)
+}
+
+func deinterleaveBlock[T uint8 | float64](depth int, bits []T, length int) []T {
+	cols := len(bits) / depth
+	out := make([]T, cols*depth)
+	for i, bit := range bits[:cols*depth] {
+		out[(i%depth)*cols+i/depth] = bit
+	}
+	return out[:length]
+}
+
+func init() {
+	RegisterStage("conv_interleaver", newConvInterleaver)
+	RegisterStage("block_interleaver", newBlockInterleaver)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// jitterStreamIdle — через сколько после момента воспроизведения последнего кадра сообщения
+// состояние его потока удаляется из буфера джиттера.
+const jitterStreamIdle = time.Minute
+
+// JitterStats — состояние буфера джиттера, возвращаемое на /stats.
+type JitterStats struct {
+	PlayoutDelayMs  float64 `json:"playout_delay_ms"`  // Фиксированная задержка воспроизведения
+	FrameIntervalMs float64 `json:"frame_interval_ms"` // Номинальный интервал между кадрами сообщения
+	Occupancy       int     `json:"occupancy"`         // Кадров в буфере сейчас
+	MaxOccupancy    int     `json:"max_occupancy"`     // Наибольшая заполненность с момента запуска
+	Buffered        int64   `json:"buffered"`          // Кадров, выданных транспортному уровню через буфер
+	LateDrops       int64   `json:"late_drops"`        // Кадров, отброшенных из-за опоздания
+	Streams         int     `json:"streams"`           // Отслеживаемых сообщений
+}
+
+// jitterStream — опорная точка воспроизведения сообщения (sender + send_time).
+type jitterStream struct {
+	anchor     time.Time // Момент прихода первого кадра сообщения в буфер
+	firstNum   int       // Номер первого пришедшего сегмента
+	lastPlayAt time.Time // Наибольший момент воспроизведения кадра сообщения
+}
+
+// JitterBuffer — буфер джиттера перед пересылкой на /transfer. Кадр сегмента n сообщения
+// выдается транспортному уровню в момент anchor + playoutDelay + (n - n0) * frameInterval,
+// где anchor и n0 — момент прихода и номер первого пришедшего кадра сообщения. Так неравномерная
+// задержка обработки сглаживается фиксированной задержкой воспроизведения, а кадр, пришедший
+// позже своего момента воспроизведения, отбрасывается как опоздавший.
+// Все методы допускают вызов на nil-буфере (буфер отключен в конфигурации).
+type JitterBuffer struct {
+	mu            sync.Mutex
+	playoutDelay  time.Duration
+	frameInterval time.Duration
+	streams       map[string]*jitterStream
+	occupancy     int
+	maxOccupancy  int
+	buffered      int64
+	lateDrops     int64
+}
+
+// NewJitterBuffer создает буфер джиттера по конфигурации.
+func NewJitterBuffer(cfg JitterConfig) (*JitterBuffer, error) {
+	if cfg.PlayoutDelay.Duration <= 0 {
+		return nil, fmt.Errorf("задержка воспроизведения должна быть положительной, задано %s", cfg.PlayoutDelay)
+	}
+	if cfg.FrameInterval.Duration < 0 {
+		return nil, fmt.Errorf("интервал между кадрами не может быть отрицательным, задано %s", cfg.FrameInterval)
+	}
+	return &JitterBuffer{
+		playoutDelay:  cfg.PlayoutDelay.Duration,
+		frameInterval: cfg.FrameInterval.Duration,
+		streams:       map[string]*jitterStream{},
+	}, nil
+}
+
+// Hold задерживает кадр до момента его воспроизведения. Возвращает false, если кадр опоздал
+// и должен быть отброшен. Отмена ctx прекращает ожидание досрочно (кадр выдается сразу).
+func (jb *JitterBuffer) Hold(ctx context.Context, sender, sendTime string, segmentNumber int) bool {
+	if jb == nil {
+		return true
+	}
+	now := time.Now()
+	key := sender + "|" + sendTime
+
+	jb.mu.Lock()
+	for k, s := range jb.streams {
+		if now.Sub(s.lastPlayAt) > jitterStreamIdle {
+			delete(jb.streams, k)
+		}
+	}
+	stream, ok := jb.streams[key]
+	if !ok {
+		stream = &jitterStream{anchor: now, firstNum: segmentNumber}
+		jb.streams[key] = stream
+	}
+	playAt := stream.anchor.Add(jb.playoutDelay + time.Duration(segmentNumber-stream.firstNum)*jb.frameInterval)
+	if now.After(playAt) {
+		jb.lateDrops++
+		jb.mu.Unlock()
+		return false
+	}
+	if playAt.After(stream.lastPlayAt) {
+		stream.lastPlayAt = playAt
+	}
+	jb.occupancy++
+	jb.maxOccupancy = max(jb.maxOccupancy, jb.occupancy)
+	jb.mu.Unlock()
+
+	timer := time.NewTimer(playAt.Sub(now))
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		timer.Stop()
+	}
+
+	jb.mu.Lock()
+	jb.occupancy--
+	jb.buffered++
+	jb.mu.Unlock()
+	return true
+}
+
+// Stats возвращает текущее состояние буфера.
+func (jb *JitterBuffer) Stats() *JitterStats {
+	if jb == nil {
+		return nil
+	}
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+	return &JitterStats{
+		PlayoutDelayMs:  float64(jb.playoutDelay.Microseconds()) / 1000,
+		FrameIntervalMs: float64(jb.frameInterval.Microseconds()) / 1000,
+		Occupancy:       jb.occupancy,
+		MaxOccupancy:    jb.maxOccupancy,
+		Buffered:        jb.buffered,
+		LateDrops:       jb.lateDrops,
+		Streams:         len(jb.streams),
+	}
+}
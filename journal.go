@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Операции журнала исходящих сегментов.
+const (
+	journalOpSend    = "send"    // Сегмент подготовлен к отправке на /transfer
+	journalOpAck     = "ack"     // Транспортный уровень подтвердил получение (статус 200)
+	journalOpAbandon = "abandon" // Транспортный уровень явно отказал, повторная отправка не требуется
+)
+
+// journalEntry — одна запись журнала исходящих сегментов.
+type journalEntry struct {
+	Op      string                   `json:"op"`
+	ID      string                   `json:"id"`
+	Time    time.Time                `json:"time"`
+	Request *OutgoingTransferRequest `json:"request,omitempty"` // Заполнено только для операции send
+}
+
+// OutboundJournal — персистентный журнал исходящих сегментов.
+// Каждый сегмент записывается в журнал перед отправкой на /transfer и отмечается подтвержденным
+// после ответа транспортного уровня. Сегменты, оставшиеся неподтвержденными (например, из-за
+// аварийного завершения процесса посреди передачи файла), повторно отправляются при следующем запуске.
+// Все методы допускают вызов на nil-журнале (журнал отключен в конфигурации).
+type OutboundJournal struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// journalID формирует идентификатор сегмента в журнале из его идентифицирующих полей.
+func journalID(req OutgoingTransferRequest) string {
+	return fmt.Sprintf("%s|%s|%d", req.Sender, req.SendTime, req.SegmentNumber)
+}
+
+// OpenOutboundJournal открывает (или создает) журнал исходящих сегментов.
+func OpenOutboundJournal(path string) (*OutboundJournal, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть журнал исходящих сегментов %s: %w", path, err)
+	}
+	return &OutboundJournal{path: path, file: file}, nil
+}
+
+// write дописывает запись в журнал и сбрасывает ее на диск.
+func (j *OutboundJournal) write(entry journalEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Journal ERROR: Не удалось сериализовать запись журнала %s: %v", entry.ID, err)
+		return
+	}
+	data = append(data, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Write(data); err != nil {
+		log.Printf("Journal ERROR: Не удалось записать в журнал %s: %v", j.path, err)
+		return
+	}
+	if err := j.file.Sync(); err != nil {
+		log.Printf("Journal ERROR: Не удалось сбросить журнал %s на диск: %v", j.path, err)
+	}
+}
+
+// Append записывает сегмент, подготовленный к отправке, и возвращает его идентификатор в журнале.
+func (j *OutboundJournal) Append(req OutgoingTransferRequest) string {
+	id := journalID(req)
+	if j == nil {
+		return id
+	}
+	j.write(journalEntry{Op: journalOpSend, ID: id, Time: time.Now().UTC(), Request: &req})
+	return id
+}
+
+// Ack отмечает сегмент как подтвержденный транспортным уровнем.
+func (j *OutboundJournal) Ack(id string) {
+	if j == nil {
+		return
+	}
+	j.write(journalEntry{Op: journalOpAck, ID: id, Time: time.Now().UTC()})
+}
+
+// Abandon отмечает сегмент, от которого транспортный уровень явно отказался.
+func (j *OutboundJournal) Abandon(id string) {
+	if j == nil {
+		return
+	}
+	j.write(journalEntry{Op: journalOpAbandon, ID: id, Time: time.Now().UTC()})
+}
+
+// pendingLocked читает журнал и возвращает неподтвержденные сегменты в порядке их записи.
+// Вызывается под блокировкой j.mu.
+func (j *OutboundJournal) pendingLocked() ([]journalEntry, error) {
+	file, err := os.Open(j.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var order []string
+	sends := make(map[string]journalEntry)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// Недописанная строка в конце журнала — типичное следствие аварийного завершения
+			continue
+		}
+		switch entry.Op {
+		case journalOpSend:
+			if entry.Request == nil {
+				continue
+			}
+			if _, ok := sends[entry.ID]; !ok {
+				order = append(order, entry.ID)
+			}
+			sends[entry.ID] = entry
+		case journalOpAck, journalOpAbandon:
+			delete(sends, entry.ID)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]journalEntry, 0, len(sends))
+	for _, id := range order {
+		if entry, ok := sends[id]; ok {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
+
+// compactLocked перезаписывает журнал, оставляя в нем только переданные записи.
+// Вызывается под блокировкой j.mu.
+func (j *OutboundJournal) compactLocked(entries []journalEntry) error {
+	tmpPath := j.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(tmp)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	tmp.Close()
+	if err := os.Rename(tmpPath, j.path); err != nil {
+		return err
+	}
+
+	// Переоткрываем журнал, так как старый файловый дескриптор указывает на замененный файл
+	j.file.Close()
+	j.file, err = os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	return err
+}
+
+// RecoverJournal повторно отправляет на /transfer все сегменты, не подтвержденные транспортным
+// уровнем до остановки процесса, и выводит в лог итог восстановления.
+// Сегменты, которые не удалось отправить, остаются в журнале до следующего запуска.
+func (j *OutboundJournal) RecoverJournal() {
+	if j == nil {
+		return
+	}
+
+	// Чтение и сжатие журнала выполняются под одной блокировкой, чтобы не потерять
+	// записи сегментов, принятых обработчиком /code во время восстановления.
+	j.mu.Lock()
+	entries, err := j.pendingLocked()
+	if err != nil {
+		j.mu.Unlock()
+		log.Printf("Journal ERROR: Не удалось прочитать журнал %s для восстановления: %v", j.path, err)
+		return
+	}
+	// Сжимаем журнал: подтвержденные записи больше не нужны
+	if err := j.compactLocked(entries); err != nil {
+		log.Printf("Journal ERROR: Не удалось сжать журнал %s: %v", j.path, err)
+	}
+	j.mu.Unlock()
+	if len(entries) == 0 {
+		log.Println("Journal: Неподтвержденных сегментов не найдено, восстановление не требуется.")
+		return
+	}
+
+	log.Printf("Journal: Найдено %d неподтвержденных сегментов, повторная отправка на %s...", len(entries), TransferURL)
+	delivered, rejected, failed := 0, 0, 0
+	for _, entry := range entries {
+		req := *entry.Request
+		outgoingJSON, err := json.Marshal(req)
+		if err != nil {
+			log.Printf("Journal ERROR: Не удалось сериализовать сегмент %s: %v", entry.ID, err)
+			failed++
+			continue
+		}
+
+		resp, err := postTransfer(outgoingJSON)
+		switch {
+		case err != nil:
+			log.Printf("Journal: Сегмент #%d/%d от %s не отправлен: %v", req.SegmentNumber, req.TotalSegments, req.Sender, err)
+			failed++
+		case resp.StatusCode == http.StatusOK:
+			j.Ack(entry.ID)
+			delivered++
+		default:
+			log.Printf("Journal: Сегмент #%d/%d от %s отклонен транспортным уровнем (статус %s)", req.SegmentNumber, req.TotalSegments, req.Sender, resp.Status)
+			j.Abandon(entry.ID)
+			rejected++
+		}
+	}
+
+	log.Printf("Journal: Восстановление завершено: всего %d, доставлено %d, отклонено %d, не отправлено %d (останутся в журнале).",
+		len(entries), delivered, rejected, failed)
+}
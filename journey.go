@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Уровни модели, к которым относятся события пути сегмента.
+const (
+	LayerTransport = "transport" // Обмен с транспортным уровнем (/code, /transfer)
+	LayerChannel   = "channel"   // Канальный уровень: очередь, кодирование, этапы обработки потока, декодирование
+	LayerPhysical  = "physical"  // Физический канал: искажения и задержка кадра
+)
+
+// PipelineStep — шаг обработки кадра канальным уровнем с длиной потока до и после шага.
+type PipelineStep struct {
+	Time       time.Time `json:"time"`
+	Layer      string    `json:"layer"`
+	Step       string    `json:"step"`
+	InBits     int       `json:"in_bits"`
+	OutBits    int       `json:"out_bits"`
+	DurationUs float64   `json:"duration_us"`
+	Detail     string    `json:"detail,omitempty"`
+}
+
+// addStep добавляет в отчет шаг обработки, начавшийся в момент start и завершившийся сейчас.
+func (r *ChannelReport) addStep(layer, step string, start time.Time, inBits, outBits int, detail string) {
+	r.Steps = append(r.Steps, PipelineStep{
+		Time:       start.UTC(),
+		Layer:      layer,
+		Step:       step,
+		InBits:     inBits,
+		OutBits:    outBits,
+		DurationUs: float64(time.Since(start).Nanoseconds()) / 1000,
+		Detail:     detail,
+	})
+}
+
+// JourneyEvent — событие пути сегмента: этап жизненного цикла или шаг обработки кадра.
+type JourneyEvent struct {
+	Time       time.Time `json:"time"`
+	OffsetMs   float64   `json:"offset_ms"` // Время от приема сегмента на /code
+	Layer      string    `json:"layer"`
+	Stage      string    `json:"stage"`
+	Detail     string    `json:"detail,omitempty"`
+	InBits     *int      `json:"in_bits,omitempty"`     // Длина потока до шага (только для шагов обработки кадра)
+	OutBits    *int      `json:"out_bits,omitempty"`    // Длина потока после шага
+	DurationUs *float64  `json:"duration_us,omitempty"` // Длительность шага
+}
+
+// SegmentJourney — полный упорядоченный путь сегмента через уровни модели.
+type SegmentJourney struct {
+	ID            string         `json:"id"`
+	Sender        string         `json:"sender"`
+	SendTime      string         `json:"send_time"`
+	SegmentNumber int            `json:"segment_number"`
+	TotalSegments int            `json:"total_segments"`
+	State         string         `json:"state"`
+	Outcome       string         `json:"outcome,omitempty"`
+	TotalMs       float64        `json:"total_ms,omitempty"` // Время от приема до завершения обработки
+	Events        []JourneyEvent `json:"events"`
+}
+
+// lifecycleLayer возвращает уровень, к которому относится этап жизненного цикла сегмента.
+func lifecycleLayer(stage string) string {
+	switch stage {
+	case "received", "forward", SegmentStateCompleted:
+		return LayerTransport
+	default:
+		return LayerChannel
+	}
+}
+
+// newSegmentJourney объединяет этапы жизненного цикла сегмента и шаги обработки его кадра
+// в единую хронологическую последовательность.
+func newSegmentJourney(rec SegmentRecord) SegmentJourney {
+	journey := SegmentJourney{
+		ID:            rec.ID,
+		Sender:        rec.Sender,
+		SendTime:      rec.SendTime,
+		SegmentNumber: rec.SegmentNumber,
+		TotalSegments: rec.TotalSegments,
+		State:         rec.State,
+		Events:        []JourneyEvent{},
+	}
+	if rec.Result != nil {
+		journey.Outcome = rec.Result.Outcome
+	}
+	if rec.CompletedAt != nil {
+		journey.TotalMs = float64(rec.CompletedAt.Sub(rec.ReceivedAt).Microseconds()) / 1000
+	}
+	offset := func(t time.Time) float64 {
+		return float64(t.Sub(rec.ReceivedAt).Microseconds()) / 1000
+	}
+	for _, e := range rec.Lifecycle {
+		journey.Events = append(journey.Events, JourneyEvent{
+			Time:     e.Time,
+			OffsetMs: offset(e.Time),
+			Layer:    lifecycleLayer(e.Stage),
+			Stage:    e.Stage,
+			Detail:   e.Detail,
+		})
+	}
+	if rec.Channel != nil {
+		for _, step := range rec.Channel.Steps {
+			journey.Events = append(journey.Events, JourneyEvent{
+				Time:       step.Time,
+				OffsetMs:   offset(step.Time),
+				Layer:      step.Layer,
+				Stage:      step.Step,
+				Detail:     step.Detail,
+				InBits:     &step.InBits,
+				OutBits:    &step.OutBits,
+				DurationUs: &step.DurationUs,
+			})
+		}
+	}
+	// Этап channel журнала фиксируется после завершения обработки кадра, поэтому шаги обработки
+	// при сортировке по времени оказываются перед ним.
+	sort.SliceStable(journey.Events, func(i, j int) bool { return journey.Events[i].Time.Before(journey.Events[j].Time) })
+	return journey
+}
+
+// handleSegmentJourney возвращает упорядоченный путь сегмента через уровни модели с временем
+// каждого события и длиной потока на каждом шаге обработки кадра (GET /segments/{id}/journey).
+func handleSegmentJourney(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+		return
+	}
+	rec, ok := segmentRegistry.Get(r.PathValue("id"))
+	if !ok {
+		sendErrorResponse(w, "Сегмент не найден (неизвестный идентификатор или запись вытеснена из реестра)", http.StatusNotFound)
+		return
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(newSegmentJourney(rec))
+}
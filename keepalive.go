@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Состояния нисходящего звена (транспортного уровня на TransferURL).
+const (
+	DownstreamUp   = "up"
+	DownstreamDown = "down"
+)
+
+const (
+	SegmentTypeKeepalive = "keepalive" // Тип кадра keepalive, отправляемого на /transfer
+
+	defaultKeepaliveTimeout = 2 * time.Second // Время ожидания ответа на keepalive по умолчанию
+	defaultKeepaliveMisses  = 3               // Число пропущенных ответов до признания звена недоступным
+)
+
+// KeepaliveConfig задает периодическую проверку доступности транспортного уровня.
+type KeepaliveConfig struct {
+	Interval        Duration `json:"interval"`          // Период отправки keepalive; 0 — проверка отключена
+	Timeout         Duration `json:"timeout"`           // Время ожидания ответа (по умолчанию 2 с)
+	Misses          int      `json:"misses"`            // Пропущенных ответов подряд до перехода в down (по умолчанию 3)
+	RejectWhileDown bool     `json:"reject_while_down"` // Отклонять сегменты на /code (503), пока звено недоступно
+}
+
+// KeepaliveFrame — кадр keepalive, отправляемый на /transfer.
+type KeepaliveFrame struct {
+	Type     string `json:"type"`      // Всегда "keepalive"
+	Seq      uint64 `json:"seq"`       // Номер кадра keepalive
+	SendTime string `json:"send_time"` // Момент отправки (RFC3339)
+}
+
+// DownstreamStats — доступность транспортного уровня для /stats.
+type DownstreamStats struct {
+	State       string     `json:"state"`                // Состояние звена (см. Downstream*)
+	Since       time.Time  `json:"since"`                // Момент последней смены состояния
+	LastSeen    *time.Time `json:"last_seen,omitempty"`  // Момент последнего ответа на keepalive
+	LastError   string     `json:"last_error,omitempty"` // Причина последнего пропущенного ответа
+	LastRTTMs   float64    `json:"last_rtt_ms"`          // Время ответа на последний принятый keepalive
+	Sent        int64      `json:"sent"`                 // Отправлено кадров keepalive
+	Missed      int64      `json:"missed"`               // Кадров keepalive без ответа
+	Transitions int64      `json:"transitions"`          // Смен состояния звена
+}
+
+// LivenessMonitor — контроль доступности транспортного уровня: каждые interval на TransferURL
+// отправляется кадр keepalive. Любой HTTP ответ означает, что звено доступно; после misses
+// пропущенных ответов подряд звено признается недоступным (down) до первого ответа. Смена состояния
+// публикуется в журнале событий (link_down, link_up). Методы допускают вызов на nil (проверка отключена).
+type LivenessMonitor struct {
+	interval        time.Duration
+	misses          int
+	rejectWhileDown bool
+	client          *http.Client
+
+	mu          sync.Mutex
+	seq         uint64
+	up          bool
+	since       time.Time
+	lastSeen    time.Time
+	lastError   string
+	lastRTT     time.Duration
+	consecutive int // Пропущенных ответов подряд
+	missed      int64
+	transitions int64
+}
+
+// NewLivenessMonitor создает контроль доступности по конфигурации (nil, если период не задан).
+// До первого пропущенного ответа звено считается доступным.
+func NewLivenessMonitor(cfg KeepaliveConfig) (*LivenessMonitor, error) {
+	if cfg.Interval.Duration == 0 {
+		return nil, nil
+	}
+	if cfg.Interval.Duration < 0 || cfg.Timeout.Duration < 0 || cfg.Misses < 0 {
+		return nil, fmt.Errorf("период, время ожидания и число пропусков не могут быть отрицательными")
+	}
+	m := &LivenessMonitor{
+		interval:        cfg.Interval.Duration,
+		misses:          cfg.Misses,
+		rejectWhileDown: cfg.RejectWhileDown,
+		up:              true,
+		since:           time.Now().UTC(),
+	}
+	timeout := cfg.Timeout.Duration
+	if timeout == 0 {
+		timeout = defaultKeepaliveTimeout
+	}
+	m.client = &http.Client{Timeout: timeout}
+	if m.misses == 0 {
+		m.misses = defaultKeepaliveMisses
+	}
+	return m, nil
+}
+
+// Run отправляет кадры keepalive с периодом interval.
+func (m *LivenessMonitor) Run() {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		m.probe()
+	}
+}
+
+// probe отправляет один кадр keepalive и учитывает ответ.
+func (m *LivenessMonitor) probe() {
+	m.mu.Lock()
+	m.seq++
+	frame := KeepaliveFrame{Type: SegmentTypeKeepalive, Seq: m.seq, SendTime: time.Now().UTC().Format(time.RFC3339Nano)}
+	m.mu.Unlock()
+
+	body, _ := json.Marshal(frame)
+	start := time.Now()
+	resp, err := m.client.Post(TransferURL, "application/json", bytes.NewReader(body))
+	if err == nil {
+		resp.Body.Close()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.missed++
+		m.consecutive++
+		m.lastError = err.Error()
+		if m.up && m.consecutive >= m.misses {
+			m.setState(false, fmt.Sprintf("Транспортный уровень на %s недоступен: нет ответа на %d кадров keepalive подряд", TransferURL, m.consecutive))
+		}
+		return
+	}
+	m.consecutive = 0
+	m.lastSeen = time.Now().UTC()
+	m.lastRTT = time.Since(start)
+	if !m.up {
+		m.setState(true, fmt.Sprintf("Транспортный уровень на %s снова доступен", TransferURL))
+	}
+}
+
+// setState меняет состояние звена и публикует событие. Вызывается под m.mu.
+func (m *LivenessMonitor) setState(up bool, message string) {
+	m.up = up
+	downFor := time.Since(m.since)
+	m.since = time.Now().UTC()
+	m.transitions++
+	eventType, details := "link_down", map[string]interface{}{"transfer_url": TransferURL, "last_error": m.lastError}
+	if up {
+		eventType, details = "link_up", map[string]interface{}{"transfer_url": TransferURL, "down_for": downFor.String()}
+	}
+	eventLog.Publish(eventType, message, details)
+}
+
+// Rejecting сообщает, что звено недоступно и сегменты нужно отклонять.
+func (m *LivenessMonitor) Rejecting() bool {
+	if m == nil || !m.rejectWhileDown {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return !m.up
+}
+
+// RetryAfter возвращает рекомендуемую задержку повторной отправки в секундах (период keepalive).
+func (m *LivenessMonitor) RetryAfter() int {
+	return max(int(m.interval.Round(time.Second)/time.Second), 1)
+}
+
+// Stats возвращает доступность транспортного уровня (nil, если проверка отключена).
+func (m *LivenessMonitor) Stats() *DownstreamStats {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := &DownstreamStats{
+		State:       DownstreamUp,
+		Since:       m.since,
+		LastError:   m.lastError,
+		LastRTTMs:   float64(m.lastRTT.Microseconds()) / 1000,
+		Sent:        int64(m.seq),
+		Missed:      m.missed,
+		Transitions: m.transitions,
+	}
+	if !m.up {
+		stats.State = DownstreamDown
+	}
+	if !m.lastSeen.IsZero() {
+		lastSeen := m.lastSeen
+		stats.LastSeen = &lastSeen
+	}
+	return stats
+}
@@ -0,0 +1,209 @@
+package main
+
+// Линейное кодирование закодированного потока: каждые m бит заменяются кодовой группой из n бит
+// (4B/5B, 8B/10B). Группы выбираются так, чтобы в сигнале было достаточно переходов для синхронизации
+// приемника; часть возможных n-битных комбинаций не используется, поэтому многие ошибки канала
+// превращают группу в недопустимую и обнаруживаются уже на физическом уровне.
+
+// bitsToUint собирает значение из битов (старший бит первым).
+func bitsToUint(bits []uint8) int {
+	v := 0
+	for _, b := range bits {
+		v = v<<1 | int(b)
+	}
+	return v
+}
+
+// appendUintBits добавляет к потоку width младших бит значения v (старший бит первым).
+func appendUintBits(out []uint8, v, width int) []uint8 {
+	for i := width - 1; i >= 0; i-- {
+		out = append(out, uint8(v>>i)&1)
+	}
+	return out
+}
+
+// padBits дополняет поток нулевыми битами до длины, кратной multiple.
+func padBits(bits []uint8, multiple int) []uint8 {
+	if rem := len(bits) % multiple; rem != 0 {
+		bits = append(append([]uint8(nil), bits...), make([]uint8, multiple-rem)...)
+	}
+	return bits
+}
+
+// fourBFiveBTable — кодовые группы 4B/5B (FDDI, 100BASE-TX) для значений 0x0..0xF.
+var fourBFiveBTable = [16]int{
+	0b11110, 0b01001, 0b10100, 0b10101, 0b01010, 0b01011, 0b01110, 0b01111,
+	0b10010, 0b10011, 0b10110, 0b10111, 0b11010, 0b11011, 0b11100, 0b11101,
+}
+
+// fourBFiveBStage — линейный код 4B/5B: 16 из 32 пятибитных групп используются для данных,
+// остальные недопустимы (управляющие символы в потоке данных также считаются нарушением).
+type fourBFiveBStage struct {
+	decode map[int]int // Обратная таблица: кодовая группа -> значение
+}
+
+func newFourBFiveBStage(StageConfig) (StreamStage, error) {
+	decode := make(map[int]int, len(fourBFiveBTable))
+	for v, code := range fourBFiveBTable {
+		decode[code] = v
+	}
+	return &fourBFiveBStage{decode: decode}, nil
+}
+
+func (s *fourBFiveBStage) Name() string { return "4b5b" }
+
+// Apply заменяет каждые 4 бита пятибитной кодовой группой.
+func (s *fourBFiveBStage) Apply(bits []uint8) []uint8 {
+	bits = padBits(bits, 4)
+	out := make([]uint8, 0, len(bits)/4*5)
+	for i := 0; i < len(bits); i += 4 {
+		out = appendUintBits(out, fourBFiveBTable[bitsToUint(bits[i:i+4])], 5)
+	}
+	return out
+}
+
+// Invert декодирует пятибитные группы; недопустимая группа декодируется нулями и считается нарушением.
+func (s *fourBFiveBStage) Invert(bits []uint8, length int) ([]uint8, int) {
+	out := make([]uint8, 0, len(bits)/5*4)
+	violations := 0
+	for i := 0; i+5 <= len(bits); i += 5 {
+		v, ok := s.decode[bitsToUint(bits[i:i+5])]
+		if !ok {
+			violations++
+		}
+		out = appendUintBits(out, v, 4)
+	}
+	return out[:length], violations
+}
+
+// Таблицы 8B/10B: подблок 5b/6b (биты EDCBA -> abcdei) и 3b/4b (биты HGF -> fghj)
+// для текущей отрицательной (RD-) и положительной (RD+) текущей диспаратности.
+var (
+	fiveBSixBMinus = [32]int{
+		0b100111, 0b011101, 0b101101, 0b110001, 0b110101, 0b101001, 0b011001, 0b111000,
+		0b111001, 0b100101, 0b010101, 0b110100, 0b001101, 0b101100, 0b011100, 0b010111,
+		0b011011, 0b100011, 0b010011, 0b110010, 0b001011, 0b101010, 0b011010, 0b111010,
+		0b110011, 0b100110, 0b010110, 0b110110, 0b001110, 0b101110, 0b011110, 0b101011,
+	}
+	fiveBSixBPlus = [32]int{
+		0b011000, 0b100010, 0b010010, 0b110001, 0b001010, 0b101001, 0b011001, 0b000111,
+		0b000110, 0b100101, 0b010101, 0b110100, 0b001101, 0b101100, 0b011100, 0b101000,
+		0b100100, 0b100011, 0b010011, 0b110010, 0b001011, 0b101010, 0b011010, 0b000101,
+		0b001100, 0b100110, 0b010110, 0b001001, 0b001110, 0b010001, 0b100001, 0b010100,
+	}
+	threeBFourBMinus = [8]int{0b1011, 0b1001, 0b0101, 0b1100, 0b1101, 0b1010, 0b0110, 0b1110}
+	threeBFourBPlus  = [8]int{0b0100, 0b1001, 0b0101, 0b0011, 0b0010, 0b1010, 0b0110, 0b0001}
+)
+
+// Альтернативная кодировка D.x.7 (A7), исключающая серию из пяти одинаковых бит на стыке подблоков.
+const (
+	altSevenMinus = 0b0111
+	altSevenPlus  = 0b1000
+)
+
+// disparity возвращает разность числа единиц и нулей в группе из width бит.
+func disparity(code, width int) int {
+	ones := 0
+	for i := 0; i < width; i++ {
+		ones += (code >> i) & 1
+	}
+	return 2*ones - width
+}
+
+// eightBTenBStage — линейный код 8B/10B (Widmer–Franaszek) с учетом текущей диспаратности.
+// Приемник считает нарушением недопустимую кодовую группу и группу, диспаратность которой
+// не согласуется с текущей диспаратностью потока.
+type eightBTenBStage struct {
+	decode6 map[int]int // Шестибитная группа -> значение EDCBA
+	decode4 map[int]int // Четырехбитная группа -> значение HGF
+}
+
+func newEightBTenBStage(StageConfig) (StreamStage, error) {
+	s := &eightBTenBStage{decode6: map[int]int{}, decode4: map[int]int{}}
+	for v := range fiveBSixBMinus {
+		s.decode6[fiveBSixBMinus[v]] = v
+		s.decode6[fiveBSixBPlus[v]] = v
+	}
+	for v := range threeBFourBMinus {
+		s.decode4[threeBFourBMinus[v]] = v
+		s.decode4[threeBFourBPlus[v]] = v
+	}
+	s.decode4[altSevenMinus] = 7
+	s.decode4[altSevenPlus] = 7
+	return s, nil
+}
+
+func (s *eightBTenBStage) Name() string { return "8b10b" }
+
+// Apply кодирует каждый байт потока десятибитным символом, начиная с RD-.
+func (s *eightBTenBStage) Apply(bits []uint8) []uint8 {
+	bits = padBits(bits, 8)
+	out := make([]uint8, 0, len(bits)/8*10)
+	positive := false // Текущая диспаратность: false — RD-, true — RD+
+	for i := 0; i < len(bits); i += 8 {
+		v := bitsToUint(bits[i : i+8])
+		x, y := v&0x1F, v>>5
+
+		code6 := fiveBSixBMinus[x]
+		if positive {
+			code6 = fiveBSixBPlus[x]
+		}
+		if disparity(code6, 6) != 0 {
+			positive = !positive
+		}
+
+		code4 := threeBFourBMinus[y]
+		if positive {
+			code4 = threeBFourBPlus[y]
+		}
+		if y == 7 && ((!positive && (x == 17 || x == 18 || x == 20)) || (positive && (x == 11 || x == 13 || x == 14))) {
+			code4 = altSevenMinus
+			if positive {
+				code4 = altSevenPlus
+			}
+		}
+		if disparity(code4, 4) != 0 {
+			positive = !positive
+		}
+
+		out = appendUintBits(out, code6, 6)
+		out = appendUintBits(out, code4, 4)
+	}
+	return out
+}
+
+// Invert декодирует десятибитные символы, проверяя допустимость групп и текущую диспаратность.
+func (s *eightBTenBStage) Invert(bits []uint8, length int) ([]uint8, int) {
+	out := make([]uint8, 0, len(bits)/10*8)
+	violations := 0
+	positive := false
+	// checkGroup проверяет группу и обновляет текущую диспаратность; возвращает false при нарушении.
+	checkGroup := func(code, width int, table map[int]int) (int, bool) {
+		v, ok := table[code]
+		switch d := disparity(code, width); {
+		case !ok:
+			return 0, false
+		case d > 0:
+			ok = !positive // Группа с избытком единиц допустима только при RD-
+			positive = true
+		case d < 0:
+			ok = positive // Группа с избытком нулей допустима только при RD+
+			positive = false
+		}
+		return v, ok
+	}
+	for i := 0; i+10 <= len(bits); i += 10 {
+		x, ok6 := checkGroup(bitsToUint(bits[i:i+6]), 6, s.decode6)
+		y, ok4 := checkGroup(bitsToUint(bits[i+6:i+10]), 4, s.decode4)
+		if !ok6 || !ok4 {
+			violations++
+		}
+		out = appendUintBits(out, y<<5|x, 8)
+	}
+	return out[:length], violations
+}
+
+func init() {
+	RegisterStage("4b5b", newFourBFiveBStage)
+	RegisterStage("8b10b", newEightBTenBStage)
+}
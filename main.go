@@ -1,13 +1,20 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
-	"io"
 	"log"
+	"math/bits"
 	"math/rand"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
@@ -36,7 +43,7 @@ import (
       +------------------+------------------+
 		  			     |
 	+--------------------+---------------------+
-	| Кодирование [7,4] каждого блока (280 шт.)|
+	| Кодирование блоков кодеком (умолч. [7,4])|
 	+--------------------+---------------------+
 					     |
 	+--------------------+---------------------+
@@ -48,7 +55,7 @@ import (
 	+--------------------+----------------------+
 						 |
 	+--------------------+---------------------+
-	| Декодирование [7,4] каждого блока        |
+	| Декодирование каждого блока кодеком      |
 	| Проверка на ошибки (по синдрому)         |
 	+--------------------+---------------------+
 						 |
@@ -73,28 +80,53 @@ import (
 
 // Определение констант для лучшей читаемости и легкого изменения
 const (
-	ListenPort        = ":8081"                             // Порт, на котором слушает веб-сервер
-	TransferEndpoint  = "/transfer"                         // Конечная точка для пересылки данных
-	CodeEndpoint      = "/code"                             // Конечная точка для приема входных данных
-	TransferURL       = "http://localhost:8080/transfer"    // Полный URL целевого сервера (предполагается, что он запущен на 8080)
-	FixedPayloadSize  = 140                                 // X: Фиксированный размер полезной нагрузки в байтах (после паддинга/до кодирования)
-	InfoBitsPerBlock  = 4                                   // k: Количество информационных бит в блоке для кода [7,4]
-	CodedBitsPerBlock = 7                                   // n: Количество кодовых бит в блоке для кода [7,4]
-	PayloadBitLength  = FixedPayloadSize * 8                // Общее количество бит в полезной нагрузке (после паддинга)
-	NumCodingBlocks   = PayloadBitLength / InfoBitsPerBlock // Количество блоков [7,4] для кодирования (1120 / 4 = 280 блоков)
-	EncodedBitLength  = NumCodingBlocks * CodedBitsPerBlock // Общее количество бит после кодирования (280 * 7 = 1960 бит)
+	DefaultListenPort       = 8081                             // Порт, на котором слушает веб-сервер по умолчанию
+	TransferEndpoint        = "/transfer"                      // Конечная точка для пересылки данных
+	CodeEndpoint            = "/code"                          // Конечная точка для приема входных данных
+	DefaultTransferURL      = "http://localhost:8080/transfer" // Полный URL целевого сервера по умолчанию (предполагается, что он запущен на 8080)
+	DefaultPayloadSize      = 140                              // Размер полезной нагрузки по умолчанию в байтах
+	DefaultErrorProbability = 0.1                              // P по умолчанию: 10% ошибки в бите
+	DefaultLossProbability  = 0.02                             // R по умолчанию: 2% потери кадра
+	InfoBitsPerBlock        = 4                                // k: Количество информационных бит в блоке для кода [7,4]
+	CodedBitsPerBlock       = 7                                // n: Количество кодовых бит в блоке для кода [7,4]
 )
 
+// Адрес веб-сервера и размеры сегмента. Задаются при запуске флагами -port и -payload-size
+// (см. setPayloadSize) и после этого не меняются.
+var (
+	ListenPort       = fmt.Sprintf(":%d", DefaultListenPort) // Адрес, на котором слушает веб-сервер
+	FixedPayloadSize = DefaultPayloadSize                    // X: Фиксированный размер полезной нагрузки в байтах (после паддинга/до кодирования)
+	PayloadBitLength = FixedPayloadSize * 8                  // Общее количество бит в полезной нагрузке (после паддинга)
+	NumCodingBlocks  = PayloadBitLength / InfoBitsPerBlock   // Количество блоков [7,4] для кодирования (1120 / 4 = 280 блоков)
+	EncodedBitLength = NumCodingBlocks * CodedBitsPerBlock   // Общее количество бит после кодирования (280 * 7 = 1960 бит)
+)
+
+// setPayloadSize задает размер полезной нагрузки сегмента и пересчитывает зависящие от него размеры.
+func setPayloadSize(size int) {
+	FixedPayloadSize = size
+	PayloadBitLength = FixedPayloadSize * 8
+	NumCodingBlocks = PayloadBitLength / InfoBitsPerBlock
+	EncodedBitLength = NumCodingBlocks * CodedBitsPerBlock
+	shortLengthBits = bits.Len(uint(FixedPayloadSize))
+}
+
+// TransferURL — полный URL, на который пересылаются обработанные сегменты (параметр forward.url).
+// Для симуляции многозвенного канала это может быть /code следующего экземпляра канального уровня.
+var TransferURL = DefaultTransferURL
+
 // Segment представляет собой сегмент данных, передаваемый между уровнями.
 // Используется только внутри ChannelLayer.
 type Segment struct {
-	Payload       []byte `json:"payload"`        // Полезная нагрузка (часть текста или файла). Всегда FixedPayloadSize байт после паддинга.
-	Timestamp     int64  `json:"timestamp"`      // Временная метка отправителя (часть ID сообщения) в наносекундах.
-	TotalSegments int    `json:"total_segments"` // Общее количество сегментов для исходного сообщения
-	SegmentNumber int    `json:"segment_number"` // Порядковый номер данного сегмента (начинается с 1)
+	Payload        []byte `json:"payload"`         // Полезная нагрузка (часть текста или файла). Всегда FixedPayloadSize байт после паддинга.
+	Timestamp      int64  `json:"timestamp"`       // Временная метка отправителя (часть ID сообщения) в наносекундах.
+	TotalSegments  int    `json:"total_segments"`  // Общее количество сегментов для исходного сообщения
+	SegmentNumber  int    `json:"segment_number"`  // Порядковый номер данного сегмента (начинается с 1)
+	OriginalLength int    `json:"original_length"` // Длина полезной нагрузки до паддинга в байтах
 	// IsChannelError устанавливается Канальным уровнем, если декодирование сегмента не удалось
 	// (обнаружена неисправимая ошибка).
 	IsChannelError bool `json:"is_channel_error"`
+	// Corrected устанавливается Канальным уровнем, если декодер обнаружил и исправил ошибки канала.
+	Corrected bool `json:"corrected"`
 }
 
 // IncomingCodeRequest структура для парсинга входящего JSON на /code
@@ -104,6 +136,16 @@ type IncomingCodeRequest struct {
 	Sender        string `json:"sender"`
 	SendTime      string `json:"send_time"` // Приходит как строка
 	Payload       string `json:"payload"`   // Приходит как строка (может быть до FixedPayloadSize байт)
+	// Type — тип сегмента: "data" (по умолчанию) или "control" (управляющий сегмент верхних уровней:
+	// установление и разрыв соединения, keepalive).
+	Type string `json:"type,omitempty"`
+	// CallbackURL — необязательный адрес, на который POST запросом отправляется итог обработки
+	// и пересылки сегмента (полезно в асинхронном режиме).
+	CallbackURL string `json:"callback_url,omitempty"`
+	// Hops — сведения о пройденных звеньях, если сегмент переслан предыдущим экземпляром канального уровня.
+	Hops []HopRecord `json:"hops,omitempty"`
+	// Codec — необязательное имя кодека для этого сегмента (см. RegisterCoder); по умолчанию — кодек канала.
+	Codec string `json:"codec,omitempty"`
 }
 
 // OutgoingTransferRequest структура для формирования исходящего JSON на /transfer
@@ -111,8 +153,32 @@ type OutgoingTransferRequest struct {
 	SegmentNumber int    `json:"segment_number"`
 	TotalSegments int    `json:"total_segments"`
 	Sender        string `json:"sender"`
-	SendTime      string `json:"send_time"` // Отправляется как строка, как пришло
-	Payload       string `json:"payload"`   // Отправляется как строка (всегда FixedPayloadSize байт после паддинга и обработки)
+	SendTime      string `json:"send_time"`         // Отправляется как строка, как пришло
+	Payload       string `json:"payload,omitempty"` // Отправляется как строка (всегда FixedPayloadSize байт после паддинга и обработки; пусто в заглушке потерянного сегмента)
+	// Type передается для управляющих сегментов ("control"); для сегментов данных опускается.
+	Type string `json:"type,omitempty"`
+	// Lost устанавливается в заглушке, пересылаемой вместо потерянного в канале сегмента.
+	Lost bool `json:"lost,omitempty"`
+	// Degraded указывает действие режима деградации, в котором был обработан сегмент
+	// (например, "skip_impairments"). Пусто при нормальной обработке.
+	Degraded string `json:"degraded,omitempty"`
+	// IsChannelError устанавливается, если сегмент пересылается несмотря на неисправимую ошибку
+	// канала (channel_error_policy = "forward"); полезная нагрузка в этом случае может быть искажена.
+	IsChannelError bool `json:"is_channel_error,omitempty"`
+	// Corrected устанавливается, если декодер исправил ошибки канала: вместе с IsChannelError позволяет
+	// транспортному уровню различать сегменты без ошибок, с исправленными и с неисправимыми ошибками.
+	Corrected bool `json:"corrected,omitempty"`
+	// Recovered устанавливается для сегмента, потерянного в канале и восстановленного по кадру четности группы.
+	Recovered bool `json:"recovered,omitempty"`
+	// CRC32C — CRC-32C (Castagnoli) исходной полезной нагрузки без паддинга (8 шестнадцатеричных цифр),
+	// PayloadLength — ее длина в байтах. Передаются, если включен параметр crc32c.
+	CRC32C        string `json:"crc32c,omitempty"`
+	PayloadLength int    `json:"payload_length,omitempty"`
+	// Hops — сведения о звеньях, пройденных сегментом (последнее — этот экземпляр).
+	Hops []HopRecord `json:"hops,omitempty"`
+	// Link — порядковый номер кадра канального уровня и обнаруженные по нему пропуски, нарушения
+	// порядка и дубликаты (передается, если включена нумерация кадров).
+	Link *LinkSequenceInfo `json:"link,omitempty"`
 }
 
 // APIError структура для стандартизированного ответа при ошибке
@@ -122,15 +188,79 @@ type APIError struct {
 
 // ChannelLayer симулирует ненадежный канал связи с потерями и ошибками в битах.
 type ChannelLayer struct {
-	ErrorProbability float64    // P: Вероятность ошибки в бите передаваемого *закодированного* кадра
-	LossProbability  float64    // R: Вероятность потери всего *закодированного* кадра
-	rng              *rand.Rand // Собственный генератор случайных чисел для изоляции
+	ErrorProbability float64              // P: Вероятность ошибки в бите передаваемого *закодированного* кадра
+	LossProbability  float64              // R: Вероятность потери всего *закодированного* кадра
+	Coder            BlockCoder           // Кодек, которым кодируется полезная нагрузка (по умолчанию циклический [7,4])
+	FCS              *FrameCheck          // Контрольная последовательность кадра, проверяемая после декодирования (nil — не используется)
+	Puncture         *Puncturer           // Выкалывание закодированного потока (nil — не используется)
+	Scrambler        *Scrambler           // Скремблер информационных бит кадра (nil — не используется)
+	Shortening       bool                 // Передавать полезную нагрузку без дополнения, укорачивая последний блок кода
+	Retries          int                  // Число внутренних повторных передач потерянного или неисправимо искаженного кадра
+	HARQ             bool                 // Накапливать принятые передачи кадра и передавать повторно дополнительные проверочные биты
+	Decision         string               // Режим решений о принятых битах (см. Decision*; пусто — жесткие)
+	Stages           []StreamStage        // Этапы обработки закодированного потока перед передачей по каналу
+	Bitrate          float64              // Скорость передачи в канале (бит/с); 0 — кадр передается мгновенно
+	PropagationDelay time.Duration        // Задержка распространения сигнала (не зависит от длины кадра)
+	Impairments      *ImpairmentChain     // Цепочка искажений кадра в канале
+	Schedule         *ProbabilitySchedule // Расписание P и R во времени (nil — вероятности постоянны)
+	Outage           *LinkOutage          // Перерывы связи, во время которых теряются все кадры (nil — перерывов нет)
+	PathLoss         *PathLoss            // Модель затухания, задающая P через длину линии (nil — P постоянна)
+	link             *linkQueue           // Очередь кадров к линии (nil — кадры передаются независимо)
+	medium           *sharedMedium        // Общая полудуплексная среда с коллизиями (nil — кадры передаются независимо)
+	rng              *rand.Rand           // Собственный генератор случайных чисел для изоляции
+	source           *lockedSource        // Источник генератора (начальное значение и позиция для воспроизведения)
+}
+
+// lockedSource — источник случайных чисел, безопасный для использования из нескольких горутин.
+// Источник запоминает начальное значение и число выданных значений (позицию), по которым
+// последовательность можно воспроизвести.
+type lockedSource struct {
+	mu    sync.Mutex
+	src   rand.Source64
+	seed  int64
+	draws uint64
+}
+
+// newLockedSource создает источник с начальным значением seed.
+func newLockedSource(seed int64) *lockedSource {
+	return &lockedSource{src: rand.NewSource(seed).(rand.Source64), seed: seed}
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.draws++
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+	s.seed = seed
+	s.draws = 0
+}
+
+// State возвращает начальное значение и позицию источника.
+func (s *lockedSource) State() (seed int64, position uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seed, s.draws
 }
 
 // NewChannelLayer создает новый экземпляр Канального уровня с заданными вероятностями.
 func NewChannelLayer(errorProb, lossProb float64) *ChannelLayer {
-	// Использование NewSource с UnixNano обеспечивает более случайный начальный сид.
-	source := rand.NewSource(time.Now().UnixNano())
+	// Использование NewSource с UnixNano обеспечивает более случайный начальный сид
+	// (его можно заменить параметром seed конфигурации, см. Reseed).
+	// Источник защищен мьютексом, так как сегменты обрабатываются параллельно.
+	source := newLockedSource(time.Now().UnixNano())
 	rng := rand.New(source)
 
 	log.Printf("ChannelLayer: Создан с вероятностью ошибки бита P=%.4f и вероятностью потери кадра R=%.4f", errorProb, lossProb)
@@ -138,10 +268,180 @@ func NewChannelLayer(errorProb, lossProb float64) *ChannelLayer {
 	return &ChannelLayer{
 		ErrorProbability: errorProb,
 		LossProbability:  lossProb,
+		Coder:            cyclic74Coder{},
+		Impairments:      defaultImpairmentChain(),
 		rng:              rng,
+		source:           source,
 	}
 }
 
+// Reseed перезапускает генератор случайных чисел канала с начальным значением seed.
+func (cl *ChannelLayer) Reseed(seed int64) {
+	cl.rng.Seed(seed)
+}
+
+// RandState возвращает начальное значение и позицию генератора случайных чисел канала.
+func (cl *ChannelLayer) RandState() (seed int64, position uint64) {
+	return cl.source.State()
+}
+
+// ProcessOptions задает параметры обработки отдельного сегмента.
+type ProcessOptions struct {
+	SkipImpairments bool        // Не симулировать потерю кадра и ошибки в битах (кодирование и декодирование выполняются)
+	SkipCoding      bool        // Не кодировать и не декодировать: полезная нагрузка передается без изменений и без симуляции
+	Quiet           bool        // Не записывать в журнал шаги обработки (для массовых экспериментов)
+	Coder           BlockCoder  // Кодек сегмента вместо кодека канала (nil — кодек канала)
+	HARQ            *HARQBuffer // Буфер HARQ, накапливающий передачи кадра (nil — передачи декодируются независимо)
+}
+
+// logf записывает шаг обработки сегмента в журнал, если он не отключен параметром Quiet.
+func (o ProcessOptions) logf(format string, args ...interface{}) {
+	if !o.Quiet {
+		log.Printf(format, args...)
+	}
+}
+
+// Результаты декодирования сегмента (ChannelReport.Decode).
+const (
+	DecodeOK            = "ok"            // Ошибок не обнаружено
+	DecodeCorrected     = "corrected"     // Обнаруженные ошибки исправлены
+	DecodeUncorrectable = "uncorrectable" // Обнаружена неисправимая ошибка
+	DecodeSkipped       = "skipped"       // Кодирование и декодирование не выполнялись
+)
+
+// ChannelReport описывает, что произошло с сегментом при прохождении канала:
+// какие искажения были смоделированы и чем закончилось декодирование.
+type ChannelReport struct {
+	Codec              string            `json:"codec,omitempty"`               // Имя кодека (пусто, если кодирование не выполнялось)
+	CodeN              int               `json:"code_n,omitempty"`              // Длина кодового слова n (0, если кодирование не выполнялось)
+	CodeK              int               `json:"code_k,omitempty"`              // Число информационных бит в кодовом слове k
+	InfoBits           int               `json:"info_bits"`                     // Длина информационной части кадра в битах (после паддинга)
+	EncodedBits        int               `json:"encoded_bits"`                  // Длина закодированного кадра в битах (после выкалывания)
+	PuncturedBits      int               `json:"punctured_bits,omitempty"`      // Число бит, удаленных выкалыванием
+	Stages             []string          `json:"stages,omitempty"`              // Этапы обработки закодированного потока
+	TransmittedBits    int               `json:"transmitted_bits"`              // Длина кадра, переданного по каналу (после этапов обработки)
+	ImpairmentsSkipped bool              `json:"impairments_skipped,omitempty"` // Симуляция потерь и ошибок не выполнялась
+	Lost               bool              `json:"lost"`                          // Кадр потерян
+	Outage             bool              `json:"outage,omitempty"`              // Кадр потерян из-за перерыва связи
+	Collisions         int               `json:"collisions,omitempty"`          // Число кадров, передававшихся в общей среде одновременно с этим
+	FlippedBits        []int             `json:"flipped_bits,omitempty"`        // Индексы инвертированных битов переданного кадра
+	CorrectedBlocks    int               `json:"corrected_blocks"`              // Число блоков, в которых декодер исправил ошибку
+	ErrorBlocks        int               `json:"error_blocks"`                  // Число блоков с неисправимой ошибкой
+	StageViolations    int               `json:"stage_violations,omitempty"`    // Число нарушений правил кодирования, обнаруженных этапами
+	LengthMismatch     int               `json:"length_mismatch,omitempty"`     // Разность длин принятого и переданного кадров (бит)
+	ErasedBits         int               `json:"erased_bits,omitempty"`         // Число бит, стертых в канале (значение неизвестно приемнику)
+	ChannelBitErrors   int               `json:"channel_bit_errors"`            // Число бит, искаженных в канале
+	DecoderBitErrors   int               `json:"decoder_bit_errors"`            // Число ошибочных бит на входе декодера (после обращения этапов)
+	CRC32C             string            `json:"crc32c,omitempty"`              // Результат проверки CRC-32C (если включена)
+	FCSBits            int               `json:"fcs_bits,omitempty"`            // Длина контрольной последовательности кадра в битах
+	FCS                string            `json:"fcs,omitempty"`                 // Результат проверки контрольной последовательности кадра (см. FCS*)
+	Decision           string            `json:"decision,omitempty"`            // Режим решений декодера, если не жесткий (см. Decision*)
+	Decode             string            `json:"decode,omitempty"`              // Результат декодирования (см. Decode*)
+	QueueingMs         float64           `json:"queueing_ms,omitempty"`         // Ожидание окончания передачи предыдущих кадров
+	TransmissionMs     float64           `json:"transmission_ms,omitempty"`     // Время передачи кадра: TransmittedBits / Bitrate
+	PropagationMs      float64           `json:"propagation_ms,omitempty"`      // Задержка распространения
+	ExtraDelayMs       float64           `json:"extra_delay_ms,omitempty"`      // Дополнительная задержка, внесенная цепочкой искажений
+	Duplicates         int               `json:"duplicates,omitempty"`          // Число дополнительных копий, доставляемых транспортному уровню
+	DuplicateDelayMs   float64           `json:"duplicate_delay_ms,omitempty"`  // Интервал между доставкой копий (0 — сразу за сегментом)
+	Impairments        *ImpairmentRecord `json:"impairments,omitempty"`         // Случайные решения канала (nil, если симуляция пропущена)
+	ARQ                *ARQRecord        `json:"arq,omitempty"`                 // Передачи кадра по протоколу ARQ (nil, если ARQ отключен)
+	Retries            int               `json:"retries,omitempty"`             // Число внутренних повторных передач кадра (см. ChannelLayer.Retries)
+	RedundancyVersion  int               `json:"redundancy_version,omitempty"`  // Версия избыточности передачи HARQ (см. HARQBuffer)
+	RandSeed           int64             `json:"rand_seed,omitempty"`           // Начальное значение генератора случайных чисел канала
+	RandPosition       uint64            `json:"rand_position,omitempty"`       // Позиция генератора перед искажениями кадра
+	Arm                string            `json:"arm,omitempty"`                 // Вариант A/B-эксперимента, обработавший сегмент
+	Steps              []PipelineStep    `json:"-"`                             // Шаги обработки кадра (см. /segments/{id}/journey)
+	TxFrame            []uint8           `json:"-"`                             // Кадр, переданный в канал (до искажений)
+	RxFrame            []uint8           `json:"-"`                             // Кадр, принятый из канала (после искажений; nil, если потерян)
+}
+
+// ImpairmentRecord — случайные решения, принятые каналом для кадра: по нему можно проверить,
+// что симуляция соответствует заявленным вероятностям (кадр потерян, если LossDraw <= LossProbability,
+// бит инвертирован, если ErrorDraw <= ErrorProbability).
+type ImpairmentRecord struct {
+	LossProbability  float64  `json:"loss_probability"`       // Вероятность потери на момент обработки
+	LossDraw         float64  `json:"loss_draw"`              // Случайная величина решения о потере
+	ErrorProbability float64  `json:"error_probability"`      // Вероятность ошибки на момент обработки
+	ErrorDraw        *float64 `json:"error_draw,omitempty"`   // Случайная величина решения об ошибке (нет, если кадр потерян)
+	FlippedBits      []int    `json:"flipped_bits,omitempty"` // Индексы инвертированных бит
+	TransmittedBits  int      `json:"transmitted_bits"`       // Длина переданного кадра (диапазон индексов)
+	DelayMs          float64  `json:"delay_ms,omitempty"`     // Задержка кадра в канале
+}
+
+// Delay возвращает полную задержку кадра в канале (передача, распространение и дополнительная задержка).
+func (r ChannelReport) Delay() time.Duration {
+	return time.Duration((r.QueueingMs + r.TransmissionMs + r.PropagationMs + r.ExtraDelayMs) * float64(time.Millisecond))
+}
+
+// linkQueue — линия, по которой кадры передаются по одному: передача кадра начинается после
+// окончания передачи предыдущих, поэтому при потоке кадров быстрее скорости линии растет очередь,
+// а пропускная способность канала ограничена скоростью передачи.
+type linkQueue struct {
+	mu        sync.Mutex
+	busyUntil time.Time // Момент окончания передачи последнего поставленного в очередь кадра
+}
+
+// reserve занимает линию на время передачи transmission и возвращает ожидание начала передачи.
+func (q *linkQueue) reserve(transmission time.Duration) time.Duration {
+	if q == nil {
+		return 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	start := now
+	if q.busyUntil.After(now) {
+		start = q.busyUntil
+	}
+	q.busyUntil = start.Add(transmission)
+	return start.Sub(now)
+}
+
+// setFrameDelay рассчитывает задержку кадра длиной report.TransmittedBits:
+// время передачи t = L / C, ожидание освобождения линии (если кадры передаются по очереди)
+// и задержку распространения.
+func (cl *ChannelLayer) setFrameDelay(report *ChannelReport) {
+	if cl.Bitrate > 0 {
+		report.TransmissionMs = float64(report.TransmittedBits) / cl.Bitrate * 1000
+		transmission := time.Duration(float64(report.TransmittedBits) / cl.Bitrate * float64(time.Second))
+		report.QueueingMs = float64(cl.link.reserve(transmission).Microseconds()) / 1000
+	}
+	report.PropagationMs = float64(cl.PropagationDelay.Microseconds()) / 1000
+}
+
+// String возвращает краткое описание отчета для журнала жизненного цикла сегмента.
+func (r ChannelReport) String() string {
+	switch {
+	case r.Lost:
+		return "кадр потерян"
+	case r.Decode == DecodeSkipped:
+		return "кодирование пропущено"
+	}
+	desc := fmt.Sprintf("инвертировано бит: %d", len(r.FlippedBits))
+	if r.ImpairmentsSkipped {
+		desc = "симуляция искажений пропущена"
+	}
+	return fmt.Sprintf("%s, декодирование %s: %s (исправлено блоков: %d, с неисправимой ошибкой: %d)",
+		desc, r.Codec, r.Decode, r.CorrectedBlocks, r.ErrorBlocks)
+}
+
+// applyStages применяет этапы обработки потока к закодированному кадру в порядке конфигурации.
+// Возвращает передаваемый поток и длины потока перед каждым этапом (для обратного преобразования).
+// Если report не nil, этапы записываются в его шаги обработки.
+func (cl *ChannelLayer) applyStages(encoded []uint8, report *ChannelReport) ([]uint8, []int) {
+	channelBitStream := append([]uint8(nil), encoded...)
+	stageLengths := make([]int, len(cl.Stages))
+	for i, stage := range cl.Stages {
+		start := time.Now()
+		stageLengths[i] = len(channelBitStream)
+		channelBitStream = stage.Apply(channelBitStream)
+		if report != nil {
+			report.addStep(LayerChannel, "stage:"+stage.Name(), start, stageLengths[i], len(channelBitStream), "")
+		}
+	}
+	return channelBitStream, stageLengths
+}
+
 // ProcessSegment симулирует передачу сегмента через зашумленный канал.
 // Принимает сегмент (от Транспортного уровня), обрабатывает его (кодирование, симуляция
 // ошибок/потерь, декодирование) и возвращает обработанный сегмент (для Транспортного уровня)
@@ -149,7 +449,36 @@ func NewChannelLayer(errorProb, lossProb float64) *ChannelLayer {
 // Принимает внутреннюю структуру Segment с []byte payload и int64 Timestamp.
 // Ожидает payload РОВНО FixedPayloadSize байт после возможного паддинга.
 func (cl *ChannelLayer) ProcessSegment(inputSegment *Segment) *Segment {
-	log.Printf("ChannelLayer: Принят сегмент #%d/%d (timestamp %d), размер полезной нагрузки %d байт",
+	outputSegment, _ := cl.ProcessSegmentWith(inputSegment, ProcessOptions{})
+	return outputSegment
+}
+
+// ProcessSegmentWith выполняет ProcessSegment с заданными параметрами обработки
+// и дополнительно возвращает отчет о прохождении сегмента через канал. Если кадр потерян или
+// декодер обнаружил неисправимую ошибку, передача моделируется заново до cl.Retries раз; неудача
+// возвращается только после последней попытки, а отчет описывает последнюю передачу. При HARQ
+// попытки декодируются совместно с принятыми ранее.
+func (cl *ChannelLayer) ProcessSegmentWith(inputSegment *Segment, opts ProcessOptions) (*Segment, ChannelReport) {
+	if cl.HARQ {
+		opts.HARQ = NewHARQBuffer()
+	}
+	outputSegment, report := cl.transmitSegment(inputSegment, opts)
+	for retry := 1; retry <= cl.Retries && (outputSegment == nil || outputSegment.IsChannelError); retry++ {
+		reason := "потерян"
+		if outputSegment != nil {
+			reason = "принят с неисправимой ошибкой"
+		}
+		opts.logf("ChannelLayer: Кадр сегмента #%d/%d %s, повторная передача %d/%d",
+			inputSegment.SegmentNumber, inputSegment.TotalSegments, reason, retry, cl.Retries)
+		outputSegment, report = cl.transmitSegment(inputSegment, opts)
+		report.Retries = retry
+	}
+	return outputSegment, report
+}
+
+// transmitSegment моделирует однократную передачу кадра сегмента через канал.
+func (cl *ChannelLayer) transmitSegment(inputSegment *Segment, opts ProcessOptions) (*Segment, ChannelReport) {
+	opts.logf("ChannelLayer: Принят сегмент #%d/%d (timestamp %d), размер полезной нагрузки %d байт",
 		inputSegment.SegmentNumber, inputSegment.TotalSegments, inputSegment.Timestamp, len(inputSegment.Payload))
 
 	// Проверка размера входной полезной нагрузки: должна быть ровно FixedPayloadSize
@@ -163,12 +492,31 @@ func (cl *ChannelLayer) ProcessSegment(inputSegment *Segment) *Segment {
 			Timestamp:      inputSegment.Timestamp,
 			TotalSegments:  inputSegment.TotalSegments,
 			SegmentNumber:  inputSegment.SegmentNumber,
+			OriginalLength: inputSegment.OriginalLength,
 			IsChannelError: true, // Помечаем как неисправимую ошибку канала
 		}
-		return outputSegment
+		return outputSegment, ChannelReport{Decode: DecodeUncorrectable}
+	}
+
+	// Без кодирования симуляция ошибок не имеет смысла (их невозможно обнаружить),
+	// поэтому полезная нагрузка передается без изменений.
+	if opts.SkipCoding {
+		opts.logf("ChannelLayer: Кодирование и симуляция пропущены, полезная нагрузка передается без изменений.")
+		report := ChannelReport{InfoBits: PayloadBitLength, EncodedBits: PayloadBitLength, TransmittedBits: PayloadBitLength, ImpairmentsSkipped: true, Decode: DecodeSkipped}
+		report.addStep(LayerChannel, "passthrough", time.Now(), PayloadBitLength, PayloadBitLength, "кодирование и симуляция пропущены")
+		cl.setFrameDelay(&report)
+		report.TxFrame = bytesToBitStream(inputSegment.Payload)
+		report.RxFrame = report.TxFrame
+		return &Segment{
+			Payload:        append([]byte(nil), inputSegment.Payload...),
+			Timestamp:      inputSegment.Timestamp,
+			TotalSegments:  inputSegment.TotalSegments,
+			SegmentNumber:  inputSegment.SegmentNumber,
+			OriginalLength: inputSegment.OriginalLength,
+		}, report
 	}
 
-	// 1. Кодирование полезной нагрузки с использованием кода [7,4]
+	// 1. Кодирование полезной нагрузки выбранным кодеком (по умолчанию кодом [7,4])
 	// Преобразуем байты полезной нагрузки в поток битов.
 	bitStreamIn := bytesToBitStream(inputSegment.Payload) // FixedPayloadSize * 8 бит = 1120 бит
 
@@ -179,63 +527,281 @@ func (cl *ChannelLayer) ProcessSegment(inputSegment *Segment) *Segment {
 			Timestamp:      inputSegment.Timestamp,
 			TotalSegments:  inputSegment.TotalSegments,
 			SegmentNumber:  inputSegment.SegmentNumber,
+			OriginalLength: inputSegment.OriginalLength,
 			IsChannelError: true,
 		}
-		return outputSegment
+		return outputSegment, ChannelReport{Decode: DecodeUncorrectable}
 	}
-
-	// Выделяем память под закодированный поток битов. Каждый блок из 4 бит кодируется в 7 бит.
-	encodedBitStream := make([]uint8, EncodedBitLength) // NumCodingBlocks * CodedBitsPerBlock = 280 * 7 = 1960 бит
-
-	// Проходим по каждому блоку из 4 информационных бит и кодируем его.
-	for i := 0; i < NumCodingBlocks; i++ {
-		// Выбираем текущий блок информационных битов
-		blockIn := bitStreamIn[i*InfoBitsPerBlock : (i+1)*InfoBitsPerBlock]
-		// Кодируем блок
-		blockOut := cyclicEncode7_4Block(blockIn)
-		// Копируем результат кодирования (7 бит) в закодированный поток
-		copy(encodedBitStream[i*CodedBitsPerBlock:(i+1)*CodedBitsPerBlock], blockOut)
+	// При укорочении кода в кадр передаются заголовок с длиной полезной нагрузки и сама полезная
+	// нагрузка без нулевого дополнения до FixedPayloadSize байт
+	if cl.Shortening {
+		length := min(inputSegment.OriginalLength, FixedPayloadSize)
+		bitStreamIn = append(appendUintBits(nil, length, shortLengthBits), bitStreamIn[:length*8]...)
 	}
-	log.Printf("ChannelLayer: Закодировано %d бит в %d бит (блоков [7,4]: %d)", PayloadBitLength, EncodedBitLength, NumCodingBlocks)
 
-	// 2. Симуляция потери кадра
-	if cl.rng.Float64() <= cl.LossProbability {
-		log.Printf("ChannelLayer: Симуляция потери кадра для сегмента #%d/%d",
-			inputSegment.SegmentNumber, inputSegment.TotalSegments)
-		return nil // Кадр (весь закодированный сегмент) потерян
+	// Разбиваем поток на блоки по k бит (последний блок дополняется нулями) и кодируем каждый блок.
+	coder := cl.Coder
+	if opts.Coder != nil {
+		coder = opts.Coder
+	}
+	// Контрольная последовательность кадра дописывается к полезной нагрузке до кодирования
+	// и защищается кодеком вместе с ней; затем информационные биты скремблируются.
+	encodeStart := time.Now()
+	bitStreamIn = cl.FCS.Append(bitStreamIn)
+	encodedBitStream := encodeBitStream(coder, cl.Scrambler.Apply(bitStreamIn))
+	if cl.Shortening {
+		// Нулевое дополнение последнего блока известно приемнику и не передается
+		encodedBitStream = shortenBitStream(coder, encodedBitStream, len(bitStreamIn))
+	}
+	// Выкалывание повышает скорость кода: удаленные биты восстанавливаются как стирания перед декодированием
+	// (при HARQ повторные передачи содержат выколотые ранее биты)
+	motherLength := len(encodedBitStream)
+	encodedBitStream = opts.HARQ.Select(cl.Puncture, encodedBitStream)
+	report := ChannelReport{
+		Codec:              coder.Name(),
+		CodeN:              coder.N(),
+		CodeK:              coder.K(),
+		InfoBits:           len(bitStreamIn) - cl.FCS.Bits(),
+		FCSBits:            cl.FCS.Bits(),
+		EncodedBits:        len(encodedBitStream),
+		PuncturedBits:      motherLength - len(encodedBitStream),
+		ImpairmentsSkipped: opts.SkipImpairments,
+		RedundancyVersion:  opts.HARQ.Version(),
+	}
+	report.addStep(LayerChannel, "encode", encodeStart, len(bitStreamIn), len(encodedBitStream),
+		fmt.Sprintf("полезная нагрузка %d байт (дополнена до %d байт), кодек %s, блоков [%d,%d]: %d",
+			inputSegment.OriginalLength, FixedPayloadSize, coder.Name(), coder.N(), coder.K(), numBlocks(coder, len(bitStreamIn))))
+	opts.logf("ChannelLayer: Закодировано %d бит в %d бит (кодек %s, блоков [%d,%d]: %d)",
+		len(bitStreamIn), len(encodedBitStream), coder.Name(), coder.N(), coder.K(), numBlocks(coder, len(bitStreamIn)))
+
+	// 1a. Этапы обработки закодированного потока (перемежение и т.п.) в порядке конфигурации.
+	// Длина потока перед каждым этапом запоминается для обратного преобразования.
+	channelBitStream, stageLengths := cl.applyStages(encodedBitStream, &report)
+	for _, stage := range cl.Stages {
+		report.Stages = append(report.Stages, stage.Name())
+	}
+	report.TransmittedBits = len(channelBitStream)
+	cl.setFrameDelay(&report)
+	report.TxFrame = append([]uint8(nil), channelBitStream...)
+
+	// 2-3. Цепочка искажений канала (по умолчанию — потеря кадра с вероятностью R, затем инверсия
+	// одного бита с вероятностью P) применяется к кадру в том виде, в котором он передается по каналу,
+	// т.е. после этапов обработки потока. Случайные решения звеньев сохраняются в отчете.
+	var llr []float64          // Мягкие решения о битах кадра (только в режиме мягких решений)
+	var channelErasures []bool // Биты кадра, стертые в канале (nil — стираний нет)
+	if opts.SkipImpairments {
+		opts.logf("ChannelLayer: Симуляция потерь и ошибок пропущена.")
+	} else {
+		report.Impairments = &ImpairmentRecord{TransmittedBits: report.TransmittedBits}
+		report.RandSeed, report.RandPosition = cl.RandState()
+		opts.logf("ChannelLayer: Генератор случайных чисел: seed %d, позиция %d", report.RandSeed, report.RandPosition)
+		frame := &ChannelFrame{Bits: channelBitStream, Report: &report, Channel: cl, Rand: cl.rng, Segment: inputSegment}
+		frame.ErrorProbability, frame.LossProbability = cl.Schedule.Probabilities(cl.PathLoss.ErrorProbability(cl.ErrorProbability), cl.LossProbability)
+		channelStart := time.Now()
+		if outage, until := cl.Outage.Active(); outage {
+			// Во время перерыва связи кадр теряется независимо от R, цепочка искажений не применяется
+			opts.logf("ChannelLayer: Перерыв связи до %s", until.Format("15:04:05.000"))
+			frame.Lost = true
+			report.Outage = true
+		} else {
+			// В общей среде исход кадра известен по окончании передачи: кадр, с которым за это время
+			// столкнулся другой кадр, искажается после цепочки искажений
+			report.Collisions = cl.medium.transmit(time.Duration(report.TransmissionMs * float64(time.Millisecond)))
+			cl.Impairments.Apply(frame)
+			if report.Collisions > 0 && !frame.Lost {
+				opts.logf("ChannelLayer: Коллизия: кадр передавался одновременно с %d кадрами", report.Collisions)
+				cl.medium.collide(frame)
+			}
+		}
+		channelBitStream = frame.Bits // Звенья могут изменить длину кадра (проскальзывание бит, усечение)
+		llr = frame.LLR
+		channelErasures = frame.Erasures
+		report.Impairments.DelayMs = float64(report.Delay().Microseconds()) / 1000
+		if frame.Lost {
+			report.addStep(LayerPhysical, "transmit", channelStart, report.TransmittedBits, 0, "кадр потерян")
+		} else {
+			report.addStep(LayerPhysical, "transmit", channelStart, report.TransmittedBits, len(channelBitStream),
+				fmt.Sprintf("инвертировано бит: %d, задержка %.3f мс", len(report.FlippedBits), report.Impairments.DelayMs))
+		}
+		if frame.Lost {
+			opts.logf("ChannelLayer: Симуляция потери кадра для сегмента #%d/%d",
+				inputSegment.SegmentNumber, inputSegment.TotalSegments)
+			report.Lost = true
+			return nil, report // Кадр (весь закодированный сегмент) потерян
+		}
+		if len(report.FlippedBits) > 0 {
+			opts.logf("ChannelLayer: Симуляция ошибки в битах по индексам %v в закодированном потоке", report.FlippedBits)
+		} else {
+			opts.logf("ChannelLayer: Ошибка в бите не симулирована.")
+		}
 	}
 
-	// 3. Симуляция ошибки в бите (только если кадр не потерян)
-	// С вероятностью ErrorProbability, инвертируем один случайный бит в *закодированном* потоке.
-	if cl.rng.Float64() <= cl.ErrorProbability { // Используем Float66 для лучшего распределения
-		// Выбираем случайный индекс бита в закодированном потоке (длиной EncodedBitLength)
-		errorBitIndex := cl.rng.Intn(EncodedBitLength)
-		// Инвертируем бит: если 0, становится 1; если 1, становится 0.
-		encodedBitStream[errorBitIndex] = 1 - encodedBitStream[errorBitIndex]
-		log.Printf("ChannelLayer: Симуляция ошибки в бите по индексу %d в закодированном потоке", errorBitIndex)
+	report.RxFrame = append([]uint8(nil), channelBitStream...)
+	// 3c. Кадр, длина которого изменилась в канале (проскальзывание бит, усечение), не может быть
+	// разбит на кодовые слова: он приводится к переданной длине и считается принятым с неисправимой ошибкой.
+	if len(channelBitStream) != report.TransmittedBits {
+		report.LengthMismatch = len(channelBitStream) - report.TransmittedBits
+		opts.logf("ChannelLayer: Длина принятого кадра %d бит не совпадает с переданной (%d бит).",
+			len(channelBitStream), report.TransmittedBits)
+		channelBitStream = fitFrameLength(channelBitStream, report.TransmittedBits)
+		llr = nil
+		channelErasures = nil
+	}
+	// 3b. В режиме мягких решений каждому принятому биту сопоставляется LLR (если звенья цепочки
+	// не сформировали их сами), согласованный с ошибками, смоделированными цепочкой искажений.
+	// Уровень шума соответствует доле искаженных бит кадра (оценка отношения сигнал/шум приемником).
+	if cl.Decision == DecisionSoft {
+		report.Decision = DecisionSoft
+		if llr == nil {
+			ber := float64(len(report.FlippedBits)) / float64(max(len(channelBitStream), 1))
+			llr = channelLLR(report.TxFrame, channelBitStream, softNoiseSigma(ber), cl.rng)
+		}
 	} else {
-		log.Println("ChannelLayer: Ошибка в бите не симулирована.")
+		llr = nil
+	}
+	// 3d. Стертые в канале биты передаются декодеру: в режиме мягких решений как LLR = 0, в режиме
+	// жестких — маской стираний, которая переставляется вместе с битами при обращении этапов
+	// (этапы, не поддерживающие мягкие решения, маску не сохраняют).
+	var erasedMask []float64
+	for i, erased := range channelErasures {
+		if !erased {
+			continue
+		}
+		report.ErasedBits++
+		if llr != nil {
+			llr[i] = 0
+			continue
+		}
+		if erasedMask == nil {
+			erasedMask = make([]float64, len(channelErasures))
+		}
+		erasedMask[i] = 1
+	}
+	if report.ErasedBits > 0 {
+		opts.logf("ChannelLayer: Стерто %d бит кадра, отметки стираний переданы декодеру.", report.ErasedBits)
 	}
 
-	// 4. Декодирование полезной нагрузки с использованием кода [7,4]
-	// Выделяем память под декодированный поток битов (должен быть такого же размера, как и исходный поток битов)
-	decodedBitStream := make([]uint8, PayloadBitLength)
-	channelErrorDetected := false // Флаг для обнаружения неисправимых ошибок
+	// 3a. Обратное преобразование этапов обработки потока в обратном порядке.
+	// Нарушения правил кодирования, обнаруженные этапами, считаются ошибками канала.
+	for i := len(cl.Stages) - 1; i >= 0; i-- {
+		var violations int
+		start, inBits := time.Now(), len(channelBitStream)
+		channelBitStream, violations = cl.Stages[i].Invert(channelBitStream, stageLengths[i])
+		if llr != nil {
+			llr = cl.Stages[i].(SoftStage).InvertSoft(llr, stageLengths[i]) // Совместимость проверена validateDecision
+		}
+		if erasedMask != nil {
+			if stage, ok := cl.Stages[i].(SoftStage); ok {
+				erasedMask = stage.InvertSoft(erasedMask, stageLengths[i])
+			} else {
+				erasedMask = nil
+			}
+		}
+		report.StageViolations += violations
+		var detail string
+		if violations > 0 {
+			detail = fmt.Sprintf("нарушений кодирования: %d", violations)
+		}
+		report.addStep(LayerChannel, "invert:"+cl.Stages[i].Name(), start, inBits, len(channelBitStream), detail)
+	}
+	// Число ошибочных бит на входе декодера может превышать число ошибок в канале
+	// (например, при дифференциальном кодировании одна ошибка искажает два бита)
+	report.ChannelBitErrors = len(report.FlippedBits)
+	for i := range encodedBitStream {
+		if encodedBitStream[i] != channelBitStream[i] {
+			report.DecoderBitErrors++
+		}
+	}
+	encodedBitStream = channelBitStream
+
+	// 4. Декодирование полезной нагрузки выбранным кодеком
+	// Декодер каждого блока исправляет ошибки (если кодек это умеет) и сообщает о неисправимых ошибках.
+	// В режиме мягких решений декодер получает LLR (декодер кодека или алгоритм Чейза).
+	decodeStart := time.Now()
+	var decodedBitStream []uint8
+	var correctedBlocks, errorBlocks int
+	if opts.HARQ != nil {
+		// Принятые биты складываются с накопленными по предыдущим передачам кадра; бит, значения
+		// которого в передачах противоречат друг другу, декодируется как стертый
+		received := llr
+		if received == nil {
+			received = hardLLR(encodedBitStream, erasedMask)
+		}
+		combined := opts.HARQ.Combine(received)
+		if cl.Shortening {
+			combined = unshortenBitStream(coder, combined, len(bitStreamIn), shortenedLLR)
+		}
+		encodedBitStream = hardDecision(combined)
+		if llr != nil {
+			decodedBitStream, correctedBlocks, errorBlocks = decodeBitStreamSoft(coder, combined, len(bitStreamIn))
+		} else {
+			erasures := make([]bool, len(combined))
+			for i, l := range combined {
+				erasures[i] = l == 0
+			}
+			decodedBitStream, correctedBlocks, errorBlocks = decodeBitStreamWithErasures(coder, encodedBitStream, erasures, len(bitStreamIn))
+		}
+		if report.RedundancyVersion > 0 {
+			opts.logf("ChannelLayer: HARQ: передача с версией избыточности %d декодирована совместно с предыдущими", report.RedundancyVersion)
+		}
+	} else if llr != nil {
+		llr = cl.Puncture.RestoreSoft(llr, motherLength)
+		if cl.Shortening {
+			llr = unshortenBitStream(coder, llr, len(bitStreamIn), shortenedLLR)
+		}
+		encodedBitStream = hardDecision(llr)
+		decodedBitStream, correctedBlocks, errorBlocks = decodeBitStreamSoft(coder, llr, len(bitStreamIn))
+	} else {
+		var erasures []bool
+		encodedBitStream, erasures = cl.Puncture.Restore(encodedBitStream, motherLength)
+		if erasedMask != nil {
+			// Стирания канала добавляются к стираниям выколотых бит
+			if erasures == nil {
+				erasures = make([]bool, motherLength)
+			}
+			for i, mark := range cl.Puncture.RestoreSoft(erasedMask, motherLength) {
+				erasures[i] = erasures[i] || mark != 0
+			}
+		}
+		if cl.Shortening {
+			encodedBitStream = unshortenBitStream(coder, encodedBitStream, len(bitStreamIn), 0)
+			if erasures != nil {
+				erasures = unshortenBitStream(coder, erasures, len(bitStreamIn), false)
+			}
+		}
+		decodedBitStream, correctedBlocks, errorBlocks = decodeBitStreamWithErasures(coder, encodedBitStream, erasures, len(bitStreamIn))
+	}
+	report.addStep(LayerChannel, "decode", decodeStart, len(encodedBitStream), len(decodedBitStream),
+		fmt.Sprintf("исправлено блоков: %d, с неисправимой ошибкой: %d", correctedBlocks, errorBlocks))
+	// Обнаружена неисправимая ошибка в одном из блоков или этапов либо нарушена длина кадра
+	channelErrorDetected := errorBlocks > 0 || report.StageViolations > 0 || report.LengthMismatch != 0
+	report.CorrectedBlocks = correctedBlocks
+	report.ErrorBlocks = errorBlocks
+	opts.logf("ChannelLayer: Декодировано %d бит обратно в %d бит (исправлено блоков: %d, с неисправимой ошибкой: %d)",
+		len(encodedBitStream), len(decodedBitStream), correctedBlocks, errorBlocks)
+
+	// 4a. Проверка контрольной последовательности кадра. Несовпадение означает, что декодер
+	// пропустил ошибку или исправил блок неверно; оно учитывается отдельно от неисправимых ошибок кодека.
+	decodedBitStream, report.FCS = cl.FCS.Verify(cl.Scrambler.Apply(decodedBitStream))
+	fcsFailed := report.FCS == FCSFailed
+	if fcsFailed {
+		opts.logf("ChannelLayer: Контрольная последовательность кадра %s не совпала после декодирования.", cl.FCS.Name())
+	}
 
-	// Проходим по каждому блоку из 7 принятых битов и декодируем его.
-	for i := 0; i < NumCodingBlocks; i++ {
-		// Выбираем текущий блок принятых битов (который мог содержать ошибки)
-		blockIn := encodedBitStream[i*CodedBitsPerBlock : (i+1)*CodedBitsPerBlock]
-		// Декодируем блок. Функция пытается обнаружить ошибки.
-		blockOut, detectedError := cyclicDecode7_4Block(blockIn)
-		// Копируем результат декодирования (4 бита, независимо от того, была ли ошибка) в декодированный поток
-		copy(decodedBitStream[i*InfoBitsPerBlock:(i+1)*InfoBitsPerBlock], blockOut)
-		// Если декодер обнаружил ошибку в этом блоке, устанавливаем общий флаг ошибки канала.
-		if detectedError {
-			channelErrorDetected = true // Обнаружена неисправимая ошибка в одном из блоков
+	// Длина укороченной полезной нагрузки берется из заголовка кадра; заголовок, не согласованный
+	// с длиной кадра, означает необнаруженную декодером ошибку. Полезная нагрузка дополняется
+	// нулями до FixedPayloadSize байт только внутри канального уровня.
+	originalLength := inputSegment.OriginalLength
+	if cl.Shortening {
+		payloadBits := decodedBitStream[shortLengthBits:]
+		originalLength = len(payloadBits) / 8
+		if bitsToUint(decodedBitStream[:shortLengthBits]) != originalLength {
+			opts.logf("ChannelLayer: Заголовок длины укороченного кадра искажен.")
+			channelErrorDetected = true
 		}
+		decodedBitStream = make([]uint8, PayloadBitLength)
+		copy(decodedBitStream, payloadBits)
 	}
-	log.Printf("ChannelLayer: Декодировано %d бит обратно в %d бит", EncodedBitLength, PayloadBitLength)
 
 	// Преобразуем декодированный поток битов обратно в байты.
 	decodedPayload := bitStreamToBytes(decodedBitStream)
@@ -249,15 +815,22 @@ func (cl *ChannelLayer) ProcessSegment(inputSegment *Segment) *Segment {
 			Timestamp:      inputSegment.Timestamp,
 			TotalSegments:  inputSegment.TotalSegments,
 			SegmentNumber:  inputSegment.SegmentNumber,
+			OriginalLength: inputSegment.OriginalLength,
 			IsChannelError: true,
 		}
-		return outputSegment
+		report.Decode = DecodeUncorrectable
+		return outputSegment, report
 	}
 
 	if channelErrorDetected {
-		log.Println("ChannelLayer: Обнаружена неисправимая ошибка при декодировании.")
+		opts.logf("ChannelLayer: Обнаружена неисправимая ошибка при декодировании.")
+		report.Decode = DecodeUncorrectable
+	} else if correctedBlocks > 0 {
+		opts.logf("ChannelLayer: Декодирование успешно, ошибки исправлены.")
+		report.Decode = DecodeCorrected
 	} else {
-		log.Println("ChannelLayer: Декодирование успешно (ошибка отсутствовала или была исправлена).")
+		opts.logf("ChannelLayer: Декодирование успешно (ошибка отсутствовала или была исправлена).")
+		report.Decode = DecodeOK
 	}
 
 	// Создаем итоговый сегмент с декодированной полезной нагрузкой и флагом ошибки.
@@ -267,10 +840,12 @@ func (cl *ChannelLayer) ProcessSegment(inputSegment *Segment) *Segment {
 		Timestamp:      inputSegment.Timestamp,
 		TotalSegments:  inputSegment.TotalSegments,
 		SegmentNumber:  inputSegment.SegmentNumber,
-		IsChannelError: channelErrorDetected,
+		OriginalLength: originalLength,
+		IsChannelError: channelErrorDetected || fcsFailed,
+		Corrected:      report.Decode == DecodeCorrected && !fcsFailed,
 	}
 
-	return outputSegment
+	return outputSegment, report
 }
 
 // cyclicEncode7_4Block кодирует 4 информационных бита в 7 кодовых бит, используя циклический код [7,4].
@@ -394,7 +969,35 @@ func bitStreamToBytes(bitStream []uint8) []byte {
 	return byteData
 }
 
-var channelLayer *ChannelLayer // Глобальный экземпляр канального уровня
+var channelLayer *ChannelLayer             // Глобальный экземпляр канального уровня
+var quotaManager *QuotaManager             // Глобальный менеджер квот по ключам API
+var outboundJournal *OutboundJournal       // Глобальный журнал исходящих сегментов (nil, если отключен)
+var deduplicator *Deduplicator             // Глобальный детектор дубликатов (nil, если отключен)
+var stateStore StateStore                  // Глобальное хранилище разделяемого состояния
+var processingQueue *FairQueue             // Глобальная очередь обработки сегментов
+var overloadController *OverloadController // Глобальный контроллер перегрузки (nil, если отключен)
+var packetCapture *PacketCapture           // Глобальный захват кадров в pcapng (nil, если отключен)
+var frameTap *FrameTap                     // Глобальный ответвитель кадров (nil, если отключен)
+var linkARQ *ARQ                           // Глобальный протокол повторной передачи (nil, если отключен)
+var flowControl *FlowControl               // Глобальное управление потоком кредитами (nil, если отключено)
+var linkSequencer *LinkSequencer           // Глобальная нумерация кадров канального уровня (nil, если отключена)
+var linkTable *LinkTable                   // Глобальная таблица соединений с отправителями (nil, если соединение не требуется)
+var downstream *LivenessMonitor            // Глобальный контроль доступности транспортного уровня (nil, если отключен)
+var jitterBuffer *JitterBuffer             // Глобальный буфер джиттера (nil, если отключен)
+var asyncByDefault bool                    // Обрабатывать ли сегменты асинхронно, если клиент не указал режим явно
+
+// recordRejected учитывает в статистике сегмент, отклоненный до обработки.
+func recordRejected(req IncomingCodeRequest, payloadLen int) {
+	statistics.Record(SegmentOutcomeRecord{
+		Time:          time.Now().UTC(),
+		Sender:        req.Sender,
+		SendTime:      req.SendTime,
+		SegmentNumber: req.SegmentNumber,
+		TotalSegments: req.TotalSegments,
+		PayloadBytes:  payloadLen,
+		Outcome:       OutcomeRejected,
+	})
+}
 
 // handleCode обрабатывает входящие POST запросы на /code
 func handleCode(w http.ResponseWriter, r *http.Request) {
@@ -405,24 +1008,52 @@ func handleCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Версия формата ответа определяется до обработки, чтобы неверная версия не тратила квоту
+	apiVersion, err := requestedAPIVersion(r)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set(APIVersionHeader, strconv.Itoa(apiVersion))
+
 	var req IncomingCodeRequest
-	decoder := json.NewDecoder(r.Body)
 	// Ограничиваем размер читаемого тела запроса, чтобы избежать злонамеренных запросов
-	// Учитывая, что payload сам по себе до 140 байт, разумный лимит может быть, например, 1KB.
-	r.Body = http.MaxBytesReader(w, r.Body, 1024) // Ограничение до 1 KB
+	// Учитывая, что payload сам по себе до FixedPayloadSize байт (в JSON до 6 символов на байт),
+	// разумный лимит — 1KB на остальные поля плюс экранированная полезная нагрузка.
+	maxBodySize := int64(1024 + 6*FixedPayloadSize)
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(&req); err != nil {
 		// Проверяем, не была ли ошибка из-за превышения лимита
 		if _, ok := err.(*http.MaxBytesError); ok {
-			sendErrorResponse(w, fmt.Sprintf("Тело запроса слишком большое. Максимально допустимый размер — %d байт.", 1024), http.StatusRequestEntityTooLarge)
+			sendErrorResponse(w, fmt.Sprintf("Тело запроса слишком большое. Максимально допустимый размер — %d байт.", maxBodySize), http.StatusRequestEntityTooLarge)
 			return
 		}
 		sendErrorResponse(w, fmt.Sprintf("Не удалось декодировать запрос JSON: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	// Валидация размера полезной нагрузки: должна быть больше 0 и не более FixedPayloadSize
+	// Валидация типа сегмента
+	switch req.Type {
+	case "", SegmentTypeData, SegmentTypeControl:
+	default:
+		sendErrorResponse(w, fmt.Sprintf("Неизвестный тип сегмента '%s' (допустимо: %s, %s)", req.Type, SegmentTypeData, SegmentTypeControl), http.StatusBadRequest)
+		return
+	}
+
+	// Валидация кодека, выбранного для сегмента
+	var coder BlockCoder
+	if req.Codec != "" {
+		var err error
+		if coder, err = LookupCoder(req.Codec); err != nil {
+			sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Валидация размера полезной нагрузки: должна быть больше 0 (кроме управляющих сегментов) и не более FixedPayloadSize
 	originalPayloadBytes := []byte(req.Payload)
-	if len(originalPayloadBytes) == 0 {
+	if len(originalPayloadBytes) == 0 && req.Type != SegmentTypeControl {
 		sendErrorResponse(w, "Недопустимый размер полезной нагрузки: полезная нагрузка не может быть пустой.", http.StatusBadRequest)
 		return
 	}
@@ -430,127 +1061,144 @@ func handleCode(w http.ResponseWriter, r *http.Request) {
 		sendErrorResponse(w, fmt.Sprintf("Неверный размер полезной нагрузки: ожидалось %d байт или меньше, получено %d. Размер полезной нагрузки превышает максимально допустимый.", FixedPayloadSize, len(originalPayloadBytes)), http.StatusBadRequest)
 		return
 	}
+	// Размер кадра, согласованный с отправителем, может быть меньше FixedPayloadSize
+	linkParams, negotiated := negotiator.Lookup(req.Sender)
+	if negotiated && len(originalPayloadBytes) > linkParams.FrameSize {
+		sendErrorResponse(w, fmt.Sprintf("Неверный размер полезной нагрузки: согласованный размер кадра %d байт, получено %d.", linkParams.FrameSize, len(originalPayloadBytes)), http.StatusBadRequest)
+		return
+	}
 
-	// --- Паддинг полезной нагрузки до FixedPayloadSize байт ---
-	paddedPayloadBytes := make([]byte, FixedPayloadSize)
-	// Копируем оригинальные данные в начало нового среза.
-	// Остаток среза будет заполнен нулевыми байтами (\x00) по умолчанию.
-	copy(paddedPayloadBytes, originalPayloadBytes)
-	// ---------------------------------------------
-
-	// Парсинг строки send_time в time.Time
-	// Пытаемся распарсить в формате RFC3339 (рекомендуется)
-	parsedTime, err := time.Parse(time.RFC3339, req.SendTime)
-	if err != nil {
-		// Если RFC3339 не сработал, пробуем исходный формат из примера
-		parsedTime, err = time.Parse("2006-01-02 15:04:05 -0700 MST", req.SendTime)
-		if err != nil {
-			sendErrorResponse(w, fmt.Sprintf("Не удалось проанализировать send_time '%s': %v. Ожидается формат, аналогичный RFC3339 (например, '2006-01-02T15:04:05Z') или '2006-01-02 15:04:05 -0700 MST'.", req.SendTime, err), http.StatusBadRequest)
+	if req.CallbackURL != "" {
+		if err := validateCallbackURL(req.CallbackURL); err != nil {
+			sendErrorResponse(w, fmt.Sprintf("Неверный callback_url '%s': %v", req.CallbackURL, err), http.StatusBadRequest)
 			return
 		}
 	}
-
-	// Подготовка внутренней структуры Segment для обработки ChannelLayer
-	internalSegment := &Segment{
-		Payload:       paddedPayloadBytes,    // Используем паддированную полезную нагрузку (FixedPayloadSize байт)
-		Timestamp:     parsedTime.UnixNano(), // Используем метку времени в наносекундах
-		TotalSegments: req.TotalSegments,
-		SegmentNumber: req.SegmentNumber,
-		// IsChannelError будет установлен ChannelLayer
+	if err := validateHops(req.Hops); err != nil {
+		sendErrorResponse(w, fmt.Sprintf("Сегмент отклонен: %v", err), http.StatusLoopDetected)
+		return
 	}
 
-	log.Printf("Web Server: Принят сегмент #%d/%d от %s, обработка с полезной нагрузкой размера %d (ориг. %d)...",
-		req.SegmentNumber, req.TotalSegments, req.Sender, len(internalSegment.Payload), len(originalPayloadBytes))
-
-	// Обработка сегмента с использованием ChannelLayer
-	processedSegment := channelLayer.ProcessSegment(internalSegment)
+	job := &segmentJob{
+		ID:              newSegmentID(),
+		Request:         req,
+		OriginalPayload: originalPayloadBytes,
+		Coder:           coder,
+		Window:          linkParams.Window,
+		ReceivedAt:      time.Now(),
+		APIVersion:      apiVersion,
+	}
 
-	// --- Проверка результатов обработки канальным уровнем ---
-	if processedSegment == nil {
-		// Сегмент был потерян
-		log.Printf("Web Server: Сегмент #%d/%d потерян во время симуляции канала.", req.SegmentNumber, req.TotalSegments)
-		sendErrorResponse(w, "Сегмент потерян во время моделирования канала", http.StatusRequestTimeout) // 408 Request Timeout - разумный статус для потери
+	// Парсинг строки send_time в time.Time
+	parsedTime, err := parseSendTime(req.SendTime)
+	if err != nil {
+		sendErrorResponse(w, fmt.Sprintf("Не удалось проанализировать send_time '%s': %v. Ожидается формат, аналогичный RFC3339 (например, '2006-01-02T15:04:05Z') или '2006-01-02 15:04:05 -0700 MST'.", req.SendTime, err), http.StatusBadRequest)
 		return
 	}
-
-	if processedSegment.IsChannelError {
-		// Канальный уровень обнаружил неисправимую ошибку
-		log.Printf("Web Server: Канальный уровень обнаружил неисправимую ошибку для сегмента #%d/%d. Отправка ответа с ошибкой (Статус 500).", req.SegmentNumber, req.TotalSegments)
-		// Возвращаем 500, как запрошено, если канальный уровень не справился
-		sendErrorResponse(w, "Во время обработки обнаружена неисправимая ошибка канала", http.StatusInternalServerError)
+	job.Timestamp = parsedTime.UnixNano()
+
+	// Проверка квоты ключа API (сегменты в час, байты в сутки).
+	// Состояние квоты возвращается в заголовках X-Quota-* независимо от результата.
+	apiKey := r.Header.Get(APIKeyHeader)
+	quotaStatus := quotaManager.Consume(apiKey, int64(len(originalPayloadBytes)))
+	quotaStatus.SetHeaders(w.Header())
+	if !quotaStatus.Allowed {
+		recordRejected(req, len(originalPayloadBytes))
+		log.Printf("Web Server: Сегмент #%d/%d от %s отклонен: %s (ключ '%s')", req.SegmentNumber, req.TotalSegments, req.Sender, quotaStatus.Reason, apiKey)
+		w.Header().Set("Retry-After", fmt.Sprintf("%d", quotaStatus.RetryAfter(time.Now())))
+		sendErrorResponse(w, fmt.Sprintf("Квота исчерпана: %s.", quotaStatus.Reason), http.StatusTooManyRequests)
 		return
 	}
-	// --- Конец проверки результатов обработки канальным уровнем ---
-
-	// --- Обработка прошла успешно (нет потери, нет неисправимой ошибки). Теперь отправляем на /transfer ---
+	// Сегмент, отклоненный после проверки квоты, квоту не расходует
+	refundQuota := func() {
+		quotaManager.Refund(apiKey, int64(len(originalPayloadBytes)), quotaStatus).SetHeaders(w.Header())
+	}
 
-	// Используем обработанную полезную нагрузку из processedSegment и конвертируем ее обратно в строку.
-	// Она всегда будет FixedPayloadSize байт.
-	outgoingPayloadString := string(processedSegment.Payload)
+	// Пока транспортный уровень недоступен, сегменты могут отклоняться: переслать их все равно некуда
+	if downstream.Rejecting() {
+		recordRejected(req, len(originalPayloadBytes))
+		refundQuota()
+		log.Printf("Web Server: Сегмент #%d/%d от %s отклонен: транспортный уровень недоступен", req.SegmentNumber, req.TotalSegments, req.Sender)
+		w.Header().Set("Retry-After", strconv.Itoa(downstream.RetryAfter()))
+		sendErrorResponse(w, fmt.Sprintf("Транспортный уровень на %s недоступен (нет ответа на keepalive).", TransferURL), http.StatusServiceUnavailable)
+		return
+	}
 
-	outgoingRequest := OutgoingTransferRequest{
-		SegmentNumber: req.SegmentNumber,     // Используем оригинал из входящего запроса
-		TotalSegments: req.TotalSegments,     // Используем оригинал из входящего запроса
-		Sender:        req.Sender,            // Используем оригинал из входящего запроса
-		SendTime:      req.SendTime,          // Используем оригинальный строковый формат из входящего запроса
-		Payload:       outgoingPayloadString, // Используем обработанную (декодированную) и паддированную полезную нагрузку (как строку, всегда FixedPayloadSize символов/байт)
+	// Сегменты принимаются только по установленному соединению с отправителем
+	if !linkTable.Admit(req.Sender) {
+		recordRejected(req, len(originalPayloadBytes))
+		refundQuota()
+		log.Printf("Web Server: Сегмент #%d/%d от %s отклонен: соединение не установлено", req.SegmentNumber, req.TotalSegments, req.Sender)
+		sendErrorResponse(w, fmt.Sprintf("Соединение канального уровня не установлено: отправьте кадр %s на %s.", LinkFrameConnect, LinkEndpoint), http.StatusConflict)
+		return
 	}
 
-	outgoingJSON, err := json.Marshal(outgoingRequest)
+	// Управление потоком: сегмент занимает кредит отправителя до окончания обработки
+	credits, err := flowControl.Acquire(r.Context(), req.Sender)
 	if err != nil {
-		log.Printf("Web Server ERROR: Не удалось сериализовать исходящий JSON для сегмента #%d/%d: %v", req.SegmentNumber, req.TotalSegments, err)
-		sendErrorResponse(w, fmt.Sprintf("Не удалось упорядочить исходящий JSON: %v", err), http.StatusInternalServerError) // 500, т.к. внутренняя ошибка при подготовке к отправке
+		recordRejected(req, len(originalPayloadBytes))
+		refundQuota()
+		log.Printf("Web Server: Сегмент #%d/%d от %s отклонен управлением потоком: %v", req.SegmentNumber, req.TotalSegments, req.Sender, err)
+		flowControl.SetHeaders(w.Header(), 0)
+		sendErrorResponse(w, fmt.Sprintf("Сегмент отклонен управлением потоком: %v.", err), http.StatusTooManyRequests)
 		return
 	}
+	flowControl.SetHeaders(w.Header(), credits)
 
-	log.Printf("Web Server: Обработка канальным уровнем успешна. Отправка сегмента #%d/%d на %s с размером полезной нагрузки %d",
-		req.SegmentNumber, req.TotalSegments, TransferURL, len(outgoingRequest.Payload))
+	segmentRegistry.Register(job)
+	w.Header().Set("X-Segment-ID", job.ID)
+	if req.Type != SegmentTypeControl {
+		retransmitBuffer.Store(job)
+	}
 
-	// Отправка POST запроса на конечную точку /transfer
-	resp, err := http.Post(TransferURL, "application/json", bytes.NewBuffer(outgoingJSON))
-	if err != nil {
-		// Ошибка при отправке запроса на целевой сервер (например, целевой сервер недоступен)
-		log.Printf("Web Server ERROR: Не удалось отправить сегмент #%d/%d на целевую конечную точку (%s): %v", req.SegmentNumber, req.TotalSegments, TransferURL, err)
-		// Отправляем 500, т.к. конечный этап (отправка) не удался
-		sendErrorResponse(w, fmt.Sprintf("Не удалось отправить сегмент в конечную точку передачи: %v", err), http.StatusInternalServerError)
+	// Асинхронный режим: сразу отвечаем 202 с идентификатором сегмента, а обработка и пересылка
+	// выполняются в фоне. Итог можно узнать на /segments/{id}.
+	if asyncRequested(r) {
+		go func() {
+			processSegmentJob(context.Background(), job)
+			flowControl.Release(req.Sender)
+		}()
+
+		log.Printf("Web Server: Сегмент #%d/%d от %s принят в асинхронном режиме (id %s)", req.SegmentNumber, req.TotalSegments, req.Sender, job.ID)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":     "Сегмент принят к обработке.",
+			"segment_id": job.ID,
+			"status_url": SegmentsEndpoint + job.ID,
+		})
 		return
 	}
-	defer resp.Body.Close()
 
-	// Чтение ответа от конечной точки /transfer (опционально, для логирования/отладки)
-	body, errReadBody := io.ReadAll(resp.Body)
-	if errReadBody != nil {
-		log.Printf("Web Server WARNING: Не удалось прочитать тело ответа от конечной точки /transfer для сегмента #%d/%d: %v", req.SegmentNumber, req.TotalSegments, errReadBody)
-	} else {
-		log.Printf("Web Server: Получен ответ от конечной точки /transfer для сегмента #%d/%d (Status: %s): %s", req.SegmentNumber, req.TotalSegments, resp.Status, string(body))
+	// Синхронный режим: ответ на /code отражает итог обработки и пересылки
+	result := processSegmentJob(r.Context(), job)
+	flowControl.Release(req.Sender)
+	writeSegmentResult(w, job, result)
+}
+
+// parseSendTime разбирает send_time отправителя.
+func parseSendTime(sendTime string) (time.Time, error) {
+	// Пытаемся распарсить в формате RFC3339 (рекомендуется)
+	parsedTime, err := time.Parse(time.RFC3339, sendTime)
+	if err != nil {
+		// Если RFC3339 не сработал, пробуем исходный формат из примера
+		parsedTime, err = time.Parse(sendTimeLegacyLayout, sendTime)
 	}
+	return parsedTime, err
+}
 
-	// --- Проверяем статус ответа от /transfer и определяем итоговый статус ответа на /code ---
-	if resp.StatusCode == http.StatusOK {
-		// Канальный уровень успешно обработал сегмент И /transfer вернул 200.
-		// Это полное успешное выполнение для данного сегмента. Отвечаем 200.
-		w.WriteHeader(http.StatusOK)
-		responseMsg := map[string]interface{}{
-			"status":          "Сегмент обработан канальным уровнем и успешно передан.",
-			"transfer_status": resp.Status,
+// asyncRequested определяет, запрошена ли асинхронная обработка: параметром ?async=true|false,
+// заголовком "Prefer: respond-async" или (по умолчанию) настройкой async.enabled.
+func asyncRequested(r *http.Request) bool {
+	if v := r.URL.Query().Get("async"); v != "" {
+		async, err := strconv.ParseBool(v)
+		if err == nil {
+			return async
 		}
-		if body != nil {
-			responseMsg["transfer_response_body"] = string(body)
-		}
-		json.NewEncoder(w).Encode(responseMsg)
-		log.Printf("Web Server: Ответили на /code для сегмента #%d/%d со статусом OK (статус transfer: %s)", req.SegmentNumber, req.TotalSegments, resp.Status)
-	} else {
-		// Канальный уровень обработал успешно, но /transfer вернул НЕ 200 статус.
-		// Это означает, что отправка на следующий уровень не удалась.
-		// Отвечаем 500, так как весь процесс для данного сегмента не завершился успехом.
-		errMsg := fmt.Sprintf("Transfer to endpoint failed with status: %s", resp.Status)
-		if body != nil && len(body) > 0 {
-			errMsg += fmt.Sprintf(". Transfer response body: %s", string(body))
-		}
-		log.Printf("Web Server: Ответили на /code для сегмента #%d/%d со статусом 500 (статус transfer: %s)", req.SegmentNumber, req.TotalSegments, resp.Status)
-		sendErrorResponse(w, errMsg, http.StatusInternalServerError)
 	}
-	// --- Конец проверки статуса /transfer ---
+	if strings.Contains(r.Header.Get("Prefer"), "respond-async") {
+		return true
+	}
+	return asyncByDefault
 }
 
 // sendErrorResponse отправляет стандартизированный JSON ответ с ошибкой и логирует ее.
@@ -566,9 +1214,389 @@ func sendErrorResponse(w http.ResponseWriter, message string, statusCode int) {
 }
 
 func main() {
-	// Инициализация канального уровня с заданными вероятностями ошибки и потери
-	// При необходимости эти значения можно вынести в аргументы командной строки или файл конфигурации.
-	channelLayer = NewChannelLayer(0.1, 0.02) // Пример: P=0.1 (10% ошибки в бите), R=0.02 (2% потери кадра)
+	seed := flag.Int64("seed", 0, "начальное значение генератора случайных чисел канала (заменяет параметр seed конфигурации)")
+	port := flag.Int("port", DefaultListenPort, "порт веб-сервера")
+	transferURL := flag.String("transfer-url", "", "адрес пересылки сегментов (заменяет параметр forward.url конфигурации)")
+	errorProb := flag.Float64("p", DefaultErrorProbability, "P: вероятность ошибки в бите закодированного кадра")
+	lossProb := flag.Float64("r", DefaultLossProbability, "R: вероятность потери кадра")
+	payloadSize := flag.Int("payload-size", DefaultPayloadSize, "размер полезной нагрузки сегмента в байтах")
+	codec := flag.String("codec", "", "кодек полезной нагрузки (заменяет параметр codec конфигурации)")
+	flag.Parse()
+
+	if *port < 1 || *port > 65535 {
+		log.Fatalf("Неверный порт %d", *port)
+	}
+	ListenPort = fmt.Sprintf(":%d", *port)
+	if *payloadSize < 1 {
+		log.Fatalf("Неверный размер полезной нагрузки %d: должен быть положительным", *payloadSize)
+	}
+	setPayloadSize(*payloadSize)
+	for name, prob := range map[string]float64{"p": *errorProb, "r": *lossProb} {
+		if prob < 0 || prob > 1 {
+			log.Fatalf("Неверная вероятность -%s=%g: ожидается значение от 0 до 1", name, prob)
+		}
+	}
+
+	// Загрузка конфигурации (путь задается переменной окружения CHANNEL_LAYER_CONFIG)
+	config, err := LoadConfig(configPath())
+	if err != nil {
+		log.Fatalf("Не удалось загрузить конфигурацию: %v", err)
+	}
+	if *seed != 0 {
+		config.Seed = *seed
+	}
+	if *transferURL != "" {
+		config.Forward.URL = *transferURL
+	}
+	if *codec != "" {
+		config.Codec = *codec
+	}
+
+	// В режиме высокой доступности дожидаемся, пока экземпляр станет активным,
+	// и только затем открываем журнал и занимаем порт
+	if config.HA.LockFile != "" {
+		if err := acquireLeadership(config.HA.LockFile); err != nil {
+			log.Fatalf("Не удалось перейти в активный режим: %v", err)
+		}
+	}
+
+	quotaManager = NewQuotaManager(config.Quotas, config.DefaultQuota)
+
+	// Инициализация статистики и (при наличии пути в конфигурации) ее хранилища
+	var statsStore *StatsStore
+	if config.Stats.DBPath != "" {
+		statsStore, err = OpenStatsStore(config.Stats.DBPath)
+		if err != nil {
+			log.Fatalf("Не удалось открыть хранилище статистики: %v", err)
+		}
+		log.Printf("Статистика сохраняется в %s (итоги сегментов: %v)", config.Stats.DBPath, config.Stats.RecordSegments)
+	}
+	statistics = NewStatistics(statsStore, config.Stats.RecordSegments)
+	go statistics.Run(10 * time.Second)
+
+	// Хранилище разделяемого состояния и детектор дубликатов
+	stateStore, err = NewStateStore(config.State)
+	if err != nil {
+		log.Fatalf("Не удалось создать хранилище состояния: %v", err)
+	}
+	if config.Dedup.Enabled {
+		deduplicator = NewDeduplicator(stateStore, config.Dedup.TTL.Duration, config.Dedup.Lease.Duration)
+	}
+	negotiator = NewNegotiator(config.Negotiation, stateStore)
+
+	segmentRegistry = NewSegmentRegistry(config.Async.RegistrySize, stateStore, config.Async.RegistryTTL.Duration)
+	asyncByDefault = config.Async.Enabled
+	if config.API.DefaultVersion < APIVersion1 || config.API.DefaultVersion > APIVersion2 {
+		log.Fatalf("Неподдерживаемая версия API по умолчанию: %d", config.API.DefaultVersion)
+	}
+	defaultAPIVersion = config.API.DefaultVersion
+	forwardConfig = config.Forward
+	if config.Forward.URL != "" {
+		if err := validateCallbackURL(config.Forward.URL); err != nil {
+			log.Fatalf("Неверный адрес пересылки '%s': %v", config.Forward.URL, err)
+		}
+		TransferURL = config.Forward.URL
+	}
+	hopNodeName = config.Forward.NodeName
+	if hopNodeName == "" {
+		hopNodeName = defaultHopNodeName()
+	}
+	controlConfig = config.Control
+	downstream, err = NewLivenessMonitor(config.Keepalive)
+	if err != nil {
+		log.Fatalf("Неверная конфигурация keepalive: %v", err)
+	}
+	if downstream != nil {
+		action := "сегменты принимаются"
+		if config.Keepalive.RejectWhileDown {
+			action = "сегменты отклоняются (503)"
+		}
+		log.Printf("Web Server: Keepalive на %s каждые %s, пока транспортный уровень недоступен, %s", TransferURL, config.Keepalive.Interval, action)
+		go downstream.Run()
+	}
+	retransmitBuffer, err = NewRetransmitBuffer(config.Retransmit)
+	if err != nil {
+		log.Fatalf("Неверная конфигурация буфера повторной передачи: %v", err)
+	}
+	if retransmitBuffer != nil {
+		log.Printf("Web Server: Буфер повторной передачи на %d сообщений (хранение %s), запросы на %s", config.Retransmit.Messages, config.Retransmit.TTL, NakEndpoint)
+	}
+	crc32cEnabled = config.CRC32C
+	if config.Forward.NackURL != "" {
+		if err := validateCallbackURL(config.Forward.NackURL); err != nil {
+			log.Fatalf("Неверный адрес NACK '%s': %v", config.Forward.NackURL, err)
+		}
+		nackURL = config.Forward.NackURL
+		log.Printf("Отрицательные подтверждения отправляются на %s", nackURL)
+	}
+	switch config.ChannelErrorPolicy {
+	case ChannelErrorPolicyError, ChannelErrorPolicyDrop, ChannelErrorPolicyForward:
+		channelErrorPolicy = config.ChannelErrorPolicy
+	default:
+		log.Fatalf("Неизвестная политика обработки ошибок канала '%s' (допустимо: %s, %s, %s)",
+			config.ChannelErrorPolicy, ChannelErrorPolicyError, ChannelErrorPolicyDrop, ChannelErrorPolicyForward)
+	}
+
+	processingQueue = NewFairQueue(config.Queue.Workers, config.Queue.Capacity, config.Queue.Weights, config.Queue.DefaultWeight)
+	if config.Overload.Enabled {
+		overloadController, err = NewOverloadController(processingQueue, config.Overload)
+		if err != nil {
+			log.Fatalf("Не удалось создать контроллер перегрузки: %v", err)
+		}
+		go overloadController.Run(250 * time.Millisecond)
+	}
+
+	if config.ClockSkew.Offset.Duration != 0 || config.ClockSkew.DriftPPM != 0 {
+		clockSkew = &ClockSkew{
+			Offset:   config.ClockSkew.Offset.Duration,
+			DriftPPM: config.ClockSkew.DriftPPM,
+			Epoch:    time.Now(),
+		}
+		log.Printf("Метки времени на /transfer смещаются на %s с уходом %.1f ppm", clockSkew.Offset, clockSkew.DriftPPM)
+	}
+	if config.Jitter.Enabled {
+		jitterBuffer, err = NewJitterBuffer(config.Jitter)
+		if err != nil {
+			log.Fatalf("Не удалось создать буфер джиттера: %v", err)
+		}
+		log.Printf("Буфер джиттера: задержка воспроизведения %s, интервал между кадрами %s", config.Jitter.PlayoutDelay, config.Jitter.FrameInterval)
+	}
+
+	linkARQ, err = NewARQ(config.ARQ, stateStore)
+	if err != nil {
+		log.Fatalf("Неверная конфигурация ARQ: %v", err)
+	}
+	if linkARQ != nil {
+		log.Printf("ChannelLayer: ARQ: %s", linkARQ.Describe())
+	}
+
+	flowControl, err = NewFlowControl(config.FlowControl)
+	if err != nil {
+		log.Fatalf("Неверная конфигурация управления потоком: %v", err)
+	}
+	if flowControl != nil {
+		action := "отклоняются"
+		if config.FlowControl.Queue {
+			action = "ожидают кредита"
+		}
+		log.Printf("Web Server: Управление потоком: окно %d сегментов на отправителя, сегменты сверх окна %s", config.FlowControl.Window, action)
+	}
+
+	linkTable = NewLinkTable(config.Handshake, stateStore)
+	if linkTable != nil {
+		log.Printf("ChannelLayer: Сегменты принимаются только по соединению, установленному кадром %s на %s", LinkFrameConnect, LinkEndpoint)
+	}
+
+	linkSequencer = NewLinkSequencer(config.Sequence)
+	if linkSequencer != nil {
+		log.Printf("ChannelLayer: Кадры нумеруются порядковыми номерами канального уровня")
+	}
+
+	parityFEC, err = NewParityFEC(config.FEC)
+	if err != nil {
+		log.Fatalf("Неверная конфигурация межкадровой коррекции: %v", err)
+	}
+	if parityFEC != nil {
+		log.Printf("ChannelLayer: Кадр четности после каждых %d кадров данных сообщения", config.FEC.GroupSize)
+	}
+
+	if config.Capture.Path != "" {
+		packetCapture, err = OpenPacketCapture(config.Capture.Path)
+		if err != nil {
+			log.Fatalf("Не удалось открыть файл захвата кадров: %v", err)
+		}
+		log.Printf("Кадры записываются в %s (pcapng)", config.Capture.Path)
+	}
+	frameTap, err = NewFrameTap(config.Tap)
+	if err != nil {
+		log.Fatalf("Неверный адрес ответвителя кадров: %v", err)
+	}
+	if frameTap != nil {
+		log.Printf("Копии кадров отправляются на %s", config.Tap.URL)
+	}
+
+	payloadHooks, err = NewPayloadHooks(config.Hooks)
+	if err != nil {
+		log.Fatalf("Не удалось создать обработчики полезной нагрузки: %v", err)
+	}
+	for _, stage := range []string{HookStagePreCoding, HookStagePostDecoding} {
+		if payloadHooks.Has(stage) {
+			log.Printf("Обработчики полезной нагрузки %s: %s", stage, strings.Join(payloadHooks.Names(stage), " -> "))
+		}
+	}
+
+	// Открытие журнала исходящих сегментов
+	if config.Journal.Path != "" {
+		outboundJournal, err = OpenOutboundJournal(config.Journal.Path)
+		if err != nil {
+			log.Fatalf("Не удалось открыть журнал исходящих сегментов: %v", err)
+		}
+		log.Printf("Исходящие сегменты журналируются в %s", config.Journal.Path)
+	}
+
+	// При остановке процесса сохраняем незавершенную минуту статистики
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-stop
+		log.Printf("Получен сигнал %v, завершение работы", sig)
+		statistics.Flush()
+		os.Exit(0)
+	}()
+
+	// Инициализация канального уровня с вероятностями ошибки и потери из флагов -p и -r
+	channelLayer = NewChannelLayer(*errorProb, *lossProb)
+	if config.Seed != 0 {
+		channelLayer.Reseed(config.Seed)
+	}
+	channelSeed, _ := channelLayer.RandState()
+	log.Printf("ChannelLayer: Начальное значение генератора случайных чисел (seed): %d", channelSeed)
+	if err := RegisterCodecs(config.Codecs); err != nil {
+		log.Fatalf("Неверная конфигурация кодеков: %v", err)
+	}
+	channelLayer.Coder, err = LookupCoder(config.Codec)
+	if err != nil {
+		log.Fatalf("Не удалось выбрать кодек: %v", err)
+	}
+	log.Printf("ChannelLayer: Кодек %s [%d,%d]", channelLayer.Coder.Name(), channelLayer.Coder.N(), channelLayer.Coder.K())
+	channelLayer.FCS, err = NewFrameCheck(config.FCS)
+	if err != nil {
+		log.Fatalf("Неверная конфигурация FCS: %v", err)
+	}
+	if channelLayer.FCS != nil {
+		log.Printf("ChannelLayer: Контрольная последовательность кадра %s (%d бит)", channelLayer.FCS.Name(), channelLayer.FCS.Bits())
+	}
+	channelLayer.Shortening = config.Shortening
+	if config.Retries < 0 {
+		log.Fatalf("Число внутренних повторных передач не может быть отрицательным, задано %d", config.Retries)
+	}
+	if config.Retries > 0 && config.ARQ.Mode != "" {
+		log.Fatalf("Внутренние повторные передачи (retries) несовместимы с ARQ: повторные передачи выполняет протокол")
+	}
+	channelLayer.Retries = config.Retries
+	if config.HARQ && config.Retries == 0 {
+		log.Fatalf("HARQ требует повторных передач: задайте retries")
+	}
+	channelLayer.HARQ = config.HARQ
+	if channelLayer.HARQ {
+		log.Printf("ChannelLayer: HARQ: передачи кадра накапливаются и декодируются совместно")
+	}
+	if channelLayer.Retries > 0 {
+		log.Printf("ChannelLayer: Потерянный или неисправимо искаженный кадр передается повторно до %d раз", channelLayer.Retries)
+	}
+	if channelLayer.Shortening {
+		log.Printf("ChannelLayer: Укорочение кода: полезная нагрузка передается без дополнения до %d байт", FixedPayloadSize)
+	}
+	channelLayer.Schedule, err = NewProbabilitySchedule(config.Schedule)
+	if err != nil {
+		log.Fatalf("Неверная конфигурация расписания канала: %v", err)
+	}
+	if channelLayer.Schedule != nil {
+		log.Printf("ChannelLayer: P и R изменяются по расписанию из %d интервалов", len(config.Schedule.Steps))
+	}
+	channelLayer.PathLoss, err = NewPathLoss(config.PathLoss)
+	if err != nil {
+		log.Fatalf("Неверная конфигурация модели затухания: %v", err)
+	}
+	if pl := channelLayer.PathLoss; pl != nil {
+		log.Printf("ChannelLayer: P задается длиной линии %g м (показатель затухания %g): SNR %.2f дБ, P=%.3g",
+			pl.Distance(), config.PathLoss.Exponent, pl.SNRdB(), pl.ErrorProbability(0))
+	}
+	channelLayer.Outage, err = NewLinkOutage(config.Outages)
+	if err != nil {
+		log.Fatalf("Неверная конфигурация перерывов связи: %v", err)
+	}
+	if len(config.Outages) > 0 {
+		log.Printf("ChannelLayer: Запланировано перерывов связи: %d", len(config.Outages))
+	}
+	channelLayer.Scrambler, err = NewScrambler(config.Scrambler)
+	if err != nil {
+		log.Fatalf("Неверная конфигурация скремблера: %v", err)
+	}
+	if channelLayer.Scrambler != nil {
+		log.Printf("ChannelLayer: Скремблер %s", channelLayer.Scrambler.Name())
+	}
+	channelLayer.Puncture, err = NewPuncturer(config.Puncture)
+	if err != nil {
+		log.Fatalf("Неверная конфигурация выкалывания: %v", err)
+	}
+	if stats := channelLayer.PunctureStats(); stats != nil {
+		log.Printf("ChannelLayer: Выкалывание по шаблону %s, скорость кода %.3f вместо %.3f", stats.Pattern, stats.CodeRate, stats.MotherRate)
+	}
+	channelLayer.Stages, err = NewStreamStages(config.Stages)
+	if err != nil {
+		log.Fatalf("Не удалось создать этапы обработки потока: %v", err)
+	}
+	for _, stage := range channelLayer.Stages {
+		log.Printf("ChannelLayer: Этап обработки потока %s", stage.Name())
+	}
+	if err := validateDecision(config.Decision, channelLayer.Stages); err != nil {
+		log.Fatalf("Неверный режим решений: %v", err)
+	}
+	channelLayer.Decision = config.Decision
+	if channelLayer.Decision == DecisionSoft {
+		log.Printf("ChannelLayer: Мягкие решения (LLR) при декодировании")
+	}
+	if len(config.Impairments) > 0 {
+		channelLayer.Impairments, err = NewImpairmentChain(config.Impairments)
+		if err != nil {
+			log.Fatalf("Не удалось создать цепочку искажений: %v", err)
+		}
+	}
+	log.Printf("ChannelLayer: Цепочка искажений: %s", strings.Join(channelLayer.Impairments.Names(), " -> "))
+	if config.Link.Bitrate < 0 || config.Link.PropagationDelay.Duration < 0 {
+		log.Fatalf("Скорость передачи и задержка распространения канала не могут быть отрицательными")
+	}
+	channelLayer.Bitrate = config.Link.Bitrate
+	channelLayer.PropagationDelay = config.Link.PropagationDelay.Duration
+	if err := channelLayer.Impairments.CheckBitrate(channelLayer.Bitrate); err != nil {
+		log.Fatalf("Неверная цепочка искажений: %v", err)
+	}
+	if channelLayer.Bitrate > 0 || channelLayer.PropagationDelay > 0 {
+		log.Printf("ChannelLayer: Скорость передачи %.0f бит/с, задержка распространения %s", channelLayer.Bitrate, channelLayer.PropagationDelay)
+	}
+	if config.Link.Queueing {
+		if channelLayer.Bitrate <= 0 {
+			log.Fatalf("Очередь кадров к линии (link.queueing) требует скорости передачи (link.bitrate)")
+		}
+		channelLayer.link = &linkQueue{}
+		log.Printf("ChannelLayer: Кадры передаются по линии по одному, ожидая окончания передачи предыдущих")
+	}
+	if config.Link.HalfDuplex {
+		switch {
+		case config.Link.Queueing:
+			log.Fatalf("Общая полудуплексная среда (link.half_duplex) несовместима с очередью кадров (link.queueing)")
+		case channelLayer.Bitrate <= 0 && config.Link.Airtime.Duration <= 0:
+			log.Fatalf("Общая полудуплексная среда (link.half_duplex) требует скорости передачи (link.bitrate) или окна занятости эфира (link.airtime)")
+		}
+		collision := config.Link.Collision
+		if collision == "" {
+			collision = CollisionCorrupt
+		}
+		if collision != CollisionCorrupt && collision != CollisionLose {
+			log.Fatalf("Неизвестное действие при коллизии '%s' (допустимо: %s, %s)", config.Link.Collision, CollisionCorrupt, CollisionLose)
+		}
+		channelLayer.medium = &sharedMedium{airtime: config.Link.Airtime.Duration, collision: collision}
+		airtime := "время передачи кадра"
+		if config.Link.Airtime.Duration > 0 {
+			airtime = config.Link.Airtime.String()
+		}
+		log.Printf("ChannelLayer: Общая полудуплексная среда: окно занятости эфира — %s, при коллизии — %s", airtime, collision)
+	}
+	if len(config.AB.Arms) > 0 {
+		abSplit, err = NewABSplit(config.AB.Arms, channelLayer, config.Impairments)
+		if err != nil {
+			log.Fatalf("Не удалось настроить A/B-эксперимент: %v", err)
+		}
+		log.Printf("A/B-эксперимент: сегменты распределяются между вариантами %s", abSplit.Describe())
+	}
+	senderProfiles, err = NewSenderProfiles(config.Profiles, channelLayer)
+	if err != nil {
+		log.Fatalf("Неверная конфигурация профилей отправителей: %v", err)
+	}
+	if senderProfiles != nil {
+		log.Printf("ChannelLayer: Профили отправителей: %s", senderProfiles.Describe())
+	}
 
 	log.Println("--- Запуск веб-сервера на", ListenPort, "---")
 	log.Println("Прослушивание POST запросов на", CodeEndpoint)
@@ -576,9 +1604,46 @@ func main() {
 
 	// Регистрация обработчика для конечной точки /code
 	http.HandleFunc(CodeEndpoint, handleCode)
+	// Регистрация обработчиков статистики
+	http.HandleFunc(StatsEndpoint, handleStats)
+	http.HandleFunc(StatsHistoryEndpoint, handleStatsHistory)
+	http.HandleFunc(StatsSegmentsEndpoint, handleStatsSegments)
+	http.HandleFunc(EventsEndpoint, handleEvents)
+	http.HandleFunc(WSStatsEndpoint, handleWSStats)
+	// Регистрация обработчика состояния сегментов
+	http.HandleFunc(SegmentsEndpoint+"{id}", handleSegmentStatus)
+	http.HandleFunc(SegmentsEndpoint+"{sender}/{timestamp}/{n}", handleSegmentLifecycle)
+	http.HandleFunc(SegmentsEndpoint+"{id}/journey", handleSegmentJourney)
+	// Автоматический поиск предельной вероятности ошибки
+	http.HandleFunc(ExperimentMaxErrorRateEndpoint, handleExperimentMaxErrorRate)
+	// Отладочная форма манчестерского сигнала кадра
+	http.HandleFunc(DebugManchesterEndpoint, handleDebugManchester)
+	// Отладочный дамп кадра сегмента до и после искажений
+	http.HandleFunc(DebugFramesEndpoint+"{id}/hex", handleDebugFrameHex)
+	// Начальное значение генератора случайных чисел канала
+	http.HandleFunc(AdminSeedEndpoint, handleAdminSeed)
+	// Перерывы связи
+	http.HandleFunc(AdminOutageEndpoint, handleAdminOutage)
+	// Длина линии связи (модель затухания)
+	http.HandleFunc(AdminDistanceEndpoint, handleAdminDistance)
+	// Установление и разрыв соединений канального уровня
+	http.HandleFunc(LinkEndpoint, handleLink)
+	http.HandleFunc(AdminLinksEndpoint, handleAdminLinks)
+	// Запросы повторной передачи сегментов из буфера
+	http.HandleFunc(NakEndpoint, handleNak)
+	// Согласование параметров звена
+	http.HandleFunc(NegotiateEndpoint, handleNegotiate)
+	// Настройка таймера и окна ARQ во время работы
+	http.HandleFunc(AdminARQEndpoint, handleAdminARQ)
+	// Просмотр хранилища разделяемого состояния
+	http.HandleFunc(StateEndpoint, handleState)
+	http.HandleFunc(StateEndpoint+"/compact", handleState)
+
+	// Повторная отправка сегментов, не подтвержденных до предыдущей остановки процесса
+	go outboundJournal.RecoverJournal()
 
 	// Запуск HTTP сервера. log.Fatalf вызывается при фатальной ошибке (например, порт уже занят).
-	err := http.ListenAndServe(ListenPort, nil)
+	err = http.ListenAndServe(ListenPort, nil)
 	if err != nil {
 		log.Fatalf("Не удалось запустить сервер: %v", err)
 	}
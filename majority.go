@@ -0,0 +1,118 @@
+package main
+
+// Исправляющие декодеры кода [7,4]. Исходный декодер cyclic74 только обнаруживает ошибки;
+// здесь реализованы два способа исправления одиночной ошибки из учебника, дающие одинаковый
+// результат на любом принятом слове:
+//   - cyclic74_syndrome — табличный: ненулевой синдром (s2, s1, s0) указывает позицию ошибки;
+//   - cyclic74_majority — мажоритарный: каждый информационный бит оценивается голосованием
+//     принятого значения и всех проверочных сумм, содержащих этот бит.
+//
+// Позиции в кодовом слове нумеруются как в срезе: 0 — v6, ..., 6 — v0.
+
+// cyclic74Checks — проверочные суммы синдрома (s0, s1, s2): позиции, сумма которых по модулю 2
+// равна нулю для кодового слова (см. cyclicDecode7_4Block).
+var cyclic74Checks = [3][]int{
+	{6, 3, 2, 0}, // s0 = v0 + v3 + v4 + v6
+	{5, 3, 1, 0}, // s1 = v1 + v3 + v5 + v6
+	{4, 2, 1, 0}, // s2 = v2 + v4 + v5 + v6
+}
+
+var (
+	cyclic74SyndromeTable [8]int  // Синдром (s2 s1 s0) -> позиция одиночной ошибки (-1 для нулевого синдрома)
+	cyclic74DualChecks    [][]int // Все ненулевые комбинации проверочных сумм (слова дуального кода)
+)
+
+func init() {
+	cyclic74SyndromeTable[0] = -1
+	for pos := 0; pos < CodedBitsPerBlock; pos++ {
+		syndrome := 0
+		for j, check := range cyclic74Checks {
+			for _, p := range check {
+				if p == pos {
+					syndrome |= 1 << j
+				}
+			}
+		}
+		cyclic74SyndromeTable[syndrome] = pos
+	}
+	for mask := 1; mask < 1<<len(cyclic74Checks); mask++ {
+		var inCheck [CodedBitsPerBlock]uint8
+		for j, check := range cyclic74Checks {
+			if mask>>j&1 == 1 {
+				for _, p := range check {
+					inCheck[p] ^= 1
+				}
+			}
+		}
+		var positions []int
+		for p, in := range inCheck {
+			if in == 1 {
+				positions = append(positions, p)
+			}
+		}
+		cyclic74DualChecks = append(cyclic74DualChecks, positions)
+	}
+
+	RegisterCoder(cyclic74SyndromeCoder{})
+	RegisterCoder(cyclic74MajorityCoder{})
+}
+
+// cyclic74SyndromeCoder — код [7,4] с табличным исправлением одиночной ошибки по синдрому.
+type cyclic74SyndromeCoder struct{ cyclic74Coder }
+
+func (cyclic74SyndromeCoder) Name() string { return "cyclic74_syndrome" }
+
+func (cyclic74SyndromeCoder) DecodeBlock(codedBits []uint8) ([]uint8, bool, bool) {
+	received := append([]uint8(nil), codedBits...)
+	syndrome := 0
+	for j, check := range cyclic74Checks {
+		var s uint8
+		for _, p := range check {
+			s ^= received[p]
+		}
+		syndrome |= int(s) << j
+	}
+	if pos := cyclic74SyndromeTable[syndrome]; pos >= 0 {
+		received[pos] ^= 1
+	}
+	return received[:InfoBitsPerBlock], syndrome != 0, false
+}
+
+// cyclic74MajorityCoder — код [7,4] с мажоритарным декодированием. Для информационного бита
+// голосуют принятое значение и четыре оценки по словам дуального кода, содержащим этот бит
+// (сумма остальных бит слова). Одиночная ошибка в другой позиции искажает ровно две оценки
+// из четырех, ошибка в самом бите — только принятое значение, поэтому большинство из пяти
+// голосов всегда верно.
+type cyclic74MajorityCoder struct{ cyclic74Coder }
+
+func (cyclic74MajorityCoder) Name() string { return "cyclic74_majority" }
+
+func (cyclic74MajorityCoder) DecodeBlock(codedBits []uint8) ([]uint8, bool, bool) {
+	infoBits := make([]uint8, InfoBitsPerBlock)
+	corrected := false
+	for pos := range infoBits {
+		votes, ones := 1, int(codedBits[pos])
+		for _, check := range cyclic74DualChecks {
+			estimate, contains := uint8(0), false
+			for _, p := range check {
+				if p == pos {
+					contains = true
+				} else {
+					estimate ^= codedBits[p]
+				}
+			}
+			if !contains {
+				continue
+			}
+			votes++
+			ones += int(estimate)
+			if estimate != codedBits[pos] {
+				corrected = true // Хотя бы одна проверочная сумма нарушена
+			}
+		}
+		if 2*ones > votes {
+			infoBits[pos] = 1
+		}
+	}
+	return infoBits, corrected, false
+}
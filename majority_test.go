@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+// TestCyclic74DecodersAgree сравнивает табличный и мажоритарный декодеры кода [7,4] на всех
+// 128 возможных принятых словах; расхождение означает ошибку в одной из реализаций.
+func TestCyclic74DecodersAgree(t *testing.T) {
+	syndromeDecoder, majorityDecoder := cyclic74SyndromeCoder{}, cyclic74MajorityCoder{}
+	word := make([]uint8, CodedBitsPerBlock)
+	for v := 0; v < 1<<CodedBitsPerBlock; v++ {
+		for i := range word {
+			word[i] = uint8(v>>(CodedBitsPerBlock-1-i)) & 1
+		}
+		info1, corrected1, _ := syndromeDecoder.DecodeBlock(word)
+		info2, corrected2, _ := majorityDecoder.DecodeBlock(word)
+		if bitsToUint(info1) != bitsToUint(info2) || corrected1 != corrected2 {
+			t.Errorf("слово %07b: табличный декодер %v (исправлено: %t), мажоритарный %v (исправлено: %t)",
+				v, info1, corrected1, info2, corrected2)
+		}
+	}
+}
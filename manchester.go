@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// DebugManchesterEndpoint — конечная точка с манчестерской формой сигнала кадра (для демонстраций).
+const DebugManchesterEndpoint = "/debug/manchester"
+
+// Параметры построения формы сигнала.
+const (
+	defaultManchesterSamplesPerBit = 8  // Отсчетов на бит по умолчанию
+	maxManchesterSamplesPerBit     = 64 // Максимальное число отсчетов на бит
+)
+
+// manchesterStage — манчестерское кодирование (соглашение IEEE 802.3): бит 0 передается переходом
+// от высокого уровня к низкому (полубиты 10), бит 1 — от низкого к высокому (01). Переход в середине
+// каждого бита обеспечивает синхронизацию приемника ценой удвоения полосы. Пары полубитов 00 и 11
+// (отсутствие перехода) недопустимы и считаются нарушением кодирования.
+type manchesterStage struct{}
+
+func newManchesterStage(StageConfig) (StreamStage, error) {
+	return manchesterStage{}, nil
+}
+
+func (manchesterStage) Name() string { return "manchester" }
+
+// Apply заменяет каждый бит парой полубитов.
+func (manchesterStage) Apply(bits []uint8) []uint8 {
+	return manchesterEncode(bits)
+}
+
+// Invert декодирует пары полубитов; пара без перехода декодируется нулем и считается нарушением.
+func (manchesterStage) Invert(bits []uint8, length int) ([]uint8, int) {
+	out := make([]uint8, 0, len(bits)/2)
+	violations := 0
+	for i := 0; i+2 <= len(bits); i += 2 {
+		switch {
+		case bits[i] == 0 && bits[i+1] == 1:
+			out = append(out, 1)
+		case bits[i] == 1 && bits[i+1] == 0:
+			out = append(out, 0)
+		default:
+			out = append(out, 0)
+			violations++
+		}
+	}
+	return out[:length], violations
+}
+
+// manchesterEncode возвращает последовательность полубитов манчестерского кода.
+func manchesterEncode(bits []uint8) []uint8 {
+	out := make([]uint8, 0, len(bits)*2)
+	for _, b := range bits {
+		out = append(out, 1-b, b)
+	}
+	return out
+}
+
+// ManchesterWaveform — форма манчестерского сигнала кадра: уровни +1/-1 с заданным числом отсчетов на бит.
+type ManchesterWaveform struct {
+	Codec           string   `json:"codec"`            // Кодек полезной нагрузки
+	Stages          []string `json:"stages"`           // Этапы обработки потока
+	FrameBits       int      `json:"frame_bits"`       // Длина передаваемого кадра в битах (до манчестерского кодирования)
+	Offset          int      `json:"offset"`           // Номер первого бита фрагмента
+	Bits            string   `json:"bits"`             // Биты фрагмента кадра
+	SamplesPerBit   int      `json:"samples_per_bit"`  // Отсчетов на бит (по половине на каждый полубит)
+	Samples         []int    `json:"samples"`          // Отсчеты сигнала (+1 — высокий уровень, -1 — низкий)
+	ManchesterStage bool     `json:"manchester_stage"` // Манчестерское кодирование входит в этапы обработки потока канала
+}
+
+// frameWaveformBits возвращает передаваемый по каналу кадр для полезной нагрузки (после паддинга,
+// кодирования и этапов обработки потока) и признак того, что последним этапом уже является
+// манчестерское кодирование (тогда кадр — последовательность полубитов).
+func (cl *ChannelLayer) frameWaveformBits(payload []byte) ([]uint8, bool) {
+	padded := make([]byte, FixedPayloadSize)
+	copy(padded, payload)
+	frame, _ := cl.applyStages(encodeBitStream(cl.Coder, bytesToBitStream(padded)), nil)
+	if n := len(cl.Stages); n > 0 {
+		if _, ok := cl.Stages[n-1].(manchesterStage); ok {
+			return frame, true
+		}
+	}
+	return frame, false
+}
+
+// handleDebugManchester возвращает манчестерскую форму сигнала кадра для полезной нагрузки из параметра
+// payload с учетом текущего кодека и этапов обработки потока. Параметры offset и count задают
+// фрагмент кадра (в битах), samples_per_bit — число отсчетов на бит (четное, по умолчанию 8).
+func handleDebugManchester(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	payload := []byte(query.Get("payload"))
+	if len(payload) > FixedPayloadSize {
+		sendErrorResponse(w, fmt.Sprintf("Размер полезной нагрузки превышает %d байт", FixedPayloadSize), http.StatusBadRequest)
+		return
+	}
+	intParam := func(name string, def int) (int, error) {
+		v := query.Get(name)
+		if v == "" {
+			return def, nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("Неверное значение параметра %s: '%s'", name, v)
+		}
+		return n, nil
+	}
+	samplesPerBit, err := intParam("samples_per_bit", defaultManchesterSamplesPerBit)
+	if err == nil && (samplesPerBit < 2 || samplesPerBit%2 != 0 || samplesPerBit > maxManchesterSamplesPerBit) {
+		err = fmt.Errorf("Параметр samples_per_bit должен быть четным числом от 2 до %d", maxManchesterSamplesPerBit)
+	}
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	frame, isChips := channelLayer.frameWaveformBits(payload)
+	// Если манчестерское кодирование уже выполнено этапом канала, кадр состоит из полубитов
+	chips := frame
+	frameBits := len(frame)
+	if isChips {
+		frameBits /= 2
+	} else {
+		chips = manchesterEncode(frame)
+	}
+
+	offset, err := intParam("offset", 0)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	count, err := intParam("count", frameBits)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if offset > frameBits {
+		offset = frameBits
+	}
+	if offset+count > frameBits {
+		count = frameBits - offset
+	}
+
+	waveform := ManchesterWaveform{
+		Codec:           channelLayer.Coder.Name(),
+		Stages:          []string{},
+		FrameBits:       frameBits,
+		Offset:          offset,
+		SamplesPerBit:   samplesPerBit,
+		Samples:         make([]int, 0, count*samplesPerBit),
+		ManchesterStage: isChips,
+	}
+	for _, stage := range channelLayer.Stages {
+		waveform.Stages = append(waveform.Stages, stage.Name())
+	}
+	bits := make([]byte, 0, count)
+	for i := offset; i < offset+count; i++ {
+		first, second := chips[2*i], chips[2*i+1]
+		bits = append(bits, '0'+second) // Значение бита определяется уровнем второй половины
+		for _, chip := range []uint8{first, second} {
+			level := -1
+			if chip == 1 {
+				level = 1
+			}
+			for s := 0; s < samplesPerBit/2; s++ {
+				waveform.Samples = append(waveform.Samples, level)
+			}
+		}
+	}
+	waveform.Bits = string(bits)
+	json.NewEncoder(w).Encode(waveform)
+}
+
+func init() {
+	RegisterStage("manchester", newManchesterStage)
+}
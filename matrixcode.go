@@ -0,0 +1,390 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Ограничения линейных кодов, заданных матрицами.
+const (
+	matrixCodeMaxK        = 20      // Для вычисления минимального расстояния перебираются все 2^k кодовых слов
+	matrixCodeMaxSyndrome = 63      // n-k: синдром хранится в uint64
+	matrixCodeMaxPatterns = 1 << 20 // Наибольший размер таблицы синдромов
+)
+
+// matrixCodeFile — файл с матрицами линейного кода в формате JSON. Строки матриц задаются
+// строками из 0 и 1 ("1000110") или массивами ([1, 0, 0, 0, 1, 1, 0]).
+type matrixCodeFile struct {
+	Generator   []json.RawMessage `json:"generator"`              // Порождающая матрица G (k x n)
+	ParityCheck []json.RawMessage `json:"parity_check,omitempty"` // Проверочная матрица H ((n-k) x n)
+}
+
+// matrixCode — линейный блочный код [n,k], заданный порождающей матрицей G и проверочной матрицей H.
+// Кодирование: c = i·G. Декодирование синдромное: s = H·r^T; ошибки весом до t = (d-1)/2
+// (d — минимальное расстояние кода) исправляются по таблице синдромов, остальные ненулевые синдромы
+// означают обнаруженную неисправимую ошибку. Информационные биты восстанавливаются по k независимым
+// столбцам G, поэтому G не обязана быть систематической.
+type matrixCode struct {
+	name      string
+	n, k      int
+	generator [][]uint8
+	columns   []uint64         // Столбцы H как битовые маски синдромов
+	infoSet   []int            // Позиции независимых столбцов G
+	inverse   [][]uint8        // Обратная к подматрице G на позициях infoSet
+	leaders   map[uint64][]int // Синдром -> позиции ошибок (лидер смежного класса весом до t)
+	distance  int              // Минимальное расстояние кода
+	t         int              // Число гарантированно исправляемых ошибок
+}
+
+// loadMatrixCodeFile читает матрицы G и H из файла: JSON (см. matrixCodeFile) или CSV, в котором
+// строки G и H разделены пустой строкой. H может отсутствовать, если G систематическая ([I | P]).
+func loadMatrixCodeFile(path string) (generator, parityCheck [][]uint8, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("не удалось прочитать файл матриц: %w", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var file matrixCodeFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, nil, fmt.Errorf("неверный JSON файла матриц: %w", err)
+		}
+		if generator, err = parseMatrixRows(file.Generator); err != nil {
+			return nil, nil, fmt.Errorf("generator: %w", err)
+		}
+		if parityCheck, err = parseMatrixRows(file.ParityCheck); err != nil {
+			return nil, nil, fmt.Errorf("parity_check: %w", err)
+		}
+		return generator, parityCheck, nil
+	}
+	// CSV: пустая строка отделяет G от H
+	current := &generator
+	for i, block := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n\n") {
+		if i > 1 {
+			return nil, nil, fmt.Errorf("в CSV ожидается не более двух матриц")
+		}
+		if i == 1 {
+			current = &parityCheck
+		}
+		reader := csv.NewReader(strings.NewReader(block))
+		reader.FieldsPerRecord = -1
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("неверный CSV файла матриц: %w", err)
+			}
+			row, err := parseMatrixRow(strings.Join(record, ""))
+			if err != nil {
+				return nil, nil, err
+			}
+			*current = append(*current, row)
+		}
+	}
+	return generator, parityCheck, nil
+}
+
+// parseMatrixRows разбирает строки матрицы из JSON.
+func parseMatrixRows(raw []json.RawMessage) ([][]uint8, error) {
+	var rows [][]uint8
+	for i, r := range raw {
+		var text string
+		if json.Unmarshal(r, &text) != nil {
+			var values []int
+			if err := json.Unmarshal(r, &values); err != nil {
+				return nil, fmt.Errorf("строка %d: ожидается строка из 0 и 1 или массив", i+1)
+			}
+			var sb strings.Builder
+			for _, v := range values {
+				fmt.Fprint(&sb, v)
+			}
+			text = sb.String()
+		}
+		row, err := parseMatrixRow(text)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: %w", i+1, err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseMatrixRow разбирает строку матрицы из 0 и 1 (пробелы игнорируются).
+func parseMatrixRow(text string) ([]uint8, error) {
+	text = strings.Join(strings.Fields(text), "")
+	row := make([]uint8, 0, len(text))
+	for _, ch := range text {
+		if ch != '0' && ch != '1' {
+			return nil, fmt.Errorf("недопустимый элемент матрицы '%c' (допустимо: 0, 1)", ch)
+		}
+		row = append(row, uint8(ch-'0'))
+	}
+	return row, nil
+}
+
+// newMatrixCode создает линейный код по матрицам G и H (H выводится из систематической G, если не задана).
+func newMatrixCode(name string, generator, parityCheck [][]uint8) (*matrixCode, error) {
+	k := len(generator)
+	if k == 0 || len(generator[0]) == 0 {
+		return nil, fmt.Errorf("порождающая матрица пуста")
+	}
+	n := len(generator[0])
+	for i, row := range generator {
+		if len(row) != n {
+			return nil, fmt.Errorf("строка %d матрицы G содержит %d элементов, ожидалось %d", i+1, len(row), n)
+		}
+	}
+	if k >= n || k > matrixCodeMaxK || n-k > matrixCodeMaxSyndrome {
+		return nil, fmt.Errorf("размер G %dx%d не поддерживается (k < n, k <= %d, n-k <= %d)", k, n, matrixCodeMaxK, matrixCodeMaxSyndrome)
+	}
+	if parityCheck == nil {
+		var err error
+		if parityCheck, err = systematicParityCheck(generator); err != nil {
+			return nil, err
+		}
+	}
+	if len(parityCheck) != n-k {
+		return nil, fmt.Errorf("проверочная матрица содержит %d строк, ожидалось n-k = %d", len(parityCheck), n-k)
+	}
+	c := &matrixCode{name: name, n: n, k: k, generator: generator, columns: make([]uint64, n)}
+	for j, row := range parityCheck {
+		if len(row) != n {
+			return nil, fmt.Errorf("строка %d матрицы H содержит %d элементов, ожидалось %d", j+1, len(row), n)
+		}
+		for i, bit := range row {
+			c.columns[i] |= uint64(bit) << j
+		}
+	}
+	for i, row := range generator {
+		if c.syndrome(row) != 0 {
+			return nil, fmt.Errorf("строка %d матрицы G не удовлетворяет G·H^T = 0", i+1)
+		}
+	}
+	var err error
+	if c.infoSet, c.inverse, err = informationSet(generator); err != nil {
+		return nil, err
+	}
+	c.distance = c.minimumDistance()
+	c.buildSyndromeTable()
+	return c, nil
+}
+
+// systematicParityCheck строит H = [P^T | I] для систематической матрицы G = [I | P].
+func systematicParityCheck(generator [][]uint8) ([][]uint8, error) {
+	k, n := len(generator), len(generator[0])
+	for i, row := range generator {
+		for j := 0; j < k; j++ {
+			if row[j] != boolBit(i == j) {
+				return nil, fmt.Errorf("проверочная матрица не задана, а G не имеет вид [I | P]")
+			}
+		}
+	}
+	parityCheck := make([][]uint8, n-k)
+	for j := range parityCheck {
+		parityCheck[j] = make([]uint8, n)
+		for i := 0; i < k; i++ {
+			parityCheck[j][i] = generator[i][k+j]
+		}
+		parityCheck[j][k+j] = 1
+	}
+	return parityCheck, nil
+}
+
+// boolBit возвращает 1 для true и 0 для false.
+func boolBit(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// informationSet находит k линейно независимых столбцов G и обратную к образованной ими подматрице.
+func informationSet(generator [][]uint8) ([]int, [][]uint8, error) {
+	k, n := len(generator), len(generator[0])
+	// Приведение [G | I] к ступенчатому виду по столбцам G: строки I накапливают преобразования
+	rows := make([][]uint8, k)
+	for i := range rows {
+		rows[i] = append(append([]uint8(nil), generator[i]...), make([]uint8, k)...)
+		rows[i][n+i] = 1
+	}
+	var infoSet []int
+	for col := 0; col < n && len(infoSet) < k; col++ {
+		r := len(infoSet)
+		pivot := -1
+		for i := r; i < k; i++ {
+			if rows[i][col] == 1 {
+				pivot = i
+				break
+			}
+		}
+		if pivot < 0 {
+			continue
+		}
+		rows[r], rows[pivot] = rows[pivot], rows[r]
+		for i := 0; i < k; i++ {
+			if i != r && rows[i][col] == 1 {
+				for j := range rows[i] {
+					rows[i][j] ^= rows[r][j]
+				}
+			}
+		}
+		infoSet = append(infoSet, col)
+	}
+	if len(infoSet) < k {
+		return nil, nil, fmt.Errorf("строки G линейно зависимы (ранг %d < k = %d)", len(infoSet), k)
+	}
+	// Строка r приведенной матрицы: E·G, где E = rows[r][n:], равна единичному вектору на позициях
+	// infoSet, поэтому i = c_J · A, где столбец r матрицы A — E_r
+	inverse := make([][]uint8, k)
+	for j := range inverse {
+		inverse[j] = make([]uint8, k)
+		for r := 0; r < k; r++ {
+			inverse[j][r] = rows[r][n+j]
+		}
+	}
+	return infoSet, inverse, nil
+}
+
+// syndrome вычисляет синдром H·r^T.
+func (c *matrixCode) syndrome(word []uint8) uint64 {
+	var s uint64
+	for i, bit := range word {
+		if bit == 1 {
+			s ^= c.columns[i]
+		}
+	}
+	return s
+}
+
+// minimumDistance перебирает все ненулевые кодовые слова (в порядке кода Грея) и возвращает наименьший вес.
+func (c *matrixCode) minimumDistance() int {
+	word := make([]uint8, c.n)
+	distance := c.n
+	for i := 1; i < 1<<c.k; i++ {
+		row := c.generator[trailingZeros(i)]
+		weight := 0
+		for j := range word {
+			word[j] ^= row[j]
+			weight += int(word[j])
+		}
+		distance = min(distance, weight)
+	}
+	return distance
+}
+
+// trailingZeros возвращает номер младшего единичного бита i (i > 0).
+func trailingZeros(i int) int {
+	z := 0
+	for i&1 == 0 {
+		i >>= 1
+		z++
+	}
+	return z
+}
+
+// buildSyndromeTable заполняет таблицу синдромов для ошибок весом до t = (d-1)/2
+// (t уменьшается, если таблица превысила бы допустимый размер).
+func (c *matrixCode) buildSyndromeTable() {
+	c.t = (c.distance - 1) / 2
+	for c.t > 0 && binomialSum(c.n, c.t) > matrixCodeMaxPatterns {
+		c.t--
+	}
+	c.leaders = make(map[uint64][]int)
+	var positions []int
+	var enumerate func(start int, syndrome uint64)
+	enumerate = func(start int, syndrome uint64) {
+		if len(positions) > 0 {
+			c.leaders[syndrome] = append([]int(nil), positions...)
+		}
+		if len(positions) == c.t {
+			return
+		}
+		for p := start; p < c.n; p++ {
+			positions = append(positions, p)
+			enumerate(p+1, syndrome^c.columns[p])
+			positions = positions[:len(positions)-1]
+		}
+	}
+	enumerate(0, 0)
+}
+
+// binomialSum возвращает сумму C(n, w) для w = 1..t.
+func binomialSum(n, t int) int {
+	sum, term := 0, 1
+	for w := 1; w <= t; w++ {
+		term = term * (n - w + 1) / w
+		sum += term
+		if sum > matrixCodeMaxPatterns {
+			break
+		}
+	}
+	return sum
+}
+
+func (c *matrixCode) Name() string { return c.name }
+func (c *matrixCode) N() int       { return c.n }
+func (c *matrixCode) K() int       { return c.k }
+
+// EncodeBlock вычисляет кодовое слово c = i·G.
+func (c *matrixCode) EncodeBlock(infoBits []uint8) []uint8 {
+	codeword := make([]uint8, c.n)
+	for i, bit := range infoBits {
+		if bit == 1 {
+			for j, g := range c.generator[i] {
+				codeword[j] ^= g
+			}
+		}
+	}
+	return codeword
+}
+
+// DecodeBlock исправляет ошибки по таблице синдромов и восстанавливает информационные биты.
+func (c *matrixCode) DecodeBlock(codedBits []uint8) ([]uint8, bool, bool) {
+	received := append([]uint8(nil), codedBits...)
+	corrected, uncorrectable := false, false
+	if s := c.syndrome(received); s != 0 {
+		if positions, ok := c.leaders[s]; ok {
+			for _, p := range positions {
+				received[p] ^= 1
+			}
+			corrected = true
+		} else {
+			uncorrectable = true
+		}
+	}
+	infoBits := make([]uint8, c.k)
+	for j := range infoBits {
+		var bit uint8
+		for r, col := range c.infoSet {
+			bit ^= received[col] & c.inverse[j][r]
+		}
+		infoBits[j] = bit
+	}
+	return infoBits, corrected, uncorrectable
+}
+
+func init() {
+	RegisterCodecType("matrix", func(cfg CodecConfig) (BlockCoder, error) {
+		if cfg.File == "" {
+			return nil, fmt.Errorf("не задан файл матриц (file)")
+		}
+		generator, parityCheck, err := loadMatrixCodeFile(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		code, err := newMatrixCode(cfg.Name, generator, parityCheck)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("ChannelLayer: Линейный код %s [%d,%d] из %s: минимальное расстояние %d, исправляет ошибок в блоке: %d",
+			code.name, code.n, code.k, cfg.File, code.distance, code.t)
+		return code, nil
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Действия при коллизии кадров в общей среде (link.collision).
+const (
+	CollisionCorrupt = "corrupt" // Каждый бит кадра искажается с вероятностью 1/2 (наложение сигналов)
+	CollisionLose    = "lose"    // Кадр теряется
+)
+
+// airTransmission — кадр, находящийся в эфире общей среды.
+type airTransmission struct {
+	end      time.Time // Окончание передачи
+	collided int       // Число кадров, передававшихся одновременно с этим
+}
+
+// sharedMedium — общая полудуплексная среда передачи: в каждый момент в эфире может находиться
+// только один кадр. Кадр занимает эфир с начала передачи на время airtime (по умолчанию — время
+// передачи кадра); если за это время в эфир выходит другой кадр, возникает коллизия, и искажаются
+// оба кадра. Исход кадра известен только по окончании его передачи, поэтому обработка кадра
+// ожидает освобождения эфира. Методы допускают вызов на nil (кадры передаются независимо).
+type sharedMedium struct {
+	airtime   time.Duration // Фиксированное окно занятости эфира (0 — время передачи кадра)
+	collision string        // Действие при коллизии (см. Collision*)
+
+	mu    sync.Mutex
+	onAir []*airTransmission
+}
+
+// transmit выводит кадр в эфир на время transmission (или окно airtime, если оно задано),
+// ожидает окончания передачи и возвращает число кадров, столкнувшихся с ним.
+func (m *sharedMedium) transmit(transmission time.Duration) int {
+	if m == nil {
+		return 0
+	}
+	if m.airtime > 0 {
+		transmission = m.airtime
+	}
+	now := time.Now()
+	t := &airTransmission{end: now.Add(transmission)}
+	m.mu.Lock()
+	for _, other := range m.onAir {
+		// Кадры в эфире начали передачу не позже этого, поэтому пересекаются с ним, если еще не закончили
+		if other.end.After(now) {
+			other.collided++
+			t.collided++
+		}
+	}
+	m.onAir = append(m.onAir, t)
+	m.mu.Unlock()
+
+	time.Sleep(transmission)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onAir = slices.DeleteFunc(m.onAir, func(other *airTransmission) bool { return other == t })
+	return t.collided
+}
+
+// collide искажает кадр, попавший в коллизию, согласно настройке среды.
+func (m *sharedMedium) collide(frame *ChannelFrame) {
+	if m.collision == CollisionLose {
+		frame.Lost = true
+		return
+	}
+	for i := range frame.Bits {
+		if frame.Rand.Intn(2) == 1 {
+			frame.Flip(i)
+		}
+	}
+}
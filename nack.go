@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+var nackURL string // Адрес приема отрицательных подтверждений на стороне отправителя (пусто — отключено)
+
+// SegmentNack — отрицательное подтверждение сегмента, отправляемое на сторону отправителя
+// при потере сегмента в канале или неисправимой ошибке, чтобы отправитель мог повторить передачу.
+type SegmentNack struct {
+	SegmentID     string    `json:"segment_id"`
+	Sender        string    `json:"sender"`
+	SendTime      string    `json:"send_time"`
+	SegmentNumber int       `json:"segment_number"`
+	TotalSegments int       `json:"total_segments"`
+	Reason        string    `json:"reason"` // Итог обработки: "lost" или "channel_error"
+	Time          time.Time `json:"time"`
+}
+
+// nackRequired сообщает, требует ли итог обработки отрицательного подтверждения.
+func nackRequired(outcome string) bool {
+	return outcome == OutcomeLost || outcome == OutcomeChannelError
+}
+
+// sendNack отправляет отрицательное подтверждение сегмента на nackURL.
+func sendNack(job *segmentJob, outcome string) {
+	req := job.Request
+	body, err := json.Marshal(SegmentNack{
+		SegmentID:     job.ID,
+		Sender:        req.Sender,
+		SendTime:      req.SendTime,
+		SegmentNumber: req.SegmentNumber,
+		TotalSegments: req.TotalSegments,
+		Reason:        outcome,
+		Time:          time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("NACK ERROR: Не удалось сериализовать NACK для сегмента %s: %v", job.ID, err)
+		return
+	}
+
+	status, attempts, err := postWithRetry(nackURL, body)
+	if err != nil {
+		log.Printf("NACK ERROR: NACK сегмента #%d/%d от %s не доставлен на %s после %d попыток: %v", req.SegmentNumber, req.TotalSegments, req.Sender, nackURL, attempts, err)
+		segmentRegistry.Event(job.ID, "nack", fmt.Sprintf("NACK не доставлен после %d попыток: %v", attempts, err))
+		return
+	}
+	log.Printf("NACK: NACK сегмента #%d/%d от %s (%s) доставлен на %s", req.SegmentNumber, req.TotalSegments, req.Sender, outcome, nackURL)
+	segmentRegistry.Event(job.ID, "nack", fmt.Sprintf("NACK (%s) доставлен (%s, попытка %d)", outcome, status, attempts))
+}
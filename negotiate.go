@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const NegotiateEndpoint = "/negotiate" // Конечная точка согласования параметров звена
+
+// NegotiationConfig задает согласование параметров звена с транспортным уровнем.
+type NegotiationConfig struct {
+	Enabled bool `json:"enabled"` // Принимать запросы /negotiate и применять согласованные параметры
+}
+
+// LinkParameters — параметры звена, согласованные с отправителем.
+type LinkParameters struct {
+	Sender    string    `json:"sender"`
+	Window    int       `json:"window,omitempty"` // Окно Go-Back-N в кадрах (0 — окно из конфигурации ARQ)
+	FrameSize int       `json:"frame_size"`       // Наибольшая полезная нагрузка сегмента в байтах
+	AgreedAt  time.Time `json:"agreed_at"`
+}
+
+// NegotiateRequest — предложение параметров звена от транспортного уровня на /negotiate.
+// Нулевое значение параметра означает наибольшее допустимое.
+type NegotiateRequest struct {
+	Sender    string `json:"sender"`
+	Window    int    `json:"window,omitempty"`
+	FrameSize int    `json:"frame_size,omitempty"`
+}
+
+// NegotiateResponse — согласованные параметры и пределы канального уровня, с которыми они сверялись.
+type NegotiateResponse struct {
+	LinkParameters
+	MaxWindow    int `json:"max_window,omitempty"` // Окно Go-Back-N из конфигурации (0 — ARQ без окна)
+	MaxFrameSize int `json:"max_frame_size"`       // Наибольшая полезная нагрузка сегмента канала
+}
+
+// Negotiator — параметры звена, согласованные с отправителями: размер окна Go-Back-N и размер кадра
+// (полезной нагрузки сегмента). Предложение отправителя сверяется с пределами канального уровня и
+// уменьшается до них; согласованные параметры сохраняются в хранилище состояния по отправителю
+// и действуют для всех его последующих сегментов. Методы допускают вызов на nil (согласование отключено).
+type Negotiator struct {
+	store StateStore
+}
+
+// NewNegotiator создает согласование параметров по конфигурации (nil, если оно отключено).
+func NewNegotiator(cfg NegotiationConfig, store StateStore) *Negotiator {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &Negotiator{store: store}
+}
+
+// negotiationKey формирует ключ параметров отправителя в хранилище состояния.
+func negotiationKey(sender string) string {
+	return "negotiated:" + sender
+}
+
+// Negotiate сверяет предложение отправителя с пределами канального уровня и сохраняет согласованные параметры.
+func (n *Negotiator) Negotiate(req NegotiateRequest) (NegotiateResponse, error) {
+	resp := NegotiateResponse{MaxWindow: linkARQ.Window(), MaxFrameSize: FixedPayloadSize}
+	if req.Window < 0 || req.FrameSize < 0 {
+		return resp, fmt.Errorf("окно и размер кадра не могут быть отрицательными")
+	}
+	if req.Window > 0 && resp.MaxWindow == 0 {
+		return resp, fmt.Errorf("окно не согласуется: ARQ не работает в режиме %s", ARQGoBackN)
+	}
+	params := LinkParameters{
+		Sender:    req.Sender,
+		Window:    min(req.Window, resp.MaxWindow),
+		FrameSize: resp.MaxFrameSize,
+		AgreedAt:  time.Now().UTC(),
+	}
+	if req.FrameSize > 0 {
+		params.FrameSize = min(req.FrameSize, resp.MaxFrameSize)
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return resp, err
+	}
+	if err := n.store.Set(negotiationKey(req.Sender), string(data), 0); err != nil {
+		return resp, fmt.Errorf("не удалось сохранить параметры: %w", err)
+	}
+	resp.LinkParameters = params
+	return resp, nil
+}
+
+// Lookup возвращает параметры, согласованные с отправителем. При недоступности хранилища
+// параметры считаются несогласованными (отказ не блокирует канал).
+func (n *Negotiator) Lookup(sender string) (LinkParameters, bool) {
+	if n == nil {
+		return LinkParameters{}, false
+	}
+	data, ok, err := n.store.Get(negotiationKey(sender))
+	if err != nil {
+		log.Printf("Negotiator ERROR: Не удалось прочитать параметры отправителя %s: %v", sender, err)
+		return LinkParameters{}, false
+	}
+	if !ok {
+		return LinkParameters{}, false
+	}
+	var params LinkParameters
+	if err := json.Unmarshal([]byte(data), &params); err != nil {
+		log.Printf("Negotiator ERROR: Поврежденные параметры отправителя %s: %v", sender, err)
+		return LinkParameters{}, false
+	}
+	return params, true
+}
+
+var negotiator *Negotiator // Глобальное согласование параметров звена (nil, если отключено)
+
+// handleNegotiate согласует (POST) или возвращает (GET, параметр sender) параметры звена отправителя.
+func handleNegotiate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if negotiator == nil {
+		sendErrorResponse(w, "Согласование параметров не используется (negotiation.enabled = false)", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		params, ok := negotiator.Lookup(r.URL.Query().Get("sender"))
+		if !ok {
+			sendErrorResponse(w, "Параметры с отправителем не согласованы", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(params)
+		return
+	case http.MethodPost:
+	default:
+		sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req NegotiateRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&req); err != nil {
+		sendErrorResponse(w, fmt.Sprintf("Неверный JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Sender == "" {
+		sendErrorResponse(w, "Не указан отправитель (sender)", http.StatusBadRequest)
+		return
+	}
+	resp, err := negotiator.Negotiate(req)
+	if err != nil {
+		log.Printf("Web Server: Параметры звена с %s не согласованы: %v", req.Sender, err)
+		sendErrorResponse(w, fmt.Sprintf("Параметры не согласованы: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+	log.Printf("Web Server: Согласованы параметры звена с %s: окно %d, кадр %d байт (предложено: окно %d, кадр %d)",
+		req.Sender, resp.Window, resp.FrameSize, req.Window, req.FrameSize)
+	json.NewEncoder(w).Encode(resp)
+}
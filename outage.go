@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const AdminOutageEndpoint = "/admin/outage" // Конечная точка перерывов связи
+
+// OutageConfig — запланированный перерыв связи, во время которого теряются все кадры независимо от R.
+type OutageConfig struct {
+	Start    Duration `json:"start"`           // Начало перерыва относительно запуска
+	Duration Duration `json:"duration"`        // Длительность перерыва
+	Every    Duration `json:"every,omitempty"` // Период повторения перерыва (0 — однократный)
+}
+
+// outageWindow — перерыв связи с абсолютным временем начала (с показанием монотонных часов).
+type outageWindow struct {
+	from     time.Time
+	duration time.Duration
+	every    time.Duration
+}
+
+// activeAt сообщает, идет ли перерыв в момент now, и возвращает момент его окончания.
+func (o outageWindow) activeAt(now time.Time) (bool, time.Time) {
+	elapsed := now.Sub(o.from)
+	if elapsed < 0 {
+		return false, time.Time{}
+	}
+	if o.every > 0 {
+		elapsed %= o.every
+	}
+	if elapsed >= o.duration {
+		return false, time.Time{}
+	}
+	return true, now.Add(o.duration - elapsed)
+}
+
+// expiredAt сообщает, что однократный перерыв закончился к моменту now.
+func (o outageWindow) expiredAt(now time.Time) bool {
+	return o.every == 0 && now.Sub(o.from) >= o.duration
+}
+
+// LinkOutage — перерывы связи канала: запланированные в конфигурации и запущенные через /admin/outage.
+// Во время перерыва каждый кадр теряется, цепочка искажений к нему не применяется. Методы допускают
+// вызов на nil (перерывов нет).
+type LinkOutage struct {
+	mu      sync.Mutex
+	windows []outageWindow
+	active  bool // Перерыв шел при последней проверке (для журнала начала и окончания)
+}
+
+// NewLinkOutage создает перерывы связи по конфигурации; время начала отсчитывается от момента вызова.
+func NewLinkOutage(cfgs []OutageConfig) (*LinkOutage, error) {
+	o := &LinkOutage{}
+	now := time.Now()
+	for i, cfg := range cfgs {
+		if cfg.Start.Duration < 0 {
+			return nil, fmt.Errorf("перерыв %d: начало не может быть отрицательным, задано %s", i+1, cfg.Start)
+		}
+		if cfg.Duration.Duration <= 0 {
+			return nil, fmt.Errorf("перерыв %d: длительность должна быть положительной, задано %s", i+1, cfg.Duration)
+		}
+		if cfg.Every.Duration != 0 && cfg.Every.Duration <= cfg.Duration.Duration {
+			return nil, fmt.Errorf("перерыв %d: период повторения %s должен быть больше длительности %s", i+1, cfg.Every, cfg.Duration)
+		}
+		o.windows = append(o.windows, outageWindow{from: now.Add(cfg.Start.Duration), duration: cfg.Duration.Duration, every: cfg.Every.Duration})
+	}
+	return o, nil
+}
+
+// Start планирует однократный перерыв связи длительностью duration, начинающийся через delay.
+func (o *LinkOutage) Start(delay, duration time.Duration) {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.windows = append(o.windows, outageWindow{from: time.Now().Add(delay), duration: duration})
+}
+
+// Cancel отменяет все перерывы связи, в том числе идущий и запланированные в конфигурации.
+func (o *LinkOutage) Cancel() {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.windows = nil
+}
+
+// Active сообщает, идет ли сейчас перерыв связи, и возвращает момент его окончания
+// (наиболее поздний из пересекающихся перерывов).
+func (o *LinkOutage) Active() (bool, time.Time) {
+	if o == nil {
+		return false, time.Time{}
+	}
+	now := time.Now()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	active, until := false, time.Time{}
+	windows := o.windows[:0]
+	for _, window := range o.windows {
+		if window.expiredAt(now) {
+			continue // Закончившиеся однократные перерывы больше не нужны
+		}
+		windows = append(windows, window)
+		if ok, end := window.activeAt(now); ok {
+			active = true
+			if end.After(until) {
+				until = end
+			}
+		}
+	}
+	o.windows = windows
+	if active != o.active {
+		if active {
+			log.Printf("ChannelLayer: Перерыв связи до %s: все кадры теряются", until.Format("15:04:05.000"))
+		} else {
+			log.Printf("ChannelLayer: Перерыв связи закончился")
+		}
+		o.active = active
+	}
+	return active, until
+}
+
+// OutageWindowState — перерыв связи в ответе /admin/outage.
+type OutageWindowState struct {
+	Start    time.Time `json:"start"`           // Начало перерыва (ближайшего повторения для периодического)
+	Duration string    `json:"duration"`        // Длительность перерыва
+	Every    string    `json:"every,omitempty"` // Период повторения
+}
+
+// OutageState — состояние перерывов связи.
+type OutageState struct {
+	Active  bool                `json:"active"`          // Идет перерыв связи
+	Until   *time.Time          `json:"until,omitempty"` // Окончание идущего перерыва
+	Windows []OutageWindowState `json:"windows"`         // Идущие и запланированные перерывы
+}
+
+// State возвращает текущее состояние перерывов связи.
+func (o *LinkOutage) State() OutageState {
+	active, until := o.Active()
+	state := OutageState{Active: active, Windows: []OutageWindowState{}}
+	if active {
+		state.Until = &until
+	}
+	if o == nil {
+		return state
+	}
+	now := time.Now()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, window := range o.windows {
+		start := window.from
+		if window.every > 0 && now.After(start) {
+			// Начало текущего или следующего повторения
+			start = start.Add(now.Sub(start) / window.every * window.every)
+			if now.Sub(start) >= window.duration {
+				start = start.Add(window.every)
+			}
+		}
+		ws := OutageWindowState{Start: start.Round(0), Duration: window.duration.String()}
+		if window.every > 0 {
+			ws.Every = window.every.String()
+		}
+		state.Windows = append(state.Windows, ws)
+	}
+	return state
+}
+
+// handleAdminOutage возвращает (GET) перерывы связи, запускает (POST {"duration": "10s", "start": "5s"})
+// однократный перерыв через start (по умолчанию сразу) или отменяет (DELETE) все перерывы.
+func handleAdminOutage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req struct {
+			Start    Duration `json:"start"`
+			Duration Duration `json:"duration"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendErrorResponse(w, fmt.Sprintf("Неверный JSON: %v", err), http.StatusBadRequest)
+			return
+		}
+		if req.Duration.Duration <= 0 {
+			sendErrorResponse(w, "Длительность перерыва (duration) должна быть положительной", http.StatusBadRequest)
+			return
+		}
+		if req.Start.Duration < 0 {
+			sendErrorResponse(w, "Начало перерыва (start) не может быть отрицательным", http.StatusBadRequest)
+			return
+		}
+		channelLayer.Outage.Start(req.Start.Duration, req.Duration.Duration)
+		log.Printf("ChannelLayer: Запланирован перерыв связи через %s на %s", req.Start, req.Duration)
+	case http.MethodDelete:
+		channelLayer.Outage.Cancel()
+		log.Printf("ChannelLayer: Перерывы связи отменены")
+	default:
+		sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(channelLayer.Outage.State())
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Режимы работы канального уровня.
+const (
+	ModeNormal   = "normal"   // Полная обработка: кодирование, симуляция потерь и ошибок, декодирование
+	ModeDegraded = "degraded" // Упрощенная обработка при длительной перегрузке
+)
+
+// Действия в режиме деградации.
+const (
+	DegradeSkipImpairments = "skip_impairments" // Не симулировать потери и ошибки (кодирование и декодирование выполняются)
+	DegradeSkipCoding      = "skip_coding"      // Не кодировать и не проверять полезную нагрузку (передается без изменений)
+)
+
+// OverloadStatus — состояние контроллера перегрузки, возвращаемое на /stats.
+type OverloadStatus struct {
+	Mode             string    `json:"mode"`                     // Текущий режим работы
+	Action           string    `json:"action"`                   // Действие в режиме деградации
+	DegradedSince    time.Time `json:"degraded_since,omitempty"` // Момент перехода в режим деградации
+	Transitions      int64     `json:"transitions"`              // Число смен режима с момента запуска
+	DegradedSegments int64     `json:"degraded_segments"`        // Число сегментов, обработанных в режиме деградации
+}
+
+// OverloadController отслеживает загрузку очереди обработки и при длительной перегрузке
+// переводит канальный уровень в режим деградации вместо того, чтобы сегменты ждали до таймаута.
+// Перегрузкой считается превышение порога числа ожидающих сегментов или времени ожидания
+// старейшего из них. Переход в режим деградации происходит, если перегрузка длится не менее
+// sustain; возврат в нормальный режим — после recover непрерывной работы без перегрузки.
+// Все методы допускают вызов на nil (контроллер отключен).
+type OverloadController struct {
+	mu             sync.Mutex
+	queue          *FairQueue
+	queueThreshold int
+	waitThreshold  time.Duration
+	sustain        time.Duration
+	recover        time.Duration
+	action         string
+
+	overloadedSince time.Time // Начало текущего интервала перегрузки (нулевое, если перегрузки нет)
+	healthySince    time.Time // Начало текущего интервала без перегрузки в режиме деградации
+	status          OverloadStatus
+}
+
+// NewOverloadController создает контроллер перегрузки для очереди обработки.
+func NewOverloadController(queue *FairQueue, cfg OverloadConfig) (*OverloadController, error) {
+	switch cfg.Action {
+	case DegradeSkipImpairments, DegradeSkipCoding:
+	default:
+		return nil, fmt.Errorf("неизвестное действие режима деградации '%s' (допустимо: %s, %s)",
+			cfg.Action, DegradeSkipImpairments, DegradeSkipCoding)
+	}
+	log.Printf("OverloadController: Создан (порог очереди %d, порог ожидания %v, перегрузка %v, восстановление %v, действие %s)",
+		cfg.QueueThreshold, cfg.WaitThreshold.Duration, cfg.Sustain.Duration, cfg.Recover.Duration, cfg.Action)
+
+	return &OverloadController{
+		queue:          queue,
+		queueThreshold: cfg.QueueThreshold,
+		waitThreshold:  cfg.WaitThreshold.Duration,
+		sustain:        cfg.Sustain.Duration,
+		recover:        cfg.Recover.Duration,
+		action:         cfg.Action,
+		status:         OverloadStatus{Mode: ModeNormal, Action: cfg.Action},
+	}, nil
+}
+
+// overloaded сообщает, превышены ли пороги загрузки очереди.
+func (oc *OverloadController) overloaded(stats QueueStats, oldestWait time.Duration) bool {
+	if oc.queueThreshold > 0 && stats.Waiting >= oc.queueThreshold {
+		return true
+	}
+	return oc.waitThreshold > 0 && oldestWait >= oc.waitThreshold
+}
+
+// check оценивает загрузку очереди и при необходимости меняет режим работы.
+func (oc *OverloadController) check(now time.Time) {
+	stats := oc.queue.Stats()
+	oldestWait := oc.queue.OldestWait(now)
+	overloaded := oc.overloaded(stats, oldestWait)
+
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+
+	if overloaded {
+		oc.healthySince = time.Time{}
+		if oc.overloadedSince.IsZero() {
+			oc.overloadedSince = now
+		}
+	} else {
+		oc.overloadedSince = time.Time{}
+		if oc.healthySince.IsZero() {
+			oc.healthySince = now
+		}
+	}
+
+	switch oc.status.Mode {
+	case ModeNormal:
+		if overloaded && now.Sub(oc.overloadedSince) >= oc.sustain {
+			oc.status.Mode = ModeDegraded
+			oc.status.DegradedSince = now.UTC()
+			oc.status.Transitions++
+			eventLog.Publish("mode_changed",
+				fmt.Sprintf("Длительная перегрузка: переход в режим деградации (%s)", oc.action),
+				map[string]interface{}{
+					"mode":            ModeDegraded,
+					"action":          oc.action,
+					"queue_waiting":   stats.Waiting,
+					"oldest_wait_ms":  oldestWait.Milliseconds(),
+					"overloaded_for":  now.Sub(oc.overloadedSince).String(),
+					"queue_threshold": oc.queueThreshold,
+				})
+		}
+	case ModeDegraded:
+		if !overloaded && now.Sub(oc.healthySince) >= oc.recover {
+			degradedFor := now.Sub(oc.status.DegradedSince)
+			oc.status.Mode = ModeNormal
+			oc.status.DegradedSince = time.Time{}
+			oc.status.Transitions++
+			eventLog.Publish("mode_changed",
+				"Перегрузка устранена: возврат в нормальный режим",
+				map[string]interface{}{
+					"mode":         ModeNormal,
+					"degraded_for": degradedFor.String(),
+				})
+		}
+	}
+}
+
+// Run периодически проверяет загрузку очереди.
+func (oc *OverloadController) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		oc.check(now)
+	}
+}
+
+// Options возвращает параметры обработки сегмента для текущего режима работы
+// и учитывает сегменты, обработанные в режиме деградации.
+func (oc *OverloadController) Options() (ProcessOptions, string) {
+	if oc == nil {
+		return ProcessOptions{}, ""
+	}
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	if oc.status.Mode != ModeDegraded {
+		return ProcessOptions{}, ""
+	}
+	oc.status.DegradedSegments++
+	switch oc.action {
+	case DegradeSkipCoding:
+		return ProcessOptions{SkipCoding: true}, oc.action
+	default:
+		return ProcessOptions{SkipImpairments: true}, oc.action
+	}
+}
+
+// Status возвращает текущее состояние контроллера.
+func (oc *OverloadController) Status() *OverloadStatus {
+	if oc == nil {
+		return nil
+	}
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	status := oc.status
+	return &status
+}
@@ -0,0 +1,49 @@
+package main
+
+// parityCoder — бит четности на каждый байт (код [9,8]): простейшая схема-эталон для сравнения
+// с циклическим кодом. Обнаруживается любое нечетное число ошибок в байте, четное (в том числе
+// двойная ошибка) пропускается; исправление невозможно.
+//
+// При контроле на четность (even) проверочный бит дополняет число единиц в байте до четного,
+// при контроле на нечетность (odd) — до нечетного, поэтому нулевой байт кодируется ненулевым
+// словом и «залипание» линии в нуле также обнаруживается.
+type parityCoder struct {
+	odd bool // Контроль на нечетность
+}
+
+func (c parityCoder) Name() string {
+	if c.odd {
+		return "parity_odd"
+	}
+	return "parity_even"
+}
+func (parityCoder) N() int { return 9 }
+func (parityCoder) K() int { return 8 }
+
+// parityBit возвращает проверочный бит для информационных бит.
+func (c parityCoder) parityBit(bits []uint8) uint8 {
+	var p uint8
+	for _, b := range bits {
+		p ^= b
+	}
+	if c.odd {
+		p ^= 1
+	}
+	return p
+}
+
+// EncodeBlock дописывает к байту проверочный бит.
+func (c parityCoder) EncodeBlock(infoBits []uint8) []uint8 {
+	return append(append(make([]uint8, 0, 9), infoBits...), c.parityBit(infoBits))
+}
+
+// DecodeBlock проверяет четность байта; несовпадение означает неисправимую ошибку.
+func (c parityCoder) DecodeBlock(codedBits []uint8) ([]uint8, bool, bool) {
+	infoBits := append([]uint8(nil), codedBits[:8]...)
+	return infoBits, false, c.parityBit(infoBits) != codedBits[8]
+}
+
+func init() {
+	RegisterCoder(parityCoder{})
+	RegisterCoder(parityCoder{odd: true})
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sync/atomic"
+)
+
+const AdminDistanceEndpoint = "/admin/distance" // Конечная точка расстояния линии связи
+
+// PathLossConfig задает вероятность ошибки P через длину линии связи вместо непосредственного значения.
+type PathLossConfig struct {
+	Distance          float64 `json:"distance"`                     // Длина линии (м); 0 — модель не используется
+	Exponent          float64 `json:"exponent"`                     // Показатель затухания (2 — свободное пространство, 3–4 — городская застройка)
+	ReferenceDistance float64 `json:"reference_distance,omitempty"` // Опорное расстояние (м), по умолчанию 1
+	ReferenceSNRdB    float64 `json:"reference_snr_db"`             // Отношение сигнал/шум на бит канала на опорном расстоянии (дБ)
+}
+
+// PathLoss — модель затухания сигнала с расстоянием: отношение сигнал/шум на бит канала убывает
+// как SNR(d) = SNR(d0) - 10·n·lg(d/d0), а P — вероятность ошибки в бите при двоичной фазовой
+// манипуляции, P = Q(√(2·SNR)). Расстояние можно менять во время работы (/admin/distance), и P
+// меняется непрерывно. Методы допускают вызов на nil (используется P из конфигурации).
+type PathLoss struct {
+	exponent          float64
+	referenceDistance float64
+	referenceSNRdB    float64
+	distance          atomic.Uint64 // math.Float64bits текущего расстояния
+}
+
+// NewPathLoss создает модель затухания по конфигурации (nil, если расстояние не задано).
+func NewPathLoss(cfg PathLossConfig) (*PathLoss, error) {
+	if cfg.Distance == 0 {
+		return nil, nil
+	}
+	if cfg.Exponent <= 0 {
+		return nil, fmt.Errorf("показатель затухания должен быть положительным, задано %g", cfg.Exponent)
+	}
+	if cfg.ReferenceDistance == 0 {
+		cfg.ReferenceDistance = 1
+	}
+	if cfg.ReferenceDistance < 0 {
+		return nil, fmt.Errorf("опорное расстояние должно быть положительным, задано %g", cfg.ReferenceDistance)
+	}
+	pl := &PathLoss{exponent: cfg.Exponent, referenceDistance: cfg.ReferenceDistance, referenceSNRdB: cfg.ReferenceSNRdB}
+	if err := pl.SetDistance(cfg.Distance); err != nil {
+		return nil, err
+	}
+	return pl, nil
+}
+
+// Distance возвращает текущую длину линии (м).
+func (pl *PathLoss) Distance() float64 {
+	return math.Float64frombits(pl.distance.Load())
+}
+
+// SetDistance изменяет длину линии; следующие кадры передаются с новой вероятностью ошибки.
+func (pl *PathLoss) SetDistance(distance float64) error {
+	if pl == nil {
+		return fmt.Errorf("модель затухания не настроена (path_loss в конфигурации)")
+	}
+	if !(distance > 0) || math.IsInf(distance, 0) {
+		return fmt.Errorf("расстояние должно быть положительным, задано %g", distance)
+	}
+	pl.distance.Store(math.Float64bits(distance))
+	return nil
+}
+
+// SNRdB возвращает отношение сигнал/шум на бит канала на текущем расстоянии (дБ).
+func (pl *PathLoss) SNRdB() float64 {
+	return pl.referenceSNRdB - 10*pl.exponent*math.Log10(pl.Distance()/pl.referenceDistance)
+}
+
+// ErrorProbability возвращает P на текущем расстоянии или p, если модель не используется.
+func (pl *PathLoss) ErrorProbability(p float64) float64 {
+	if pl == nil {
+		return p
+	}
+	snr := math.Pow(10, pl.SNRdB()/10)
+	return 0.5 * math.Erfc(math.Sqrt(snr))
+}
+
+// DistanceState — состояние модели затухания в ответе /admin/distance.
+type DistanceState struct {
+	Distance         float64 `json:"distance"`          // Длина линии (м)
+	SNRdB            float64 `json:"snr_db"`            // Отношение сигнал/шум на бит канала (дБ)
+	ErrorProbability float64 `json:"error_probability"` // Вероятность ошибки в бите P
+}
+
+// handleAdminDistance возвращает (GET) или изменяет (POST {"distance": 120}) длину линии связи.
+func handleAdminDistance(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	pl := channelLayer.PathLoss
+	if pl == nil {
+		sendErrorResponse(w, "Модель затухания не настроена (path_loss в конфигурации)", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req struct {
+			Distance float64 `json:"distance"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendErrorResponse(w, fmt.Sprintf("Неверный JSON: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := pl.SetDistance(req.Distance); err != nil {
+			sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("ChannelLayer: Длина линии %g м: SNR %.2f дБ, P=%.3g", pl.Distance(), pl.SNRdB(), pl.ErrorProbability(0))
+	default:
+		sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(DistanceState{Distance: pl.Distance(), SNRdB: pl.SNRdB(), ErrorProbability: pl.ErrorProbability(0)})
+}
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Политики обработки сегментов с неисправимой ошибкой канала.
+const (
+	ChannelErrorPolicyError   = "error"   // Не пересылать, вернуть отправителю ошибку (500)
+	ChannelErrorPolicyDrop    = "drop"    // Не пересылать, ответить отправителю как о потерянном сегменте (408)
+	ChannelErrorPolicyForward = "forward" // Переслать на /transfer с is_channel_error=true
+)
+
+var channelErrorPolicy = ChannelErrorPolicyError // Глобальная политика обработки ошибок канала
+
+// Типы сегментов (поле type запроса /code).
+const (
+	SegmentTypeData    = "data"    // Сегмент данных (по умолчанию)
+	SegmentTypeControl = "control" // Управляющий сегмент (установление и разрыв соединения, keepalive)
+)
+
+var controlConfig ControlConfig // Глобальные параметры обработки управляющих сегментов
+
+// segmentJob — сегмент, принятый на /code и прошедший проверку, вместе с данными для его обработки.
+type segmentJob struct {
+	ID              string              // Идентификатор сегмента в реестре сегментов
+	Request         IncomingCodeRequest // Исходный запрос
+	OriginalPayload []byte              // Полезная нагрузка до паддинга
+	Coder           BlockCoder          // Кодек, выбранный в запросе (nil — кодек канала)
+	Window          int                 // Окно Go-Back-N, согласованное с отправителем (0 — из конфигурации)
+	Timestamp       int64               // Метка времени отправителя (send_time) в наносекундах
+	ReceivedAt      time.Time           // Момент приема запроса
+	APIVersion      int                 // Версия формата синхронного ответа (см. APIVersion*)
+}
+
+// SegmentResult — итог обработки сегмента канальным уровнем и пересылки на /transfer.
+// В синхронном режиме возвращается клиенту в ответе на /code, в асинхронном — доступен на /segments/{id}.
+type SegmentResult struct {
+	Outcome        string       `json:"outcome"`                          // Итог обработки (см. Outcome*)
+	StatusCode     int          `json:"status_code"`                      // HTTP статус, соответствующий итогу
+	Error          string       `json:"error,omitempty"`                  // Описание ошибки (для неуспешных итогов)
+	Status         string       `json:"status,omitempty"`                 // Описание успешного итога
+	TransferStatus string       `json:"transfer_status,omitempty"`        // Статус ответа /transfer
+	TransferBody   string       `json:"transfer_response_body,omitempty"` // Тело ответа /transfer
+	Duplicate      bool         `json:"duplicate,omitempty"`              // Сегмент уже был доставлен ранее
+	Forwarded      bool         `json:"forwarded,omitempty"`              // Сегмент принят конечной точкой /transfer
+	Coding         *CodingStats `json:"coding,omitempty"`                 // Накладные расходы кодирования сегмента
+}
+
+// outgoingSegmentType возвращает тип сегмента для запроса /transfer: сегменты данных
+// пересылаются без поля type, как и до появления управляющих сегментов.
+func outgoingSegmentType(segmentType string) string {
+	if segmentType == SegmentTypeControl {
+		return SegmentTypeControl
+	}
+	return ""
+}
+
+// failedResult формирует итог неуспешной обработки.
+func failedResult(outcome string, statusCode int, message string) SegmentResult {
+	return SegmentResult{Outcome: outcome, StatusCode: statusCode, Error: message}
+}
+
+// payloadHookContext формирует описание сегмента для обработчиков полезной нагрузки.
+func payloadHookContext(job *segmentJob) PayloadHookContext {
+	return PayloadHookContext{
+		SegmentID:     job.ID,
+		Sender:        job.Request.Sender,
+		SendTime:      job.Request.SendTime,
+		SegmentNumber: job.Request.SegmentNumber,
+		TotalSegments: job.Request.TotalSegments,
+	}
+}
+
+// decodedPayload возвращает полезную нагрузку принятого из канала сегмента для пересылки на /transfer:
+// применяет обработчики после декодирования и, если trim, отсекает паддинг по исходной длине.
+// Обработчики получают полезную нагрузку той длины, что была передана в канал; поврежденная
+// нагрузка (при политике forward) им не передается.
+func decodedPayload(ctx context.Context, hookContext PayloadHookContext, segment *Segment, trim bool) ([]byte, error) {
+	payload, length := segment.Payload, segment.OriginalLength
+	if payloadHooks.Has(HookStagePostDecoding) && !segment.IsChannelError {
+		hookContext.Stage = HookStagePostDecoding
+		decoded, err := payloadHooks.Run(ctx, hookContext, payload[:length])
+		if err != nil {
+			return nil, err
+		}
+		segmentRegistry.Event(hookContext.SegmentID, HookStagePostDecoding, fmt.Sprintf("Полезная нагрузка преобразована: %d -> %d байт", length, len(decoded)))
+		payload, length = decoded, len(decoded)
+		if len(decoded) < FixedPayloadSize {
+			payload = make([]byte, FixedPayloadSize)
+			copy(payload, decoded)
+		}
+	}
+	if trim && length < len(payload) {
+		payload = payload[:length]
+	}
+	return payload, nil
+}
+
+// processSegmentJob выполняет полный цикл обработки принятого сегмента: проверку на дубликат,
+// ожидание очереди обработки, симуляцию канала и пересылку на /transfer.
+// Итог учитывается в статистике и реестре сегментов.
+func processSegmentJob(ctx context.Context, job *segmentJob) (result SegmentResult) {
+	req := job.Request
+	claimedKey := ""                // Ключ сегмента в детекторе дубликатов (пуст, пока сегмент не занят)
+	var coding *CodingStats         // Накладные расходы кодирования (nil, пока сегмент не передан в канал)
+	var channelReport ChannelReport // Отчет о прохождении сегмента через канал
+
+	// Итог обработки сегмента учитывается в статистике на каждом пути завершения
+	defer func() {
+		result.Coding = coding
+		outcomeRecord := SegmentOutcomeRecord{
+			Time:          time.Now().UTC(),
+			Sender:        req.Sender,
+			SendTime:      req.SendTime,
+			SegmentNumber: req.SegmentNumber,
+			TotalSegments: req.TotalSegments,
+			PayloadBytes:  len(job.OriginalPayload),
+			Outcome:       result.Outcome,
+			DurationMs:    float64(time.Since(job.ReceivedAt).Microseconds()) / 1000,
+		}
+		if coding != nil {
+			outcomeRecord.InfoBits = coding.InfoBits
+			outcomeRecord.EncodedBits = coding.EncodedBits
+			outcomeRecord.ChannelBitErrors = channelReport.ChannelBitErrors
+			outcomeRecord.DecoderBitErrors = channelReport.DecoderBitErrors
+			outcomeRecord.FCSFailed = channelReport.FCS == FCSFailed
+			outcomeRecord.Retries = channelReport.Retries
+			outcomeRecord.Impairments = channelReport.Impairments
+			outcomeRecord.Arm = channelReport.Arm
+		}
+		statistics.Record(outcomeRecord)
+		abSplit.Record(outcomeRecord.Arm, outcomeRecord)
+		// Сегмент, занятый детектором дубликатов, освобождается (или запоминается как доставленный)
+		if claimedKey != "" {
+			deduplicator.Finish(claimedKey, result.Outcome == OutcomeDelivered)
+		}
+		segmentRegistry.Complete(job.ID, result)
+		// Итог отправляется на callback_url клиента, если он указан
+		if req.CallbackURL != "" {
+			go notifyCallback(job, result)
+		}
+		// О потере и неисправимой ошибке уведомляется сторона отправителя
+		if nackURL != "" && nackRequired(result.Outcome) {
+			go sendNack(job, result.Outcome)
+		}
+	}()
+
+	// Обнаружение дубликатов: сегмент, уже доставленный на /transfer (этим или другим экземпляром),
+	// повторно не обрабатывается и не пересылается
+	key := dedupKey(req.Sender, req.SendTime, req.SegmentNumber)
+	switch deduplicator.Claim(key) {
+	case DedupDelivered:
+		log.Printf("Web Server: Сегмент #%d/%d от %s уже был доставлен, повторная передача не выполняется.", req.SegmentNumber, req.TotalSegments, req.Sender)
+		return SegmentResult{
+			Outcome:    OutcomeDuplicate,
+			StatusCode: http.StatusOK,
+			Status:     "Сегмент уже был доставлен ранее (дубликат), повторная передача не выполнялась.",
+			Duplicate:  true,
+		}
+	case DedupInProgress:
+		log.Printf("Web Server: Сегмент #%d/%d от %s уже обрабатывается.", req.SegmentNumber, req.TotalSegments, req.Sender)
+		return failedResult(OutcomeDuplicate, http.StatusConflict, "Сегмент уже обрабатывается, повторите запрос позже")
+	}
+	if deduplicator != nil {
+		claimedKey = key
+	}
+
+	// Ожидание свободного обработчика. Очередь обслуживает отправителей справедливо (с учетом весов),
+	// поэтому поток сегментов большого файла не задерживает сообщения других отправителей.
+	slot, err := processingQueue.Acquire(ctx, req.Sender)
+	if err != nil {
+		log.Printf("Web Server: Сегмент #%d/%d от %s не поставлен в очередь обработки: %v", req.SegmentNumber, req.TotalSegments, req.Sender, err)
+		return failedResult(OutcomeRejected, http.StatusServiceUnavailable, fmt.Sprintf("Сегмент не может быть обработан: %v", err))
+	}
+	// Обработчик освобождается и досрочно, перед ожиданием в буфере джиттера
+	defer slot.Release()
+	segmentRegistry.SetState(job.ID, SegmentStateProcessing)
+
+	// Обработчики перед кодированием могут изменить полезную нагрузку (в том числе ее длину);
+	// в канал передается результат их работы
+	hookContext := payloadHookContext(job)
+	channelPayload := job.OriginalPayload
+	if payloadHooks.Has(HookStagePreCoding) {
+		hookContext.Stage = HookStagePreCoding
+		channelPayload, err = payloadHooks.Run(ctx, hookContext, job.OriginalPayload)
+		if err != nil {
+			log.Printf("Web Server ERROR: Обработчик полезной нагрузки перед кодированием для сегмента #%d/%d: %v", req.SegmentNumber, req.TotalSegments, err)
+			return failedResult(OutcomeRejected, http.StatusBadGateway, fmt.Sprintf("Ошибка обработчика полезной нагрузки перед кодированием: %v", err))
+		}
+		if len(channelPayload) > FixedPayloadSize {
+			return failedResult(OutcomeRejected, http.StatusUnprocessableEntity, fmt.Sprintf("Обработчик перед кодированием вернул %d байт, максимально допустимо %d", len(channelPayload), FixedPayloadSize))
+		}
+		segmentRegistry.Event(job.ID, HookStagePreCoding, fmt.Sprintf("Полезная нагрузка преобразована: %d -> %d байт", len(job.OriginalPayload), len(channelPayload)))
+	}
+
+	// --- Паддинг полезной нагрузки до FixedPayloadSize байт ---
+	paddedPayloadBytes := make([]byte, FixedPayloadSize)
+	// Копируем оригинальные данные в начало нового среза.
+	// Остаток среза будет заполнен нулевыми байтами (\x00) по умолчанию.
+	copy(paddedPayloadBytes, channelPayload)
+	// ---------------------------------------------
+
+	// Подготовка внутренней структуры Segment для обработки ChannelLayer
+	internalSegment := &Segment{
+		Payload:        paddedPayloadBytes, // Используем паддированную полезную нагрузку (FixedPayloadSize байт)
+		Timestamp:      job.Timestamp,      // Используем метку времени в наносекундах
+		TotalSegments:  req.TotalSegments,
+		SegmentNumber:  req.SegmentNumber,
+		OriginalLength: len(channelPayload),
+		// IsChannelError будет установлен ChannelLayer
+	}
+
+	log.Printf("Web Server: Принят сегмент #%d/%d от %s, обработка с полезной нагрузкой размера %d (ориг. %d)...",
+		req.SegmentNumber, req.TotalSegments, req.Sender, len(internalSegment.Payload), len(job.OriginalPayload))
+
+	// Обработка сегмента с использованием ChannelLayer.
+	// При длительной перегрузке обработка упрощается согласно режиму деградации.
+	processOptions, degradedAction := overloadController.Options()
+	// Управляющий трафик верхних уровней может быть освобожден от симуляции потерь и ошибок,
+	// чтобы установление соединения не срывалось случайными искажениями
+	if req.Type == SegmentTypeControl && controlConfig.ExemptImpairments {
+		processOptions.SkipImpairments = true
+	}
+	// Кодек, выбранный в запросе, заменяет кодек канала (и варианта A/B-эксперимента)
+	processOptions.Coder = job.Coder
+	// В A/B-эксперименте сегмент обрабатывается каналом выбранного варианта
+	arm, channel := abSplit.Pick(req.Sender, req.SendTime, req.SegmentNumber)
+	// Отправитель с профилем обрабатывается собственным каналом
+	if profileChannel := senderProfiles.Channel(req.Sender); profileChannel != nil {
+		arm, channel = "", profileChannel
+	}
+	linkSeq := linkSequencer.Next(req.Sender)
+	processedSegment, report := linkARQ.Transmit(ctx, channel, req.Sender+"|"+req.SendTime, internalSegment, job.Window, slot, processOptions)
+	report.Arm = arm
+	channelReport = report
+	// CRC-32C исходной полезной нагрузки обнаруживает ошибки, пропущенные поблочным декодером
+	// (при обработчиках полезной нагрузки в канале проверяется CRC-32C переданной в канал нагрузки)
+	payloadCRC := ""
+	if crc32cEnabled {
+		payloadCRC = payloadCRC32C(job.OriginalPayload)
+		verifyCRC32C(processedSegment, &channelReport, payloadCRC32C(channelPayload))
+	}
+	// Кадр учитывается в группе межкадровой коррекции (кадр четности передается по завершении группы)
+	parityFEC.Record(job, internalSegment, processedSegment, channel, channelReport, linkSeq)
+	segmentRegistry.SetChannel(job.ID, channelReport, degradedAction)
+	packetCapture.WriteFrame(req, channelReport)
+	frameTap.Mirror(job, channelPayload, channelReport, processedSegment)
+	coding = newCodingStats(len(job.OriginalPayload), channelReport)
+	// Кадр находится в канале в течение времени передачи и распространения. Обработчик очереди
+	// на это время не занимается: задержка кадра не ограничивает пропускную способность обработки
+	if delay := channelReport.Delay(); delay > 0 {
+		slot.Release()
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+
+	// --- Проверка результатов обработки канальным уровнем ---
+	if processedSegment == nil {
+		// Сегмент был потерян
+		log.Printf("Web Server: Сегмент #%d/%d потерян во время симуляции канала.", req.SegmentNumber, req.TotalSegments)
+		result = failedResult(OutcomeLost, http.StatusRequestTimeout, "Сегмент потерян во время моделирования канала") // 408 Request Timeout - разумный статус для потери
+		// Транспортный уровень может быть уведомлен о потере заглушкой, чтобы быстрее запросить повторную передачу
+		if forwardConfig.LostPlaceholders {
+			result.TransferStatus = forwardLostPlaceholder(job)
+		}
+		return result
+	}
+
+	if processedSegment.IsChannelError {
+		// Канальный уровень обнаружил неисправимую ошибку; дальнейшие действия задает политика
+		switch channelErrorPolicy {
+		case ChannelErrorPolicyForward:
+			log.Printf("Web Server: Канальный уровень обнаружил неисправимую ошибку для сегмента #%d/%d. Сегмент пересылается с флагом ошибки канала.", req.SegmentNumber, req.TotalSegments)
+		case ChannelErrorPolicyDrop:
+			log.Printf("Web Server: Канальный уровень обнаружил неисправимую ошибку для сегмента #%d/%d. Сегмент отброшен.", req.SegmentNumber, req.TotalSegments)
+			return failedResult(OutcomeChannelError, http.StatusRequestTimeout, "Сегмент с неисправимой ошибкой канала отброшен")
+		default:
+			log.Printf("Web Server: Канальный уровень обнаружил неисправимую ошибку для сегмента #%d/%d. Отправка ответа с ошибкой (Статус 500).", req.SegmentNumber, req.TotalSegments)
+			// Возвращаем 500, как запрошено, если канальный уровень не справился
+			return failedResult(OutcomeChannelError, http.StatusInternalServerError, "Во время обработки обнаружена неисправимая ошибка канала")
+		}
+	}
+	// --- Конец проверки результатов обработки канальным уровнем ---
+
+	// --- Обработка прошла успешно (нет потери, неисправимая ошибка отсутствует или пересылается). Теперь отправляем на /transfer ---
+
+	// Используем обработанную полезную нагрузку из processedSegment и конвертируем ее обратно в строку.
+	// Она всегда будет FixedPayloadSize байт, если не включено отсечение паддинга (нулевой паддинг
+	// отсекается для транспортных уровней, которые используют полезную нагрузку как точный текст;
+	// при укорочении кода паддинг не передается по каналу, и транспортный уровень получает ровно исходные байты).
+	outgoingPayload, err := decodedPayload(ctx, hookContext, processedSegment, forwardConfig.TrimPadding || channel.Shortening)
+	if err != nil {
+		log.Printf("Web Server ERROR: Обработчик полезной нагрузки после декодирования для сегмента #%d/%d: %v", req.SegmentNumber, req.TotalSegments, err)
+		return failedResult(OutcomeForwardFailed, http.StatusBadGateway, fmt.Sprintf("Ошибка обработчика полезной нагрузки после декодирования: %v", err))
+	}
+	outgoingPayloadString := string(outgoingPayload)
+
+	// Приемник проверяет порядковый номер кадра; номер кадра с неисправимой ошибкой недостоверен
+	var linkInfo *LinkSequenceInfo
+	if !processedSegment.IsChannelError {
+		linkInfo = linkSequencer.Receive(req.Sender, linkSeq)
+	}
+	if linkInfo != nil && (linkInfo.Gap > 0 || linkInfo.Late) {
+		log.Printf("ChannelLayer: Кадр #%d звена %s: пропущено номеров %d, вне порядка: %t", linkInfo.Seq, req.Sender, linkInfo.Gap, linkInfo.Late)
+		segmentRegistry.Event(job.ID, "link_sequence", fmt.Sprintf("Кадр #%d: пропущено номеров %d, вне порядка: %t", linkInfo.Seq, linkInfo.Gap, linkInfo.Late))
+	}
+
+	outgoingRequest := OutgoingTransferRequest{
+		SegmentNumber:  req.SegmentNumber,                    // Используем оригинал из входящего запроса
+		TotalSegments:  req.TotalSegments,                    // Используем оригинал из входящего запроса
+		Sender:         req.Sender,                           // Используем оригинал из входящего запроса
+		SendTime:       clockSkew.SkewSendTime(req.SendTime), // Используем оригинальный строковый формат из входящего запроса (со смещением часов, если включено)
+		Payload:        outgoingPayloadString,                // Используем обработанную (декодированную) полезную нагрузку (как строку, FixedPayloadSize байт или исходной длины при trim_padding)
+		Type:           outgoingSegmentType(req.Type),        // Тип передается только для управляющих сегментов
+		Degraded:       degradedAction,                       // Помечаем сегменты, обработанные в режиме деградации
+		IsChannelError: processedSegment.IsChannelError,      // Установлен только при политике "forward"
+		Corrected:      processedSegment.Corrected,           // Ошибки канала исправлены декодером
+		CRC32C:         payloadCRC,                           // Контрольная сумма исходной полезной нагрузки (если включена)
+		Link:           linkInfo,                             // Порядковый номер кадра канального уровня (если включен)
+	}
+	if payloadCRC != "" {
+		outgoingRequest.PayloadLength = len(job.OriginalPayload)
+	}
+	// История звеньев передается, если сегмент пришел от предыдущего звена или включена в конфигурации
+	if forwardConfig.CarryHops || len(req.Hops) > 0 {
+		outgoingRequest.Hops = appendHop(req.Hops, channelReport)
+	}
+
+	// Буфер джиттера выдает кадр транспортному уровню в момент его воспроизведения;
+	// ожидание не занимает обработчик очереди
+	if jitterBuffer != nil {
+		slot.Release()
+		segmentRegistry.Event(job.ID, "jitter_buffer", "Кадр ожидает момента воспроизведения")
+		if !jitterBuffer.Hold(ctx, req.Sender, req.SendTime, req.SegmentNumber) {
+			log.Printf("Web Server: Сегмент #%d/%d от %s опоздал и отброшен буфером джиттера.", req.SegmentNumber, req.TotalSegments, req.Sender)
+			return failedResult(OutcomeLost, http.StatusRequestTimeout, "Сегмент опоздал к моменту воспроизведения и отброшен буфером джиттера")
+		}
+	}
+
+	outgoingJSON, err := json.Marshal(outgoingRequest)
+	if err != nil {
+		log.Printf("Web Server ERROR: Не удалось сериализовать исходящий JSON для сегмента #%d/%d: %v", req.SegmentNumber, req.TotalSegments, err)
+		return failedResult(OutcomeForwardFailed, http.StatusInternalServerError, fmt.Sprintf("Не удалось упорядочить исходящий JSON: %v", err)) // 500, т.к. внутренняя ошибка при подготовке к отправке
+	}
+
+	log.Printf("Web Server: Обработка канальным уровнем успешна. Отправка сегмента #%d/%d на %s с размером полезной нагрузки %d",
+		req.SegmentNumber, req.TotalSegments, TransferURL, len(outgoingRequest.Payload))
+
+	// Сегмент записывается в журнал исходящих сегментов до отправки, чтобы после аварийного
+	// завершения процесса его можно было отправить повторно (см. RecoverJournal).
+	journalID := outboundJournal.Append(outgoingRequest)
+
+	// Отправка POST запроса на конечную точку /transfer
+	resp, err := forwardSegment(job.ID, outgoingJSON)
+	if err != nil {
+		// Ошибка при отправке запроса на целевой сервер (например, целевой сервер недоступен)
+		log.Printf("Web Server ERROR: Не удалось отправить сегмент #%d/%d на целевую конечную точку (%s): %v", req.SegmentNumber, req.TotalSegments, TransferURL, err)
+		// Отправляем 500, т.к. конечный этап (отправка) не удался
+		return failedResult(OutcomeForwardFailed, http.StatusInternalServerError, fmt.Sprintf("Не удалось отправить сегмент в конечную точку передачи: %v", err))
+	}
+	body := resp.Body
+	if body != nil {
+		log.Printf("Web Server: Получен ответ от конечной точки /transfer для сегмента #%d/%d (Status: %s): %s", req.SegmentNumber, req.TotalSegments, resp.Status, string(body))
+	}
+	// Кадр, продублированный в канале, доставляется транспортному уровню повторно (с интервалом
+	// между копиями — в фоне); ответы на копии не влияют на итог обработки сегмента
+	if channelReport.Duplicates > 0 {
+		interval := time.Duration(channelReport.DuplicateDelayMs * float64(time.Millisecond))
+		if interval > 0 {
+			go forwardDuplicates(job, outgoingRequest, outgoingJSON, channelReport.Duplicates, interval)
+		} else {
+			forwardDuplicates(job, outgoingRequest, outgoingJSON, channelReport.Duplicates, interval)
+		}
+	}
+
+	// --- Проверяем статус ответа от /transfer и определяем итоговый статус ответа на /code ---
+	if resp.StatusCode != http.StatusOK {
+		// Канальный уровень обработал успешно, но /transfer вернул НЕ 200 статус.
+		// Это означает, что отправка на следующий уровень не удалась.
+		// Отвечаем 500, так как весь процесс для данного сегмента не завершился успехом.
+		// Транспортный уровень ответил явным отказом: повторная отправка при восстановлении не нужна
+		outboundJournal.Abandon(journalID)
+		errMsg := fmt.Sprintf("Transfer to endpoint failed with status: %s", resp.Status)
+		if len(body) > 0 {
+			errMsg += fmt.Sprintf(". Transfer response body: %s", string(body))
+		}
+		result = failedResult(OutcomeForwardFailed, http.StatusInternalServerError, errMsg)
+		result.TransferStatus = resp.Status
+		return result
+	}
+
+	// Канальный уровень успешно обработал сегмент И /transfer вернул 200.
+	// Это полное успешное выполнение для данного сегмента.
+	outboundJournal.Ack(journalID)
+	if processedSegment.IsChannelError {
+		// Сегмент с ошибкой канала передан транспортному уровню, но доставленным не считается:
+		// повторная передача сегмента отправителем будет обработана заново
+		return SegmentResult{
+			Outcome:        OutcomeChannelError,
+			StatusCode:     http.StatusOK,
+			Status:         "Сегмент с неисправимой ошибкой канала передан с флагом is_channel_error.",
+			TransferStatus: resp.Status,
+			TransferBody:   string(body),
+			Forwarded:      true,
+		}
+	}
+	return SegmentResult{
+		Outcome:        OutcomeDelivered,
+		StatusCode:     http.StatusOK,
+		Status:         "Сегмент обработан канальным уровнем и успешно передан.",
+		TransferStatus: resp.Status,
+		TransferBody:   string(body),
+		Forwarded:      true,
+	}
+}
+
+// writeSegmentResult отправляет клиенту синхронный ответ на /code по итогу обработки сегмента.
+func writeSegmentResult(w http.ResponseWriter, job *segmentJob, result SegmentResult) {
+	req := job.Request
+	if job.APIVersion >= APIVersion2 {
+		writeSegmentResultV2(w, job, result)
+		return
+	}
+	if result.Error != "" {
+		log.Printf("Web Server: Ответили на /code для сегмента #%d/%d со статусом %d (%s)", req.SegmentNumber, req.TotalSegments, result.StatusCode, result.Outcome)
+		sendErrorResponse(w, result.Error, result.StatusCode)
+		return
+	}
+
+	w.WriteHeader(result.StatusCode)
+	responseMsg := map[string]interface{}{
+		"status":     result.Status,
+		"segment_id": job.ID,
+	}
+	if result.TransferStatus != "" {
+		responseMsg["transfer_status"] = result.TransferStatus
+		responseMsg["transfer_response_body"] = result.TransferBody
+	}
+	if result.Duplicate {
+		responseMsg["duplicate"] = true
+	}
+	if result.Outcome == OutcomeChannelError {
+		responseMsg["is_channel_error"] = true
+	}
+	if result.Coding != nil {
+		responseMsg["coding"] = result.Coding
+	}
+	json.NewEncoder(w).Encode(responseMsg)
+	log.Printf("Web Server: Ответили на /code для сегмента #%d/%d со статусом OK (статус transfer: %s)", req.SegmentNumber, req.TotalSegments, result.TransferStatus)
+}
+
+// writeSegmentResultV2 отправляет синхронный ответ на /code в формате версии 2:
+// итог моделирования канала (потеря, неисправимая ошибка) не считается ошибкой запроса.
+func writeSegmentResultV2(w http.ResponseWriter, job *segmentJob, result SegmentResult) {
+	req := job.Request
+	statusCode := statusCodeV2(result)
+	w.Header().Set(SegmentOutcomeHeader, result.Outcome)
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(SegmentResponseV2{
+		SegmentID:      job.ID,
+		Outcome:        result.Outcome,
+		Delivered:      result.Forwarded || result.Duplicate,
+		Status:         result.Status,
+		Error:          result.Error,
+		TransferStatus: result.TransferStatus,
+		TransferBody:   result.TransferBody,
+		Duplicate:      result.Duplicate,
+		Coding:         result.Coding,
+	})
+	log.Printf("Web Server: Ответили на /code (API v2) для сегмента #%d/%d со статусом %d (%s)", req.SegmentNumber, req.TotalSegments, statusCode, result.Outcome)
+}
+
+// forwardDuplicates доставляет на /transfer copies копий сегмента, продублированного в канале,
+// с интервалом interval между копиями. При нумерации кадров копии помечаются как дубликаты
+// (или не доставляются, если включен drop_duplicates).
+func forwardDuplicates(job *segmentJob, outgoingRequest OutgoingTransferRequest, outgoingJSON []byte, copies int, interval time.Duration) {
+	req := job.Request
+	for i := 1; i <= copies; i++ {
+		if interval > 0 {
+			time.Sleep(interval)
+		}
+		if outgoingRequest.Link != nil {
+			link, deliver := linkSequencer.Duplicate(outgoingRequest.Link)
+			if !deliver {
+				log.Printf("ChannelLayer: Копия %d кадра #%d (сегмент #%d/%d) отброшена как дубликат", i, link.Seq, req.SegmentNumber, req.TotalSegments)
+				continue
+			}
+			duplicate := outgoingRequest
+			duplicate.Link = link
+			outgoingJSON, _ = json.Marshal(duplicate) // Сериализация исходного сегмента уже выполнена успешно
+		}
+		if dupResp, err := forwardSegment(job.ID, outgoingJSON); err != nil {
+			log.Printf("Web Server ERROR: Не удалось отправить копию %d сегмента #%d/%d: %v", i, req.SegmentNumber, req.TotalSegments, err)
+		} else {
+			log.Printf("Web Server: Копия %d сегмента #%d/%d отправлена на %s (Status: %s)", i, req.SegmentNumber, req.TotalSegments, TransferURL, dupResp.Status)
+		}
+	}
+}
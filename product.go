@@ -0,0 +1,82 @@
+package main
+
+import "fmt"
+
+// productCoder — двумерный код-произведение: k = rows*cols информационных бит записываются
+// в матрицу по строкам, к каждой строке добавляется бит четности строки, к каждому столбцу —
+// бит четности столбца, в угол — общий бит четности. Кодовое слово — матрица
+// (rows+1)×(cols+1), передаваемая по строкам, n = (rows+1)*(cols+1).
+//
+// Одиночная ошибка нарушает четность ровно одной строки и одного столбца и исправляется
+// на их пересечении. Если нарушена четность нескольких строк или столбцов (или только строк,
+// или только столбцов), ошибка считается обнаруженной, но неисправимой: так код обнаруживает
+// многие многобитные искажения, в том числе все двойные ошибки.
+type productCoder struct {
+	rows, cols int
+}
+
+func (c productCoder) Name() string { return fmt.Sprintf("product%dx%d", c.rows, c.cols) }
+func (c productCoder) N() int       { return (c.rows + 1) * (c.cols + 1) }
+func (c productCoder) K() int       { return c.rows * c.cols }
+
+// EncodeBlock записывает информационные биты в матрицу и дополняет ее битами четности.
+func (c productCoder) EncodeBlock(infoBits []uint8) []uint8 {
+	width := c.cols + 1
+	codeword := make([]uint8, c.N())
+	for r := 0; r < c.rows; r++ {
+		for col := 0; col < c.cols; col++ {
+			bit := infoBits[r*c.cols+col]
+			codeword[r*width+col] = bit
+			codeword[r*width+c.cols] ^= bit      // Четность строки
+			codeword[c.rows*width+col] ^= bit    // Четность столбца
+			codeword[c.rows*width+c.cols] ^= bit // Общая четность (угловой бит)
+		}
+	}
+	return codeword
+}
+
+// DecodeBlock проверяет четность строк и столбцов и исправляет одиночную ошибку.
+func (c productCoder) DecodeBlock(codedBits []uint8) ([]uint8, bool, bool) {
+	width := c.cols + 1
+	received := append([]uint8(nil), codedBits...)
+
+	var badRows, badCols []int
+	for r := 0; r <= c.rows; r++ {
+		var parity uint8
+		for col := 0; col <= c.cols; col++ {
+			parity ^= received[r*width+col]
+		}
+		if parity != 0 {
+			badRows = append(badRows, r)
+		}
+	}
+	for col := 0; col <= c.cols; col++ {
+		var parity uint8
+		for r := 0; r <= c.rows; r++ {
+			parity ^= received[r*width+col]
+		}
+		if parity != 0 {
+			badCols = append(badCols, col)
+		}
+	}
+
+	corrected, uncorrectable := false, false
+	switch {
+	case len(badRows) == 0 && len(badCols) == 0:
+	case len(badRows) == 1 && len(badCols) == 1:
+		received[badRows[0]*width+badCols[0]] ^= 1
+		corrected = true
+	default:
+		uncorrectable = true
+	}
+
+	infoBits := make([]uint8, 0, c.K())
+	for r := 0; r < c.rows; r++ {
+		infoBits = append(infoBits, received[r*width:r*width+c.cols]...)
+	}
+	return infoBits, corrected, uncorrectable
+}
+
+func init() {
+	RegisterCoder(productCoder{rows: 8, cols: 8})
+}
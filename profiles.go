@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SenderProfileConfig — параметры канала отдельного отправителя; незаданные параметры берутся
+// у канала.
+type SenderProfileConfig struct {
+	P     *float64 `json:"p,omitempty"`     // Вероятность ошибки в бите
+	R     *float64 `json:"r,omitempty"`     // Вероятность потери кадра
+	Codec string   `json:"codec,omitempty"` // Кодек
+}
+
+// SenderProfiles — профили канала по отправителю: сегменты отправителя с профилем обрабатываются
+// собственным каналом с его вероятностями и кодеком (например, у alice шумная линия, у bob чистая).
+// Остальные параметры (этапы, цепочка искажений, скорость линии) общие с каналом, а постоянные
+// вероятности профиля не изменяются расписанием канала. Все методы допускают вызов на nil.
+type SenderProfiles struct {
+	channels map[string]*ChannelLayer
+}
+
+var senderProfiles *SenderProfiles // Глобальные профили отправителей (nil — не заданы)
+
+// NewSenderProfiles создает каналы профилей на основе канала base (nil, если профили не заданы).
+func NewSenderProfiles(profiles map[string]SenderProfileConfig, base *ChannelLayer) (*SenderProfiles, error) {
+	if len(profiles) == 0 {
+		return nil, nil
+	}
+	s := &SenderProfiles{channels: make(map[string]*ChannelLayer, len(profiles))}
+	for sender, cfg := range profiles {
+		if err := validProbability(cfg.P); err != nil {
+			return nil, fmt.Errorf("профиль %s: P: %w", sender, err)
+		}
+		if err := validProbability(cfg.R); err != nil {
+			return nil, fmt.Errorf("профиль %s: R: %w", sender, err)
+		}
+		cl := *base
+		cl.Schedule = nil
+		if cfg.P != nil {
+			cl.ErrorProbability = *cfg.P
+			cl.PathLoss = nil
+		}
+		if cfg.R != nil {
+			cl.LossProbability = *cfg.R
+		}
+		if cfg.Codec != "" {
+			coder, err := LookupCoder(cfg.Codec)
+			if err != nil {
+				return nil, fmt.Errorf("профиль %s: %w", sender, err)
+			}
+			cl.Coder = coder
+		}
+		s.channels[sender] = &cl
+	}
+	return s, nil
+}
+
+// Channel возвращает канал профиля отправителя (nil, если профиль не задан).
+func (s *SenderProfiles) Channel(sender string) *ChannelLayer {
+	if s == nil {
+		return nil
+	}
+	return s.channels[sender]
+}
+
+// Describe возвращает описание профилей для журнала.
+func (s *SenderProfiles) Describe() string {
+	senders := make([]string, 0, len(s.channels))
+	for sender := range s.channels {
+		senders = append(senders, sender)
+	}
+	sort.Strings(senders)
+	parts := make([]string, len(senders))
+	for i, sender := range senders {
+		cl := s.channels[sender]
+		parts[i] = fmt.Sprintf("%s (P=%.4f, R=%.4f, %s)", sender, cl.ErrorProbability, cl.LossProbability, cl.Coder.Name())
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxErasureTrials — наибольшее число стертых бит в блоке, для которого декодер со стираниями
+// перебирает все варианты их значений (2^maxErasureTrials попыток). При большем числе стираний
+// стертые биты заменяются нулями и блок декодируется как обычно.
+const maxErasureTrials = 8
+
+// Puncturer — выкалывание (перфорация) закодированного потока по периодическому шаблону:
+// бит с номером i передается, если pattern[i mod len(pattern)] = 1, и удаляется, если 0.
+// Выкалывание повышает скорость кода (k/n делится на долю передаваемых бит) ценой
+// корректирующей способности. Перед декодированием удаленные биты восстанавливаются как
+// стирания (позиции известны, значения — нет).
+type Puncturer struct {
+	pattern []uint8
+	kept    int // Число единиц в шаблоне
+}
+
+// NewPuncturer создает выкалывание по шаблону из символов 0 и 1 (например, "1110");
+// пустой шаблон — выкалывание не используется (nil).
+func NewPuncturer(pattern string) (*Puncturer, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	p := &Puncturer{pattern: make([]uint8, len(pattern))}
+	for i, c := range pattern {
+		switch c {
+		case '0':
+		case '1':
+			p.pattern[i] = 1
+			p.kept++
+		default:
+			return nil, fmt.Errorf("шаблон выкалывания '%s' должен состоять из 0 и 1", pattern)
+		}
+	}
+	if p.kept == 0 {
+		return nil, fmt.Errorf("шаблон выкалывания '%s' не передает ни одного бита", pattern)
+	}
+	return p, nil
+}
+
+// Pattern возвращает шаблон выкалывания в виде строки (пусто, если выкалывание не используется).
+func (p *Puncturer) Pattern() string {
+	if p == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, bit := range p.pattern {
+		b.WriteByte('0' + bit)
+	}
+	return b.String()
+}
+
+// KeptFraction возвращает долю передаваемых бит (1, если выкалывание не используется).
+func (p *Puncturer) KeptFraction() float64 {
+	if p == nil {
+		return 1
+	}
+	return float64(p.kept) / float64(len(p.pattern))
+}
+
+// Apply удаляет из потока биты, помеченные в шаблоне нулями.
+func (p *Puncturer) Apply(bits []uint8) []uint8 {
+	if p == nil {
+		return bits
+	}
+	out := make([]uint8, 0, len(bits))
+	for i, bit := range bits {
+		if p.pattern[i%len(p.pattern)] == 1 {
+			out = append(out, bit)
+		}
+	}
+	return out
+}
+
+// Restore восстанавливает поток исходной длины length: удаленные биты заменяются нулями
+// и помечаются как стертые. Если выкалывание не используется, erasures = nil.
+func (p *Puncturer) Restore(bits []uint8, length int) (out []uint8, erasures []bool) {
+	if p == nil {
+		return bits, nil
+	}
+	out = make([]uint8, length)
+	erasures = make([]bool, length)
+	j := 0
+	for i := range out {
+		if p.pattern[i%len(p.pattern)] == 0 || j >= len(bits) {
+			erasures[i] = true
+			continue
+		}
+		out[i] = bits[j]
+		j++
+	}
+	return out, erasures
+}
+
+// decodeBlockWithErasures декодирует блок со стертыми битами. Перебираются все значения стертых
+// бит (не более 2^maxErasureTrials вариантов): предпочтение отдается варианту, который
+// является кодовым словом, затем — варианту, исправленному декодером. Так стирания обрабатываются
+// любым кодеком без собственной поддержки стираний.
+func decodeBlockWithErasures(coder BlockCoder, block []uint8, erased []bool) ([]uint8, bool, bool) {
+	var positions []int
+	for i, e := range erased {
+		if e {
+			positions = append(positions, i)
+		}
+	}
+	if len(positions) == 0 || len(positions) > maxErasureTrials {
+		return coder.DecodeBlock(block)
+	}
+	candidate := append([]uint8(nil), block...)
+	var fallback []uint8
+	for fill := 0; fill < 1<<len(positions); fill++ {
+		for j, pos := range positions {
+			candidate[pos] = uint8(fill>>j) & 1
+		}
+		infoBits, corrected, uncorrectable := coder.DecodeBlock(candidate)
+		if uncorrectable {
+			continue
+		}
+		if !corrected {
+			return infoBits, false, false
+		}
+		if fallback == nil {
+			fallback = infoBits
+		}
+	}
+	if fallback != nil {
+		return fallback, true, false
+	}
+	return coder.DecodeBlock(block)
+}
+
+// decodeBitStreamWithErasures работает как decodeBitStream, но учитывает стертые биты
+// (erasures[i] — бит i потока стерт); при erasures = nil совпадает с decodeBitStream.
+func decodeBitStreamWithErasures(coder BlockCoder, encoded []uint8, erasures []bool, infoLen int) (decoded []uint8, correctedBlocks, errorBlocks int) {
+	if erasures == nil {
+		return decodeBitStream(coder, encoded, infoLen)
+	}
+	n, k := coder.N(), coder.K()
+	blocks := len(encoded) / n
+	decoded = make([]uint8, blocks*k)
+	for i := 0; i < blocks; i++ {
+		infoBits, corrected, uncorrectable := decodeBlockWithErasures(coder, encoded[i*n:(i+1)*n], erasures[i*n:(i+1)*n])
+		copy(decoded[i*k:(i+1)*k], infoBits)
+		if corrected {
+			correctedBlocks++
+		}
+		if uncorrectable {
+			errorBlocks++
+		}
+	}
+	return decoded[:infoLen], correctedBlocks, errorBlocks
+}
+
+// PunctureStats — параметры выкалывания для /stats.
+type PunctureStats struct {
+	Pattern      string  `json:"pattern"`       // Шаблон выкалывания
+	KeptFraction float64 `json:"kept_fraction"` // Доля передаваемых бит закодированного потока
+	MotherRate   float64 `json:"mother_rate"`   // Скорость кода без выкалывания (k/n кодека канала)
+	CodeRate     float64 `json:"code_rate"`     // Итоговая скорость кода после выкалывания
+}
+
+// PunctureStats возвращает параметры выкалывания канала (nil, если выкалывание не используется).
+func (cl *ChannelLayer) PunctureStats() *PunctureStats {
+	if cl == nil || cl.Puncture == nil {
+		return nil
+	}
+	motherRate := float64(cl.Coder.K()) / float64(cl.Coder.N())
+	return &PunctureStats{
+		Pattern:      cl.Puncture.Pattern(),
+		KeptFraction: cl.Puncture.KeptFraction(),
+		MotherRate:   motherRate,
+		CodeRate:     motherRate / cl.Puncture.KeptFraction(),
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull возвращается, когда очередь обработки заполнена.
+var ErrQueueFull = errors.New("очередь обработки переполнена")
+
+// queueTicket — место в очереди обработки, ожидающее свободного обработчика.
+type queueTicket struct {
+	sender     string
+	finish     float64       // Виртуальное время окончания обслуживания (тег WFQ)
+	seq        uint64        // Порядковый номер постановки в очередь (для одинаковых тегов — FIFO)
+	index      int           // Позиция в куче (-1, если билет уже извлечен)
+	ready      chan struct{} // Закрывается, когда билету выделен обработчик
+	enqueuedAt time.Time
+}
+
+// ticketHeap — куча билетов, упорядоченная по тегу окончания обслуживания.
+type ticketHeap []*queueTicket
+
+func (h ticketHeap) Len() int { return len(h) }
+func (h ticketHeap) Less(i, j int) bool {
+	if h[i].finish != h[j].finish {
+		return h[i].finish < h[j].finish
+	}
+	return h[i].seq < h[j].seq
+}
+func (h ticketHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *ticketHeap) Push(x interface{}) {
+	t := x.(*queueTicket)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+func (h *ticketHeap) Pop() interface{} {
+	old := *h
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	t.index = -1
+	*h = old[:len(old)-1]
+	return t
+}
+
+// FairQueue — очередь обработки сегментов со взвешенным справедливым обслуживанием отправителей
+// (self-clocked fair queueing). Одновременно обрабатывается не более workers сегментов; ожидающие
+// сегменты получают обработчик в порядке виртуального времени окончания обслуживания, которое для
+// каждого отправителя растет на 1/weight за сегмент. Поэтому отправитель, передающий большой файл,
+// не может надолго задержать короткие сообщения чата других отправителей.
+type FairQueue struct {
+	mu            sync.Mutex
+	workers       int                // Максимальное число одновременно обрабатываемых сегментов
+	busy          int                // Число занятых обработчиков
+	capacity      int                // Максимальное число ожидающих сегментов
+	waiting       ticketHeap         // Ожидающие сегменты
+	virtualTime   float64            // Тег последнего выданного на обработку сегмента
+	lastFinish    map[string]float64 // Тег последнего поставленного в очередь сегмента каждого отправителя
+	queued        map[string]int     // Число ожидающих сегментов каждого отправителя
+	weights       map[string]float64 // Веса отправителей
+	defaultWeight float64            // Вес отправителей, отсутствующих в weights
+	seq           uint64
+}
+
+// NewFairQueue создает очередь обработки с заданным числом обработчиков, емкостью и весами отправителей.
+func NewFairQueue(workers, capacity int, weights map[string]float64, defaultWeight float64) *FairQueue {
+	if workers < 1 {
+		workers = 1
+	}
+	if defaultWeight <= 0 {
+		defaultWeight = 1
+	}
+	log.Printf("FairQueue: Создана с %d обработчиками, емкостью %d и %d персональными весами отправителей",
+		workers, capacity, len(weights))
+
+	return &FairQueue{
+		workers:       workers,
+		capacity:      capacity,
+		lastFinish:    make(map[string]float64),
+		queued:        make(map[string]int),
+		weights:       weights,
+		defaultWeight: defaultWeight,
+	}
+}
+
+// weightFor возвращает вес отправителя.
+func (q *FairQueue) weightFor(sender string) float64 {
+	if w, ok := q.weights[sender]; ok && w > 0 {
+		return w
+	}
+	return q.defaultWeight
+}
+
+// QueueSlot — обработчик очереди, выделенный сегменту. Обработчик освобождается по окончании
+// обработки или досрочно (Release), а на время ожидания, которому обработчик не нужен, его можно
+// вернуть очереди (Suspend). Методы вызываются только из горутины, обрабатывающей сегмент,
+// и допускают вызов на nil.
+type QueueSlot struct {
+	queue  *FairQueue
+	sender string
+	held   bool // Обработчик занят сегментом
+}
+
+// Acquire ожидает, пока сегменту отправителя будет выделен обработчик, и возвращает его;
+// обработчик необходимо освободить (Release) по окончании обработки.
+// Возвращает ErrQueueFull, если очередь заполнена, или ошибку контекста, если клиент
+// перестал ждать ответа.
+func (q *FairQueue) Acquire(ctx context.Context, sender string) (*QueueSlot, error) {
+	if err := q.acquire(ctx, sender, true); err != nil {
+		return nil, err
+	}
+	return &QueueSlot{queue: q, sender: sender, held: true}, nil
+}
+
+// Release освобождает обработчик; повторный вызов ничего не делает.
+func (s *QueueSlot) Release() {
+	if s == nil || !s.held {
+		return
+	}
+	s.held = false
+	s.queue.release()
+}
+
+// Suspend возвращает обработчик очереди на время wait и затем снова ожидает обработчика. Сегмент
+// уже принят в обработку, поэтому повторное ожидание не ограничено емкостью очереди. Возвращает
+// результат wait; если ctx отменен до выделения обработчика, возвращает false и обработчик остается
+// свободным.
+func (s *QueueSlot) Suspend(ctx context.Context, wait func() bool) bool {
+	if s == nil || !s.held {
+		return wait()
+	}
+	s.Release()
+	ok := wait()
+	if err := s.queue.acquire(ctx, s.sender, false); err != nil {
+		return false
+	}
+	s.held = true
+	return ok
+}
+
+// acquire ожидает, пока сегменту отправителя будет выделен обработчик. limited ограничивает
+// ожидание емкостью очереди.
+func (q *FairQueue) acquire(ctx context.Context, sender string, limited bool) error {
+	q.mu.Lock()
+	// Свободный обработчик и пустая очередь — обслуживаем сразу
+	if q.busy < q.workers && len(q.waiting) == 0 {
+		q.busy++
+		q.virtualTime = max(q.virtualTime, q.lastFinish[sender]) + 1/q.weightFor(sender)
+		delete(q.lastFinish, sender)
+		q.mu.Unlock()
+		return nil
+	}
+	if limited && q.capacity > 0 && len(q.waiting) >= q.capacity {
+		q.mu.Unlock()
+		return ErrQueueFull
+	}
+
+	q.seq++
+	ticket := &queueTicket{
+		sender:     sender,
+		finish:     max(q.virtualTime, q.lastFinish[sender]) + 1/q.weightFor(sender),
+		seq:        q.seq,
+		ready:      make(chan struct{}),
+		enqueuedAt: time.Now(),
+	}
+	q.lastFinish[sender] = ticket.finish
+	q.queued[sender]++
+	heap.Push(&q.waiting, ticket)
+	q.mu.Unlock()
+
+	select {
+	case <-ticket.ready:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if ticket.index < 0 {
+			// Обработчик уже выделен одновременно с отменой — возвращаем его
+			q.releaseLocked()
+		} else {
+			heap.Remove(&q.waiting, ticket.index)
+			q.dequeuedLocked(ticket)
+		}
+		return ctx.Err()
+	}
+}
+
+// release освобождает обработчик и передает его следующему сегменту в очереди.
+func (q *FairQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.releaseLocked()
+}
+
+// releaseLocked освобождает обработчик. Вызывается под блокировкой q.mu.
+func (q *FairQueue) releaseLocked() {
+	q.busy--
+	for q.busy < q.workers && len(q.waiting) > 0 {
+		ticket := heap.Pop(&q.waiting).(*queueTicket)
+		q.virtualTime = ticket.finish
+		q.dequeuedLocked(ticket)
+		q.busy++
+		close(ticket.ready)
+	}
+}
+
+// dequeuedLocked обновляет учет отправителя после извлечения его сегмента из очереди.
+// Сведения об отправителях без ожидающих сегментов удаляются, если их тег уже не влияет на обслуживание.
+func (q *FairQueue) dequeuedLocked(ticket *queueTicket) {
+	q.queued[ticket.sender]--
+	if q.queued[ticket.sender] <= 0 {
+		delete(q.queued, ticket.sender)
+		if q.lastFinish[ticket.sender] <= q.virtualTime {
+			delete(q.lastFinish, ticket.sender)
+		}
+	}
+}
+
+// OldestWait возвращает время ожидания старейшего сегмента в очереди.
+func (q *FairQueue) OldestWait(now time.Time) time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var oldest time.Duration
+	for _, t := range q.waiting {
+		oldest = max(oldest, now.Sub(t.enqueuedAt))
+	}
+	return oldest
+}
+
+// QueueStats — состояние очереди обработки.
+type QueueStats struct {
+	Workers int            `json:"workers"` // Число обработчиков
+	Busy    int            `json:"busy"`    // Число занятых обработчиков
+	Waiting int            `json:"waiting"` // Число ожидающих сегментов
+	Senders map[string]int `json:"senders"` // Число ожидающих сегментов по отправителям
+}
+
+// Stats возвращает текущее состояние очереди.
+func (q *FairQueue) Stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	senders := make(map[string]int, len(q.queued))
+	for sender, n := range q.queued {
+		senders[sender] = n
+	}
+	return QueueStats{
+		Workers: q.workers,
+		Busy:    q.busy,
+		Waiting: len(q.waiting),
+		Senders: senders,
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const APIKeyHeader = "X-API-Key" // Заголовок, в котором клиент передает свой ключ API
+
+// Quota описывает ограничения для одного ключа API.
+// Нулевое значение лимита означает, что соответствующее ограничение не применяется.
+type Quota struct {
+	SegmentsPerHour int64 `json:"segments_per_hour"` // Максимальное число сегментов в течение часа
+	BytesPerDay     int64 `json:"bytes_per_day"`     // Максимальный объем полезной нагрузки (в байтах, до паддинга) в сутки
+}
+
+// quotaUsage хранит текущее потребление квоты одним ключом в пределах окон учета.
+type quotaUsage struct {
+	hourStart time.Time // Начало текущего часового окна
+	segments  int64     // Количество сегментов в текущем часовом окне
+	dayStart  time.Time // Начало текущего суточного окна
+	bytes     int64     // Количество байт в текущем суточном окне
+}
+
+// QuotaStatus описывает состояние квоты ключа после проверки очередного сегмента.
+type QuotaStatus struct {
+	Allowed           bool      // true, если сегмент укладывается в квоту и был учтен
+	Quota             Quota     // Примененная квота
+	SegmentsRemaining int64     // Сколько сегментов еще можно отправить в текущем часе
+	SegmentsReset     time.Time // Момент сброса часового окна
+	BytesRemaining    int64     // Сколько байт еще можно отправить в текущих сутках
+	BytesReset        time.Time // Момент сброса суточного окна
+	RetryAt           time.Time // Момент, когда отклоненный сегмент снова уложится в квоту
+	Reason            string    // Причина отказа (если Allowed == false)
+}
+
+// QuotaManager учитывает потребление квот по ключам API.
+// Окна учета фиксированные: часовое окно начинается в начале часа, суточное — в полночь UTC.
+// Потребление хранится только для ключей, обращавшихся в текущих сутках.
+type QuotaManager struct {
+	mu           sync.Mutex
+	quotas       map[string]Quota
+	defaultQuota Quota
+	usage        map[string]*quotaUsage
+	prunedDay    time.Time        // Начало суток, в которые потребление прошлых суток уже удалено
+	now          func() time.Time // Источник времени (выделен для удобства подмены)
+}
+
+// NewQuotaManager создает менеджер квот с заданными квотами по ключам и квотой по умолчанию.
+func NewQuotaManager(quotas map[string]Quota, defaultQuota Quota) *QuotaManager {
+	log.Printf("QuotaManager: Создан с %d персональными квотами (по умолчанию: %d сегм./час, %d байт/сутки)",
+		len(quotas), defaultQuota.SegmentsPerHour, defaultQuota.BytesPerDay)
+
+	return &QuotaManager{
+		quotas:       quotas,
+		defaultQuota: defaultQuota,
+		usage:        make(map[string]*quotaUsage),
+		now:          time.Now,
+	}
+}
+
+// quotaFor возвращает квоту, действующую для указанного ключа.
+func (qm *QuotaManager) quotaFor(key string) Quota {
+	if q, ok := qm.quotas[key]; ok {
+		return q
+	}
+	return qm.defaultQuota
+}
+
+// Consume проверяет, укладывается ли сегмент размером payloadBytes в квоту ключа, и,
+// если укладывается, учитывает его. Если сегмент превышает хотя бы один из лимитов,
+// потребление не изменяется, а в статусе возвращается причина отказа.
+func (qm *QuotaManager) Consume(key string, payloadBytes int64) QuotaStatus {
+	qm.mu.Lock()
+	defer qm.mu.Unlock()
+
+	now := qm.now().UTC()
+	quota := qm.quotaFor(key)
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	// С началом новых суток удаляем потребление ключей, не обращавшихся в новых сутках:
+	// их окна учета истекли, а иначе учет рос бы с каждым когда-либо переданным ключом
+	if !qm.prunedDay.Equal(dayStart) {
+		qm.prunedDay = dayStart
+		for k, u := range qm.usage {
+			if !u.dayStart.Equal(dayStart) {
+				delete(qm.usage, k)
+			}
+		}
+	}
+
+	usage, ok := qm.usage[key]
+	if !ok {
+		usage = &quotaUsage{}
+		qm.usage[key] = usage
+	}
+
+	// Сбрасываем счетчики, если текущие окна учета истекли
+	hourStart := now.Truncate(time.Hour)
+	if !usage.hourStart.Equal(hourStart) {
+		usage.hourStart = hourStart
+		usage.segments = 0
+	}
+	if !usage.dayStart.Equal(dayStart) {
+		usage.dayStart = dayStart
+		usage.bytes = 0
+	}
+
+	status := QuotaStatus{
+		Allowed:       true,
+		Quota:         quota,
+		SegmentsReset: hourStart.Add(time.Hour),
+		BytesReset:    dayStart.AddDate(0, 0, 1),
+	}
+
+	if quota.SegmentsPerHour > 0 && usage.segments+1 > quota.SegmentsPerHour {
+		status.Allowed = false
+		status.Reason = "превышена квота сегментов в час"
+		status.RetryAt = status.SegmentsReset
+	} else if quota.BytesPerDay > 0 && usage.bytes+payloadBytes > quota.BytesPerDay {
+		status.Allowed = false
+		status.Reason = "превышена квота байт в сутки"
+		status.RetryAt = status.BytesReset
+	}
+
+	if status.Allowed {
+		usage.segments++
+		usage.bytes += payloadBytes
+	}
+
+	status.SegmentsRemaining = quota.SegmentsPerHour - usage.segments
+	status.BytesRemaining = quota.BytesPerDay - usage.bytes
+	return status
+}
+
+// Refund возвращает в квоту ключа сегмент, учтенный Consume со статусом status, но не принятый
+// к обработке. Сегмент возвращается только в окна учета, в которых он был учтен.
+// Возвращается состояние квоты после возврата.
+func (qm *QuotaManager) Refund(key string, payloadBytes int64, status QuotaStatus) QuotaStatus {
+	qm.mu.Lock()
+	defer qm.mu.Unlock()
+
+	usage, ok := qm.usage[key]
+	if !ok || !status.Allowed {
+		return status
+	}
+	if usage.hourStart.Add(time.Hour).Equal(status.SegmentsReset) && usage.segments > 0 {
+		usage.segments--
+	}
+	if usage.dayStart.AddDate(0, 0, 1).Equal(status.BytesReset) {
+		usage.bytes = max(usage.bytes-payloadBytes, 0)
+	}
+	status.SegmentsRemaining = status.Quota.SegmentsPerHour - usage.segments
+	status.BytesRemaining = status.Quota.BytesPerDay - usage.bytes
+	return status
+}
+
+// RetryAfter возвращает время (в секундах), через которое отклоненный сегмент уложится в квоту.
+func (s QuotaStatus) RetryAfter(now time.Time) int64 {
+	seconds := int64(s.RetryAt.Sub(now).Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// SetHeaders записывает состояние квоты в заголовки ответа.
+// Заголовки выставляются только для лимитов, которые действительно применяются.
+func (s QuotaStatus) SetHeaders(h http.Header) {
+	if s.Quota.SegmentsPerHour > 0 {
+		h.Set("X-Quota-Segments-Limit", strconv.FormatInt(s.Quota.SegmentsPerHour, 10))
+		h.Set("X-Quota-Segments-Remaining", strconv.FormatInt(max(s.SegmentsRemaining, 0), 10))
+		h.Set("X-Quota-Segments-Reset", strconv.FormatInt(s.SegmentsReset.Unix(), 10))
+	}
+	if s.Quota.BytesPerDay > 0 {
+		h.Set("X-Quota-Bytes-Limit", strconv.FormatInt(s.Quota.BytesPerDay, 10))
+		h.Set("X-Quota-Bytes-Remaining", strconv.FormatInt(max(s.BytesRemaining, 0), 10))
+		h.Set("X-Quota-Bytes-Reset", strconv.FormatInt(s.BytesReset.Unix(), 10))
+	}
+}
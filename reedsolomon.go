@@ -0,0 +1,220 @@
+package main
+
+import "fmt"
+
+// Арифметика поля GF(2^8) с примитивным многочленом x^8 + x^4 + x^3 + x^2 + 1 (0x11D).
+var (
+	gf256Exp [512]uint8 // gf256Exp[i] = α^i (удвоенная длина, чтобы не брать индекс по модулю 255)
+	gf256Log [256]int   // gf256Log[α^i] = i
+)
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gf256Exp[i] = uint8(x)
+		gf256Log[x] = i
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+	for i := 255; i < len(gf256Exp); i++ {
+		gf256Exp[i] = gf256Exp[i-255]
+	}
+}
+
+// gf256Mul умножает элементы поля.
+func gf256Mul(a, b uint8) uint8 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gf256Exp[gf256Log[a]+gf256Log[b]]
+}
+
+// gf256Div делит элементы поля (b != 0).
+func gf256Div(a, b uint8) uint8 {
+	if a == 0 {
+		return 0
+	}
+	return gf256Exp[gf256Log[a]+255-gf256Log[b]]
+}
+
+// gf256PolyEval вычисляет многочлен (коэффициенты по возрастанию степеней) в точке x.
+func gf256PolyEval(poly []uint8, x uint8) uint8 {
+	var y uint8
+	for i := len(poly) - 1; i >= 0; i-- {
+		y = gf256Mul(y, x) ^ poly[i]
+	}
+	return y
+}
+
+// reedSolomonCoder — код Рида — Соломона RS(n,k) над GF(256): k байт полезной нагрузки кодируются
+// в n байт (систематически: байты данных, затем n-k проверочных байт). Код исправляет до
+// t = (n-k)/2 ошибочных байт в блоке независимо от числа ошибочных бит в каждом байте, поэтому
+// устойчив к пакетам ошибок: пакет длиной до 8(t-1)+1 бит затрагивает не более t байт.
+// При n < 255 используется укороченный код. Корни порождающего многочлена — α^0 .. α^(n-k-1).
+type reedSolomonCoder struct {
+	name      string
+	n, k      int
+	generator []uint8 // Порождающий многочлен (по возрастанию степеней, старший коэффициент 1)
+}
+
+// newReedSolomonCoder создает кодек RS(n,k) с именем name.
+func newReedSolomonCoder(name string, n, k int) (*reedSolomonCoder, error) {
+	if k < 1 || n <= k || n > 255 {
+		return nil, fmt.Errorf("параметры RS(n,k) должны удовлетворять 0 < k < n <= 255, задано n=%d, k=%d", n, k)
+	}
+	// g(x) = (x + α^0)(x + α^1)...(x + α^(n-k-1))
+	generator := []uint8{1}
+	for i := 0; i < n-k; i++ {
+		next := make([]uint8, len(generator)+1)
+		for j, c := range generator {
+			next[j+1] ^= c
+			next[j] ^= gf256Mul(c, gf256Exp[i])
+		}
+		generator = next
+	}
+	return &reedSolomonCoder{name: name, n: n, k: k, generator: generator}, nil
+}
+
+func (c *reedSolomonCoder) Name() string { return c.name }
+func (c *reedSolomonCoder) N() int       { return c.n * 8 }
+func (c *reedSolomonCoder) K() int       { return c.k * 8 }
+
+// EncodeBlock кодирует k байт (8k бит) в n байт (8n бит).
+func (c *reedSolomonCoder) EncodeBlock(infoBits []uint8) []uint8 {
+	message := bitStreamToBytes(infoBits)
+	parityLen := c.n - c.k
+	// Остаток от деления m(x) * x^(n-k) на g(x); байт codeword[j] — коэффициент при x^(n-1-j)
+	codeword := make([]uint8, c.n)
+	copy(codeword, message)
+	remainder := make([]uint8, parityLen) // remainder[0] — старший коэффициент
+	for _, b := range message {
+		feedback := b ^ remainder[0]
+		copy(remainder, remainder[1:])
+		remainder[parityLen-1] = 0
+		if feedback != 0 {
+			for j := 0; j < parityLen; j++ {
+				remainder[j] ^= gf256Mul(feedback, c.generator[parityLen-1-j])
+			}
+		}
+	}
+	copy(codeword[c.k:], remainder)
+	return bytesToBitStream(codeword)
+}
+
+// syndromes вычисляет синдромы S_i = r(α^i), i = 0..n-k-1; возвращает признак ненулевого синдрома.
+func (c *reedSolomonCoder) syndromes(received []uint8) ([]uint8, bool) {
+	syndromes := make([]uint8, c.n-c.k)
+	nonzero := false
+	for i := range syndromes {
+		var s uint8
+		for _, b := range received {
+			s = gf256Mul(s, gf256Exp[i]) ^ b
+		}
+		syndromes[i] = s
+		nonzero = nonzero || s != 0
+	}
+	return syndromes, nonzero
+}
+
+// DecodeBlock декодирует n байт: алгоритм Берлекэмпа — Мэсси находит многочлен локаторов ошибок,
+// процедура Ченя — позиции ошибок, алгоритм Форни — их значения.
+func (c *reedSolomonCoder) DecodeBlock(codedBits []uint8) ([]uint8, bool, bool) {
+	received := bitStreamToBytes(codedBits)
+	syndromes, nonzero := c.syndromes(received)
+	if !nonzero {
+		return bytesToBitStream(received[:c.k]), false, false
+	}
+	uncorrectable := func() ([]uint8, bool, bool) {
+		return bytesToBitStream(received[:c.k]), false, true
+	}
+
+	// Берлекэмп — Мэсси: locator (по возрастанию степеней, locator[0] = 1)
+	locator, previous := []uint8{1}, []uint8{1}
+	degree, shift := 0, 1
+	var lastDiscrepancy uint8 = 1
+	for step := range syndromes {
+		discrepancy := syndromes[step]
+		for i := 1; i <= degree && i < len(locator); i++ {
+			discrepancy ^= gf256Mul(locator[i], syndromes[step-i])
+		}
+		if discrepancy == 0 {
+			shift++
+			continue
+		}
+		scale := gf256Div(discrepancy, lastDiscrepancy)
+		updated := make([]uint8, max(len(locator), len(previous)+shift))
+		copy(updated, locator)
+		for i, coef := range previous {
+			updated[i+shift] ^= gf256Mul(scale, coef)
+		}
+		if 2*degree <= step {
+			previous, locator = locator, updated
+			degree = step + 1 - degree
+			lastDiscrepancy = discrepancy
+			shift = 1
+		} else {
+			locator = updated
+			shift++
+		}
+	}
+	if 2*degree > c.n-c.k {
+		return uncorrectable()
+	}
+
+	// Многочлен значений ошибок Ω(x) = S(x)Λ(x) mod x^(n-k)
+	evaluator := make([]uint8, c.n-c.k)
+	for i := range evaluator {
+		for j := 0; j <= i && j < len(locator); j++ {
+			evaluator[i] ^= gf256Mul(locator[j], syndromes[i-j])
+		}
+	}
+	// Формальная производная Λ'(x): в поле характеристики 2 остаются только нечетные степени
+	derivative := make([]uint8, max(len(locator)-1, 1))
+	for i := 1; i < len(locator); i += 2 {
+		derivative[i-1] = locator[i]
+	}
+
+	// Процедура Ченя и алгоритм Форни: байт received[j] — коэффициент при x^p, p = n-1-j,
+	// его локатор X = α^p; ошибка в нем, если Λ(X^-1) = 0, значение ошибки e = X·Ω(X^-1) / Λ'(X^-1)
+	found := 0
+	for j := 0; j < c.n; j++ {
+		p := c.n - 1 - j
+		xInv := gf256Exp[(255-p)%255]
+		if gf256PolyEval(locator, xInv) != 0 {
+			continue
+		}
+		denominator := gf256PolyEval(derivative, xInv)
+		if denominator == 0 {
+			return uncorrectable()
+		}
+		received[j] ^= gf256Mul(gf256Exp[p], gf256Div(gf256PolyEval(evaluator, xInv), denominator))
+		found++
+	}
+	if found != degree {
+		return uncorrectable()
+	}
+	if _, nonzero := c.syndromes(received); nonzero {
+		return uncorrectable()
+	}
+	return bytesToBitStream(received[:c.k]), true, false
+}
+
+func init() {
+	// Классический RS(255,223) (t = 16; полезная нагрузка сегмента занимает один укороченный блок
+	// с дополнением нулями) и укороченный RS(160,140) под размер полезной нагрузки (t = 10)
+	for _, params := range []struct {
+		name string
+		n, k int
+	}{{"rs255_223", 255, 223}, {"rs160_140", 160, 140}} {
+		coder, err := newReedSolomonCoder(params.name, params.n, params.k)
+		if err != nil {
+			panic(err)
+		}
+		RegisterCoder(coder)
+	}
+	RegisterCodecType("reed_solomon", func(cfg CodecConfig) (BlockCoder, error) {
+		return newReedSolomonCoder(cfg.Name, cfg.N, cfg.K)
+	})
+}
@@ -0,0 +1,87 @@
+package main
+
+import "fmt"
+
+// repetitionCoder — код повторения [n,1]: каждый бит передается n раз и восстанавливается
+// голосованием по большинству. Базовый кодек для сравнения с [7,4] в отчетах статистики: при
+// нечетном n исправляет до (n-1)/2 ошибок в блоке, при четном n равенство голосов означает
+// неисправимую ошибку.
+type repetitionCoder struct {
+	name string
+	n    int // Число повторений бита
+}
+
+// newRepetitionCoder создает код повторения с n повторениями и именем name (по умолчанию repetition<n>).
+func newRepetitionCoder(name string, n int) (repetitionCoder, error) {
+	if n < 2 {
+		return repetitionCoder{}, fmt.Errorf("число повторений должно быть не менее 2, задано %d", n)
+	}
+	if name == "" {
+		name = fmt.Sprintf("repetition%d", n)
+	}
+	return repetitionCoder{name: name, n: n}, nil
+}
+
+func (c repetitionCoder) Name() string { return c.name }
+func (c repetitionCoder) N() int       { return c.n }
+func (repetitionCoder) K() int         { return 1 }
+
+// EncodeBlock повторяет информационный бит n раз.
+func (c repetitionCoder) EncodeBlock(infoBits []uint8) []uint8 {
+	codeword := make([]uint8, c.n)
+	for i := range codeword {
+		codeword[i] = infoBits[0]
+	}
+	return codeword
+}
+
+// DecodeBlock восстанавливает бит голосованием по большинству.
+func (c repetitionCoder) DecodeBlock(codedBits []uint8) ([]uint8, bool, bool) {
+	ones := 0
+	for _, bit := range codedBits {
+		ones += int(bit)
+	}
+	zeros := c.n - ones
+	switch {
+	case ones == zeros:
+		return []uint8{codedBits[0]}, false, true
+	case ones > zeros:
+		return []uint8{1}, zeros > 0, false
+	default:
+		return []uint8{0}, ones > 0, false
+	}
+}
+
+// DecodeBlockSoft восстанавливает бит по сумме LLR всех повторений (оптимальное решение
+// для канала с гауссовым шумом); нулевая сумма означает неисправимую ошибку.
+func (c repetitionCoder) DecodeBlockSoft(llr []float64) ([]uint8, bool, bool) {
+	sum := 0.0
+	for _, l := range llr {
+		sum += l
+	}
+	if sum == 0 {
+		return []uint8{0}, false, true
+	}
+	bit := uint8(0)
+	if sum < 0 {
+		bit = 1
+	}
+	corrected := false
+	for _, hard := range hardDecision(llr) {
+		corrected = corrected || hard != bit
+	}
+	return []uint8{bit}, corrected, false
+}
+
+func init() {
+	for _, n := range []int{3, 5} {
+		coder, err := newRepetitionCoder("", n)
+		if err != nil {
+			panic(err)
+		}
+		RegisterCoder(coder)
+	}
+	RegisterCodecType("repetition", func(cfg CodecConfig) (BlockCoder, error) {
+		return newRepetitionCoder(cfg.Name, cfg.N)
+	})
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const NakEndpoint = "/nak" // Конечная точка запросов повторной передачи от транспортного уровня
+
+// RetransmitConfig задает буфер повторной передачи сегментов.
+type RetransmitConfig struct {
+	Messages int      `json:"messages"` // Сообщений в буфере; 0 — буфер и /nak отключены
+	TTL      Duration `json:"ttl"`      // Время хранения сообщения с момента приема первого сегмента (0 — без ограничения)
+}
+
+// NakRequest — запрос повторной передачи сегмента на /nak. Сообщение задается отправителем и меткой
+// времени timestamp (в наносекундах) или строкой send_time в том же формате, что и на /code.
+type NakRequest struct {
+	Sender        string `json:"sender"`
+	Timestamp     int64  `json:"timestamp,omitempty"`
+	SendTime      string `json:"send_time,omitempty"`
+	SegmentNumber int    `json:"segment_number"`
+}
+
+// RetransmitStats — статистика буфера повторной передачи для /stats.
+type RetransmitStats struct {
+	Messages      int   `json:"messages"`      // Сообщений в буфере
+	Segments      int   `json:"segments"`      // Сегментов в буфере
+	Naks          int64 `json:"naks"`          // Принятых запросов /nak
+	Retransmitted int64 `json:"retransmitted"` // Сегментов, повторно обработанных по запросу
+	Misses        int64 `json:"misses"`        // Запросов сегментов, которых нет в буфере
+	Evicted       int64 `json:"evicted"`       // Сообщений, вытесненных из буфера или устаревших
+}
+
+// retransmitKey идентифицирует сообщение в буфере повторной передачи: отправитель и send_time,
+// как и сообщение ARQ (разные отправители могут использовать одинаковый send_time).
+type retransmitKey struct {
+	sender    string
+	timestamp int64
+}
+
+// retransmitMessage — сегменты одного сообщения в буфере повторной передачи.
+type retransmitMessage struct {
+	storedAt time.Time
+	segments map[int]*segmentJob
+}
+
+// RetransmitBuffer хранит принятые сегменты данных последних сообщений, чтобы транспортный уровень
+// мог запросить повторную обработку сегмента (/nak), не обращаясь к отправителю. Сообщение
+// идентифицируется отправителем и меткой времени send_time; хранится не более messages сообщений, самые старые
+// вытесняются. Методы допускают вызов на nil (буфер отключен).
+type RetransmitBuffer struct {
+	capacity int
+	ttl      time.Duration
+
+	mu       sync.Mutex
+	messages map[retransmitKey]*retransmitMessage
+	order    []retransmitKey // Сообщения в порядке поступления (для вытеснения)
+
+	naks          atomic.Int64
+	retransmitted atomic.Int64
+	misses        atomic.Int64
+	evicted       atomic.Int64
+}
+
+// NewRetransmitBuffer создает буфер повторной передачи по конфигурации (nil, если он отключен).
+func NewRetransmitBuffer(cfg RetransmitConfig) (*RetransmitBuffer, error) {
+	if cfg.Messages == 0 {
+		return nil, nil
+	}
+	if cfg.Messages < 0 || cfg.TTL.Duration < 0 {
+		return nil, fmt.Errorf("число сообщений и время хранения не могут быть отрицательными")
+	}
+	return &RetransmitBuffer{
+		capacity: cfg.Messages,
+		ttl:      cfg.TTL.Duration,
+		messages: make(map[retransmitKey]*retransmitMessage),
+	}, nil
+}
+
+// expireLocked удаляет устаревшие сообщения и вытесняет самые старые сверх емкости. Вызывается под b.mu.
+func (b *RetransmitBuffer) expireLocked(now time.Time) {
+	for len(b.order) > 0 {
+		oldest := b.messages[b.order[0]]
+		if len(b.order) <= b.capacity && (b.ttl == 0 || now.Sub(oldest.storedAt) < b.ttl) {
+			break
+		}
+		delete(b.messages, b.order[0])
+		b.order = b.order[1:]
+		b.evicted.Add(1)
+	}
+}
+
+// Store помещает принятый сегмент данных в буфер. Повторно принятый сегмент заменяет прежний.
+func (b *RetransmitBuffer) Store(job *segmentJob) {
+	if b == nil {
+		return
+	}
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := retransmitKey{sender: job.Request.Sender, timestamp: job.Timestamp}
+	msg, ok := b.messages[key]
+	if !ok {
+		msg = &retransmitMessage{storedAt: now, segments: make(map[int]*segmentJob)}
+		b.messages[key] = msg
+		b.order = append(b.order, key)
+	}
+	msg.segments[job.Request.SegmentNumber] = job
+	b.expireLocked(now)
+}
+
+// Lookup возвращает сегмент сообщения отправителя sender из буфера.
+func (b *RetransmitBuffer) Lookup(sender string, timestamp int64, segmentNumber int) (*segmentJob, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expireLocked(time.Now())
+	msg, ok := b.messages[retransmitKey{sender: sender, timestamp: timestamp}]
+	if !ok {
+		return nil, false
+	}
+	job, ok := msg.segments[segmentNumber]
+	return job, ok
+}
+
+// Stats возвращает статистику буфера повторной передачи (nil, если он отключен).
+func (b *RetransmitBuffer) Stats() *RetransmitStats {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	b.expireLocked(time.Now())
+	stats := &RetransmitStats{Messages: len(b.messages)}
+	for _, msg := range b.messages {
+		stats.Segments += len(msg.segments)
+	}
+	b.mu.Unlock()
+	stats.Naks = b.naks.Load()
+	stats.Retransmitted = b.retransmitted.Load()
+	stats.Misses = b.misses.Load()
+	stats.Evicted = b.evicted.Load()
+	return stats
+}
+
+var retransmitBuffer *RetransmitBuffer // Глобальный буфер повторной передачи (nil, если отключен)
+
+// handleNak повторно обрабатывает сегмент из буфера по запросу транспортного уровня: сегмент заново
+// проходит через канал и пересылается на /transfer как новая передача (с новым идентификатором
+// в реестре сегментов). Ответ совпадает с синхронным ответом /code.
+func handleNak(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+		return
+	}
+	if retransmitBuffer == nil {
+		sendErrorResponse(w, "Буфер повторной передачи не используется (retransmit.messages = 0)", http.StatusNotFound)
+		return
+	}
+	var req NakRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&req); err != nil {
+		sendErrorResponse(w, fmt.Sprintf("Неверный JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Sender == "" {
+		sendErrorResponse(w, "Не указан отправитель (sender)", http.StatusBadRequest)
+		return
+	}
+	timestamp := req.Timestamp
+	if req.SendTime != "" {
+		parsedTime, err := parseSendTime(req.SendTime)
+		if err != nil {
+			sendErrorResponse(w, fmt.Sprintf("Не удалось проанализировать send_time '%s': %v", req.SendTime, err), http.StatusBadRequest)
+			return
+		}
+		timestamp = parsedTime.UnixNano()
+	}
+	if timestamp == 0 {
+		sendErrorResponse(w, "Не указано сообщение (timestamp или send_time)", http.StatusBadRequest)
+		return
+	}
+	retransmitBuffer.naks.Add(1)
+
+	original, ok := retransmitBuffer.Lookup(req.Sender, timestamp, req.SegmentNumber)
+	if !ok {
+		retransmitBuffer.misses.Add(1)
+		log.Printf("Web Server: NAK сегмента #%d сообщения %d от %s: сегмента нет в буфере повторной передачи", req.SegmentNumber, timestamp, req.Sender)
+		sendErrorResponse(w, "Сегмент не найден в буфере повторной передачи (не принимался или вытеснен)", http.StatusNotFound)
+		return
+	}
+	retransmitBuffer.retransmitted.Add(1)
+
+	job := *original
+	job.ID = newSegmentID()
+	job.ReceivedAt = time.Now()
+	segmentRegistry.Register(&job)
+	segmentRegistry.Event(job.ID, "nak", fmt.Sprintf("Повторная обработка по запросу транспортного уровня (исходный сегмент %s)", original.ID))
+	w.Header().Set("X-Segment-ID", job.ID)
+	log.Printf("Web Server: NAK сегмента #%d/%d от %s: повторная обработка (id %s, исходный %s)",
+		job.Request.SegmentNumber, job.Request.TotalSegments, job.Request.Sender, job.ID, original.ID)
+
+	result := processSegmentJob(r.Context(), &job)
+	writeSegmentResult(w, &job, result)
+}
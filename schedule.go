@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// ScheduleStep — интервал расписания вероятностей канала. Незаданная вероятность остается
+// постоянной вероятностью канала.
+type ScheduleStep struct {
+	Duration Duration `json:"duration"`    // Длительность интервала
+	P        *float64 `json:"p,omitempty"` // Вероятность ошибки в бите на интервале
+	R        *float64 `json:"r,omitempty"` // Вероятность потери кадра на интервале
+}
+
+// ScheduleConfig задает изменение P и R канала во времени.
+type ScheduleConfig struct {
+	Steps []ScheduleStep `json:"steps"` // Интервалы по порядку, начиная с момента запуска
+	Loop  bool           `json:"loop"`  // Повторять расписание по кругу (иначе действует последний интервал)
+}
+
+// ProbabilitySchedule — расписание вероятностей канала: например, 0–60 с P = 0.01, 60–120 с P = 0.2.
+// Время отсчитывается по монотонным часам от создания расписания, так что перевод системных часов
+// не сдвигает интервалы. Методы допускают вызов на nil (вероятности постоянны).
+type ProbabilitySchedule struct {
+	steps   []ScheduleStep
+	loop    bool
+	period  time.Duration // Суммарная длительность интервалов
+	started time.Time     // Момент запуска (с показанием монотонных часов)
+	current atomic.Int64  // Индекс текущего интервала (для журнала смены интервалов)
+}
+
+// NewProbabilitySchedule создает расписание по конфигурации (nil, если интервалы не заданы).
+func NewProbabilitySchedule(cfg ScheduleConfig) (*ProbabilitySchedule, error) {
+	if len(cfg.Steps) == 0 {
+		return nil, nil
+	}
+	s := &ProbabilitySchedule{steps: cfg.Steps, loop: cfg.Loop, started: time.Now()}
+	for i, step := range cfg.Steps {
+		if step.Duration.Duration <= 0 {
+			return nil, fmt.Errorf("интервал %d: длительность должна быть положительной, задано %s", i+1, step.Duration)
+		}
+		if err := validProbability(step.P); err != nil {
+			return nil, fmt.Errorf("интервал %d: P: %w", i+1, err)
+		}
+		if err := validProbability(step.R); err != nil {
+			return nil, fmt.Errorf("интервал %d: R: %w", i+1, err)
+		}
+		s.period += step.Duration.Duration
+	}
+	return s, nil
+}
+
+// stepAt возвращает индекс интервала, действующего через elapsed после запуска.
+func (s *ProbabilitySchedule) stepAt(elapsed time.Duration) int {
+	if s.loop {
+		elapsed %= s.period
+	}
+	for i, step := range s.steps {
+		if elapsed < step.Duration.Duration {
+			return i
+		}
+		elapsed -= step.Duration.Duration
+	}
+	return len(s.steps) - 1
+}
+
+// Probabilities возвращает P и R, действующие в текущий момент, при постоянных вероятностях канала p и r.
+func (s *ProbabilitySchedule) Probabilities(p, r float64) (float64, float64) {
+	if s == nil {
+		return p, r
+	}
+	index := s.stepAt(time.Since(s.started))
+	step := s.steps[index]
+	if step.P != nil {
+		p = *step.P
+	}
+	if step.R != nil {
+		r = *step.R
+	}
+	if previous := s.current.Swap(int64(index)); previous != int64(index) {
+		log.Printf("ChannelLayer: Расписание канала: интервал %d/%d, P=%.4f, R=%.4f", index+1, len(s.steps), p, r)
+	}
+	return p, r
+}
@@ -0,0 +1,78 @@
+package main
+
+// Параметры расширенного кода Хэмминга [8,4].
+const (
+	SECDED84N = 8 // n: длина кодового слова
+	SECDED84K = 4 // k: число информационных бит
+)
+
+// secded84Coder — расширенный код Хэмминга [8,4] (SECDED): исправляет одиночную ошибку в блоке
+// и обнаруживает двойную. К коду Хэмминга (7,4) добавлен бит общей четности всего слова.
+//
+// Позиции 1..7 устроены так же, как в коде (15,11): проверочные биты на позициях 1, 2, 4,
+// информационные — на позициях 3, 5, 6, 7; позиция 8 — бит общей четности. При декодировании:
+//   - синдром 0 и четность сошлась — ошибок нет;
+//   - четность не сошлась — одиночная ошибка на позиции, равной синдрому (0 — в бите четности), исправляется;
+//   - синдром ненулевой, а четность сошлась — двойная ошибка, блок неисправим.
+type secded84Coder struct{}
+
+func (secded84Coder) Name() string { return "secded84" }
+func (secded84Coder) N() int       { return SECDED84N }
+func (secded84Coder) K() int       { return SECDED84K }
+
+// EncodeBlock кодирует 4 информационных бита в кодовое слово [8,4].
+func (secded84Coder) EncodeBlock(infoBits []uint8) []uint8 {
+	codeword := make([]uint8, SECDED84N)
+	i := 0
+	for p := 1; p < SECDED84N; p++ {
+		if !isPowerOfTwo(p) {
+			codeword[p-1] = infoBits[i]
+			i++
+		}
+	}
+	syndrome := hammingSyndrome(codeword[:SECDED84N-1])
+	for j := 0; 1<<j < SECDED84N; j++ {
+		codeword[(1<<j)-1] = uint8(syndrome>>j) & 1
+	}
+	codeword[SECDED84N-1] = bitParity(codeword[:SECDED84N-1])
+	return codeword
+}
+
+// DecodeBlock декодирует кодовое слово [8,4], исправляя одиночную и обнаруживая двойную ошибку.
+func (secded84Coder) DecodeBlock(codedBits []uint8) ([]uint8, bool, bool) {
+	received := append([]uint8(nil), codedBits...)
+	syndrome := hammingSyndrome(received[:SECDED84N-1])
+	parityError := bitParity(received) != 0
+	corrected, uncorrectable := false, false
+	switch {
+	case parityError && syndrome != 0:
+		received[syndrome-1] ^= 1
+		corrected = true
+	case parityError:
+		// Ошибка в самом бите общей четности: информационные биты не затронуты
+		corrected = true
+	case syndrome != 0:
+		uncorrectable = true
+	}
+
+	infoBits := make([]uint8, 0, SECDED84K)
+	for p := 1; p < SECDED84N; p++ {
+		if !isPowerOfTwo(p) {
+			infoBits = append(infoBits, received[p-1])
+		}
+	}
+	return infoBits, corrected, uncorrectable
+}
+
+// bitParity возвращает сумму бит по модулю 2.
+func bitParity(bits []uint8) uint8 {
+	var parity uint8
+	for _, bit := range bits {
+		parity ^= bit
+	}
+	return parity
+}
+
+func init() {
+	RegisterCoder(secded84Coder{})
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+const AdminSeedEndpoint = "/admin/seed" // Конечная точка начального значения генератора случайных чисел
+
+// SeedState — состояние генератора случайных чисел канала.
+type SeedState struct {
+	Seed     int64  `json:"seed"`
+	Position uint64 `json:"position"` // Число значений, выданных генератором с момента установки seed
+}
+
+// handleAdminSeed возвращает (GET) или устанавливает (POST {"seed": N}) начальное значение генератора
+// случайных чисел канала. Новое значение перезапускает генераторы канала и вариантов A/B-эксперимента,
+// так что последующие кадры повторяют прогон с тем же seed.
+func handleAdminSeed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req struct {
+			Seed *int64 `json:"seed"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendErrorResponse(w, fmt.Sprintf("Неверный JSON: %v", err), http.StatusBadRequest)
+			return
+		}
+		if req.Seed == nil {
+			sendErrorResponse(w, "Не задано начальное значение (seed)", http.StatusBadRequest)
+			return
+		}
+		channelLayer.Reseed(*req.Seed)
+		abSplit.Reseed(*req.Seed)
+		log.Printf("ChannelLayer: Генератор случайных чисел перезапущен с seed %d", *req.Seed)
+	default:
+		sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+		return
+	}
+	seed, position := channelLayer.RandState()
+	json.NewEncoder(w).Encode(SeedState{Seed: seed, Position: position})
+}
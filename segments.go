@@ -0,0 +1,418 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const SegmentsEndpoint = "/segments/" // Префикс конечных точек состояния сегментов
+
+// Состояния сегмента в реестре.
+const (
+	SegmentStateQueued     = "queued"     // Сегмент принят и ожидает обработки
+	SegmentStateProcessing = "processing" // Сегмент обрабатывается канальным уровнем
+	SegmentStateCompleted  = "completed"  // Обработка и пересылка завершены (итог в Result)
+)
+
+// SegmentEvent — этап жизненного цикла сегмента.
+type SegmentEvent struct {
+	Time   time.Time `json:"time"`
+	Stage  string    `json:"stage"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// SegmentFeedEvent — этап жизненного цикла сегмента в общей ленте событий реестра
+// (для живых панелей мониторинга, см. /ws/stats).
+type SegmentFeedEvent struct {
+	Seq           uint64 `json:"seq"` // Порядковый номер в ленте
+	SegmentID     string `json:"segment_id"`
+	Sender        string `json:"sender"`
+	SegmentNumber int    `json:"segment_number"`
+	SegmentEvent
+}
+
+const segmentFeedSize = 1000 // Сколько последних этапов сегментов хранится в ленте
+
+// ForwardAttempt — попытка пересылки сегмента на /transfer.
+type ForwardAttempt struct {
+	Time       time.Time `json:"time"`
+	DurationMs float64   `json:"duration_ms"`
+	StatusCode int       `json:"status_code,omitempty"` // HTTP статус ответа (0, если ответ не получен)
+	Status     string    `json:"status,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// SegmentRecord — запись реестра о принятом сегменте.
+type SegmentRecord struct {
+	ID              string           `json:"id"`
+	Sender          string           `json:"sender"`
+	SendTime        string           `json:"send_time"`
+	Timestamp       int64            `json:"timestamp"` // send_time в наносекундах
+	SegmentNumber   int              `json:"segment_number"`
+	TotalSegments   int              `json:"total_segments"`
+	State           string           `json:"state"`
+	ReceivedAt      time.Time        `json:"received_at"`
+	CompletedAt     *time.Time       `json:"completed_at,omitempty"`
+	Result          *SegmentResult   `json:"result,omitempty"`
+	Channel         *ChannelReport   `json:"channel,omitempty"`          // Искажения в канале и результат декодирования
+	Degraded        string           `json:"degraded,omitempty"`         // Действие режима деградации, если он применялся
+	ForwardAttempts []ForwardAttempt `json:"forward_attempts,omitempty"` // Попытки пересылки на /transfer
+	Lifecycle       []SegmentEvent   `json:"lifecycle"`                  // Этапы обработки в хронологическом порядке
+}
+
+// segmentIdentity формирует ключ сегмента по отправителю, send_time (в наносекундах) и номеру.
+func segmentIdentity(sender string, timestamp int64, segmentNumber int) string {
+	return fmt.Sprintf("%s|%d|%d", sender, timestamp, segmentNumber)
+}
+
+// SegmentRegistry хранит состояние последних принятых сегментов, чтобы итог их обработки
+// можно было запросить позже (в асинхронном режиме — единственный способ узнать итог).
+// В памяти хранится не более capacity записей; самые старые вытесняются. Снимки записей при приеме
+// и по завершении обработки сохраняются в хранилище состояния на ttl, и записи, которых нет
+// в памяти (вытесненные, принятые до перезапуска или другим экземпляром), читаются из него.
+type SegmentRegistry struct {
+	store      StateStore
+	ttl        time.Duration
+	mu         sync.Mutex
+	records    map[string]*SegmentRecord
+	byIdentity map[string][]string // Идентификаторы записей по ключу сегмента (повторные передачи — несколько записей)
+	order      []string            // Идентификаторы в порядке регистрации (для вытеснения)
+	capacity   int
+	feed       []SegmentFeedEvent // Последние этапы всех сегментов
+	feedSeq    uint64             // Номер последнего этапа в ленте
+}
+
+// NewSegmentRegistry создает реестр сегментов заданной емкости со снимками записей в store.
+func NewSegmentRegistry(capacity int, store StateStore, ttl time.Duration) *SegmentRegistry {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &SegmentRegistry{
+		store:      store,
+		ttl:        ttl,
+		records:    make(map[string]*SegmentRecord),
+		byIdentity: make(map[string][]string),
+		capacity:   capacity,
+	}
+}
+
+// newSegmentID генерирует случайный идентификатор сегмента.
+func newSegmentID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// segmentRecordKey формирует ключ снимка записи в хранилище состояния. Каждая передача сегмента
+// отмечается собственным ключом segmentIdentityPrefix(identity)+id, поэтому экземпляры, принявшие
+// передачи одного сегмента, не перезаписывают отметки друг друга.
+func segmentRecordKey(id string) string            { return "segment:" + id }
+func segmentIdentityPrefix(identity string) string { return "segment-ids:" + identity + ":" }
+
+// persist сохраняет снимок записи в хранилище состояния.
+func (sr *SegmentRegistry) persist(rec SegmentRecord) {
+	data, err := json.Marshal(rec)
+	if err == nil {
+		err = sr.store.Set(segmentRecordKey(rec.ID), string(data), sr.ttl)
+	}
+	if err != nil {
+		log.Printf("Registry ERROR: Не удалось сохранить состояние сегмента %s: %v", rec.ID, err)
+	}
+}
+
+// persistIdentity отмечает запись id как передачу сегмента identity в хранилище состояния.
+func (sr *SegmentRegistry) persistIdentity(identity, id string) {
+	if err := sr.store.Set(segmentIdentityPrefix(identity)+id, id, sr.ttl); err != nil {
+		log.Printf("Registry ERROR: Не удалось сохранить передачу %s сегмента %s: %v", id, identity, err)
+	}
+}
+
+// stored читает снимок записи из хранилища состояния.
+func (sr *SegmentRegistry) stored(id string) (SegmentRecord, bool) {
+	var rec SegmentRecord
+	data, ok, err := sr.store.Get(segmentRecordKey(id))
+	if err != nil {
+		log.Printf("Registry ERROR: Не удалось прочитать состояние сегмента %s: %v", id, err)
+		return rec, false
+	}
+	if !ok || json.Unmarshal([]byte(data), &rec) != nil {
+		return rec, false
+	}
+	return rec, true
+}
+
+// storedIDs читает из хранилища состояния идентификаторы записей сегмента.
+func (sr *SegmentRegistry) storedIDs(identity string) []string {
+	prefix := segmentIdentityPrefix(identity)
+	keys, err := sr.store.Keys(prefix)
+	if err != nil {
+		log.Printf("Registry ERROR: Не удалось прочитать передачи сегмента %s: %v", identity, err)
+		return nil
+	}
+	ids := make([]string, len(keys))
+	for i, key := range keys {
+		ids[i] = strings.TrimPrefix(key, prefix)
+	}
+	return ids
+}
+
+// Register добавляет принятый сегмент в реестр в состоянии queued.
+func (sr *SegmentRegistry) Register(job *segmentJob) {
+	sr.mu.Lock()
+
+	receivedAt := job.ReceivedAt.UTC()
+	sr.records[job.ID] = &SegmentRecord{
+		ID:            job.ID,
+		Sender:        job.Request.Sender,
+		SendTime:      job.Request.SendTime,
+		Timestamp:     job.Timestamp,
+		SegmentNumber: job.Request.SegmentNumber,
+		TotalSegments: job.Request.TotalSegments,
+		State:         SegmentStateQueued,
+		ReceivedAt:    receivedAt,
+	}
+	sr.appendEventLocked(sr.records[job.ID], SegmentEvent{
+		Time:   receivedAt,
+		Stage:  "received",
+		Detail: fmt.Sprintf("Принят на %s, полезная нагрузка %d байт", CodeEndpoint, len(job.OriginalPayload)),
+	})
+	identity := segmentIdentity(job.Request.Sender, job.Timestamp, job.Request.SegmentNumber)
+	sr.byIdentity[identity] = append(sr.byIdentity[identity], job.ID)
+
+	sr.order = append(sr.order, job.ID)
+	for len(sr.order) > sr.capacity {
+		sr.evictLocked(sr.order[0])
+		sr.order = sr.order[1:]
+	}
+	snapshot := copyRecord(sr.records[job.ID])
+	sr.mu.Unlock()
+
+	sr.persist(snapshot)
+	sr.persistIdentity(identity, job.ID)
+}
+
+// evictLocked удаляет запись из реестра. Вызывается под блокировкой sr.mu.
+func (sr *SegmentRegistry) evictLocked(id string) {
+	rec, ok := sr.records[id]
+	if !ok {
+		return
+	}
+	delete(sr.records, id)
+	identity := segmentIdentity(rec.Sender, rec.Timestamp, rec.SegmentNumber)
+	ids := sr.byIdentity[identity]
+	for i, other := range ids {
+		if other == id {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(sr.byIdentity, identity)
+	} else {
+		sr.byIdentity[identity] = ids
+	}
+}
+
+// appendEventLocked добавляет этап в жизненный цикл сегмента и в общую ленту.
+// Вызывается под блокировкой sr.mu.
+func (sr *SegmentRegistry) appendEventLocked(rec *SegmentRecord, event SegmentEvent) {
+	rec.Lifecycle = append(rec.Lifecycle, event)
+	sr.feedSeq++
+	sr.feed = append(sr.feed, SegmentFeedEvent{
+		Seq:           sr.feedSeq,
+		SegmentID:     rec.ID,
+		Sender:        rec.Sender,
+		SegmentNumber: rec.SegmentNumber,
+		SegmentEvent:  event,
+	})
+	if len(sr.feed) > segmentFeedSize {
+		sr.feed = append([]SegmentFeedEvent(nil), sr.feed[len(sr.feed)-segmentFeedSize:]...)
+	}
+}
+
+// FeedSince возвращает этапы сегментов из ленты с номером больше since и номер последнего этапа.
+func (sr *SegmentRegistry) FeedSince(since uint64) ([]SegmentFeedEvent, uint64) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	result := []SegmentFeedEvent{}
+	for _, e := range sr.feed {
+		if e.Seq > since {
+			result = append(result, e)
+		}
+	}
+	return result, sr.feedSeq
+}
+
+// update применяет изменение к записи сегмента под блокировкой реестра.
+func (sr *SegmentRegistry) update(id string, fn func(rec *SegmentRecord)) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	if rec, ok := sr.records[id]; ok {
+		fn(rec)
+	}
+}
+
+// Event добавляет этап в жизненный цикл сегмента.
+func (sr *SegmentRegistry) Event(id, stage, detail string) {
+	sr.update(id, func(rec *SegmentRecord) {
+		sr.appendEventLocked(rec, SegmentEvent{Time: time.Now().UTC(), Stage: stage, Detail: detail})
+	})
+}
+
+// SetState изменяет состояние сегмента.
+func (sr *SegmentRegistry) SetState(id, state string) {
+	sr.update(id, func(rec *SegmentRecord) {
+		rec.State = state
+		sr.appendEventLocked(rec, SegmentEvent{Time: time.Now().UTC(), Stage: state})
+	})
+}
+
+// SetChannel сохраняет отчет о прохождении сегмента через канал.
+func (sr *SegmentRegistry) SetChannel(id string, report ChannelReport, degraded string) {
+	sr.update(id, func(rec *SegmentRecord) {
+		rec.Channel = &report
+		rec.Degraded = degraded
+		sr.appendEventLocked(rec, SegmentEvent{Time: time.Now().UTC(), Stage: "channel", Detail: report.String()})
+	})
+}
+
+// AddForwardAttempt сохраняет попытку пересылки сегмента на /transfer.
+func (sr *SegmentRegistry) AddForwardAttempt(id string, attempt ForwardAttempt) {
+	sr.update(id, func(rec *SegmentRecord) {
+		rec.ForwardAttempts = append(rec.ForwardAttempts, attempt)
+		detail := attempt.Status
+		if attempt.Error != "" {
+			detail = attempt.Error
+		}
+		sr.appendEventLocked(rec, SegmentEvent{Time: attempt.Time, Stage: "forward", Detail: detail})
+	})
+}
+
+// Complete сохраняет итог обработки сегмента.
+func (sr *SegmentRegistry) Complete(id string, result SegmentResult) {
+	var snapshot *SegmentRecord
+	sr.update(id, func(rec *SegmentRecord) {
+		now := time.Now().UTC()
+		rec.State = SegmentStateCompleted
+		rec.CompletedAt = &now
+		rec.Result = &result
+		sr.appendEventLocked(rec, SegmentEvent{Time: now, Stage: SegmentStateCompleted, Detail: result.Outcome})
+		c := copyRecord(rec)
+		snapshot = &c
+	})
+	if snapshot != nil {
+		sr.persist(*snapshot)
+	}
+}
+
+// copyRecord возвращает копию записи, не разделяющую срезы с реестром.
+func copyRecord(rec *SegmentRecord) SegmentRecord {
+	c := *rec
+	c.ForwardAttempts = append([]ForwardAttempt(nil), rec.ForwardAttempts...)
+	c.Lifecycle = append([]SegmentEvent(nil), rec.Lifecycle...)
+	return c
+}
+
+// Get возвращает копию записи о сегменте (из памяти или из хранилища состояния).
+func (sr *SegmentRegistry) Get(id string) (SegmentRecord, bool) {
+	sr.mu.Lock()
+	rec, ok := sr.records[id]
+	if ok {
+		defer sr.mu.Unlock()
+		return copyRecord(rec), true
+	}
+	sr.mu.Unlock()
+	return sr.stored(id)
+}
+
+// Lookup возвращает копии всех записей о сегменте с заданным отправителем, send_time и номером
+// (по одной на каждую передачу сегмента) в порядке приема.
+func (sr *SegmentRegistry) Lookup(sender string, timestamp int64, segmentNumber int) []SegmentRecord {
+	identity := segmentIdentity(sender, timestamp, segmentNumber)
+	sr.mu.Lock()
+	ids := sr.byIdentity[identity]
+	result := make([]SegmentRecord, 0, len(ids))
+	known := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		result = append(result, copyRecord(sr.records[id]))
+		known[id] = true
+	}
+	sr.mu.Unlock()
+	// Передачи, которых нет в памяти: принятые до перезапуска, другим экземпляром или вытесненные
+	for _, id := range sr.storedIDs(identity) {
+		if known[id] {
+			continue
+		}
+		if rec, ok := sr.stored(id); ok {
+			result = append(result, rec)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].ReceivedAt.Before(result[j].ReceivedAt) })
+	return result
+}
+
+var segmentRegistry *SegmentRegistry // Глобальный реестр сегментов
+
+// handleSegmentStatus возвращает состояние сегмента по его идентификатору (GET /segments/{id}).
+func handleSegmentStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rec, ok := segmentRegistry.Get(r.PathValue("id"))
+	if !ok {
+		sendErrorResponse(w, "Сегмент не найден (неизвестный идентификатор или запись вытеснена из реестра)", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(rec)
+}
+
+// handleSegmentLifecycle возвращает жизненный цикл сегмента по отправителю, send_time и номеру
+// (GET /segments/{sender}/{timestamp}/{n}). timestamp задается в наносекундах или строкой send_time
+// в том же формате, в котором она была передана на /code (с экранированием для URL).
+func handleSegmentLifecycle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sender := r.PathValue("sender")
+	timestamp, err := strconv.ParseInt(r.PathValue("timestamp"), 10, 64)
+	if err != nil {
+		parsedTime, parseErr := parseSendTime(r.PathValue("timestamp"))
+		if parseErr != nil {
+			sendErrorResponse(w, fmt.Sprintf("Неверная метка времени '%s': ожидается время в наносекундах или send_time", r.PathValue("timestamp")), http.StatusBadRequest)
+			return
+		}
+		timestamp = parsedTime.UnixNano()
+	}
+	segmentNumber, err := strconv.Atoi(r.PathValue("n"))
+	if err != nil {
+		sendErrorResponse(w, fmt.Sprintf("Неверный номер сегмента '%s'", r.PathValue("n")), http.StatusBadRequest)
+		return
+	}
+
+	records := segmentRegistry.Lookup(sender, timestamp, segmentNumber)
+	if len(records) == 0 {
+		sendErrorResponse(w, "Сегмент не найден (не принимался этим экземпляром или запись вытеснена из реестра)", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sender":         sender,
+		"timestamp":      timestamp,
+		"segment_number": segmentNumber,
+		"transmissions":  records,
+	})
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+const sequenceWindow = 1024 // Число последних номеров, для которых приемник помнит пропуски
+
+// SequenceConfig задает порядковые номера кадров канального уровня.
+type SequenceConfig struct {
+	Enabled        bool `json:"enabled"`         // Нумеровать кадры и проверять порядок на приеме
+	DropDuplicates bool `json:"drop_duplicates"` // Не доставлять транспортному уровню повторные копии кадра
+}
+
+// LinkSequenceInfo — порядковый номер кадра канального уровня и результат его проверки приемником,
+// передаваемые на /transfer вместе с сегментом.
+type LinkSequenceInfo struct {
+	Seq       uint32 `json:"seq"`                 // Порядковый номер кадра в звене отправителя (с 0)
+	Gap       int    `json:"gap,omitempty"`       // Число пропущенных номеров перед этим кадром
+	Late      bool   `json:"late,omitempty"`      // Кадр пришел позже следующих за ним и заполнил пропуск
+	Duplicate bool   `json:"duplicate,omitempty"` // Кадр с этим номером уже был принят
+}
+
+// SequenceStats — статистика порядковых номеров для /stats.
+type SequenceStats struct {
+	Links      int   `json:"links"`      // Звеньев (отправителей)
+	Frames     int64 `json:"frames"`     // Пронумерованных кадров
+	Received   int64 `json:"received"`   // Кадров, принятых без ошибки
+	Missing    int64 `json:"missing"`    // Пропущенных номеров (кадры потеряны или отброшены)
+	Late       int64 `json:"late"`       // Кадров, пришедших вне порядка после следующих
+	Duplicates int64 `json:"duplicates"` // Повторных копий кадров
+}
+
+// linkSequence — состояние нумерации одного звена: счетчик передатчика и ожидаемый номер приемника.
+type linkSequence struct {
+	next     uint32              // Номер следующего передаваемого кадра
+	expected uint32              // Номер, следующий за наибольшим принятым
+	received bool                // Приемник принял хотя бы один кадр
+	missing  map[uint32]struct{} // Пропущенные номера в пределах sequenceWindow
+}
+
+// LinkSequencer — порядковые номера кадров канального уровня, независимые от segment_number
+// транспортного уровня. Передатчик присваивает номер каждому кадру звена (отправителя), переданному
+// в канал; повторные передачи ARQ и копии кадра, продублированного в канале, сохраняют номер.
+// Приемник по номерам обнаруживает пропуски (кадры потеряны или отброшены из-за неисправимой
+// ошибки), кадры вне порядка и дубликаты. Методы допускают вызов на nil (нумерация отключена).
+type LinkSequencer struct {
+	dropDuplicates bool
+
+	mu    sync.Mutex
+	links map[string]*linkSequence
+
+	frames     atomic.Int64
+	received   atomic.Int64
+	missing    atomic.Int64
+	late       atomic.Int64
+	duplicates atomic.Int64
+}
+
+// NewLinkSequencer создает нумерацию кадров по конфигурации (nil, если она отключена).
+func NewLinkSequencer(cfg SequenceConfig) *LinkSequencer {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &LinkSequencer{dropDuplicates: cfg.DropDuplicates, links: make(map[string]*linkSequence)}
+}
+
+// link возвращает состояние звена отправителя. Вызывается под s.mu.
+func (s *LinkSequencer) link(sender string) *linkSequence {
+	l, ok := s.links[sender]
+	if !ok {
+		l = &linkSequence{missing: make(map[uint32]struct{})}
+		s.links[sender] = l
+	}
+	return l
+}
+
+// Next присваивает номер очередному кадру звена отправителя.
+func (s *LinkSequencer) Next(sender string) uint32 {
+	if s == nil {
+		return 0
+	}
+	s.frames.Add(1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := s.link(sender)
+	seq := l.next
+	l.next++
+	return seq
+}
+
+// Receive проверяет номер кадра, принятого без ошибки (nil, если нумерация отключена).
+func (s *LinkSequencer) Receive(sender string, seq uint32) *LinkSequenceInfo {
+	if s == nil {
+		return nil
+	}
+	info := &LinkSequenceInfo{Seq: seq}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := s.link(sender)
+	_, late := l.missing[seq]
+	switch {
+	case !l.received || seq >= l.expected:
+		// Номера между ожидаемым и принятым пропущены; помнятся только последние sequenceWindow
+		if l.received {
+			info.Gap = int(seq - l.expected)
+		} else {
+			info.Gap = int(seq)
+		}
+		for n := seq - uint32(min(info.Gap, sequenceWindow)); n < seq; n++ {
+			l.missing[n] = struct{}{}
+		}
+		for n := range l.missing {
+			if seq-n > sequenceWindow {
+				delete(l.missing, n)
+			}
+		}
+		l.expected = seq + 1
+		l.received = true
+		s.missing.Add(int64(info.Gap))
+	case late:
+		delete(l.missing, seq)
+		info.Late = true
+		s.missing.Add(-1)
+		s.late.Add(1)
+	default:
+		info.Duplicate = true
+		s.duplicates.Add(1)
+		return info
+	}
+	s.received.Add(1)
+	return info
+}
+
+// Duplicate отмечает повторную копию принятого кадра и возвращает сведения для нее, а также признак
+// того, что копию нужно доставить транспортному уровню (false при drop_duplicates).
+func (s *LinkSequencer) Duplicate(info *LinkSequenceInfo) (*LinkSequenceInfo, bool) {
+	if s == nil || info == nil {
+		return nil, true
+	}
+	s.duplicates.Add(1)
+	return &LinkSequenceInfo{Seq: info.Seq, Duplicate: true}, !s.dropDuplicates
+}
+
+// Stats возвращает статистику порядковых номеров (nil, если нумерация отключена).
+func (s *LinkSequencer) Stats() *SequenceStats {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	links := len(s.links)
+	s.mu.Unlock()
+	return &SequenceStats{
+		Links:      links,
+		Frames:     s.frames.Load(),
+		Received:   s.received.Load(),
+		Missing:    s.missing.Load(),
+		Late:       s.late.Load(),
+		Duplicates: s.duplicates.Load(),
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"math/bits"
+	"math/rand"
+	"sync"
+)
+
+const shortenedLLR = 1e6 // LLR непереданного бита укороченного блока (достоверно известный ноль)
+
+// shortLengthBits — длина заголовка с длиной полезной нагрузки в байтах в укороченном кадре:
+// число бит, достаточное для FixedPayloadSize (пересчитывается в setPayloadSize).
+var shortLengthBits = bits.Len(uint(DefaultPayloadSize))
+
+var systematicCache sync.Map // Имя кодека -> bool: информационные биты занимают первые k позиций слова
+
+// systematicPrefix сообщает, что кодек систематический и информационные биты блока передаются
+// в первых k позициях кодового слова без изменений (проверяется на единичных и случайных словах).
+// Только такие кодеки допускают укорочение без знания их внутреннего устройства.
+func systematicPrefix(coder BlockCoder) bool {
+	if cached, ok := systematicCache.Load(coder.Name()); ok {
+		return cached.(bool)
+	}
+	k := coder.K()
+	rng := rand.New(rand.NewSource(int64(k)))
+	systematic := true
+	info := make([]uint8, k)
+	for trial := 0; trial < k+16 && systematic; trial++ {
+		for i := range info {
+			if trial < k {
+				info[i] = 0
+			} else {
+				info[i] = uint8(rng.Intn(2))
+			}
+		}
+		if trial < k {
+			info[trial] = 1
+		}
+		codeword := coder.EncodeBlock(info)
+		for i := 0; i < k; i++ {
+			if codeword[i] != info[i] {
+				systematic = false
+				break
+			}
+		}
+	}
+	systematicCache.Store(coder.Name(), systematic)
+	return systematic
+}
+
+// shortenedBits возвращает число информационных бит последнего блока, которые известны приемнику
+// (нулевое дополнение) и не передаются в укороченном кадре.
+func shortenedBits(coder BlockCoder, infoLen int) int {
+	if !systematicPrefix(coder) {
+		return 0
+	}
+	if rem := infoLen % coder.K(); rem != 0 {
+		return coder.K() - rem
+	}
+	return 0
+}
+
+// shortenBitStream удаляет из закодированного потока биты нулевого дополнения последнего блока
+// (укорочение кода): блок передается как [n - s, k - s] код.
+func shortenBitStream(coder BlockCoder, encoded []uint8, infoLen int) []uint8 {
+	s := shortenedBits(coder, infoLen)
+	if s == 0 {
+		return encoded
+	}
+	start := len(encoded) - coder.N() // Начало последнего блока
+	k := coder.K()
+	out := append([]uint8(nil), encoded[:start+k-s]...)
+	return append(out, encoded[start+k:]...)
+}
+
+// unshortenBitStream восстанавливает последний блок укороченного потока, вставляя на место
+// непереданных бит значение known (нулевой бит, достоверный LLR или отсутствие стирания).
+func unshortenBitStream[T uint8 | float64 | bool](coder BlockCoder, received []T, infoLen int, known T) []T {
+	s := shortenedBits(coder, infoLen)
+	if s == 0 || len(received) < coder.N()-s {
+		return received
+	}
+	start := len(received) - (coder.N() - s)
+	k := coder.K()
+	out := append([]T(nil), received[:start+k-s]...)
+	for i := 0; i < s; i++ {
+		out = append(out, known)
+	}
+	return append(out, received[start+k-s:]...)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// Форматы send_time, принимаемые на /code (см. parseSendTime).
+const sendTimeLegacyLayout = "2006-01-02 15:04:05 -0700 MST"
+
+// ClockSkew — симуляция рассинхронизации часов отправителя и получателя. Метка времени t,
+// пересылаемая на /transfer, заменяется на t + offset + (t - epoch) * driftPPM / 10^6:
+// постоянное смещение и уход часов, накопленный с момента запуска. Результат зависит только от t,
+// поэтому сегменты одного сообщения (с одинаковым send_time) по-прежнему получают одинаковую метку.
+type ClockSkew struct {
+	Offset   time.Duration // Постоянное смещение часов
+	DriftPPM float64       // Уход часов (миллионных долей)
+	Epoch    time.Time     // Момент, с которого накапливается уход
+}
+
+var clockSkew *ClockSkew // Глобальная симуляция рассинхронизации часов (nil, если отключена)
+
+// Apply возвращает метку времени с учетом смещения и ухода часов.
+func (cs *ClockSkew) Apply(t time.Time) time.Time {
+	drift := time.Duration(float64(t.Sub(cs.Epoch)) * cs.DriftPPM / 1e6)
+	return t.Add(cs.Offset + drift)
+}
+
+// SkewSendTime применяет рассинхронизацию к строке send_time, сохраняя ее формат.
+// Нераспознанная строка и вызов на nil возвращают send_time без изменений.
+func (cs *ClockSkew) SkewSendTime(sendTime string) string {
+	if cs == nil {
+		return sendTime
+	}
+	if t, err := time.Parse(time.RFC3339, sendTime); err == nil {
+		return cs.Apply(t).Format(time.RFC3339Nano)
+	}
+	if t, err := time.Parse(sendTimeLegacyLayout, sendTime); err == nil {
+		return cs.Apply(t).Format(sendTimeLegacyLayout)
+	}
+	log.Printf("ClockSkew: Не удалось разобрать send_time '%s', метка передается без изменений", sendTime)
+	return sendTime
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Режимы принятия решений о принятых битах (ChannelLayer.Decision).
+const (
+	DecisionHard = "hard" // Жесткие решения: декодер получает биты
+	DecisionSoft = "soft" // Мягкие решения: декодер получает логарифмические отношения правдоподобия (LLR)
+)
+
+// chaseTestBits — число наименее надежных бит блока, все комбинации инверсии которых перебирает
+// декодер Чейза (2^chaseTestBits пробных слов на блок).
+const chaseTestBits = 4
+
+// SoftDecoder — кодек с собственным декодером мягких решений. llr[i] > 0 означает, что бит i
+// вероятнее равен 0; |llr[i]| — надежность решения, llr[i] = 0 — стертый бит.
+// Кодеки без этого интерфейса декодируются алгоритмом Чейза поверх DecodeBlock.
+type SoftDecoder interface {
+	DecodeBlockSoft(llr []float64) (infoBits []uint8, corrected, uncorrectable bool)
+}
+
+// SoftStage — этап обработки потока, переставляющий биты без их изменения (перемежители),
+// который может так же переставить мягкие решения. Этапы без этого интерфейса (линейные коды)
+// несовместимы с мягкими решениями.
+type SoftStage interface {
+	InvertSoft(llr []float64, length int) []float64
+}
+
+// validateDecision проверяет режим принятия решений и совместимость с ним этапов обработки потока.
+func validateDecision(decision string, stages []StreamStage) error {
+	switch decision {
+	case "", DecisionHard:
+		return nil
+	case DecisionSoft:
+		for _, stage := range stages {
+			if _, ok := stage.(SoftStage); !ok {
+				return fmt.Errorf("этап %s не поддерживает мягкие решения", stage.Name())
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("неизвестный режим решений '%s' (допустимо: %s, %s)", decision, DecisionHard, DecisionSoft)
+}
+
+// softNoiseSigma возвращает среднеквадратичное отклонение шума канала BPSK/AWGN (амплитуда
+// сигнала 1), в котором вероятность ошибки бита при жестком решении равна p:
+// p = Q(1/σ), т.е. σ = -1/Φ⁻¹(p). Значение p ограничивается диапазоном [1e-6, 0.45].
+func softNoiseSigma(p float64) float64 {
+	p = math.Min(math.Max(p, 1e-6), 0.45)
+	return -1 / (math.Sqrt2 * math.Erfinv(2*p-1))
+}
+
+// channelLLR формирует мягкие решения для принятого кадра rx, переданного как tx. Каждому биту
+// сопоставляется отсчет канала BPSK/AWGN с шумом sigma, согласованный с жестким решением:
+// для верно принятого бита отсчет выбирается из нормального распределения при условии
+// правильного знака, для искаженного — при условии неправильного. Поэтому ошибки остаются
+// теми же, что смоделировала цепочка искажений, а их надежность, как правило, мала.
+func channelLLR(tx, rx []uint8, sigma float64, rng *rand.Rand) []float64 {
+	llr := make([]float64, len(rx))
+	// Вероятность неверного знака отсчета: Φ(-1/σ)
+	pErr := 0.5 * math.Erfc(1/(sigma*math.Sqrt2))
+	for i := range rx {
+		// u — равномерное значение в области функции распределения, отвечающей нужному знаку
+		u := pErr + rng.Float64()*(1-pErr)
+		if i < len(tx) && tx[i] != rx[i] {
+			u = rng.Float64() * pErr
+		}
+		u = math.Min(math.Max(u, 1e-12), 1-1e-12)
+		y := 1 + sigma*math.Sqrt2*math.Erfinv(2*u-1) // Отсчет относительно переданного знака
+		if i < len(tx) && tx[i] == 1 {
+			y = -y
+		}
+		llr[i] = 2 * y / (sigma * sigma)
+	}
+	return llr
+}
+
+// hardDecision возвращает жесткие решения по мягким (llr > 0 — бит 0; стертый бит — 0).
+func hardDecision(llr []float64) []uint8 {
+	bits := make([]uint8, len(llr))
+	for i, l := range llr {
+		if l < 0 {
+			bits[i] = 1
+		}
+	}
+	return bits
+}
+
+// chaseDecodeBlock декодирует блок алгоритмом Чейза (вариант 2): инвертируются все комбинации
+// chaseTestBits наименее надежных бит, каждое пробное слово декодируется жестким декодером,
+// и из найденных кодовых слов выбирается ближайшее к принятому по мягкой метрике (сумма
+// надежностей бит, в которых кодовое слово расходится с жестким решением). Так декодер,
+// только обнаруживающий ошибки, начинает их исправлять, а исправляющий — исправляет больше.
+func chaseDecodeBlock(coder BlockCoder, llr []float64) ([]uint8, bool, bool) {
+	hard := hardDecision(llr)
+	order := make([]int, len(llr))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return math.Abs(llr[order[a]]) < math.Abs(llr[order[b]]) })
+	test := order[:min(chaseTestBits, len(order))]
+
+	var best []uint8
+	bestMetric := math.Inf(1)
+	bestCorrected := false
+	candidate := make([]uint8, len(hard))
+	for pattern := 0; pattern < 1<<len(test); pattern++ {
+		copy(candidate, hard)
+		for j, pos := range test {
+			candidate[pos] ^= uint8(pattern>>j) & 1
+		}
+		infoBits, _, uncorrectable := coder.DecodeBlock(candidate)
+		if uncorrectable {
+			continue
+		}
+		codeword := coder.EncodeBlock(infoBits)
+		metric, differs := 0.0, false
+		for i, bit := range codeword {
+			if bit != hard[i] {
+				metric += math.Abs(llr[i])
+				differs = true
+			}
+		}
+		if metric < bestMetric {
+			best, bestMetric, bestCorrected = infoBits, metric, differs
+		}
+	}
+	if best == nil {
+		infoBits, _, _ := coder.DecodeBlock(hard)
+		return infoBits, false, true
+	}
+	return best, bestCorrected, false
+}
+
+// decodeBitStreamSoft декодирует поток мягких решений поблочно (собственным декодером кодека
+// или алгоритмом Чейза) и возвращает первые infoLen информационных бит, а также число блоков
+// с исправленными и с неисправимыми ошибками.
+func decodeBitStreamSoft(coder BlockCoder, llr []float64, infoLen int) (decoded []uint8, correctedBlocks, errorBlocks int) {
+	n, k := coder.N(), coder.K()
+	blocks := len(llr) / n
+	decoded = make([]uint8, blocks*k)
+	soft, hasSoft := coder.(SoftDecoder)
+	for i := 0; i < blocks; i++ {
+		var infoBits []uint8
+		var corrected, uncorrectable bool
+		if hasSoft {
+			infoBits, corrected, uncorrectable = soft.DecodeBlockSoft(llr[i*n : (i+1)*n])
+		} else {
+			infoBits, corrected, uncorrectable = chaseDecodeBlock(coder, llr[i*n:(i+1)*n])
+		}
+		copy(decoded[i*k:(i+1)*k], infoBits)
+		if corrected {
+			correctedBlocks++
+		}
+		if uncorrectable {
+			errorBlocks++
+		}
+	}
+	return decoded[:infoLen], correctedBlocks, errorBlocks
+}
+
+// RestoreSoft восстанавливает поток мягких решений исходной длины length: удаленные выкалыванием
+// биты получают LLR = 0 (стирание).
+func (p *Puncturer) RestoreSoft(llr []float64, length int) []float64 {
+	if p == nil {
+		return llr
+	}
+	out := make([]float64, length)
+	j := 0
+	for i := range out {
+		if p.pattern[i%len(p.pattern)] == 1 && j < len(llr) {
+			out[i] = llr[j]
+			j++
+		}
+	}
+	return out
+}
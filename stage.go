@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// StreamStage — преобразование закодированного потока бит между кодеком и каналом
+// (перемежение, линейное кодирование и т.п.). Этапы применяются к закодированному кадру
+// в порядке конфигурации перед симуляцией искажений и обращаются в обратном порядке
+// перед декодированием.
+type StreamStage interface {
+	Name() string
+	// Apply преобразует поток бит перед передачей в канал.
+	Apply(bits []uint8) []uint8
+	// Invert выполняет обратное преобразование принятого потока и возвращает поток длиной length
+	// (длина потока до Apply). violations — число нарушений правил кодирования, обнаруженных
+	// этапом (например, недопустимых кодовых групп); такие нарушения считаются ошибками канала.
+	Invert(bits []uint8, length int) (out []uint8, violations int)
+}
+
+// StageConfig описывает этап обработки потока в конфигурации. Набор используемых параметров
+// зависит от типа этапа.
+type StageConfig struct {
+	Type  string `json:"type"`            // Тип этапа (см. RegisterStage)
+	Depth int    `json:"depth,omitempty"` // Глубина (число ветвей или строк перемежителя)
+	Delay int    `json:"delay,omitempty"` // Шаг задержки ветви сверточного перемежителя (в битах)
+}
+
+var stageFactories = map[string]func(StageConfig) (StreamStage, error){} // Конструкторы этапов по типу
+
+// RegisterStage регистрирует конструктор этапа обработки потока.
+func RegisterStage(stageType string, factory func(StageConfig) (StreamStage, error)) {
+	stageFactories[stageType] = factory
+}
+
+// NewStreamStages создает этапы обработки потока по конфигурации.
+func NewStreamStages(configs []StageConfig) ([]StreamStage, error) {
+	stages := make([]StreamStage, 0, len(configs))
+	for i, cfg := range configs {
+		factory, ok := stageFactories[cfg.Type]
+		if !ok {
+			types := make([]string, 0, len(stageFactories))
+			for t := range stageFactories {
+				types = append(types, t)
+			}
+			sort.Strings(types)
+			return nil, fmt.Errorf("этап %d: неизвестный тип '%s' (допустимо: %s)", i+1, cfg.Type, strings.Join(types, ", "))
+		}
+		stage, err := factory(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("этап %d (%s): %w", i+1, cfg.Type, err)
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
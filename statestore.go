@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StateEndpoint — отладочная конечная точка для просмотра хранилища состояния.
+const StateEndpoint = "/debug/state"
+
+// Поддерживаемые хранилища состояния.
+const (
+	StateBackendMemory = "memory" // Состояние в памяти процесса (по умолчанию)
+	StateBackendRedis  = "redis"  // Состояние в Redis, общее для нескольких экземпляров
+	StateBackendFile   = "file"   // Состояние во встроенном файловом хранилище, переживающее перезапуск
+)
+
+// StateStore — хранилище разделяемого состояния канального уровня (кэш обнаружения дубликатов,
+// состояние ARQ, реестр сегментов, соединения и согласованные параметры звена). При размещении
+// в Redis несколько экземпляров за балансировщиком нагрузки ведут себя как один логический канал.
+type StateStore interface {
+	// SetNX сохраняет значение, только если ключ отсутствует. Возвращает true, если значение записано.
+	SetNX(key, value string, ttl time.Duration) (bool, error)
+	// Set сохраняет значение (перезаписывая существующее). Нулевой ttl означает хранение без срока.
+	Set(key, value string, ttl time.Duration) error
+	// Get возвращает значение и признак его наличия.
+	Get(key string) (string, bool, error)
+	// Delete удаляет ключ.
+	Delete(key string) error
+	// Keys возвращает ключи, начинающиеся с prefix.
+	Keys(prefix string) ([]string, error)
+}
+
+// NewStateStore создает хранилище состояния согласно конфигурации.
+func NewStateStore(cfg StateConfig) (StateStore, error) {
+	switch cfg.Backend {
+	case "", StateBackendMemory:
+		log.Println("StateStore: Состояние хранится в памяти процесса")
+		return newMemoryStateStore(), nil
+	case StateBackendRedis:
+		log.Printf("StateStore: Состояние хранится в Redis (%s, база %d, префикс ключей '%s')", cfg.RedisAddr, cfg.RedisDB, cfg.KeyPrefix)
+		return newRedisStateStore(cfg), nil
+	case StateBackendFile:
+		log.Printf("StateStore: Состояние хранится в файле %s", cfg.FilePath)
+		return newFileStateStore(cfg.FilePath)
+	default:
+		return nil, fmt.Errorf("неизвестное хранилище состояния '%s' (допустимо: %s, %s, %s)", cfg.Backend, StateBackendMemory, StateBackendRedis, StateBackendFile)
+	}
+}
+
+// memoryEntry — значение в памяти вместе со сроком хранения.
+type memoryEntry struct {
+	value     string
+	expiresAt time.Time // Нулевое время — без срока хранения
+}
+
+// expired сообщает, истек ли срок хранения значения.
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// memoryStateStore хранит состояние в памяти процесса.
+type memoryStateStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	writes  int // Счетчик записей для периодической очистки устаревших значений
+}
+
+func newMemoryStateStore() *memoryStateStore {
+	return &memoryStateStore{entries: make(map[string]memoryEntry)}
+}
+
+// expiry вычисляет момент истечения срока хранения.
+func expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+// setLocked записывает значение и время от времени удаляет устаревшие записи.
+func (m *memoryStateStore) setLocked(key, value string, ttl time.Duration) {
+	m.entries[key] = memoryEntry{value: value, expiresAt: expiry(ttl)}
+	m.writes++
+	if m.writes%1024 == 0 {
+		now := time.Now()
+		for k, e := range m.entries {
+			if e.expired(now) {
+				delete(m.entries, k)
+			}
+		}
+	}
+}
+
+func (m *memoryStateStore) SetNX(key, value string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[key]; ok && !e.expired(time.Now()) {
+		return false, nil
+	}
+	m.setLocked(key, value, ttl)
+	return true, nil
+}
+
+func (m *memoryStateStore) Set(key, value string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setLocked(key, value, ttl)
+	return nil
+}
+
+func (m *memoryStateStore) Get(key string) (string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok || e.expired(time.Now()) {
+		return "", false, nil
+	}
+	return e.value, true, nil
+}
+
+func (m *memoryStateStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
+
+func (m *memoryStateStore) Keys(prefix string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	var keys []string
+	for key, e := range m.entries {
+		if !e.expired(now) && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// StateEntry — значение хранилища состояния в ответе конечной точки просмотра.
+type StateEntry struct {
+	Key       string     `json:"key"`
+	Value     string     `json:"value"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Entries возвращает актуальные значения, ключи которых начинаются с prefix, упорядоченные по ключу.
+func (m *memoryStateStore) Entries(prefix string) []StateEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	entries := make([]StateEntry, 0)
+	for key, e := range m.entries {
+		if e.expired(now) || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		entry := StateEntry{Key: key, Value: e.value}
+		if !e.expiresAt.IsZero() {
+			expiresAt := e.expiresAt.UTC()
+			entry.ExpiresAt = &expiresAt
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries
+}
+
+// StateSnapshot — ответ конечной точки просмотра хранилища состояния.
+type StateSnapshot struct {
+	Backend string                 `json:"backend"`
+	File    map[string]interface{} `json:"file,omitempty"` // Сведения о журнале файлового хранилища
+	Count   int                    `json:"count"`
+	Entries []StateEntry           `json:"entries"`
+}
+
+// handleState возвращает содержимое хранилища состояния (GET /debug/state?prefix=...).
+// POST /debug/state/compact немедленно сжимает журнал файлового хранилища.
+// Хранилище в Redis просматривается средствами самого Redis.
+func handleState(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Path == StateEndpoint+"/compact" {
+		if r.Method != http.MethodPost {
+			sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+			return
+		}
+		fs, ok := stateStore.(*fileStateStore)
+		if !ok {
+			sendErrorResponse(w, "Сжатие поддерживается только файловым хранилищем состояния", http.StatusConflict)
+			return
+		}
+		if err := fs.Compact(); err != nil {
+			sendErrorResponse(w, fmt.Sprintf("Не удалось сжать файл состояния: %v", err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(StateSnapshot{Backend: StateBackendFile, File: fs.FileInfo(), Entries: []StateEntry{}})
+		return
+	}
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+		return
+	}
+	prefix := r.URL.Query().Get("prefix")
+	var snapshot StateSnapshot
+	switch store := stateStore.(type) {
+	case *memoryStateStore:
+		snapshot = StateSnapshot{Backend: StateBackendMemory, Entries: store.Entries(prefix)}
+	case *fileStateStore:
+		snapshot = StateSnapshot{Backend: StateBackendFile, File: store.FileInfo(), Entries: store.Entries(prefix)}
+	default:
+		sendErrorResponse(w, "Просмотр поддерживается только для хранилищ memory и file", http.StatusNotImplemented)
+		return
+	}
+	snapshot.Count = len(snapshot.Entries)
+	json.NewEncoder(w).Encode(snapshot)
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// Операции журнала файлового хранилища состояния.
+const (
+	fileStateOpSet    = "set"
+	fileStateOpDelete = "del"
+)
+
+// fileStateCompactMin — минимальное число записей журнала, начиная с которого выполняется сжатие.
+const fileStateCompactMin = 1024
+
+// fileStateRecord — одна запись журнала файлового хранилища.
+type fileStateRecord struct {
+	Op        string     `json:"op"`
+	Key       string     `json:"key"`
+	Value     string     `json:"value,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// fileStateStore — встроенное хранилище состояния в файле: значения хранятся в памяти,
+// а каждое изменение дописывается в журнал, который воспроизводится при запуске, поэтому
+// состояние (например, кэш доставленных сегментов) переживает перезапуск процесса.
+// Когда журнал становится вдвое длиннее числа актуальных записей, он сжимается:
+// актуальные значения переписываются в новый файл, который атомарно заменяет старый.
+type fileStateStore struct {
+	*memoryStateStore
+	path        string
+	file        *os.File
+	records     int // Число записей в журнале
+	compactions int // Число выполненных сжатий
+}
+
+// newFileStateStore открывает файловое хранилище и восстанавливает состояние из журнала.
+func newFileStateStore(path string) (*fileStateStore, error) {
+	fs := &fileStateStore{memoryStateStore: newMemoryStateStore(), path: path}
+	if err := fs.replay(); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть файл состояния %s: %w", path, err)
+	}
+	fs.file = file
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.maybeCompactLocked()
+	return fs, nil
+}
+
+// replay воспроизводит журнал в памяти. Отсутствующий файл означает пустое состояние.
+func (fs *fileStateStore) replay() error {
+	file, err := os.Open(fs.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("не удалось прочитать файл состояния %s: %w", fs.path, err)
+	}
+	defer file.Close()
+
+	now := time.Now()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var rec fileStateRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// Последняя запись могла быть записана не полностью при аварийном завершении
+			log.Printf("StateStore WARNING: Пропущена поврежденная запись файла состояния %s: %v", fs.path, err)
+			continue
+		}
+		fs.records++
+		switch rec.Op {
+		case fileStateOpSet:
+			entry := memoryEntry{value: rec.Value}
+			if rec.ExpiresAt != nil {
+				entry.expiresAt = *rec.ExpiresAt
+			}
+			if entry.expired(now) {
+				delete(fs.entries, rec.Key)
+				continue
+			}
+			fs.entries[rec.Key] = entry
+		case fileStateOpDelete:
+			delete(fs.entries, rec.Key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("не удалось прочитать файл состояния %s: %w", fs.path, err)
+	}
+	log.Printf("StateStore: Восстановлено %d значений из %s (%d записей журнала)", len(fs.entries), fs.path, fs.records)
+	return nil
+}
+
+// appendLocked дописывает запись в журнал. Вызывается под блокировкой fs.mu.
+// Ошибка означает, что изменение не переживет перезапуск.
+func (fs *fileStateStore) appendLocked(rec fileStateRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать запись состояния %s: %w", rec.Key, err)
+	}
+	if _, err := fs.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("не удалось записать в файл состояния %s: %w", fs.path, err)
+	}
+	fs.records++
+	fs.maybeCompactLocked()
+	return nil
+}
+
+// updateLocked применяет изменение значения key в памяти и записывает его в журнал. Если запись
+// в журнал не удалась, прежнее значение восстанавливается, чтобы память не расходилась с журналом.
+// Вызывается под блокировкой fs.mu.
+func (fs *fileStateStore) updateLocked(key string, apply func() fileStateRecord) error {
+	prev, had := fs.entries[key]
+	if err := fs.appendLocked(apply()); err != nil {
+		if had {
+			fs.entries[key] = prev
+		} else {
+			delete(fs.entries, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// setRecord формирует запись журнала для значения из памяти.
+func setRecord(key string, e memoryEntry) fileStateRecord {
+	rec := fileStateRecord{Op: fileStateOpSet, Key: key, Value: e.value}
+	if !e.expiresAt.IsZero() {
+		expiresAt := e.expiresAt.UTC()
+		rec.ExpiresAt = &expiresAt
+	}
+	return rec
+}
+
+// maybeCompactLocked сжимает журнал, если он заметно длиннее числа актуальных значений.
+// Вызывается под блокировкой fs.mu.
+func (fs *fileStateStore) maybeCompactLocked() {
+	if fs.records < fileStateCompactMin || fs.records < 2*len(fs.entries) {
+		return
+	}
+	if err := fs.compactLocked(); err != nil {
+		log.Printf("StateStore ERROR: Не удалось сжать файл состояния %s: %v", fs.path, err)
+	}
+}
+
+// compactLocked переписывает журнал, оставляя только актуальные значения.
+func (fs *fileStateStore) compactLocked() error {
+	tmpPath := fs.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(tmp)
+	now := time.Now()
+	records := 0
+	for key, e := range fs.entries {
+		if e.expired(now) {
+			delete(fs.entries, key)
+			continue
+		}
+		data, err := json.Marshal(setRecord(key, e))
+		if err != nil {
+			continue
+		}
+		writer.Write(append(data, '\n'))
+		records++
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	tmp.Close()
+	if err := os.Rename(tmpPath, fs.path); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(fs.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	fs.file.Close()
+	fs.file = file
+	log.Printf("StateStore: Файл состояния %s сжат: %d -> %d записей", fs.path, fs.records, records)
+	fs.records = records
+	fs.compactions++
+	return nil
+}
+
+func (fs *fileStateStore) SetNX(key, value string, ttl time.Duration) (bool, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if e, ok := fs.entries[key]; ok && !e.expired(time.Now()) {
+		return false, nil
+	}
+	err := fs.updateLocked(key, func() fileStateRecord {
+		fs.setLocked(key, value, ttl)
+		return setRecord(key, fs.entries[key])
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (fs *fileStateStore) Set(key, value string, ttl time.Duration) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.updateLocked(key, func() fileStateRecord {
+		fs.setLocked(key, value, ttl)
+		return setRecord(key, fs.entries[key])
+	})
+}
+
+func (fs *fileStateStore) Delete(key string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.updateLocked(key, func() fileStateRecord {
+		delete(fs.entries, key)
+		return fileStateRecord{Op: fileStateOpDelete, Key: key}
+	})
+}
+
+// Compact сжимает журнал немедленно.
+func (fs *fileStateStore) Compact() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.compactLocked()
+}
+
+// FileInfo возвращает сведения о журнале для конечной точки просмотра состояния.
+func (fs *fileStateStore) FileInfo() map[string]interface{} {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return map[string]interface{}{
+		"path":        fs.path,
+		"records":     fs.records,
+		"compactions": fs.compactions,
+	}
+}
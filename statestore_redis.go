@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redisIOTimeout = 2 * time.Second // Таймаут сетевых операций с Redis
+
+// errRedisNil соответствует отсутствующему значению (nil bulk string) в ответе Redis.
+var errRedisNil = errors.New("redis: nil")
+
+// redisStateStore хранит состояние в Redis. Используется минимальный клиент протокола RESP
+// поверх одного TCP-соединения, которое восстанавливается при ошибках ввода-вывода.
+type redisStateStore struct {
+	mu       sync.Mutex
+	addr     string
+	password string
+	db       int
+	prefix   string
+	conn     net.Conn
+	reader   *bufio.Reader
+}
+
+func newRedisStateStore(cfg StateConfig) *redisStateStore {
+	return &redisStateStore{
+		addr:     cfg.RedisAddr,
+		password: cfg.RedisPassword,
+		db:       cfg.RedisDB,
+		prefix:   cfg.KeyPrefix,
+	}
+}
+
+// connectLocked устанавливает соединение с Redis, выполняя AUTH и SELECT при необходимости.
+func (r *redisStateStore) connectLocked() error {
+	conn, err := net.DialTimeout("tcp", r.addr, redisIOTimeout)
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к Redis %s: %w", r.addr, err)
+	}
+	r.conn = conn
+	r.reader = bufio.NewReader(conn)
+
+	if r.password != "" {
+		if _, err := r.doLocked("AUTH", r.password); err != nil {
+			r.closeLocked()
+			return fmt.Errorf("ошибка аутентификации в Redis: %w", err)
+		}
+	}
+	if r.db != 0 {
+		if _, err := r.doLocked("SELECT", strconv.Itoa(r.db)); err != nil {
+			r.closeLocked()
+			return fmt.Errorf("не удалось выбрать базу Redis %d: %w", r.db, err)
+		}
+	}
+	return nil
+}
+
+// closeLocked закрывает текущее соединение.
+func (r *redisStateStore) closeLocked() {
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+		r.reader = nil
+	}
+}
+
+// do выполняет команду Redis, при необходимости (пере)подключаясь.
+func (r *redisStateStore) do(args ...string) (interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn == nil {
+		if err := r.connectLocked(); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := r.doLocked(args...)
+	var redisErr redisError
+	if err != nil && !errors.Is(err, errRedisNil) && !errors.As(err, &redisErr) {
+		// Ошибка ввода-вывода: соединение в неизвестном состоянии, восстановим его при следующем вызове
+		r.closeLocked()
+	}
+	return reply, err
+}
+
+// doLocked отправляет команду в формате RESP и читает ответ.
+func (r *redisStateStore) doLocked(args ...string) (interface{}, error) {
+	r.conn.SetDeadline(time.Now().Add(redisIOTimeout))
+
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	if _, err := r.conn.Write(buf); err != nil {
+		return nil, err
+	}
+	return r.readReply()
+}
+
+// redisError — ошибка, возвращенная сервером Redis (ответ вида "-ERR ...").
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// readReply читает один ответ в формате RESP2.
+func (r *redisStateStore) readReply() (interface{}, error) {
+	line, err := r.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: некорректная строка ответа %q", line)
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, errRedisNil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r.reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, errRedisNil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			items[i], err = r.readReply()
+			if err != nil && !errors.Is(err, errRedisNil) {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: неизвестный тип ответа %q", line)
+	}
+}
+
+// setArgs формирует аргументы команды SET с учетом срока хранения.
+func (r *redisStateStore) setArgs(key, value string, ttl time.Duration, extra ...string) []string {
+	args := []string{"SET", r.prefix + key, value}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	return append(args, extra...)
+}
+
+func (r *redisStateStore) SetNX(key, value string, ttl time.Duration) (bool, error) {
+	_, err := r.do(r.setArgs(key, value, ttl, "NX")...)
+	if errors.Is(err, errRedisNil) {
+		return false, nil // Ключ уже существует
+	}
+	return err == nil, err
+}
+
+func (r *redisStateStore) Set(key, value string, ttl time.Duration) error {
+	_, err := r.do(r.setArgs(key, value, ttl)...)
+	return err
+}
+
+func (r *redisStateStore) Get(key string) (string, bool, error) {
+	reply, err := r.do("GET", r.prefix+key)
+	if errors.Is(err, errRedisNil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	value, _ := reply.(string)
+	return value, true, nil
+}
+
+func (r *redisStateStore) Delete(key string) error {
+	_, err := r.do("DEL", r.prefix+key)
+	return err
+}
+
+// redisGlobEscaper экранирует символы шаблона MATCH команды SCAN.
+var redisGlobEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+func (r *redisStateStore) Keys(prefix string) ([]string, error) {
+	pattern := redisGlobEscaper.Replace(r.prefix+prefix) + "*"
+	var keys []string
+	cursor := "0"
+	for {
+		reply, err := r.do("SCAN", cursor, "MATCH", pattern, "COUNT", "100")
+		if err != nil {
+			return nil, err
+		}
+		items, ok := reply.([]interface{})
+		if !ok || len(items) != 2 {
+			return nil, fmt.Errorf("redis: некорректный ответ SCAN")
+		}
+		cursor, _ = items[0].(string)
+		batch, _ := items[1].([]interface{})
+		for _, item := range batch {
+			if key, ok := item.(string); ok {
+				keys = append(keys, strings.TrimPrefix(key, r.prefix))
+			}
+		}
+		if cursor == "0" || cursor == "" {
+			return keys, nil
+		}
+	}
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	StatsEndpoint         = "/stats"          // Конечная точка с текущей статистикой
+	StatsHistoryEndpoint  = "/stats/history"  // Конечная точка с сохраненной поминутной статистикой
+	StatsSegmentsEndpoint = "/stats/segments" // Конечная точка с сохраненными результатами отдельных сегментов
+)
+
+// Возможные итоги обработки сегмента.
+const (
+	OutcomeDelivered     = "delivered"      // Сегмент обработан и успешно передан на /transfer
+	OutcomeLost          = "lost"           // Кадр потерян в канале
+	OutcomeChannelError  = "channel_error"  // Обнаружена неисправимая ошибка канала
+	OutcomeForwardFailed = "forward_failed" // Сегмент обработан, но передача на /transfer не удалась
+	OutcomeRejected      = "rejected"       // Сегмент отклонен до обработки (например, по квоте)
+	OutcomeDuplicate     = "duplicate"      // Сегмент уже был доставлен ранее, повторно не передавался
+	OutcomeRecovered     = "recovered"      // Потерянный сегмент восстановлен по кадру четности и передан на /transfer
+)
+
+// StatsCounters содержит счетчики итогов обработки сегментов.
+type StatsCounters struct {
+	Received         int64 `json:"received"`           // Сегментов принято к обработке
+	Delivered        int64 `json:"delivered"`          // Сегментов успешно передано на /transfer
+	Lost             int64 `json:"lost"`               // Кадров потеряно в канале
+	ChannelErrors    int64 `json:"channel_errors"`     // Сегментов с неисправимой ошибкой канала
+	ForwardFailed    int64 `json:"forward_failed"`     // Сегментов, которые не удалось передать на /transfer
+	Rejected         int64 `json:"rejected"`           // Сегментов, отклоненных до обработки
+	Duplicates       int64 `json:"duplicates"`         // Повторно присланных уже доставленных сегментов
+	Recovered        int64 `json:"recovered"`          // Потерянных сегментов, восстановленных по кадру четности
+	PayloadBytes     int64 `json:"payload_bytes"`      // Суммарный объем исходной полезной нагрузки (байт)
+	PayloadBits      int64 `json:"payload_bits"`       // Суммарная длина исходной полезной нагрузки кадров, переданных в канал (бит)
+	InfoBits         int64 `json:"info_bits"`          // Суммарная длина информационной части кадров (бит, после паддинга)
+	EncodedBits      int64 `json:"encoded_bits"`       // Суммарная длина переданных в канал кадров (бит)
+	ChannelBitErrors int64 `json:"channel_bit_errors"` // Бит, искаженных в канале
+	DecoderBitErrors int64 `json:"decoder_bit_errors"` // Ошибочных бит на входе декодера (после обращения этапов обработки потока)
+	FCSFailures      int64 `json:"fcs_failures"`       // Кадров, контрольная последовательность которых не совпала после декодирования
+	Retries          int64 `json:"retries"`            // Внутренних повторных передач кадров
+	RetriedSegments  int64 `json:"retried_segments"`   // Сегментов, кадр которых передавался повторно
+}
+
+// add учитывает в счетчиках итог обработки одного сегмента.
+func (c *StatsCounters) add(rec SegmentOutcomeRecord) {
+	outcome := rec.Outcome
+	if outcome != OutcomeRejected && outcome != OutcomeRecovered {
+		c.Received++
+		c.PayloadBytes += int64(rec.PayloadBytes)
+	}
+	if rec.EncodedBits > 0 {
+		c.PayloadBits += int64(rec.PayloadBytes * 8)
+	}
+	c.InfoBits += int64(rec.InfoBits)
+	c.EncodedBits += int64(rec.EncodedBits)
+	c.ChannelBitErrors += int64(rec.ChannelBitErrors)
+	c.DecoderBitErrors += int64(rec.DecoderBitErrors)
+	if rec.FCSFailed {
+		c.FCSFailures++
+	}
+	if rec.Retries > 0 {
+		c.Retries += int64(rec.Retries)
+		c.RetriedSegments++
+	}
+	switch outcome {
+	case OutcomeDelivered:
+		c.Delivered++
+	case OutcomeLost:
+		c.Lost++
+	case OutcomeChannelError:
+		c.ChannelErrors++
+	case OutcomeForwardFailed:
+		c.ForwardFailed++
+	case OutcomeRejected:
+		c.Rejected++
+	case OutcomeDuplicate:
+		c.Duplicates++
+	case OutcomeRecovered:
+		c.Recovered++
+	}
+}
+
+// sub возвращает разность счетчиков (прирост относительно prev).
+func (c StatsCounters) sub(prev StatsCounters) StatsCounters {
+	return StatsCounters{
+		Received:         c.Received - prev.Received,
+		Delivered:        c.Delivered - prev.Delivered,
+		Lost:             c.Lost - prev.Lost,
+		ChannelErrors:    c.ChannelErrors - prev.ChannelErrors,
+		ForwardFailed:    c.ForwardFailed - prev.ForwardFailed,
+		Rejected:         c.Rejected - prev.Rejected,
+		Duplicates:       c.Duplicates - prev.Duplicates,
+		Recovered:        c.Recovered - prev.Recovered,
+		PayloadBytes:     c.PayloadBytes - prev.PayloadBytes,
+		PayloadBits:      c.PayloadBits - prev.PayloadBits,
+		InfoBits:         c.InfoBits - prev.InfoBits,
+		EncodedBits:      c.EncodedBits - prev.EncodedBits,
+		ChannelBitErrors: c.ChannelBitErrors - prev.ChannelBitErrors,
+		DecoderBitErrors: c.DecoderBitErrors - prev.DecoderBitErrors,
+		FCSFailures:      c.FCSFailures - prev.FCSFailures,
+		Retries:          c.Retries - prev.Retries,
+		RetriedSegments:  c.RetriedSegments - prev.RetriedSegments,
+	}
+}
+
+// Efficiency возвращает показатели эффективности кодирования по накопленным счетчикам
+// (nil, если ни один кадр не был передан в канал).
+func (c StatsCounters) Efficiency() *CodingEfficiency {
+	if c.EncodedBits == 0 {
+		return nil
+	}
+	efficiency := &CodingEfficiency{
+		CodeRate:            float64(c.InfoBits) / float64(c.EncodedBits),
+		RedundancyBytes:     float64(c.EncodedBits-c.InfoBits) / 8,
+		EffectiveThroughput: float64(c.PayloadBits) / float64(c.EncodedBits),
+	}
+	if c.ChannelBitErrors > 0 {
+		efficiency.ErrorMultiplication = float64(c.DecoderBitErrors) / float64(c.ChannelBitErrors)
+	}
+	return efficiency
+}
+
+// isZero сообщает, что в счетчиках не учтено ни одного сегмента.
+func (c StatsCounters) isZero() bool {
+	return c == StatsCounters{}
+}
+
+// CodingEfficiency — показатели эффективности кодирования.
+type CodingEfficiency struct {
+	CodeRate            float64 `json:"code_rate"`            // Доля информационных бит в переданных кадрах (k/n)
+	RedundancyBytes     float64 `json:"redundancy_bytes"`     // Объем избыточности, добавленной кодированием (байт)
+	EffectiveThroughput float64 `json:"effective_throughput"` // Доля бит исходной полезной нагрузки (без паддинга) в переданных кадрах
+	// ErrorMultiplication — отношение числа ошибочных бит на входе декодера к числу ошибок в канале
+	// (больше 1, если этапы обработки потока размножают ошибки; 0, если ошибок в канале не было).
+	ErrorMultiplication float64 `json:"error_multiplication,omitempty"`
+}
+
+// CodingStats — накладные расходы кодирования для одного сегмента.
+type CodingStats struct {
+	Codec       string `json:"codec,omitempty"` // Имя кодека (пусто, если кодирование не выполнялось)
+	N           int    `json:"n"`               // Длина кодового слова
+	K           int    `json:"k"`               // Число информационных бит в кодовом слове
+	PayloadBits int    `json:"payload_bits"`    // Длина исходной полезной нагрузки (бит, без паддинга)
+	InfoBits    int    `json:"info_bits"`       // Длина информационной части кадра (бит, после паддинга)
+	EncodedBits int    `json:"encoded_bits"`    // Длина переданного в канал кадра (бит)
+	CodingEfficiency
+}
+
+// newCodingStats вычисляет накладные расходы кодирования сегмента по отчету канала
+// (nil, если кадр не был передан в канал).
+func newCodingStats(payloadBytes int, report ChannelReport) *CodingStats {
+	if report.EncodedBits == 0 {
+		return nil
+	}
+	counters := StatsCounters{PayloadBits: int64(payloadBytes * 8), InfoBits: int64(report.InfoBits), EncodedBits: int64(report.EncodedBits)}
+	return &CodingStats{
+		Codec:            report.Codec,
+		N:                report.CodeN,
+		K:                report.CodeK,
+		PayloadBits:      payloadBytes * 8,
+		InfoBits:         report.InfoBits,
+		EncodedBits:      report.EncodedBits,
+		CodingEfficiency: *counters.Efficiency(),
+	}
+}
+
+// MinuteStats — агрегированная статистика за одну минуту.
+type MinuteStats struct {
+	Minute time.Time `json:"minute"` // Начало минуты (UTC)
+	StatsCounters
+}
+
+// SegmentOutcomeRecord — итог обработки одного сегмента (сохраняется, если включено в конфигурации).
+type SegmentOutcomeRecord struct {
+	Time             time.Time `json:"time"`
+	Sender           string    `json:"sender"`
+	SendTime         string    `json:"send_time"`
+	SegmentNumber    int       `json:"segment_number"`
+	TotalSegments    int       `json:"total_segments"`
+	PayloadBytes     int       `json:"payload_bytes"`
+	Outcome          string    `json:"outcome"`
+	DurationMs       float64   `json:"duration_ms"`                  // Время обработки запроса /code
+	InfoBits         int       `json:"info_bits,omitempty"`          // Длина информационной части кадра (бит)
+	EncodedBits      int       `json:"encoded_bits,omitempty"`       // Длина переданного в канал кадра (бит)
+	ChannelBitErrors int       `json:"channel_bit_errors,omitempty"` // Бит, искаженных в канале
+	DecoderBitErrors int       `json:"decoder_bit_errors,omitempty"` // Ошибочных бит на входе декодера
+	FCSFailed        bool      `json:"fcs_failed,omitempty"`         // Контрольная последовательность кадра не совпала
+	Retries          int       `json:"retries,omitempty"`            // Внутренних повторных передач кадра
+	// Impairments — случайные решения канала (для проверки соответствия заявленным вероятностям)
+	Impairments *ImpairmentRecord `json:"impairments,omitempty"`
+	Arm         string            `json:"arm,omitempty"` // Вариант A/B-эксперимента
+}
+
+// StatsSnapshot — текущее состояние статистики, возвращаемое на /stats.
+type StatsSnapshot struct {
+	StartedAt     time.Time         `json:"started_at"`
+	UptimeSeconds float64           `json:"uptime_seconds"`
+	Total         StatsCounters     `json:"total"`                 // С момента запуска
+	CurrentMinute MinuteStats       `json:"current_minute"`        // Текущая (еще не сохраненная) минута
+	Queue         *QueueStats       `json:"queue,omitempty"`       // Состояние очереди обработки
+	Overload      *OverloadStatus   `json:"overload,omitempty"`    // Режим работы (нормальный / деградация)
+	Efficiency    *CodingEfficiency `json:"efficiency,omitempty"`  // Эффективность кодирования с момента запуска
+	Puncture      *PunctureStats    `json:"puncture,omitempty"`    // Выкалывание и итоговая скорость кода
+	FEC           *FECStats         `json:"fec,omitempty"`         // Межкадровая коррекция потерь
+	ARQ           *ARQStats         `json:"arq,omitempty"`         // Повторные передачи кадров
+	Flow          *FlowStats        `json:"flow,omitempty"`        // Управление потоком кредитами
+	Sequence      *SequenceStats    `json:"sequence,omitempty"`    // Порядковые номера кадров
+	Downstream    *DownstreamStats  `json:"downstream,omitempty"`  // Доступность транспортного уровня
+	Retransmit    *RetransmitStats  `json:"retransmit,omitempty"`  // Буфер повторной передачи
+	Jitter        *JitterStats      `json:"jitter,omitempty"`      // Состояние буфера джиттера
+	Impairments   []ImpairmentStats `json:"impairments,omitempty"` // Статистика звеньев цепочки искажений
+	AB            []ABArmStats      `json:"ab,omitempty"`          // Статистика вариантов A/B-эксперимента
+}
+
+// Statistics собирает статистику обработки сегментов: общие счетчики с момента запуска
+// и поминутные агрегаты, которые по окончании каждой минуты сохраняются в StatsStore.
+type Statistics struct {
+	mu             sync.Mutex
+	startedAt      time.Time
+	total          StatsCounters
+	minute         MinuteStats
+	store          *StatsStore // nil, если сохранение отключено
+	recordSegments bool        // Сохранять ли итоги отдельных сегментов
+}
+
+// NewStatistics создает сборщик статистики. Если store равен nil, статистика хранится только в памяти.
+func NewStatistics(store *StatsStore, recordSegments bool) *Statistics {
+	now := time.Now().UTC()
+	return &Statistics{
+		startedAt:      now,
+		minute:         MinuteStats{Minute: now.Truncate(time.Minute)},
+		store:          store,
+		recordSegments: recordSegments && store != nil,
+	}
+}
+
+// Record учитывает итог обработки одного сегмента.
+func (s *Statistics) Record(rec SegmentOutcomeRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rollMinuteLocked(rec.Time)
+	s.total.add(rec)
+	s.minute.add(rec)
+
+	if s.recordSegments {
+		if err := s.store.AppendSegment(rec); err != nil {
+			log.Printf("Statistics ERROR: Не удалось сохранить итог сегмента #%d/%d: %v", rec.SegmentNumber, rec.TotalSegments, err)
+		}
+	}
+}
+
+// rollMinuteLocked сохраняет завершившуюся минуту и начинает новую, если now относится к следующей минуте.
+// Вызывается под блокировкой s.mu.
+func (s *Statistics) rollMinuteLocked(now time.Time) {
+	current := now.UTC().Truncate(time.Minute)
+	if !current.After(s.minute.Minute) {
+		return
+	}
+	if s.store != nil && !s.minute.isZero() {
+		if err := s.store.AppendMinute(s.minute); err != nil {
+			log.Printf("Statistics ERROR: Не удалось сохранить статистику за %s: %v", s.minute.Minute.Format(time.RFC3339), err)
+		}
+	}
+	s.minute = MinuteStats{Minute: current}
+}
+
+// Run периодически сохраняет завершившиеся минуты, даже если новых сегментов не поступает.
+// Блокирует вызывающую горутину до остановки ticker-а вместе с процессом.
+func (s *Statistics) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		s.mu.Lock()
+		s.rollMinuteLocked(now)
+		s.mu.Unlock()
+	}
+}
+
+// Flush сохраняет текущую (незавершенную) минуту. Вызывается при остановке процесса.
+func (s *Statistics) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.store != nil && !s.minute.isZero() {
+		if err := s.store.AppendMinute(s.minute); err != nil {
+			log.Printf("Statistics ERROR: Не удалось сохранить статистику за %s: %v", s.minute.Minute.Format(time.RFC3339), err)
+		}
+	}
+	s.minute = MinuteStats{Minute: s.minute.Minute}
+}
+
+// Snapshot возвращает копию текущей статистики.
+func (s *Statistics) Snapshot() StatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	s.rollMinuteLocked(now)
+	return StatsSnapshot{
+		StartedAt:     s.startedAt,
+		UptimeSeconds: now.Sub(s.startedAt).Seconds(),
+		Total:         s.total,
+		CurrentMinute: s.minute,
+		Efficiency:    s.total.Efficiency(),
+	}
+}
+
+var statistics *Statistics // Глобальный сборщик статистики
+
+// handleStats возвращает текущую статистику (GET /stats).
+func handleStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(currentStatsSnapshot())
+}
+
+// currentStatsSnapshot возвращает снимок статистики вместе с состоянием очереди, режима работы,
+// буфера джиттера и цепочки искажений.
+func currentStatsSnapshot() StatsSnapshot {
+	snapshot := statistics.Snapshot()
+	if processingQueue != nil {
+		queueStats := processingQueue.Stats()
+		snapshot.Queue = &queueStats
+	}
+	snapshot.Overload = overloadController.Status()
+	snapshot.Jitter = jitterBuffer.Stats()
+	snapshot.Impairments = channelLayer.Impairments.Stats()
+	snapshot.Puncture = channelLayer.PunctureStats()
+	snapshot.FEC = parityFEC.Stats()
+	snapshot.ARQ = linkARQ.Stats()
+	snapshot.Flow = flowControl.Stats()
+	snapshot.Sequence = linkSequencer.Stats()
+	snapshot.Downstream = downstream.Stats()
+	snapshot.Retransmit = retransmitBuffer.Stats()
+	snapshot.AB = abSplit.Stats()
+	return snapshot
+}
+
+// parseTimeRange извлекает из параметров запроса from/to (RFC3339).
+// По умолчанию возвращается последний час.
+func parseTimeRange(r *http.Request) (time.Time, time.Time, error) {
+	to := time.Now().UTC()
+	from := to.Add(-time.Hour)
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return from, to, err
+		}
+		from = t
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return from, to, err
+		}
+		to = t
+	}
+	return from, to, nil
+}
+
+// handleStatsHistory возвращает сохраненную поминутную статистику за интервал
+// (GET /stats/history?from=...&to=..., время в формате RFC3339).
+func handleStatsHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+		return
+	}
+	if statistics.store == nil {
+		sendErrorResponse(w, "Сохранение статистики отключено в конфигурации (stats.db_path)", http.StatusNotFound)
+		return
+	}
+	from, to, err := parseTimeRange(r)
+	if err != nil {
+		sendErrorResponse(w, fmt.Sprintf("Неверный параметр времени: %v. Ожидается формат RFC3339.", err), http.StatusBadRequest)
+		return
+	}
+
+	minutes, err := statistics.store.QueryMinutes(from, to)
+	if err != nil {
+		sendErrorResponse(w, fmt.Sprintf("Не удалось прочитать статистику: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Итог по интервалу вычисляется по сохраненным минутам
+	var total StatsCounters
+	for _, m := range minutes {
+		total.Received += m.Received
+		total.Delivered += m.Delivered
+		total.Lost += m.Lost
+		total.ChannelErrors += m.ChannelErrors
+		total.ForwardFailed += m.ForwardFailed
+		total.Rejected += m.Rejected
+		total.Duplicates += m.Duplicates
+		total.PayloadBytes += m.PayloadBytes
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from":    from,
+		"to":      to,
+		"total":   total,
+		"minutes": minutes,
+	})
+}
+
+// handleStatsSegments возвращает сохраненные итоги отдельных сегментов за интервал
+// (GET /stats/segments?from=...&to=...&sender=...&send_time=...&segment_number=...).
+// Отправитель, send_time и номер сегмента вместе идентифицируют сегмент.
+func handleStatsSegments(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+		return
+	}
+	if !statistics.recordSegments {
+		sendErrorResponse(w, "Сохранение итогов сегментов отключено в конфигурации (stats.record_segments)", http.StatusNotFound)
+		return
+	}
+	from, to, err := parseTimeRange(r)
+	if err != nil {
+		sendErrorResponse(w, fmt.Sprintf("Неверный параметр времени: %v. Ожидается формат RFC3339.", err), http.StatusBadRequest)
+		return
+	}
+
+	query := SegmentQuery{Sender: r.URL.Query().Get("sender"), SendTime: r.URL.Query().Get("send_time")}
+	if v := r.URL.Query().Get("segment_number"); v != "" {
+		query.SegmentNumber, err = strconv.Atoi(v)
+		if err != nil || query.SegmentNumber <= 0 {
+			sendErrorResponse(w, fmt.Sprintf("Неверный номер сегмента: '%s'", v), http.StatusBadRequest)
+			return
+		}
+	}
+
+	segments, err := statistics.store.QuerySegments(from, to, query)
+	if err != nil {
+		sendErrorResponse(w, fmt.Sprintf("Не удалось прочитать итоги сегментов: %v", err), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from":     from,
+		"to":       to,
+		"segments": segments,
+	})
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Типы записей в файле статистики.
+const (
+	statsRecordMinute  = "minute"
+	statsRecordSegment = "segment"
+)
+
+// statsRecord — одна строка файла статистики. Заполнено ровно одно из полей Minute или Segment.
+type statsRecord struct {
+	Type    string                `json:"type"`
+	Minute  *MinuteStats          `json:"minute,omitempty"`
+	Segment *SegmentOutcomeRecord `json:"segment,omitempty"`
+}
+
+// StatsStore — встроенное хранилище статистики в виде журнала JSON-записей (по одной на строку).
+// Записи только добавляются в конец файла, поэтому данные переживают перезапуск процесса,
+// а файл можно разбирать сторонними средствами (jq, pandas) после длительных экспериментов.
+type StatsStore struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// OpenStatsStore открывает (или создает) файл статистики по указанному пути.
+func OpenStatsStore(path string) (*StatsStore, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть файл статистики %s: %w", path, err)
+	}
+	return &StatsStore{path: path, file: file}, nil
+}
+
+// append дописывает запись в конец файла.
+func (st *StatsStore) append(rec statsRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	_, err = st.file.Write(data)
+	return err
+}
+
+// AppendMinute сохраняет агрегированную статистику за минуту.
+func (st *StatsStore) AppendMinute(m MinuteStats) error {
+	return st.append(statsRecord{Type: statsRecordMinute, Minute: &m})
+}
+
+// AppendSegment сохраняет итог обработки отдельного сегмента.
+func (st *StatsStore) AppendSegment(rec SegmentOutcomeRecord) error {
+	return st.append(statsRecord{Type: statsRecordSegment, Segment: &rec})
+}
+
+// scan последовательно читает все записи файла и передает их в fn.
+// Поврежденные строки (например, недописанные при аварийном завершении) пропускаются.
+func (st *StatsStore) scan(fn func(rec statsRecord)) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	file, err := os.Open(st.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec statsRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		fn(rec)
+	}
+	return scanner.Err()
+}
+
+// QueryMinutes возвращает поминутную статистику, начало минуты которой лежит в интервале [from, to].
+func (st *StatsStore) QueryMinutes(from, to time.Time) ([]MinuteStats, error) {
+	minutes := []MinuteStats{}
+	err := st.scan(func(rec statsRecord) {
+		if rec.Type != statsRecordMinute || rec.Minute == nil {
+			return
+		}
+		if rec.Minute.Minute.Before(from) || rec.Minute.Minute.After(to) {
+			return
+		}
+		minutes = append(minutes, *rec.Minute)
+	})
+	return minutes, err
+}
+
+// SegmentQuery — отбор итогов сегментов; пустые (нулевые) поля не ограничивают выборку.
+type SegmentQuery struct {
+	Sender        string
+	SendTime      string
+	SegmentNumber int
+}
+
+// QuerySegments возвращает итоги сегментов за интервал [from, to], удовлетворяющие отбору.
+func (st *StatsStore) QuerySegments(from, to time.Time, query SegmentQuery) ([]SegmentOutcomeRecord, error) {
+	segments := []SegmentOutcomeRecord{}
+	err := st.scan(func(rec statsRecord) {
+		if rec.Type != statsRecordSegment || rec.Segment == nil {
+			return
+		}
+		if rec.Segment.Time.Before(from) || rec.Segment.Time.After(to) {
+			return
+		}
+		if query.Sender != "" && rec.Segment.Sender != query.Sender {
+			return
+		}
+		if query.SendTime != "" && rec.Segment.SendTime != query.SendTime {
+			return
+		}
+		if query.SegmentNumber != 0 && rec.Segment.SegmentNumber != query.SegmentNumber {
+			return
+		}
+		segments = append(segments, *rec.Segment)
+	})
+	return segments, err
+}
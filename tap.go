@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Точки съема копий кадра (stage в сообщении ответвителя).
+const (
+	TapStagePreEncoding        = "pre_encoding"         // Полезная нагрузка, переданная в канал, до кодирования
+	TapStagePostErrorInjection = "post_error_injection" // Кадр после искажений в канале (биты, упакованные в байты)
+	TapStagePostDecoding       = "post_decoding"        // Полезная нагрузка после декодирования
+)
+
+const (
+	tapQueueSize = 1024            // Емкость очереди ответвителя по умолчанию (сообщений)
+	tapTimeout   = 2 * time.Second // Таймаут отправки одного сообщения
+)
+
+// TapFrame — копия кадра на одной из точек съема, отправляемая ответвителем.
+type TapFrame struct {
+	SegmentID     string    `json:"segment_id"`
+	Sender        string    `json:"sender"`
+	SendTime      string    `json:"send_time"`
+	SegmentNumber int       `json:"segment_number"`
+	TotalSegments int       `json:"total_segments"`
+	Stage         string    `json:"stage"` // Точка съема (см. TapStage*)
+	CapturedAt    time.Time `json:"captured_at"`
+	Data          []byte    `json:"data,omitempty"`         // Содержимое (base64); для кадра — биты, упакованные старшим битом вперед
+	Bits          int       `json:"bits,omitempty"`         // Длина кадра в битах (post_error_injection)
+	Lost          bool      `json:"lost,omitempty"`         // Кадр потерян в канале
+	FlippedBits   []int     `json:"flipped_bits,omitempty"` // Индексы бит, инвертированных в канале
+	ChannelError  bool      `json:"channel_error,omitempty"`
+	Decode        string    `json:"decode,omitempty"` // Итог декодирования
+}
+
+// FrameTap — ответвитель (монитор) кадров: копии кадра на каждой точке съема отправляются POST
+// запросами на url для внешних средств анализа. Отправка выполняется отдельной горутиной из очереди;
+// при переполненной очереди копии отбрасываются, так что ответвитель никогда не задерживает обработку
+// сегментов. Повторных попыток нет. Все методы допускают вызов на nil (ответвитель отключен).
+type FrameTap struct {
+	url     string
+	queue   chan TapFrame
+	client  *http.Client
+	dropped atomic.Int64 // Копии, отброшенные из-за переполнения очереди
+	failed  atomic.Int64 // Копии, которые не удалось отправить
+}
+
+// NewFrameTap создает ответвитель по конфигурации и запускает горутину отправки (nil, если url не задан).
+func NewFrameTap(cfg TapConfig) (*FrameTap, error) {
+	if cfg.URL == "" {
+		return nil, nil
+	}
+	if err := validateCallbackURL(cfg.URL); err != nil {
+		return nil, err
+	}
+	size := cfg.QueueSize
+	if size <= 0 {
+		size = tapQueueSize
+	}
+	t := &FrameTap{url: cfg.URL, queue: make(chan TapFrame, size), client: &http.Client{Timeout: tapTimeout}}
+	go t.run()
+	return t, nil
+}
+
+// run отправляет копии кадров из очереди.
+func (t *FrameTap) run() {
+	for frame := range t.queue {
+		body, err := json.Marshal(frame)
+		if err != nil {
+			continue
+		}
+		resp, err := t.client.Post(t.url, "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode/100 == 2 {
+				continue
+			}
+		}
+		// Ошибки журналируются только при первом сбое и далее на каждой сотой копии
+		if failed := t.failed.Add(1); failed == 1 || failed%100 == 0 {
+			status := "нет ответа"
+			if err == nil {
+				status = resp.Status
+			}
+			log.Printf("Tap ERROR: Не удалось отправить копию кадра на %s (%s, всего сбоев: %d)", t.url, status, failed)
+		}
+	}
+}
+
+// push ставит копию кадра в очередь отправки, не ожидая освобождения места.
+func (t *FrameTap) push(frame TapFrame) {
+	select {
+	case t.queue <- frame:
+	default:
+		if dropped := t.dropped.Add(1); dropped == 1 || dropped%100 == 0 {
+			log.Printf("Tap: Очередь ответвителя переполнена, копии кадров отбрасываются (всего: %d)", dropped)
+		}
+	}
+}
+
+// Mirror отправляет копии кадра сегмента на всех точках съема: полезную нагрузку до кодирования,
+// кадр после искажений и полезную нагрузку после декодирования (decoded — nil, если кадр потерян).
+func (t *FrameTap) Mirror(job *segmentJob, payload []byte, report ChannelReport, decoded *Segment) {
+	if t == nil {
+		return
+	}
+	req := job.Request
+	base := TapFrame{
+		SegmentID:     job.ID,
+		Sender:        req.Sender,
+		SendTime:      req.SendTime,
+		SegmentNumber: req.SegmentNumber,
+		TotalSegments: req.TotalSegments,
+		CapturedAt:    time.Now().UTC(),
+	}
+
+	pre := base
+	pre.Stage = TapStagePreEncoding
+	pre.Data = payload
+	t.push(pre)
+
+	rx := base
+	rx.Stage = TapStagePostErrorInjection
+	rx.Lost = report.Lost
+	rx.FlippedBits = report.FlippedBits
+	if report.RxFrame != nil {
+		rx.Data = packBits(report.RxFrame)
+		rx.Bits = len(report.RxFrame)
+	}
+	t.push(rx)
+
+	if decoded == nil {
+		return
+	}
+	post := base
+	post.Stage = TapStagePostDecoding
+	post.Data = decoded.Payload[:min(decoded.OriginalLength, len(decoded.Payload))]
+	post.ChannelError = decoded.IsChannelError
+	post.Decode = report.Decode
+	t.push(post)
+}
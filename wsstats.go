@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WSStatsEndpoint — конечная точка WebSocket с живой статистикой.
+const WSStatsEndpoint = "/ws/stats"
+
+// wsStatsInterval — период отправки обновлений статистики по WebSocket.
+const wsStatsInterval = time.Second
+
+// Параметры протокола WebSocket (RFC 6455).
+const (
+	wsAcceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+	wsOpPong  = 0xA
+
+	wsMaxControlPayload = 125     // Максимальная длина управляющего кадра
+	wsMaxClientPayload  = 1 << 16 // Максимальная длина принимаемого от клиента кадра
+)
+
+// WSStatsUpdate — сообщение живой статистики: полный снимок, прирост счетчиков с предыдущего
+// сообщения и новые события канального уровня и этапы сегментов.
+type WSStatsUpdate struct {
+	Time          time.Time          `json:"time"`
+	Stats         StatsSnapshot      `json:"stats"`
+	Delta         StatsCounters      `json:"delta"`          // Прирост счетчиков с предыдущего сообщения
+	Events        []Event            `json:"events"`         // Новые события канального уровня
+	SegmentEvents []SegmentFeedEvent `json:"segment_events"` // Новые этапы обработки сегментов
+}
+
+// wsConn — серверная сторона соединения WebSocket (только текстовые кадры без фрагментации).
+type wsConn struct {
+	mu   sync.Mutex // Защищает запись в соединение
+	conn net.Conn
+	rw   *bufio.ReadWriter
+}
+
+// wsUpgrade выполняет открывающее рукопожатие WebSocket и перехватывает соединение.
+func wsUpgrade(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") ||
+		!strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade") {
+		return nil, errors.New("ожидается запрос Upgrade: websocket")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, errors.New("поддерживается только версия протокола WebSocket 13")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, errors.New("отсутствует заголовок Sec-WebSocket-Key")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("перехват соединения не поддерживается")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha1.Sum([]byte(key + wsAcceptGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, rw: rw}, nil
+}
+
+// writeFrame отправляет кадр с заданным кодом операции (сервер не маскирует кадры).
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	header := []byte{0x80 | opcode}
+	switch {
+	case len(payload) <= 125:
+		header = append(header, byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(len(payload)))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(len(payload)))
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	c.rw.Write(header)
+	c.rw.Write(payload)
+	return c.rw.Flush()
+}
+
+// readFrame читает кадр клиента и снимает с него маску.
+func (c *wsConn) readFrame() (opcode byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.rw, head[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode = head[0] & 0x0F
+	if head[1]&0x80 == 0 {
+		return 0, nil, errors.New("кадр клиента не замаскирован")
+	}
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > wsMaxClientPayload || (opcode >= wsOpClose && length > wsMaxControlPayload) {
+		return 0, nil, errors.New("слишком длинный кадр клиента")
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.rw, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.rw, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
+
+// readLoop обрабатывает кадры клиента: отвечает на ping, завершает работу по close или ошибке.
+// Данные от клиента не ожидаются и игнорируются.
+func (c *wsConn) readLoop(done chan<- struct{}) {
+	defer close(done)
+	for {
+		opcode, payload, err := c.readFrame()
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case wsOpPing:
+			c.writeFrame(wsOpPong, payload)
+		case wsOpClose:
+			c.writeFrame(wsOpClose, payload)
+			return
+		}
+	}
+}
+
+// handleWSStats раз в секунду отправляет по WebSocket статистику, прирост счетчиков и новые
+// события (GET /ws/stats). Первое сообщение содержит накопленные события и этапы сегментов,
+// так что панель мониторинга сразу получает недавнюю историю.
+func handleWSStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		sendErrorResponse(w, "Метод не допускается", http.StatusMethodNotAllowed)
+		return
+	}
+	ws, err := wsUpgrade(w, r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		sendErrorResponse(w, "Не удалось установить соединение WebSocket: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer ws.conn.Close()
+	log.Printf("Web Server: Подключен клиент живой статистики %s", r.RemoteAddr)
+
+	done := make(chan struct{})
+	go ws.readLoop(done)
+
+	var lastEvent, lastSegmentEvent uint64
+	var prevTotal StatsCounters
+	send := func() error {
+		update := WSStatsUpdate{Time: time.Now().UTC(), Stats: currentStatsSnapshot()}
+		update.Delta = update.Stats.Total.sub(prevTotal)
+		prevTotal = update.Stats.Total
+		update.Events = eventLog.Since(lastEvent)
+		if n := len(update.Events); n > 0 {
+			lastEvent = update.Events[n-1].ID
+		}
+		update.SegmentEvents, lastSegmentEvent = segmentRegistry.FeedSince(lastSegmentEvent)
+		data, err := json.Marshal(update)
+		if err != nil {
+			return err
+		}
+		return ws.writeFrame(wsOpText, data)
+	}
+
+	ticker := time.NewTicker(wsStatsInterval)
+	defer ticker.Stop()
+	for err = send(); err == nil; err = send() {
+		select {
+		case <-done:
+			log.Printf("Web Server: Клиент живой статистики %s отключился", r.RemoteAddr)
+			return
+		case <-ticker.C:
+		}
+	}
+	log.Printf("Web Server ERROR: Не удалось отправить статистику клиенту %s: %v", r.RemoteAddr, err)
+}